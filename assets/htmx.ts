@@ -0,0 +1,10 @@
+import htmx from 'htmx.org';
+
+declare global {
+    interface Window {
+        htmx: typeof htmx;
+    }
+}
+
+// htmx extensions register themselves on the global htmx object.
+window.htmx = htmx;
@@ -1,6 +1,21 @@
-import 'htmx.org';
+import './htmx';
+import 'htmx.org/dist/ext/sse.js';
 import './main.css';
 
+// htmx does not swap error responses by default, but a conflict response
+// carries the conflict resolution fragment, an unprocessable entity the form
+// with its field errors and other errors are retargeted to the #errors region
+// of the page.
+const swappedErrorStatuses = [409, 422];
+
+document.body.addEventListener('htmx:beforeSwap', (event: Event & { detail?: { xhr: XMLHttpRequest, shouldSwap: boolean, isError: boolean } }) => {
+    const detail = event.detail;
+    if (detail && (swappedErrorStatuses.includes(detail.xhr.status) || detail.xhr.getResponseHeader('HX-Retarget') === '#errors')) {
+        detail.shouldSwap = true;
+        detail.isError = false;
+    }
+});
+
 const updateMealPlanDialog: HTMLDialogElement | null = document.querySelector('dialog#update-meal-day');
 
 const openUpdateDialog = (event: Event) => {
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"html/template"
+	"io/fs"
+	"meal-planning/database"
+	"meal-planning/domain"
+	myHttp "meal-planning/http"
+	"net/http"
+)
+
+type applicationConfig struct {
+	db      *sql.DB
+	backend backend
+	// household is who meals are planned for.
+	household domain.Household
+	// views holds the templates, build the esbuild output with manifest.json
+	// and the assets/ directory.
+	views           fs.FS
+	build           fs.FS
+	reloadTemplates bool
+}
+
+// application is the planner wired up against a migrated database. Tests
+// create it the same way main does.
+type application struct {
+	handler  http.Handler
+	eventBus *domain.EventBus
+	health   *healthHandler
+}
+
+func newApplication(config applicationConfig) (*application, error) {
+	myManifest, err := loadManifest(config.build, "manifest.json")
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := parseTemplates(config.views)
+	if err != nil {
+		return nil, err
+	}
+
+	appMetrics := newAppMetrics(config.db)
+
+	eventBus := domain.NewEventBus()
+	transactor := database.NewTransactor(config.db)
+	historyRepo := database.InstrumentHistoryRepository(config.backend.newHistoryRepository(config.db), appMetrics.observeQuery)
+
+	mealDayRepo := database.InstrumentMealDayRepository(config.backend.newMealDayRepository(config.db), appMetrics.observeQuery)
+	mealDayService := domain.NewMealDayService(mealDayRepo, historyRepo, transactor, eventBus, config.household)
+
+	foodRepo := database.InstrumentFoodRepository(config.backend.newFoodRepository(config.db), appMetrics.observeQuery)
+	foodService := domain.NewFoodService(foodRepo)
+
+	recipeRepo := database.InstrumentRecipeRepository(config.backend.newRecipeRepository(config.db), appMetrics.observeQuery)
+	recipeService := domain.NewRecipeService(recipeRepo, foodRepo)
+
+	nutritionRepo := database.InstrumentNutritionRepository(config.backend.newNutritionRepository(config.db), appMetrics.observeQuery)
+	diaryRepo := database.InstrumentDiaryRepository(config.backend.newDiaryRepository(config.db), appMetrics.observeQuery)
+	nutritionService := domain.NewNutritionService(nutritionRepo, diaryRepo, historyRepo, transactor, eventBus)
+
+	appMetrics.registerDomainGauges(mealDayService, nutritionService)
+
+	tmplHandler := templateHandler{
+		template: tmpl,
+		manifest: myManifest,
+	}
+	if config.reloadTemplates {
+		tmplHandler.reload = func() (*template.Template, error) {
+			return parseTemplates(config.views)
+		}
+	}
+
+	indexHandler := &indexHandler{
+		templateHandler: tmplHandler,
+		mealDayService:  mealDayService,
+	}
+
+	nutritionHandler := &nutritionHandler{
+		templateHandler:  tmplHandler,
+		nutritionService: nutritionService,
+		foodService:      foodService,
+		mealDayService:   mealDayService,
+		recipeService:    recipeService,
+	}
+
+	mealHandler := &mealHandler{
+		templateHandler:  tmplHandler,
+		mealDayService:   mealDayService,
+		recipeService:    recipeService,
+		nutritionService: nutritionService,
+	}
+
+	foodHandler := &foodHandler{
+		templateHandler: tmplHandler,
+		foodService:     foodService,
+	}
+
+	recipeHandler := &recipeHandler{
+		templateHandler: tmplHandler,
+		recipeService:   recipeService,
+		foodService:     foodService,
+	}
+
+	shoppingHandler := &shoppingHandler{
+		templateHandler: tmplHandler,
+		mealDayService:  mealDayService,
+		recipeService:   recipeService,
+	}
+
+	eventsHandler := &eventsHandler{
+		templateHandler: tmplHandler,
+		events:          eventBus,
+	}
+
+	healthHandler := &healthHandler{
+		db:      config.db,
+		backend: config.backend,
+	}
+
+	internalServerErrorHandler := &errorPageHandler{
+		templateHandler: tmplHandler,
+		statusCode:      http.StatusInternalServerError,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /assets/", newAssetsHandler(config.build, myManifest))
+	mux.HandleFunc("GET /meals", mealHandler.getMeals)
+	mux.HandleFunc("GET /meals/{date}", mealHandler.getMealByDate)
+	mux.HandleFunc("PUT /meals/{date}", mealHandler.updateMealByDate)
+	mux.HandleFunc("GET /meals/{date}/form", mealHandler.getMealFormByDate)
+	mux.HandleFunc("GET /meals/{date}/history", mealHandler.getMealHistoryByDate)
+	mux.HandleFunc("POST /meals/{date}/history/{id}/restore", mealHandler.restoreMealByDate)
+	mux.HandleFunc("GET /meals/{date}/eaten", mealHandler.getMealEatenByDate)
+	mux.HandleFunc("POST /meals/{date}/eaten", mealHandler.logMealAsEaten)
+	mux.HandleFunc("GET /nutrition/{date}", nutritionHandler.getNutritionEntryByDate)
+	mux.HandleFunc("PUT /nutrition/{date}", nutritionHandler.updateNutritionEntry)
+	mux.HandleFunc("GET /nutrition/{date}/history", nutritionHandler.getNutritionHistoryByDate)
+	mux.HandleFunc("POST /nutrition/{date}/history/{id}/restore", nutritionHandler.restoreNutritionEntry)
+	mux.HandleFunc("GET /nutrition/{date}/diary", nutritionHandler.getNutritionDiaryByDate)
+	mux.HandleFunc("POST /nutrition/{date}/diary", nutritionHandler.logFood)
+	mux.HandleFunc("GET /nutrition/{date}/diary/foods", nutritionHandler.searchDiaryFoods)
+	mux.HandleFunc("DELETE /nutrition/{date}/diary/{id}", nutritionHandler.removeDiaryEntry)
+	mux.HandleFunc("GET /foods", foodHandler.getFoods)
+	mux.HandleFunc("POST /foods", foodHandler.createFood)
+	mux.HandleFunc("GET /foods/search", foodHandler.searchFoods)
+	mux.HandleFunc("GET /foods/new", foodHandler.getNewFood)
+	mux.HandleFunc("GET /foods/{id}", foodHandler.getFood)
+	mux.HandleFunc("PUT /foods/{id}", foodHandler.updateFood)
+	mux.HandleFunc("DELETE /foods/{id}", foodHandler.deleteFood)
+	mux.HandleFunc("GET /recipes", recipeHandler.getRecipes)
+	mux.HandleFunc("POST /recipes", recipeHandler.createRecipe)
+	mux.HandleFunc("GET /recipes/search", recipeHandler.searchRecipes)
+	mux.HandleFunc("GET /recipes/new", recipeHandler.getNewRecipe)
+	mux.HandleFunc("POST /recipes/preview", recipeHandler.previewRecipe)
+	mux.HandleFunc("GET /recipes/import", recipeHandler.getRecipeImport)
+	mux.HandleFunc("POST /recipes/import", recipeHandler.importRecipe)
+	mux.HandleFunc("GET /recipes/ingredients/foods", recipeHandler.searchIngredientFoods)
+	mux.HandleFunc("GET /recipes/{id}", recipeHandler.getRecipe)
+	mux.HandleFunc("GET /recipes/{id}/export", recipeHandler.exportRecipe)
+	mux.HandleFunc("GET /recipes/{id}/ingredients", recipeHandler.getScaledIngredients)
+	mux.HandleFunc("PUT /recipes/{id}", recipeHandler.updateRecipe)
+	mux.HandleFunc("DELETE /recipes/{id}", recipeHandler.deleteRecipe)
+	mux.HandleFunc("GET /shopping", shoppingHandler.getShoppingList)
+	mux.HandleFunc("GET /healthz", healthHandler.live)
+	mux.HandleFunc("GET /readyz", healthHandler.ready)
+
+	mux.Handle("GET /events", eventsHandler)
+	mux.Handle("GET /metrics", appMetrics.registry)
+	mux.Handle("/nutrition", nutritionHandler)
+	mux.Handle("/", indexHandler)
+
+	handler := myHttp.Chain(
+		mux,
+		myHttp.RequestID,
+		myHttp.AccessLog(mux),
+		myHttp.Observe(mux, appMetrics.observeRequest),
+		myHttp.Compress,
+		myHttp.Recover(internalServerErrorHandler),
+		withUser,
+	)
+
+	return &application{
+		handler:  handler,
+		eventBus: eventBus,
+		health:   healthHandler,
+	}, nil
+}
@@ -0,0 +1,1473 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	mealplanning "meal-planning"
+	"meal-planning/database"
+	"meal-planning/domain"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// testBuild stands in for the esbuild output, which is not built for tests.
+var testBuild = fstest.MapFS{
+	"manifest.json":   {Data: []byte(`{"outputFiles":["assets/main.css","assets/main.js"]}`)},
+	"assets/main.css": {Data: []byte("body{}")},
+	"assets/main.js":  {Data: []byte("console.log('meal planning')")},
+	"assets/icon.svg": {Data: []byte("<svg></svg>")},
+}
+
+// testApplication drives the fully wired planner against a temporary database.
+type testApplication struct {
+	*application
+	db *sql.DB
+}
+
+func newTestApplication(t *testing.T) *testApplication {
+	t.Helper()
+
+	return newHouseholdTestApplication(t, domain.Household{People: 1})
+}
+
+// newHouseholdTestApplication plans meals for household.
+func newHouseholdTestApplication(t *testing.T, household domain.Household) *testApplication {
+	t.Helper()
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "meal-planner.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = sqliteBackend.migrate(context.Background(), db)
+	if err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+
+	app, err := newApplication(applicationConfig{
+		db:        db,
+		backend:   sqliteBackend,
+		household: household,
+		views:     mealplanning.Views(),
+		build:     testBuild,
+	})
+	if err != nil {
+		t.Fatalf("creating application: %v", err)
+	}
+	t.Cleanup(app.eventBus.Close)
+
+	return &testApplication{application: app, db: db}
+}
+
+// do sends an htmx request, form values are sent url encoded like htmx does.
+func (app *testApplication) do(t *testing.T, method, target string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var request *http.Request
+	if form == nil {
+		request = httptest.NewRequest(method, target, nil)
+	} else {
+		request = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	request.Header.Set("HX-Request", "true")
+	request.Header.Set("Remote-User", "alice")
+
+	recorder := httptest.NewRecorder()
+	app.handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func expectStatus(t *testing.T, response *httptest.ResponseRecorder, expected int) {
+	t.Helper()
+
+	if response.Code != expected {
+		t.Fatalf("expected status %d, got %d: %s", expected, response.Code, response.Body.String())
+	}
+}
+
+func expectBodyContains(t *testing.T, response *httptest.ResponseRecorder, fragments ...string) {
+	t.Helper()
+
+	body := response.Body.String()
+	for _, fragment := range fragments {
+		if !strings.Contains(body, fragment) {
+			t.Errorf("expected body to contain %q, got:\n%s", fragment, body)
+		}
+	}
+}
+
+func TestGetMealForm(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodGet, "/meals/2024-06-03/form", nil)
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response,
+		`<form id="meals-2024-06-03"`,
+		`hx-put="/meals/2024-06-03"`,
+		`<input type="hidden" name="version" value="0">`,
+		`name="dinner"`,
+	)
+
+	if contentType := response.Header().Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+		t.Errorf("expected an HTML fragment, got content type %q", contentType)
+	}
+}
+
+func TestUpdateMeal(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPut, "/meals/2024-06-03", url.Values{
+		"version":   {"0"},
+		"breakfast": {"Porridge"},
+		"dinner":    {"Pasta"},
+	})
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response,
+		`<div id="meals-2024-06-03"`,
+		`sse-swap="meal-day-2024-06-03"`,
+		"Porridge",
+		"Pasta",
+	)
+
+	response = app.do(t, http.MethodGet, "/meals/2024-06-03/form", nil)
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response,
+		`<input type="hidden" name="version" value="1">`,
+		`value="Porridge"`,
+		`value="Pasta"`,
+	)
+
+	response = app.do(t, http.MethodGet, "/meals/2024-06-03/history", nil)
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "alice")
+}
+
+func TestMealChangesAreStreamed(t *testing.T) {
+	app := newTestApplication(t)
+
+	returned := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		app.handler.ServeHTTP(writer, request)
+		if request.URL.Path == "/events" {
+			close(returned)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events", nil)
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+
+	// the handler subscribes before it sends the header
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("subscribing to events: %v", err)
+	}
+	defer response.Body.Close()
+
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("expected Content-Type text/event-stream, got %q", contentType)
+	}
+
+	expectStatus(t, app.do(t, http.MethodPut, "/meals/2024-06-03", url.Values{"version": {"0"}, "dinner": {"Pasta"}}), http.StatusOK)
+
+	lines := []string{}
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() && scanner.Text() != "" {
+		lines = append(lines, scanner.Text())
+	}
+
+	if len(lines) < 2 || lines[0] != "event: meal-day-2024-06-03" {
+		t.Fatalf("expected a meal-day-2024-06-03 event, got %q", lines)
+	}
+
+	data := new(strings.Builder)
+	for _, line := range lines[1:] {
+		fragment, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			t.Fatalf("expected only data lines after the event name, got %q", line)
+		}
+
+		data.WriteString(fragment + "\n")
+	}
+
+	for _, fragment := range []string{`<div id="meals-2024-06-03"`, `sse-swap="meal-day-2024-06-03"`, "Pasta"} {
+		if !strings.Contains(data.String(), fragment) {
+			t.Errorf("expected the event data to contain %q, got:\n%s", fragment, data)
+		}
+	}
+
+	cancel()
+
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the handler to return once the request is cancelled")
+	}
+}
+
+func TestUpdateMealWithStaleVersion(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPut, "/meals/2024-06-03", url.Values{"version": {"0"}, "dinner": {"Pasta"}})
+	expectStatus(t, response, http.StatusOK)
+
+	response = app.do(t, http.MethodPut, "/meals/2024-06-03", url.Values{"version": {"0"}, "dinner": {"Pizza"}})
+
+	expectStatus(t, response, http.StatusConflict)
+	expectBodyContains(t, response, "Pasta", "Pizza")
+}
+
+func TestUpdateMealRejectsInvalidRequests(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name   string
+		target string
+		form   url.Values
+	}{
+		{"invalid date", "/meals/03.06.2024", url.Values{"version": {"0"}}},
+		{"missing version", "/meals/2024-06-03", url.Values{"dinner": {"Pasta"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := app.do(t, http.MethodPut, test.target, test.form)
+
+			expectStatus(t, response, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestUpdateNutrition(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPut, "/nutrition/2024-06-03", url.Values{
+		"version":  {"0"},
+		"calories": {"2100"},
+		"weight":   {"80.5"},
+	})
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response,
+		`id="nutrition-2024-06-03"`,
+		`sse-swap="nutrition-entry-2024-06-03"`,
+		"2100",
+		"80.5",
+	)
+
+	var trigger struct {
+		UpdateNutritionData nutritionView `json:"updateNutritionData"`
+	}
+	err := json.Unmarshal([]byte(response.Header().Get("HX-Trigger")), &trigger)
+	if err != nil {
+		t.Fatalf("expected HX-Trigger to be JSON, got %q: %v", response.Header().Get("HX-Trigger"), err)
+	}
+
+	expected := nutritionView{Calories: 2100, Weight: 80.5, Version: 1}
+	actual := trigger.UpdateNutritionData
+	if actual.Date.Format("2006-01-02") != "2024-06-03" || actual.Calories != expected.Calories || actual.Weight != expected.Weight || actual.Version != expected.Version {
+		t.Errorf("expected updateNutritionData %+v for 2024-06-03, got %+v", expected, actual)
+	}
+}
+
+func TestUpdateNutritionWithStaleVersion(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPut, "/nutrition/2024-06-03", url.Values{"version": {"0"}, "calories": {"2100"}})
+	expectStatus(t, response, http.StatusOK)
+
+	response = app.do(t, http.MethodPut, "/nutrition/2024-06-03", url.Values{"version": {"0"}, "calories": {"1800"}})
+
+	expectStatus(t, response, http.StatusConflict)
+	expectBodyContains(t, response, "2100", "1800")
+
+	if trigger := response.Header().Get("HX-Trigger"); trigger != "" {
+		t.Errorf("expected no HX-Trigger for a conflict, got %q", trigger)
+	}
+}
+
+// expectHistory checks the actions recorded for entity on date, newest first,
+// and returns the entries.
+func expectHistory(t *testing.T, app *testApplication, entity domain.HistoryEntity, date time.Time, actions ...domain.HistoryAction) []domain.HistoryEntry {
+	t.Helper()
+
+	entries, err := database.NewSqlHistoryRepository(app.db).FindByEntityAndDate(context.Background(), entity, date)
+	if err != nil {
+		t.Fatalf("finding history: %v", err)
+	}
+
+	recorded := make([]domain.HistoryAction, len(entries))
+	for i, entry := range entries {
+		recorded[i] = entry.Action
+	}
+
+	if !slices.Equal(recorded, actions) {
+		t.Fatalf("expected history %v, got %v", actions, recorded)
+	}
+
+	return entries
+}
+
+func TestRestoreMeal(t *testing.T) {
+	app := newTestApplication(t)
+	date := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	app.do(t, http.MethodPut, "/meals/2024-06-03", url.Values{"version": {"0"}, "dinner": {"Pasta"}})
+	app.do(t, http.MethodPut, "/meals/2024-06-03", url.Values{"version": {"1"}, "dinner": {"Pizza"}})
+	entries := expectHistory(t, app, domain.HistoryEntityMealDay, date, domain.HistoryActionUpdate, domain.HistoryActionCreate)
+	created := strconv.FormatInt(entries[1].ID, 10)
+
+	response := app.do(t, http.MethodPost, "/meals/2024-06-03/history/"+created+"/restore", nil)
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `<div id="meals-2024-06-03"`, "Pasta")
+	expectBodyContains(t, app.do(t, http.MethodGet, "/meals/2024-06-03/form", nil), `<input type="hidden" name="version" value="3">`, `value="Pasta"`)
+	expectHistory(t, app, domain.HistoryEntityMealDay, date, domain.HistoryActionUpdate, domain.HistoryActionUpdate, domain.HistoryActionCreate)
+
+	mealDays := domain.NewMealDayService(database.NewSqlMealDayRepository(app.db), database.NewSqlHistoryRepository(app.db), database.NewTransactor(app.db), domain.NewEventBus(), domain.Household{People: 1})
+	err := mealDays.Delete(context.Background(), date)
+	if err != nil {
+		t.Fatalf("deleting meal day: %v", err)
+	}
+
+	response = app.do(t, http.MethodPost, "/meals/2024-06-03/history/"+created+"/restore", nil)
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "Pasta")
+	expectBodyContains(t, app.do(t, http.MethodGet, "/meals/2024-06-03/form", nil), `<input type="hidden" name="version" value="1">`, `value="Pasta"`)
+	expectHistory(t, app, domain.HistoryEntityMealDay, date, domain.HistoryActionCreate, domain.HistoryActionDelete, domain.HistoryActionUpdate, domain.HistoryActionUpdate, domain.HistoryActionCreate)
+}
+
+func TestRestoreNutrition(t *testing.T) {
+	app := newTestApplication(t)
+	date := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	app.do(t, http.MethodPut, "/nutrition/2024-06-03", url.Values{"version": {"0"}, "calories": {"2100"}})
+	app.do(t, http.MethodPut, "/nutrition/2024-06-03", url.Values{"version": {"1"}, "calories": {"1800"}})
+	entries := expectHistory(t, app, domain.HistoryEntityNutrition, date, domain.HistoryActionUpdate, domain.HistoryActionCreate)
+	created := strconv.FormatInt(entries[1].ID, 10)
+
+	// restored expects the restored nutrition to be sent to the chart
+	restored := func(response *httptest.ResponseRecorder, version int) {
+		t.Helper()
+
+		expectStatus(t, response, http.StatusOK)
+		expectBodyContains(t, response, `id="nutrition-2024-06-03"`, "2100")
+
+		var trigger struct {
+			UpdateNutritionData nutritionView `json:"updateNutritionData"`
+		}
+		err := json.Unmarshal([]byte(response.Header().Get("HX-Trigger")), &trigger)
+		if err != nil {
+			t.Fatalf("expected HX-Trigger to be JSON, got %q: %v", response.Header().Get("HX-Trigger"), err)
+		}
+
+		if actual := trigger.UpdateNutritionData; actual.Calories != 2100 || actual.Version != version {
+			t.Errorf("expected 2100 kcal in version %d, got %+v", version, actual)
+		}
+	}
+
+	restored(app.do(t, http.MethodPost, "/nutrition/2024-06-03/history/"+created+"/restore", nil), 3)
+	expectHistory(t, app, domain.HistoryEntityNutrition, date, domain.HistoryActionUpdate, domain.HistoryActionUpdate, domain.HistoryActionCreate)
+
+	nutrition := domain.NewNutritionService(database.NewSqlNutritionRepository(app.db), database.NewSqlDiaryRepository(app.db), database.NewSqlHistoryRepository(app.db), database.NewTransactor(app.db), domain.NewEventBus())
+	err := nutrition.Delete(context.Background(), domain.Nutrition{Date: date})
+	if err != nil {
+		t.Fatalf("deleting nutrition: %v", err)
+	}
+
+	restored(app.do(t, http.MethodPost, "/nutrition/2024-06-03/history/"+created+"/restore", nil), 1)
+	expectHistory(t, app, domain.HistoryEntityNutrition, date, domain.HistoryActionCreate, domain.HistoryActionDelete, domain.HistoryActionUpdate, domain.HistoryActionUpdate, domain.HistoryActionCreate)
+}
+
+func TestUpdateNutritionShowsFieldErrors(t *testing.T) {
+	app := newTestApplication(t)
+	tomorrow := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+
+	tests := []struct {
+		name    string
+		target  string
+		form    url.Values
+		message string
+	}{
+		{"calories not a number", "/nutrition/2024-06-03", url.Values{"version": {"0"}, "calories": {"lots"}}, "must be a whole number"},
+		{"negative calories", "/nutrition/2024-06-03", url.Values{"version": {"0"}, "calories": {"-200"}}, "must not be negative"},
+		{"weight not a number", "/nutrition/2024-06-03", url.Values{"version": {"0"}, "weight": {"NaN"}}, "must be a number"},
+		{"absurd weight", "/nutrition/2024-06-03", url.Values{"version": {"0"}, "weight": {"8000"}}, "must be between 20 and 400 kg"},
+		{"future weigh-in", "/nutrition/" + tomorrow, url.Values{"version": {"0"}, "weight": {"80"}}, "cannot be recorded for a future day"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := app.do(t, http.MethodPut, test.target, test.form)
+
+			expectStatus(t, response, http.StatusUnprocessableEntity)
+			expectBodyContains(t, response, `aria-invalid="true"`, test.message)
+
+			if trigger := response.Header().Get("HX-Trigger"); trigger != "" {
+				t.Errorf("expected no HX-Trigger for invalid input, got %q", trigger)
+			}
+
+			response = app.do(t, http.MethodGet, test.target, nil)
+			expectStatus(t, response, http.StatusOK)
+			expectBodyContains(t, response, `name="version" value="0"`)
+		})
+	}
+}
+
+func TestUpdateMealShowsFieldErrors(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPut, "/meals/2024-06-03", url.Values{
+		"version": {"0"},
+		"dinner":  {strings.Repeat("Pasta ", 20)},
+	})
+
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response,
+		`<form id="meals-2024-06-03"`,
+		`aria-describedby="dinner-error-2024-06-03"`,
+		"must be at most 100 characters",
+	)
+
+	response = app.do(t, http.MethodGet, "/meals/2024-06-03/form", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `name="version" value="0"`)
+}
+
+func TestPagesRender(t *testing.T) {
+	app := newTestApplication(t)
+
+	for _, target := range []string{"/", "/nutrition", "/recipes", "/recipes/new", "/shopping"} {
+		t.Run(target, func(t *testing.T) {
+			response := app.do(t, http.MethodGet, target, nil)
+
+			expectStatus(t, response, http.StatusOK)
+			expectBodyContains(t, response, "<html", "assets/main.js")
+		})
+	}
+}
+
+func TestServeAssets(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodGet, "/assets/main.js", nil)
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "meal planning")
+}
+
+// linkedAssets matches the stylesheets and scripts a page links to.
+var linkedAssets = regexp.MustCompile(`<(?:link|script)[^>]*?(?:href|src)="([^"]+)"`)
+
+func TestNestedPagesLoadAssets(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPost, "/foods", url.Values{"name": {"Rolled oats"}})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{"name": {"Porridge"}, "servings": {"1"}})
+	expectStatus(t, response, http.StatusNoContent)
+
+	for _, target := range []string{"/foods/new", "/foods/1", "/recipes/new", "/recipes/1", "/recipes/import"} {
+		t.Run(target, func(t *testing.T) {
+			response := app.get(t, target, nil)
+			expectStatus(t, response, http.StatusOK)
+
+			expectAssetsLoad(t, app, target, response)
+		})
+	}
+}
+
+// expectAssetsLoad requests every asset the page at target links to, resolved
+// against target like a browser does.
+func expectAssetsLoad(t *testing.T, app *testApplication, target string, response *httptest.ResponseRecorder) {
+	t.Helper()
+
+	base, err := url.Parse(target)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", target, err)
+	}
+
+	matches := linkedAssets.FindAllStringSubmatch(response.Body.String(), -1)
+	if len(matches) == 0 {
+		t.Fatalf("expected %s to link assets", target)
+	}
+
+	for _, match := range matches {
+		link, err := url.Parse(match[1])
+		if err != nil {
+			t.Fatalf("parsing asset URL %q: %v", match[1], err)
+		}
+
+		// unknown paths render the planner, so the asset must also come back
+		// as what it is
+		asset := base.ResolveReference(link).Path
+		assetResponse := app.get(t, asset, nil)
+		contentType := assetResponse.Header().Get("Content-Type")
+		if assetResponse.Code != http.StatusOK || contentType != mime.TypeByExtension(path.Ext(asset)) {
+			t.Errorf("expected %s linked from %s to load, got status %d with %q", asset, target, assetResponse.Code, contentType)
+		}
+	}
+}
+
+func (app *testApplication) get(t *testing.T, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	recorder := httptest.NewRecorder()
+	app.handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestAssetCaching(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name         string
+		target       string
+		cacheControl string
+	}{
+		{"hashed file from the manifest", "/assets/main.js", "public, max-age=31536000, immutable"},
+		{"file outside the manifest", "/assets/icon.svg", "no-cache"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := app.get(t, test.target, nil)
+
+			expectStatus(t, response, http.StatusOK)
+			if cacheControl := response.Header().Get("Cache-Control"); cacheControl != test.cacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", test.cacheControl, cacheControl)
+			}
+
+			etag := response.Header().Get("ETag")
+			if etag == "" || strings.HasPrefix(etag, "W/") {
+				t.Fatalf("expected a strong ETag, got %q", etag)
+			}
+
+			lastModified := response.Header().Get("Last-Modified")
+			if lastModified == "" {
+				t.Fatalf("expected Last-Modified to be set")
+			}
+
+			response = app.get(t, test.target, http.Header{"If-None-Match": {etag}})
+			expectStatus(t, response, http.StatusNotModified)
+
+			response = app.get(t, test.target, http.Header{"If-Modified-Since": {lastModified}})
+			expectStatus(t, response, http.StatusNotModified)
+		})
+	}
+}
+
+func TestMissingAsset(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.get(t, "/assets/missing.js", nil)
+
+	expectStatus(t, response, http.StatusNotFound)
+}
+
+func TestCompression(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		encoding       string
+		decode         func(io.Reader) (io.Reader, error)
+	}{
+		{"brotli preferred", "gzip, deflate, br", "br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+		{"gzip", "gzip", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"brotli refused", "br;q=0, gzip;q=0.5", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"identity", "", "", func(r io.Reader) (io.Reader, error) { return r, nil }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := app.get(t, "/assets/main.js", http.Header{"Accept-Encoding": {test.acceptEncoding}})
+
+			expectStatus(t, response, http.StatusOK)
+			if encoding := response.Header().Get("Content-Encoding"); encoding != test.encoding {
+				t.Errorf("expected Content-Encoding %q, got %q", test.encoding, encoding)
+			}
+
+			if vary := response.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("expected Vary Accept-Encoding, got %q", vary)
+			}
+
+			reader, err := test.decode(response.Body)
+			if err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+
+			if string(body) != "console.log('meal planning')" {
+				t.Errorf("unexpected body %q", body)
+			}
+		})
+	}
+}
+
+func TestCompressedResponsesRevalidate(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.get(t, "/assets/main.js", http.Header{"Accept-Encoding": {"gzip"}})
+	expectStatus(t, response, http.StatusOK)
+
+	etag := response.Header().Get("ETag")
+	if !strings.HasPrefix(etag, "W/") {
+		t.Fatalf("expected a weak ETag for the compressed body, got %q", etag)
+	}
+
+	response = app.get(t, "/assets/main.js", http.Header{"Accept-Encoding": {"gzip"}, "If-None-Match": {etag}})
+	expectStatus(t, response, http.StatusNotModified)
+}
+
+func TestPagesRevalidate(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.get(t, "/nutrition", nil)
+	expectStatus(t, response, http.StatusOK)
+
+	if cacheControl := response.Header().Get("Cache-Control"); cacheControl != "no-cache" {
+		t.Errorf("expected Cache-Control no-cache, got %q", cacheControl)
+	}
+
+	etag := response.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected an ETag")
+	}
+
+	response = app.get(t, "/nutrition", http.Header{"If-None-Match": {etag}})
+	expectStatus(t, response, http.StatusNotModified)
+
+	if response.Body.Len() != 0 {
+		t.Errorf("expected no body, got %q", response.Body.String())
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name        string
+		header      http.Header
+		contentType string
+		fragments   []string
+	}{
+		{"htmx fragment", http.Header{"Hx-Request": {"true"}}, "text/html; charset=utf-8", []string{`role="alert"`, "date must be an ISO date"}},
+		{"problem details", http.Header{"Accept": {"application/json"}}, "application/problem+json", []string{`"status":400`, `"title":"Bad Request"`, `"detail":"date must be an ISO date"`, `"instance":"/meals/yesterday"`}},
+		{"full page", http.Header{"Accept": {"text/html,application/xhtml+xml,*/*;q=0.8"}}, "text/html; charset=utf-8", []string{"<!DOCTYPE html>", "Bad Request", "date must be an ISO date"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := app.get(t, "/meals/yesterday", test.header)
+
+			expectStatus(t, response, http.StatusBadRequest)
+			if contentType := response.Header().Get("Content-Type"); contentType != test.contentType {
+				t.Errorf("expected Content-Type %q, got %q", test.contentType, contentType)
+			}
+
+			expectBodyContains(t, response, test.fragments...)
+		})
+	}
+}
+
+func TestErrorPagesLoadAssets(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.get(t, "/foods/42", http.Header{"Accept": {"text/html"}})
+
+	expectStatus(t, response, http.StatusNotFound)
+	expectBodyContains(t, response, "<!DOCTYPE html>", "Not Found")
+	expectAssetsLoad(t, app, "/foods/42", response)
+}
+
+func TestErrorFragmentTargetsErrorRegion(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPost, "/meals/2024-06-03/history/42/restore", nil)
+
+	expectStatus(t, response, http.StatusNotFound)
+	if target := response.Header().Get("HX-Retarget"); target != "#errors" {
+		t.Errorf("expected HX-Retarget #errors, got %q", target)
+	}
+
+	expectBodyContains(t, response, "history entry: not found")
+}
+
+func TestInternalErrorsHideDetails(t *testing.T) {
+	app := newTestApplication(t)
+	app.db.Close()
+
+	response := app.get(t, "/meals", http.Header{"Accept": {"application/problem+json"}})
+
+	expectStatus(t, response, http.StatusInternalServerError)
+	expectBodyContains(t, response, internalErrorMessage)
+	if strings.Contains(response.Body.String(), "sql") {
+		t.Errorf("expected no database details in the response, got %s", response.Body.String())
+	}
+}
+
+func TestHealthChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		prepare   func(t *testing.T, app *testApplication)
+		readiness int
+		fragment  string
+	}{
+		{"healthy", func(t *testing.T, app *testApplication) {}, http.StatusOK, "ok"},
+		{"schema version mismatch", func(t *testing.T, app *testApplication) {
+			_, err := app.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteBackend.latestSchemaVersion-1))
+			if err != nil {
+				t.Fatalf("downgrading schema version: %v", err)
+			}
+		}, http.StatusServiceUnavailable, fmt.Sprintf("expected %d", sqliteBackend.latestSchemaVersion)},
+		{"shutting down", func(t *testing.T, app *testApplication) {
+			app.health.shuttingDown.Store(true)
+		}, http.StatusServiceUnavailable, "shutting down"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			test.prepare(t, app)
+
+			response := app.get(t, "/readyz", nil)
+
+			expectStatus(t, response, test.readiness)
+			expectBodyContains(t, response, test.fragment)
+			if cacheControl := response.Header().Get("Cache-Control"); cacheControl != "no-store" {
+				t.Errorf("expected Cache-Control no-store, got %q", cacheControl)
+			}
+
+			// the process is alive even if it is not ready
+			response = app.get(t, "/healthz", nil)
+
+			expectStatus(t, response, http.StatusOK)
+			expectBodyContains(t, response, "ok")
+		})
+	}
+}
+
+func TestFoodPages(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPost, "/foods", url.Values{
+		"name":          {"Rolled oats"},
+		"brand":         {"Kölln"},
+		"kcal":          {"372"},
+		"protein":       {"13.5"},
+		"carbs":         {"58.7"},
+		"fat":           {"7"},
+		"serving_name":  {"1 cup", ""},
+		"serving_grams": {"80", ""},
+	})
+
+	expectStatus(t, response, http.StatusNoContent)
+	if location := response.Header().Get("HX-Redirect"); location != "/foods" {
+		t.Errorf("expected HX-Redirect /foods, got %q", location)
+	}
+
+	response = app.get(t, "/foods?q=oats", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `<a href="/foods/1"`, "Rolled oats", "Kölln", "372 kCal", `aria-current="page">Foods</a>`)
+
+	response = app.do(t, http.MethodGet, "/foods/search?q=milk", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `<section id="food-list"`, "No foods found")
+
+	response = app.get(t, "/foods/1", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `hx-put="/foods/1"`, `value="Rolled oats"`, `name="serving_name" aria-label="Serving name" value="1 cup"`, `value="80"`)
+
+	response = app.do(t, http.MethodPut, "/foods/1", url.Values{
+		"version": {"1"},
+		"name":    {"Rolled oats, fine"},
+		"kcal":    {"370"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodPut, "/foods/1", url.Values{
+		"version": {"1"},
+		"name":    {"Rolled oats"},
+	})
+	expectStatus(t, response, http.StatusConflict)
+
+	response = app.do(t, http.MethodDelete, "/foods/1", nil)
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.get(t, "/foods/1", nil)
+	expectStatus(t, response, http.StatusNotFound)
+}
+
+func TestCreateFoodShowsFieldErrors(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPost, "/foods", url.Values{
+		"name":          {" "},
+		"kcal":          {"lots"},
+		"fat":           {"120"},
+		"serving_name":  {"1 slice"},
+		"serving_grams": {""},
+	})
+
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response,
+		`<form id="food-form"`,
+		`hx-post="/foods"`,
+		`<p id="kcal-error"`,
+		"kcal must be a number",
+		`value="1 slice"`,
+	)
+
+	response = app.do(t, http.MethodPost, "/foods", url.Values{
+		"name": {" "},
+		"fat":  {"120"},
+	})
+
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response,
+		`<p id="name-error"`,
+		"must not be empty",
+		"fat must be between 0 and 100 g",
+	)
+}
+
+func TestNutritionDiary(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPost, "/foods", url.Values{
+		"name":    {"Rolled oats"},
+		"kcal":    {"372"},
+		"protein": {"13.5"},
+		"carbs":   {"58.7"},
+		"fat":     {"7"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/nutrition/2024-06-10/diary/foods?q=oats", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `<fieldset id="diary-foods-2024-06-10"`, `name="food_id" value="1"`, "372 kCal per 100 g")
+
+	response = app.do(t, http.MethodPost, "/nutrition/2024-06-10/diary", url.Values{
+		"food_id": {"1"},
+		"grams":   {"80"},
+		"slot":    {"breakfast"},
+	})
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "Food diary of 10.06.2024", "Rolled oats", "80 g &middot; 298 kCal", `hx-delete="/nutrition/2024-06-10/diary/1"`)
+	if trigger := response.Header().Get("HX-Trigger"); !strings.Contains(trigger, `"calories":298`) || !strings.Contains(trigger, `"caloriesFromDiary":true`) {
+		t.Errorf("expected the derived calories in HX-Trigger, got %q", trigger)
+	}
+
+	response = app.do(t, http.MethodGet, "/nutrition/2024-06-10", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `placeholder="298"`, "from food diary", "Protein 11 g &middot; Carbs 47 g &middot; Fat 6 g")
+
+	response = app.do(t, http.MethodDelete, "/nutrition/2024-06-10/diary/1", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "Nothing logged")
+
+	response = app.do(t, http.MethodDelete, "/nutrition/2024-06-10/diary/1", nil)
+	expectStatus(t, response, http.StatusNotFound)
+}
+
+func TestLogFoodShowsFieldErrors(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPost, "/nutrition/2024-06-10/diary", url.Values{
+		"food_id": {"42"},
+		"grams":   {"a bowl"},
+		"slot":    {"lunch"},
+	})
+
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response,
+		`<p id="food-error-2024-06-10"`,
+		"food must be chosen",
+		`<p id="grams-error-2024-06-10"`,
+		"must be a number",
+		`value="a bowl"`,
+		`<option value="lunch" selected>`,
+	)
+}
+
+func TestRecipePages(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodGet, "/recipes/new?name=Chili+sin+carne", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `hx-post="/recipes"`, `value="Chili sin carne"`, `aria-current="page">Recipes</a>`)
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":     {"Chili sin carne"},
+		"servings": {"4"},
+		"kcal":     {"520"},
+		"protein":  {"24"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+	if location := response.Header().Get("HX-Redirect"); location != "/recipes" {
+		t.Errorf("expected HX-Redirect /recipes, got %q", location)
+	}
+
+	response = app.do(t, http.MethodGet, "/recipes/search?q=chili", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `<a href="/recipes/1"`, "520 kCal", "4 servings")
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":     {"chili SIN carne"},
+		"servings": {"none"},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, `<p id="servings-error"`, "must be a whole number")
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":     {"chili SIN carne"},
+		"servings": {"2"},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, `<p id="name-error"`, "is already used by another recipe")
+
+	response = app.do(t, http.MethodPut, "/recipes/1", url.Values{
+		"version":  {"1"},
+		"name":     {"Chili sin carne"},
+		"servings": {"6"},
+		"kcal":     {"350"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/recipes/1", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `hx-put="/recipes/1"`, `name="version" value="2"`, `value="350"`)
+
+	response = app.do(t, http.MethodDelete, "/recipes/1", nil)
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/recipes/1", nil)
+	expectStatus(t, response, http.StatusNotFound)
+}
+
+func TestLogPlannedMealAsEaten(t *testing.T) {
+	app := newTestApplication(t)
+	today := time.Now().Format("2006-01-02")
+
+	response := app.do(t, http.MethodPut, "/meals/"+today, url.Values{
+		"version": {"0"},
+		"lunch":   {"Pizza"},
+		"dinner":  {"chili sin carne"},
+	})
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `hx-get="/meals/`+today+`/eaten"`)
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":     {"Chili sin carne"},
+		"servings": {"4"},
+		"kcal":     {"520"},
+		"protein":  {"24"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/meals/"+today+"/eaten", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "520 kCal per serving", `<a href="/recipes/new?name=Pizza"`)
+
+	response = app.do(t, http.MethodPost, "/meals/"+today+"/eaten", url.Values{
+		"slot":     {"dinner"},
+		"meal":     {"chili sin carne"},
+		"portions": {"a lot"},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, "portions must be a number", `value="a lot"`)
+
+	response = app.do(t, http.MethodPost, "/meals/"+today+"/eaten", url.Values{
+		"slot":     {"lunch"},
+		"meal":     {"Pizza"},
+		"portions": {"1"},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, "has no recipe")
+
+	response = app.do(t, http.MethodPost, "/meals/"+today+"/eaten", url.Values{
+		"slot":     {"lunch"},
+		"meal":     {"chili sin carne"},
+		"portions": {"1"},
+	})
+	expectStatus(t, response, http.StatusBadRequest)
+	expectBodyContains(t, response, "meal is not planned for this day")
+
+	response = app.do(t, http.MethodPost, "/meals/"+today+"/eaten", url.Values{
+		"slot":     {"dinner"},
+		"meal":     {"chili sin carne"},
+		"portions": {"1.5"},
+	})
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "Logged 1.5 portions of Chili sin carne (780 kCal)")
+
+	response = app.do(t, http.MethodGet, "/nutrition/"+today+"/diary", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "1.5 portions &middot; 780 kCal")
+
+	response = app.do(t, http.MethodGet, "/nutrition", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `<section id="planned-vs-actual"`, "520 kCal", "without Pizza", "780 kCal")
+}
+
+func TestRecipeNutritionFromIngredients(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPost, "/foods", url.Values{
+		"name":    {"Rolled oats"},
+		"kcal":    {"370"},
+		"protein": {"13"},
+		"carbs":   {"59"},
+		"fat":     {"7"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/recipes/ingredients/foods?ingredient_search=oats", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `<input type="radio" name="add_food" value="1">`, "Rolled oats")
+
+	response = app.do(t, http.MethodPost, "/recipes/preview", url.Values{
+		"name":     {"Porridge"},
+		"servings": {"2"},
+		"add":      {"1"},
+		"add_food": {"1"},
+	})
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `name="ingredient_food" value="1"`, `value="Rolled oats"`, "No amount, not counted")
+
+	response = app.do(t, http.MethodPost, "/recipes/preview", url.Values{
+		"name":                {"Porridge"},
+		"servings":            {"2"},
+		"ingredient_quantity": {""},
+		"ingredient_unit":     {""},
+		"ingredient_name":     {"Rolled oats"},
+		"ingredient_food":     {"1"},
+		"ingredient_grams":    {"100"},
+		"add":                 {"1"},
+		"ingredient_search":   {"Cinnamon"},
+	})
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `value="Cinnamon"`, "Not in the food catalog, not counted", "185 kCal", "370 kCal", "1 ingredient is not counted")
+
+	response = app.do(t, http.MethodPost, "/recipes/preview", url.Values{
+		"name":                {"Porridge"},
+		"servings":            {"2"},
+		"ingredient_quantity": {"", ""},
+		"ingredient_unit":     {"", ""},
+		"ingredient_name":     {"Rolled oats", "Cinnamon"},
+		"ingredient_food":     {"1", ""},
+		"ingredient_grams":    {"100", ""},
+		"remove":              {"1"},
+	})
+	expectStatus(t, response, http.StatusOK)
+	if strings.Contains(response.Body.String(), "Cinnamon") {
+		t.Errorf("expected the removed ingredient to be gone, got %s", response.Body.String())
+	}
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":                {"Porridge"},
+		"servings":            {"2"},
+		"ingredient_quantity": {"", ""},
+		"ingredient_unit":     {"", ""},
+		"ingredient_name":     {"Rolled oats", "Cinnamon"},
+		"ingredient_food":     {"1", ""},
+		"ingredient_grams":    {"plenty", ""},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, `<p id="ingredients-error"`, "amounts must be numbers")
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":                {"Porridge"},
+		"servings":            {"2"},
+		"kcal":                {"9999"},
+		"ingredient_quantity": {"", ""},
+		"ingredient_unit":     {"", ""},
+		"ingredient_name":     {"Rolled oats", "Cinnamon"},
+		"ingredient_food":     {"1", ""},
+		"ingredient_grams":    {"100", "2"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/recipes", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "185 kCal", "1 not counted")
+
+	response = app.do(t, http.MethodPut, "/recipes/1", url.Values{
+		"version":             {"1"},
+		"name":                {"Porridge"},
+		"servings":            {"1"},
+		"ingredient_quantity": {""},
+		"ingredient_unit":     {""},
+		"ingredient_name":     {"Rolled oats"},
+		"ingredient_food":     {"1"},
+		"ingredient_grams":    {"100"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/recipes/1", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "370 kCal &middot; P 13.0 g")
+}
+
+func TestImportRecipe(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodGet, "/recipes/import", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `enctype="multipart/form-data"`, `name="file"`, `name="source"`)
+
+	page := `<html><head><script type="application/ld+json">{"@context": "https://schema.org", "@type": "Recipe",
+		"name": "Pancakes", "recipeYield": "8 pancakes", "prepTime": "PT10M", "cookTime": "PT20M",
+		"recipeIngredient": ["250 g flour", "2 eggs", "1/2 l milk"],
+		"recipeInstructions": [{"@type": "HowToStep", "text": "Whisk everything."}, {"@type": "HowToStep", "text": "Fry."}]}</script></head></html>`
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "pancakes.html")
+	if err != nil {
+		t.Fatalf("creating upload: %v", err)
+	}
+	_, _ = part.Write([]byte(page))
+	_ = writer.WriteField("source", "")
+	_ = writer.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/recipes/import", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Set("Remote-User", "alice")
+	response = httptest.NewRecorder()
+	app.handler.ServeHTTP(response, request)
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response,
+		"Review recipe",
+		`hx-post="/recipes"`,
+		`value="Pancakes"`,
+		`name="servings"`, `value="8"`,
+		`name="ingredient_quantity"`, `value="250"`,
+		`name="ingredient_unit"`, `value="g"`,
+		`value="flour"`,
+		`value="eggs"`,
+		`value="milk"`,
+		`value="10"`, `value="20"`,
+		"Whisk everything.\nFry.\n",
+	)
+	expectAssetsLoad(t, app, "/recipes/import", response)
+
+	response = app.do(t, http.MethodPost, "/recipes/import", url.Values{"source": {"<html><body>No recipe here</body></html>"}})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, `role="alert"`, "no recipe described with schema.org JSON-LD", "No recipe here")
+
+	response = app.do(t, http.MethodPost, "/recipes/import", url.Values{"source": {" "}})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, "Choose a saved page or paste its source.")
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":                {"Pancakes"},
+		"servings":            {"8"},
+		"ingredient_quantity": {"250", "2"},
+		"ingredient_unit":     {"g", ""},
+		"ingredient_name":     {"flour", "eggs"},
+		"ingredient_food":     {"", ""},
+		"ingredient_grams":    {"", ""},
+		"prep_minutes":        {"10"},
+		"cook_minutes":        {"20"},
+		"instructions":        {"Whisk everything.\r\n\r\nFry.\r\n"},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/recipes/1", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `name="ingredient_grams"`, `value="250"`, ">Whisk everything.\nFry.\n</textarea>")
+}
+
+func TestImportAndExportRecipeFiles(t *testing.T) {
+	app := newTestApplication(t)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "Pancakes.cook")
+	if err != nil {
+		t.Fatalf("creating upload: %v", err)
+	}
+	_, _ = part.Write([]byte(">> servings: 8\n\nWhisk @flour{250%g}, @eggs{2}, @milk{0.5%l} and @salt{=1%pinch} in a #bowl{}.\n\nFry for ~{2%minutes} a side.\n"))
+	_ = writer.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/recipes/import", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Set("Remote-User", "alice")
+	response := httptest.NewRecorder()
+	app.handler.ServeHTTP(response, request)
+
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response,
+		"Review recipe",
+		`value="Pancakes"`,
+		`value="8"`,
+		`value="flour"`, `value="250"`, `value="eggs"`, `value="milk"`,
+		`name="ingredient_fixed" value="true"`,
+		"Whisk flour, eggs, milk and salt in a bowl.\nFry for 2 minutes a side.\n",
+	)
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":                {"Pancakes"},
+		"servings":            {"8"},
+		"ingredient_quantity": {"250", "2", "1"},
+		"ingredient_unit":     {"g", "", "pinch"},
+		"ingredient_name":     {"flour", "eggs", "salt"},
+		"ingredient_food":     {"", "", ""},
+		"ingredient_grams":    {"", "", ""},
+		"ingredient_fixed":    {"", "", "true"},
+		"instructions":        {"Whisk flour, eggs and salt.\nFry for 2 minutes a side."},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/recipes/1/ingredients?servings=16", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "500 g</span> flour", "4</span> eggs", "1 pinch</span> salt <span class=\"font-light text-slate-700\">for any servings</span>")
+
+	response = app.do(t, http.MethodGet, "/recipes/1", nil)
+	expectBodyContains(t, response, `href="/recipes/1/export?format=cooklang"`, `href="/recipes/1/export?format=markdown"`)
+
+	response = app.do(t, http.MethodGet, "/recipes/1/export?format=cooklang", nil)
+	expectStatus(t, response, http.StatusOK)
+	if disposition := response.Header().Get("Content-Disposition"); disposition != `attachment; filename=Pancakes.cook` {
+		t.Errorf("unexpected Content-Disposition %q", disposition)
+	}
+	expectBodyContains(t, response, "title: Pancakes\nservings: 8\n", "\nWhisk @flour{250%g}, @eggs{2} and @salt{=1%pinch}.\n\nFry for 2 minutes a side.\n")
+
+	response = app.do(t, http.MethodGet, "/recipes/1/export?format=markdown", nil)
+	expectStatus(t, response, http.StatusOK)
+	if contentType := response.Header().Get("Content-Type"); contentType != "text/markdown; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", contentType)
+	}
+	expectBodyContains(t, response, "# Pancakes\n", "- 250 g flour\n- 2 eggs\n", "1. Whisk flour, eggs and salt.\n")
+
+	response = app.do(t, http.MethodGet, "/recipes/1/export?format=pdf", nil)
+	expectStatus(t, response, http.StatusBadRequest)
+}
+
+func TestScaleRecipe(t *testing.T) {
+	app := newTestApplication(t)
+
+	response := app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":                {"Pancakes"},
+		"servings":            {"4"},
+		"ingredient_quantity": {"1", "2", ""},
+		"ingredient_unit":     {"cup", "", ""},
+		"ingredient_name":     {"flour", "eggs", "salt"},
+		"ingredient_food":     {"", "", ""},
+		"ingredient_grams":    {"", "", ""},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodGet, "/recipes/1", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `id="recipe-scaled"`, `hx-get="/recipes/1/ingredients"`, "1 cup</span> flour", "2</span> eggs", "<li>salt</li>")
+
+	response = app.do(t, http.MethodGet, "/recipes/1/ingredients?servings=6&units=metric", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `value="6"`, "190 g</span> flour", "3</span> eggs")
+
+	response = app.do(t, http.MethodGet, "/recipes/1/ingredients?servings=0", nil)
+	expectStatus(t, response, http.StatusBadRequest)
+
+	response = app.do(t, http.MethodGet, "/recipes/1/ingredients?servings=2&units=nautical", nil)
+	expectStatus(t, response, http.StatusBadRequest)
+}
+
+func TestShoppingList(t *testing.T) {
+	app := newTestApplication(t)
+	today := time.Now()
+
+	response := app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":                {"Pancakes"},
+		"servings":            {"2"},
+		"ingredient_quantity": {"250", "2"},
+		"ingredient_unit":     {"g", ""},
+		"ingredient_name":     {"flour", "eggs"},
+		"ingredient_food":     {"", ""},
+		"ingredient_grams":    {"", ""},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	for _, day := range []time.Time{today, today.AddDate(0, 0, 1)} {
+		response = app.do(t, http.MethodPut, "/meals/"+day.Format("2006-01-02"), url.Values{
+			"version":   {"0"},
+			"breakfast": {"pancakes"},
+			"dinner":    {"Pizza"},
+		})
+		expectStatus(t, response, http.StatusOK)
+	}
+
+	response = app.do(t, http.MethodGet, "/shopping", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `aria-current="page">Shopping`, "250 g</span> flour", "2</span> eggs", "No recipe for Pizza")
+
+	response = app.do(t, http.MethodGet, "/shopping?start="+today.AddDate(0, 0, 1).Format("2006-01-02")+"&days=1&units=imperial", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "1 cup</span> flour", "1</span> eggs", `<option value="imperial" selected>`)
+
+	response = app.do(t, http.MethodGet, "/shopping?days=100", nil)
+	expectStatus(t, response, http.StatusBadRequest)
+}
+
+func TestPlanServingsAndAttendance(t *testing.T) {
+	app := newHouseholdTestApplication(t, domain.Household{Members: []string{"Alex", "Sam", "Kim"}})
+	today := time.Now().Format("2006-01-02")
+
+	response := app.do(t, http.MethodGet, "/meals/"+today+"/form", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `name="lunch-servings"`, `placeholder="3"`, `name="lunch-absent" value="Alex" >`)
+
+	response = app.do(t, http.MethodPut, "/meals/"+today, url.Values{
+		"version":          {"0"},
+		"lunch":            {"Soup"},
+		"lunch-servings":   {"lots"},
+		"dinner":           {"Pasta"},
+		"dinner-absent":    {"Robin"},
+		"breakfast-absent": {"Kim"},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, "servings must be a whole number")
+
+	response = app.do(t, http.MethodPut, "/meals/"+today, url.Values{
+		"version":       {"0"},
+		"dinner":        {"Pasta"},
+		"dinner-absent": {"Robin"},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, "must be members of the household")
+
+	response = app.do(t, http.MethodPut, "/meals/"+today, url.Values{
+		"version":         {"0"},
+		"dinner":          {"Pasta"},
+		"dinner-servings": {"-1"},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, "must be between 0 and 100, 0 serves the household")
+
+	response = app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":                {"Pasta"},
+		"servings":            {"2"},
+		"ingredient_quantity": {"200"},
+		"ingredient_unit":     {"g"},
+		"ingredient_name":     {"spaghetti"},
+		"ingredient_food":     {""},
+		"ingredient_grams":    {""},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodPut, "/meals/"+today, url.Values{
+		"version":         {"0"},
+		"lunch":           {"Pasta"},
+		"lunch-absent":    {"Alex", "Sam"},
+		"dinner":          {"Pasta"},
+		"dinner-servings": {"6"},
+	})
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "1 serving, Alex, Sam eating out", "6 servings")
+
+	response = app.do(t, http.MethodGet, "/meals/"+today+"/form", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `name="lunch-absent" value="Sam" checked>`, `placeholder="1"`, `value="6"`)
+
+	response = app.do(t, http.MethodGet, "/shopping?days=1", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "700 g</span> spaghetti")
+}
+
+func TestPlanLeftovers(t *testing.T) {
+	app := newHouseholdTestApplication(t, domain.Household{People: 2})
+	today := time.Now()
+	tomorrow := today.AddDate(0, 0, 1)
+	cooked := today.Format("2006-01-02") + "/dinner"
+
+	response := app.do(t, http.MethodPost, "/recipes", url.Values{
+		"name":                {"Chili"},
+		"servings":            {"4"},
+		"ingredient_quantity": {"400"},
+		"ingredient_unit":     {"g"},
+		"ingredient_name":     {"beans"},
+		"ingredient_food":     {""},
+		"ingredient_grams":    {""},
+	})
+	expectStatus(t, response, http.StatusNoContent)
+
+	response = app.do(t, http.MethodPut, "/meals/"+today.Format("2006-01-02"), url.Values{
+		"version": {"0"},
+		"dinner":  {"Chili"},
+	})
+	expectStatus(t, response, http.StatusOK)
+
+	response = app.do(t, http.MethodGet, "/meals/"+tomorrow.Format("2006-01-02")+"/form", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, `name="lunch-leftovers"`, `<option value="`+cooked+`" >`+today.Format("Mon 2.1.")+` dinner: Chili</option>`)
+
+	response = app.do(t, http.MethodPut, "/meals/"+tomorrow.Format("2006-01-02"), url.Values{
+		"version":         {"0"},
+		"lunch-leftovers": {"yesterday"},
+	})
+	expectStatus(t, response, http.StatusUnprocessableEntity)
+	expectBodyContains(t, response, "leftovers must be a planned meal")
+
+	response = app.do(t, http.MethodPut, "/meals/"+tomorrow.Format("2006-01-02"), url.Values{
+		"version":         {"0"},
+		"lunch-leftovers": {cooked},
+	})
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "Chili", "Leftovers of "+today.Format("Mon 2.1.")+" dinner")
+
+	response = app.do(t, http.MethodGet, "/meals/"+today.Format("2006-01-02"), nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "Cook 2 servings more for "+tomorrow.Format("Mon 2.1.")+" lunch")
+
+	response = app.do(t, http.MethodGet, "/shopping?days=2", nil)
+	expectStatus(t, response, http.StatusOK)
+	expectBodyContains(t, response, "400 g</span> beans")
+}
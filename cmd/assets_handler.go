@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	myHttp "meal-planning/http"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// revalidateCacheControl lets browsers keep files without a content hash
+	// but makes them ask with the ETag before every use.
+	revalidateCacheControl = "no-cache"
+)
+
+// assetsHandler serves the esbuild output. Files listed in the manifest carry
+// a content hash in their name and may be cached forever, everything else is
+// revalidated.
+type assetsHandler struct {
+	build    fs.FS
+	manifest manifest
+	// startedAt stands in for the modification time of embedded files, which
+	// do not have one.
+	startedAt time.Time
+	etags     sync.Map
+}
+
+type assetETag struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+func newAssetsHandler(build fs.FS, myManifest manifest) *assetsHandler {
+	return &assetsHandler{
+		build:     build,
+		manifest:  myManifest,
+		startedAt: time.Now(),
+	}
+}
+
+func (h *assetsHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	name := strings.TrimPrefix(request.URL.Path, "/")
+	if !fs.ValidPath(name) {
+		http.NotFound(writer, request)
+		return
+	}
+
+	content, info, err := h.read(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(writer, request)
+		return
+	}
+
+	if err != nil {
+		slog.ErrorContext(request.Context(), "error reading asset", slog.String("name", name), slog.Any("reason", err))
+		http.Error(writer, "could not read asset", http.StatusInternalServerError)
+		return
+	}
+
+	modTime := info.ModTime()
+	if modTime.IsZero() {
+		modTime = h.startedAt
+	}
+
+	header := writer.Header()
+	header.Set("ETag", h.etag(name, info, content))
+	if h.manifest.isHashed(name) {
+		header.Set("Cache-Control", immutableCacheControl)
+	} else {
+		header.Set("Cache-Control", revalidateCacheControl)
+	}
+
+	// ServeContent answers If-None-Match and If-Modified-Since and sets the
+	// content type from the extension
+	http.ServeContent(writer, request, name, modTime, bytes.NewReader(content))
+}
+
+func (h *assetsHandler) read(name string) ([]byte, fs.FileInfo, error) {
+	file, err := h.build.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		return nil, nil, fs.ErrNotExist
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return content, info, nil
+}
+
+// etag returns a strong validator for the content. It is remembered as long as
+// the file keeps its modification time and size, so embedded files are only
+// hashed once.
+func (h *assetsHandler) etag(name string, info fs.FileInfo, content []byte) string {
+	cached, ok := h.etags.Load(name)
+	if ok {
+		cached := cached.(assetETag)
+		if cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+			return cached.etag
+		}
+	}
+
+	etag := myHttp.StrongETag(content)
+
+	h.etags.Store(name, assetETag{modTime: info.ModTime(), size: info.Size(), etag: etag})
+
+	return etag
+}
@@ -1,29 +1,103 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	_ "github.com/mattn/go-sqlite3"
+	"fmt"
+	_ "github.com/lib/pq"
 	"log/slog"
+	"meal-planning/database"
+	"meal-planning/database/postgres"
+	"meal-planning/domain"
+	"os"
+	"strings"
 )
 
-func connectDatabase() *sql.DB {
-	db, err := sql.Open("sqlite3", "../data/meal-planner.db")
+// databaseURLVariable configures the database. Postgres is used for
+// postgres:// and postgresql:// URLs, anything else is the path of a SQLite
+// database.
+const (
+	databaseURLVariable = "DATABASE_URL"
+	defaultDatabaseURL  = "../data/meal-planner.db"
+)
+
+// backend bundles the migrations and repositories of one storage engine.
+type backend struct {
+	name                   string
+	migrate                func(ctx context.Context, db *sql.DB) error
+	schemaVersion          func(ctx context.Context, db *sql.DB) (int, error)
+	latestSchemaVersion    int
+	newMealDayRepository   func(db *sql.DB) domain.MealDayRepository
+	newNutritionRepository func(db *sql.DB) domain.NutritionRepository
+	newHistoryRepository   func(db *sql.DB) domain.HistoryRepository
+	newFoodRepository      func(db *sql.DB) domain.FoodRepository
+	newDiaryRepository     func(db *sql.DB) domain.DiaryRepository
+	newRecipeRepository    func(db *sql.DB) domain.RecipeRepository
+}
+
+var sqliteBackend = backend{
+	name:                   "sqlite",
+	migrate:                database.Migrate,
+	schemaVersion:          database.SchemaVersion,
+	latestSchemaVersion:    database.LatestSchemaVersion(),
+	newMealDayRepository:   database.NewSqlMealDayRepository,
+	newNutritionRepository: database.NewSqlNutritionRepository,
+	newHistoryRepository:   database.NewSqlHistoryRepository,
+	newFoodRepository:      database.NewSqlFoodRepository,
+	newDiaryRepository:     database.NewSqlDiaryRepository,
+	newRecipeRepository:    database.NewSqlRecipeRepository,
+}
+
+var postgresBackend = backend{
+	name:                   "postgres",
+	migrate:                postgres.Migrate,
+	schemaVersion:          postgres.SchemaVersion,
+	latestSchemaVersion:    postgres.LatestSchemaVersion(),
+	newMealDayRepository:   postgres.NewMealDayRepository,
+	newNutritionRepository: postgres.NewNutritionRepository,
+	newHistoryRepository:   postgres.NewHistoryRepository,
+	newFoodRepository:      postgres.NewFoodRepository,
+	newDiaryRepository:     postgres.NewDiaryRepository,
+	newRecipeRepository:    postgres.NewRecipeRepository,
+}
+
+func connectDatabase(databaseURL string) (*sql.DB, backend, error) {
+	if strings.HasPrefix(databaseURL, "postgres://") || strings.HasPrefix(databaseURL, "postgresql://") {
+		db, err := sql.Open("postgres", databaseURL)
+		if err != nil {
+			return nil, backend{}, fmt.Errorf("connecting to database: %w", err)
+		}
+
+		return db, postgresBackend, nil
+	}
+
+	db, err := database.Open(databaseURL)
 	if err != nil {
-		slog.Error("failed to connect to database", slog.Any("reason", err))
-		panic(err)
+		return nil, backend{}, fmt.Errorf("connecting to database: %w", err)
 	}
 
-	return db
+	return db, sqliteBackend, nil
 }
 
-func migrateDatabase(db *sql.DB) {
-	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS meals (date TEXT PRIMARY KEY, breakfast TEXT, lunch TEXT, dinner TEXT, snacks TEXT)`)
+// openMigratedDatabase connects to the database configured for the commands
+// run besides the server and migrates it.
+func openMigratedDatabase(ctx context.Context) (*sql.DB, backend, error) {
+	databaseURL := os.Getenv(databaseURLVariable)
+	if databaseURL == "" {
+		databaseURL = defaultDatabaseURL
+	}
+
+	db, storage, err := connectDatabase(databaseURL)
 	if err != nil {
-		panic(err)
+		return nil, backend{}, err
 	}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS nutrition (date TEXT PRIMARY KEY, calories INT, weight INT)`)
+	slog.Info("Migrating database", slog.String("backend", storage.name))
+	err = storage.migrate(ctx, db)
 	if err != nil {
-		panic(err)
+		db.Close()
+		return nil, backend{}, err
 	}
+
+	return db, storage, nil
 }
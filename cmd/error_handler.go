@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"meal-planning/domain"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// internalErrorMessage is shown instead of the messages of errors without a
+// kind, which are not meant for users.
+const internalErrorMessage = "Something went wrong. Please try again later."
+
+var errorStatusCodes = map[domain.ErrorKind]int{
+	domain.ErrorKindValidation: http.StatusBadRequest,
+	domain.ErrorKindNotFound:   http.StatusNotFound,
+	domain.ErrorKindConflict:   http.StatusConflict,
+	domain.ErrorKindForbidden:  http.StatusForbidden,
+}
+
+type errorPageHandler struct {
+	templateHandler
+	statusCode int
+}
+
+type errorPageData struct {
+	Manifest   manifest
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+// problemDetails is the RFC 9457 body of JSON error responses.
+type problemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+func (h *errorPageHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	h.renderError(writer, request, h.statusCode, internalErrorMessage)
+}
+
+// serveError logs err and answers with the status code its kind maps to.
+func (handler *templateHandler) serveError(writer http.ResponseWriter, request *http.Request, err error) {
+	statusCode, ok := errorStatusCodes[domain.KindOf(err)]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		slog.ErrorContext(request.Context(), "Request failed", slog.Any("reason", err))
+	} else {
+		slog.WarnContext(request.Context(), "Request rejected", slog.Int("status", statusCode), slog.Any("reason", err))
+	}
+
+	message, ok := domain.UserMessage(err)
+	if !ok {
+		message = internalErrorMessage
+	}
+
+	handler.renderError(writer, request, statusCode, message)
+}
+
+// renderError answers htmx requests with a fragment for the page's #errors
+// region, clients that prefer JSON with problem details and everyone else with
+// a full error page.
+func (handler *templateHandler) renderError(writer http.ResponseWriter, request *http.Request, statusCode int, message string) {
+	header := writer.Header()
+	// whatever the handler wanted to trigger did not happen
+	header.Del("HX-Trigger")
+
+	data := errorPageData{
+		Manifest:   handler.manifest,
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Message:    message,
+	}
+
+	switch {
+	case request.Header.Get("HX-Request") == "true":
+		header.Set("HX-Retarget", "#errors")
+		header.Set("HX-Reswap", "innerHTML")
+		handler.serveTemplateWithStatus(writer, request, statusCode, "error-message", data)
+	case prefersJSON(request.Header.Get("Accept")):
+		header.Set("Content-Type", "application/problem+json")
+		header.Set("Cache-Control", "no-store")
+		writer.WriteHeader(statusCode)
+
+		err := json.NewEncoder(writer).Encode(problemDetails{
+			Type:     "about:blank",
+			Title:    data.Status,
+			Status:   statusCode,
+			Detail:   message,
+			Instance: request.URL.Path,
+		})
+		if err != nil {
+			slog.WarnContext(request.Context(), "error writing response", slog.Any("reason", err))
+		}
+	default:
+		handler.serveTemplateWithStatus(writer, request, statusCode, "error.gohtml", data)
+	}
+}
+
+// prefersJSON reports whether the Accept header ranks a JSON media type above
+// HTML. Wildcards count for neither, so browsers and clients without a
+// preference get HTML.
+func prefersJSON(accept string) bool {
+	var htmlQuality, jsonQuality float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			htmlQuality = max(htmlQuality, quality)
+		case "application/json", "application/problem+json":
+			jsonQuality = max(jsonQuality, quality)
+		}
+	}
+
+	return jsonQuality > htmlQuality
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestPrefersJSON(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", true},
+		{"application/problem+json, */*;q=0.5", true},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false},
+		{"text/html;q=0.5, application/json", true},
+		{"application/json;q=0.5, text/html", false},
+		{"application/json;q=0", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			if actual := prefersJSON(test.accept); actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"meal-planning/domain"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// keepAliveInterval keeps idle event streams from being closed by proxies.
+const keepAliveInterval = 30 * time.Second
+
+type eventsHandler struct {
+	templateHandler
+	events *domain.EventBus
+}
+
+// ServeHTTP streams changes as server-sent events. Each event carries the
+// re-rendered fragment of the changed meal day or nutrition entry and is named
+// after the fragment and date, e.g. "meal-day-2024-06-30", so that the htmx
+// SSE extension can swap it into the page.
+func (h *eventsHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	controller := http.NewResponseController(writer)
+
+	// the stream stays open far longer than the server's write timeout
+	err := controller.SetWriteDeadline(time.Time{})
+	if err != nil {
+		slog.ErrorContext(request.Context(), "error disabling write deadline", slog.Any("reason", err))
+		return
+	}
+
+	events, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+
+	header := writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-store")
+	header.Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+
+	err = controller.Flush()
+	if err != nil {
+		slog.ErrorContext(request.Context(), "error flushing event stream", slog.Any("reason", err))
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = io.WriteString(writer, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			err = h.writeEvent(writer, request, event)
+		}
+
+		if err == nil {
+			err = controller.Flush()
+		}
+
+		if err != nil {
+			slog.ErrorContext(request.Context(), "error writing event stream", slog.Any("reason", err))
+			return
+		}
+	}
+}
+
+func (h *eventsHandler) writeEvent(writer io.Writer, request *http.Request, event domain.Event) error {
+	var data interface{}
+	switch event.Type {
+	case domain.EventMealDayChanged:
+		data = event.MealDay
+	case domain.EventNutritionChanged:
+		data = newNutritionView(event.Nutrition)
+	default:
+		slog.WarnContext(request.Context(), "Unknown event type", slog.String("type", string(event.Type)))
+		return nil
+	}
+
+	tmpl, err := h.templates()
+	if err != nil {
+		return err
+	}
+
+	fragment := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(fragment, string(event.Type), data)
+	if err != nil {
+		return err
+	}
+
+	message := new(strings.Builder)
+	fmt.Fprintf(message, "event: %s-%s\n", event.Type, event.Date.Format("2006-01-02"))
+	for _, line := range strings.Split(strings.TrimSpace(fragment.String()), "\n") {
+		fmt.Fprintf(message, "data: %s\n", line)
+	}
+	message.WriteString("\n")
+
+	_, err = io.WriteString(writer, message.String())
+	return err
+}
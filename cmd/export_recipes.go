@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"meal-planning/domain"
+	"meal-planning/recipeimport"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// exportRecipesCommand writes every recipe to a file of its own in a
+// directory, which import-recipes reads back, e.g.
+//
+//	meal-planner export-recipes -format markdown ~/recipes
+const exportRecipesCommand = "export-recipes"
+
+func runExportRecipes(args []string) error {
+	flags := flag.NewFlagSet(exportRecipesCommand, flag.ContinueOnError)
+	formatName := flags.String("format", string(recipeimport.Cooklang), "file format, cooklang or markdown")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s %s [-format cooklang|markdown] <directory>\n\nExisting files of recipes with the same name are overwritten.\n\n", os.Args[0], exportRecipesCommand)
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected the path of one directory")
+	}
+
+	format, err := recipeimport.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, storage, err := openMigratedDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	recipeService := domain.NewRecipeService(storage.newRecipeRepository(db), storage.newFoodRepository(db))
+
+	recipes, err := recipeService.All(ctx)
+	if err != nil {
+		return err
+	}
+
+	directory := flags.Arg(0)
+	err = os.MkdirAll(directory, 0o755)
+	if err != nil {
+		return err
+	}
+
+	// names differing only in characters files cannot have would overwrite
+	// each other, and so would names differing in case on some systems
+	taken := map[string]bool{}
+	for _, recipe := range recipes {
+		base := strings.TrimSuffix(recipeimport.FileName(recipe, format), format.Extension())
+		name := base + format.Extension()
+		for i := 2; taken[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s (%d)%s", base, i, format.Extension())
+		}
+		taken[strings.ToLower(name)] = true
+
+		err = writeRecipeFile(filepath.Join(directory, name), format, recipe)
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Exported recipes", slog.Int("exported", len(recipes)), slog.String("directory", directory))
+	return nil
+}
+
+func writeRecipeFile(name string, format recipeimport.Format, recipe domain.Recipe) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	err = format.Write(file, recipe)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	return file.Close()
+}
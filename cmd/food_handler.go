@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"meal-planning/domain"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type foodHandler struct {
+	templateHandler
+	foodService *domain.FoodService
+}
+
+type foodsData struct {
+	Manifest manifest
+	Query    string
+	Foods    []domain.Food
+}
+
+// foodForm is the food being edited and the problems with the values
+// submitted for it by field name.
+type foodForm struct {
+	domain.Food
+	Errors map[string]string
+}
+
+type foodData struct {
+	Manifest manifest
+	Form     foodForm
+}
+
+func (h *foodHandler) getFoods(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query().Get("q")
+
+	foods, err := h.foodService.Search(request.Context(), query)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("searching foods: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "foods.gohtml", foodsData{
+		Manifest: h.manifest,
+		Query:    query,
+		Foods:    foods,
+	})
+}
+
+// searchFoods answers the search field of the food page as you type.
+func (h *foodHandler) searchFoods(writer http.ResponseWriter, request *http.Request) {
+	foods, err := h.foodService.Search(request.Context(), request.URL.Query().Get("q"))
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("searching foods: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "food-list", foods)
+}
+
+func (h *foodHandler) getNewFood(writer http.ResponseWriter, request *http.Request) {
+	h.serveTemplate(writer, request, "food.gohtml", foodData{
+		Manifest: h.manifest,
+		Form:     foodForm{Food: domain.Food{Source: domain.FoodSourceManual}},
+	})
+}
+
+func (h *foodHandler) getFood(writer http.ResponseWriter, request *http.Request) {
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	food, err := h.foodService.FindByID(request.Context(), id)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving food: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "food.gohtml", foodData{
+		Manifest: h.manifest,
+		Form:     foodForm{Food: food},
+	})
+}
+
+func (h *foodHandler) createFood(writer http.ResponseWriter, request *http.Request) {
+	food, fieldErrors, err := parseFoodForm(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	if len(fieldErrors) > 0 {
+		h.serveInvalidFood(writer, request, food, &domain.ValidationError{Fields: fieldErrors})
+		return
+	}
+
+	_, err = h.foodService.Create(request.Context(), food)
+
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		h.serveInvalidFood(writer, request, food, invalid)
+		return
+	}
+
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("creating food: %w", err))
+		return
+	}
+
+	redirect(writer, request, "/foods")
+}
+
+func (h *foodHandler) updateFood(writer http.ResponseWriter, request *http.Request) {
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	food, fieldErrors, err := parseFoodForm(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+	food.ID = id
+
+	if len(fieldErrors) > 0 {
+		h.serveInvalidFood(writer, request, food, &domain.ValidationError{Fields: fieldErrors})
+		return
+	}
+
+	_, err = h.foodService.Update(request.Context(), food)
+
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		h.serveInvalidFood(writer, request, food, invalid)
+		return
+	}
+
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("updating food: %w", err))
+		return
+	}
+
+	redirect(writer, request, "/foods")
+}
+
+func (h *foodHandler) deleteFood(writer http.ResponseWriter, request *http.Request) {
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	err = h.foodService.Delete(request.Context(), id)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("deleting food: %w", err))
+		return
+	}
+
+	redirect(writer, request, "/foods")
+}
+
+// serveInvalidFood shows the food as submitted with the problems next to the
+// fields.
+func (h *foodHandler) serveInvalidFood(writer http.ResponseWriter, request *http.Request, food domain.Food, invalid *domain.ValidationError) {
+	slog.InfoContext(request.Context(), "Rejected invalid food", slog.Int64("id", food.ID), slog.Any("reason", invalid))
+
+	h.serveTemplateWithStatus(writer, request, http.StatusUnprocessableEntity, "food-form", foodForm{Food: food, Errors: invalid.Fields})
+}
+
+// parseFoodForm reads the food of the food form. Values that do not parse are
+// returned as field errors, a form that cannot be read at all as error.
+func parseFoodForm(request *http.Request) (domain.Food, map[string]string, error) {
+	err := request.ParseForm()
+	if err != nil {
+		return domain.Food{}, nil, domain.NewError(domain.ErrorKindValidation, "could not parse form")
+	}
+
+	food := domain.Food{
+		Name:  request.PostForm.Get("name"),
+		Brand: strings.TrimSpace(request.PostForm.Get("brand")),
+	}
+
+	if value := request.PostForm.Get("version"); value != "" {
+		food.Version, err = strconv.Atoi(value)
+		if err != nil {
+			return domain.Food{}, nil, domain.NewError(domain.ErrorKindValidation, "version must be a number")
+		}
+	}
+
+	// empty fields mean nothing was entered, anything else has to parse
+	fieldErrors := map[string]string{}
+	parseNutrient := func(field string) float64 {
+		value := strings.TrimSpace(request.PostForm.Get(field))
+		if value == "" {
+			return 0
+		}
+
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			fieldErrors[field] = "must be a number"
+			return 0
+		}
+
+		return number
+	}
+
+	food.Per100g = domain.Nutrients{
+		Kcal:    parseNutrient("kcal"),
+		Protein: parseNutrient("protein"),
+		Carbs:   parseNutrient("carbs"),
+		Fat:     parseNutrient("fat"),
+	}
+
+	// serving sizes come as pairs of names and weights, rows left empty are
+	// the blank row of the form
+	names := request.PostForm["serving_name"]
+	grams := request.PostForm["serving_grams"]
+	for i := range max(len(names), len(grams)) {
+		servingSize := domain.ServingSize{}
+		if i < len(names) {
+			servingSize.Name = strings.TrimSpace(names[i])
+		}
+
+		value := ""
+		if i < len(grams) {
+			value = strings.TrimSpace(grams[i])
+		}
+
+		if servingSize.Name == "" && value == "" {
+			continue
+		}
+
+		if value != "" {
+			servingSize.Grams, err = strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(servingSize.Grams) || math.IsInf(servingSize.Grams, 0) {
+				fieldErrors["servingSizes"] = "must weigh a number of grams"
+			}
+		}
+
+		food.ServingSizes = append(food.ServingSizes, servingSize)
+	}
+
+	return food, fieldErrors, nil
+}
+
+// pathID parses the id path value of requests like GET /foods/{id}.
+func pathID(request *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(request.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, domain.NewError(domain.ErrorKindValidation, "id must be a number")
+	}
+
+	return id, nil
+}
+
+// redirect sends the browser to target after a change, with HX-Redirect for
+// htmx and a 303 for plain form posts.
+func redirect(writer http.ResponseWriter, request *http.Request, target string) {
+	if request.Header.Get("HX-Request") == "true" {
+		writer.Header().Set("HX-Redirect", target)
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Redirect(writer, request, target, http.StatusSeeOther)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type healthHandler struct {
+	db      *sql.DB
+	backend backend
+	// shuttingDown is set once the server stopped accepting new work, so that
+	// orchestrators stop routing traffic to it.
+	shuttingDown atomic.Bool
+}
+
+// live reports that the process is up and able to handle requests.
+func (h *healthHandler) live(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintln(writer, "ok")
+}
+
+// ready reports whether the database is reachable and fully migrated.
+func (h *healthHandler) ready(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Cache-Control", "no-store")
+
+	if h.shuttingDown.Load() {
+		http.Error(writer, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), readinessTimeout)
+	defer cancel()
+
+	err := h.db.PingContext(ctx)
+	if err != nil {
+		slog.WarnContext(request.Context(), "database is not reachable", slog.Any("reason", err))
+		http.Error(writer, "database is not reachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	version, err := h.backend.schemaVersion(ctx, h.db)
+	if err != nil {
+		slog.WarnContext(request.Context(), "error reading schema version", slog.Any("reason", err))
+		http.Error(writer, "could not read schema version", http.StatusServiceUnavailable)
+		return
+	}
+
+	if version != h.backend.latestSchemaVersion {
+		slog.WarnContext(request.Context(), "database is not migrated", slog.Int("version", version), slog.Int("expectedVersion", h.backend.latestSchemaVersion))
+		http.Error(writer, fmt.Sprintf("schema version %d, expected %d", version, h.backend.latestSchemaVersion), http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(writer, "ok")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"meal-planning/domain"
+	"meal-planning/foodimport"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// importFoodsCommand fills the food catalog from an OpenFoodFacts or FoodData
+// Central dump, e.g.
+//
+//	meal-planner import-foods en.openfoodfacts.org.products.csv.gz
+const importFoodsCommand = "import-foods"
+
+func runImportFoods(args []string) error {
+	flags := flag.NewFlagSet(importFoodsCommand, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s %s <dump>\n\nDumps are picked by extension: .csv or .tsv and .jsonl from OpenFoodFacts, .json from FoodData Central, optionally gzipped.\n", os.Args[0], importFoodsCommand)
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected the path of one dump")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, storage, err := openMigratedDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dump, err := foodimport.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer dump.Close()
+
+	foodService := domain.NewFoodService(storage.newFoodRepository(db))
+
+	result, err := foodService.Import(ctx, dump)
+	if err != nil {
+		return fmt.Errorf("importing foods: %w", err)
+	}
+
+	slog.Info("Imported foods", slog.Int("imported", result.Imported), slog.Int("skipped", result.Skipped))
+	return nil
+}
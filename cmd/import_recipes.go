@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"meal-planning/domain"
+	"meal-planning/recipeimport"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// importRecipesCommand adds the Cooklang and Markdown recipes of a directory,
+// a zip archive or a single file to the recipes, e.g.
+//
+//	meal-planner import-recipes ~/recipes
+//
+// Recipes named like one already stored replace it.
+const importRecipesCommand = "import-recipes"
+
+func runImportRecipes(args []string) error {
+	flags := flag.NewFlagSet(importRecipesCommand, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s %s <directory|zip|file>\n\nReads .cook files as Cooklang and .md or .markdown files as Markdown.\n", os.Args[0], importRecipesCommand)
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected the path of one directory, zip archive or recipe file")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, storage, err := openMigratedDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	files, err := recipeimport.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer files.Close()
+
+	recipeService := domain.NewRecipeService(storage.newRecipeRepository(db), storage.newFoodRepository(db))
+
+	result, err := recipeService.Import(ctx, files)
+	if err != nil {
+		return fmt.Errorf("importing recipes: %w", err)
+	}
+
+	slog.Info("Imported recipes", slog.Int("created", result.Created), slog.Int("updated", result.Updated), slog.Int("skipped", result.Skipped))
+	return nil
+}
@@ -3,15 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"html/template"
+	"io/fs"
 	"log/slog"
-	"meal-planning/database"
+	"math"
+	mealplanning "meal-planning"
 	"meal-planning/domain"
 	myHttp "meal-planning/http"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -20,122 +30,257 @@ type manifestFile struct {
 }
 
 type manifest struct {
+	// CssFiles and JsFiles are the URLs pages link to. They are root-relative,
+	// so they resolve the same on nested paths like /foods/{id}.
 	CssFiles []string
 	JsFiles  []string
+	// hashed holds every output file. esbuild puts a content hash into all of
+	// their names, so they never change under the same URL.
+	hashed map[string]struct{}
+}
+
+func (m manifest) isHashed(name string) bool {
+	_, ok := m.hashed[name]
+	return ok
+}
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 15 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+	// shutdownTimeout is the time in-flight requests get to finish after a
+	// shutdown signal before their connections are closed.
+	shutdownTimeout = 20 * time.Second
+)
+
+// householdVariable configures who meals are planned for, as read by
+// domain.ParseHousehold.
+const householdVariable = "HOUSEHOLD"
+
+// commands run instead of the server when named by the first argument.
+var commands = map[string]func(args []string) error{
+	importFoodsCommand:   runImportFoods,
+	importRecipesCommand: runImportRecipes,
+	exportRecipesCommand: runExportRecipes,
 }
 
 func main() {
-	slog.Info("Starting application")
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			slog.SetDefault(slog.New(myHttp.NewContextHandler(slog.NewTextHandler(os.Stderr, nil))))
 
-	slog.Info("Connecting to database")
-	db := connectDatabase()
-	defer db.Close()
-	migrateDatabase(db)
+			err := command(os.Args[2:])
+			if err != nil {
+				slog.Error("Command failed", slog.String("command", os.Args[1]), slog.Any("reason", err))
+				os.Exit(1)
+			}
 
-	mealDayRepo := database.NewSqlMealDayRepository(db)
-	mealDayService := domain.NewMealDayService(mealDayRepo)
+			return
+		}
+	}
 
-	nutritionRepo := database.NewSqlNutritionRepository(db)
-	nutritionService := domain.NewNutritionService(nutritionRepo)
+	dev := flag.Bool("dev", false, "read views and assets from the working directory and reload templates on every request")
+	flag.Parse()
 
-	slog.Info("Loading manifest")
-	file, err := os.OpenFile("./manifest.json", os.O_RDONLY, os.ModePerm)
+	slog.SetDefault(slog.New(myHttp.NewContextHandler(slog.NewTextHandler(os.Stderr, nil))))
+
+	err := run(*dev)
 	if err != nil {
-		slog.Error("Failed to open manifest.json", slog.Any("reason", err))
-		panic(err)
+		slog.Error("Application failed", slog.Any("reason", err))
+		os.Exit(1)
 	}
 
-	mFile := manifestFile{}
-	err = json.NewDecoder(file).Decode(&mFile)
+	slog.Info("Application stopped")
+}
+
+func run(dev bool) error {
+	slog.Info("Starting application")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	household, err := domain.ParseHousehold(os.Getenv(householdVariable))
 	if err != nil {
-		slog.Error("Failed to parse manifest.json", slog.Any("reason", err))
-		panic(err)
+		return fmt.Errorf("reading %s: %w", householdVariable, err)
 	}
 
-	myManifest := manifest{}
-	for _, file := range mFile.OutputFiles {
-		if path.Ext(file) == ".css" {
-			myManifest.CssFiles = append(myManifest.CssFiles, file)
-		} else if path.Ext(file) == ".js" {
-			myManifest.JsFiles = append(myManifest.JsFiles, file)
-		} else {
-			slog.Warn("Unknown file extension", slog.Any("file", file))
+	databaseURL := os.Getenv(databaseURLVariable)
+	if databaseURL == "" {
+		databaseURL = defaultDatabaseURL
+	}
+
+	slog.Info("Connecting to database")
+	db, storage, err := connectDatabase(databaseURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		slog.Info("Closing database")
+
+		err := db.Close()
+		if err != nil {
+			slog.Error("failed to close database", slog.Any("reason", err))
 		}
+	}()
+
+	slog.Info("Migrating database", slog.String("backend", storage.name))
+	err = storage.migrate(ctx, db)
+	if err != nil {
+		return err
 	}
 
-	tmpl, err := template.ParseGlob("./views/*.gohtml")
+	config := applicationConfig{
+		db:        db,
+		backend:   storage,
+		household: household,
+		views:     mealplanning.Views(),
+		build:     mealplanning.Build(),
+	}
+	if dev {
+		slog.Info("Reading views and assets from disk")
+		config.views = os.DirFS("views")
+		config.build = os.DirFS("build")
+		config.reloadTemplates = true
+	}
+
+	app, err := newApplication(config)
 	if err != nil {
-		slog.Error("Error parsing template", slog.Any("reason", err))
-		panic(err)
+		return err
 	}
 
-	tmplHandler := templateHandler{
-		template: tmpl,
+	server := &http.Server{
+		Addr:              ":8080",
+		Handler:           app.handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
 	}
-	indexHandler := &indexHandler{
-		templateHandler: tmplHandler,
-		manifest:        myManifest,
-		mealDayService:  mealDayService,
+	// event streams never finish on their own, end them so shutdown does not
+	// have to wait for them
+	server.RegisterOnShutdown(app.eventBus.Close)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", slog.String("address", server.Addr))
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		return fmt.Errorf("running server: %w", err)
+	case <-ctx.Done():
 	}
 
-	nutritionHandler := &nutritionHandler{
-		templateHandler:  tmplHandler,
-		manifest:         myManifest,
-		nutritionService: nutritionService,
+	slog.Info("Shutting down server")
+	app.health.shuttingDown.Store(true)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		return fmt.Errorf("shutting down server: %w", err)
 	}
 
-	mealHandler := &mealHandler{
-		templateHandler: tmplHandler,
-		mealDayService:  mealDayService,
+	return nil
+}
+
+func loadManifest(fsys fs.FS, name string) (manifest, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return manifest{}, fmt.Errorf("opening manifest: %w", err)
 	}
+	defer file.Close()
 
-	mux := http.NewServeMux()
-	mux.Handle("/assets/", http.StripPrefix("/assets", http.FileServer(http.Dir("./assets"))))
-	mux.HandleFunc("GET /meals", mealHandler.getMeals)
-	mux.HandleFunc("GET /meals/{date}", mealHandler.getMealByDate)
-	mux.HandleFunc("PUT /meals/{date}", mealHandler.updateMealByDate)
-	mux.HandleFunc("GET /meals/{date}/form", mealHandler.getMealFormByDate)
-	mux.HandleFunc("PUT /nutrition/{date}", nutritionHandler.updateNutritionEntry)
+	mFile := manifestFile{}
+	err = json.NewDecoder(file).Decode(&mFile)
+	if err != nil {
+		return manifest{}, fmt.Errorf("parsing manifest: %w", err)
+	}
 
-	mux.Handle("/nutrition", nutritionHandler)
-	mux.Handle("/", indexHandler)
+	myManifest := manifest{hashed: make(map[string]struct{}, len(mFile.OutputFiles))}
+	for _, file := range mFile.OutputFiles {
+		myManifest.hashed[file] = struct{}{}
 
-	slog.Info("Starting server")
-	err = http.ListenAndServe(":8080", mux)
+		if path.Ext(file) == ".css" {
+			myManifest.CssFiles = append(myManifest.CssFiles, "/"+file)
+		} else if path.Ext(file) == ".js" {
+			myManifest.JsFiles = append(myManifest.JsFiles, "/"+file)
+		} else if path.Ext(file) != ".map" {
+			slog.Warn("Unknown file extension", slog.Any("file", file))
+		}
+	}
+
+	return myManifest, nil
+}
+
+func parseTemplates(views fs.FS) (*template.Template, error) {
+	tmpl, err := template.ParseFS(views, "*.gohtml")
 	if err != nil {
-		slog.Error("error running server", slog.Any("reason", err))
-		panic(err)
+		return nil, fmt.Errorf("parsing templates: %w", err)
 	}
+
+	return tmpl, nil
 }
 
 type templateHandler struct {
 	template *template.Template
+	// manifest lists the assets full pages link to.
+	manifest manifest
+	// reload parses the templates again for every render if set, so changes to
+	// the views show up without a restart.
+	reload func() (*template.Template, error)
 }
 
-func (handler *templateHandler) serveTemplate(writer http.ResponseWriter, name string, data interface{}) {
-	bufferedWriter := myHttp.NewBufferedResponseWriter(writer)
+func (handler *templateHandler) templates() (*template.Template, error) {
+	if handler.reload != nil {
+		return handler.reload()
+	}
 
-	err := handler.template.ExecuteTemplate(bufferedWriter, name, data)
+	return handler.template, nil
+}
+
+func (handler *templateHandler) serveTemplate(writer http.ResponseWriter, request *http.Request, name string, data interface{}) {
+	handler.serveTemplateWithStatus(writer, request, http.StatusOK, name, data)
+}
+
+func (handler *templateHandler) serveTemplateWithStatus(writer http.ResponseWriter, request *http.Request, statusCode int, name string, data interface{}) {
+	tmpl, err := handler.templates()
+	if err != nil {
+		slog.ErrorContext(request.Context(), "Error loading templates", slog.Any("reason", err))
+		http.Error(writer, "could not load templates", http.StatusInternalServerError)
+		return
+	}
+
+	// nothing is sent before Close, a failing template can still be answered
+	// with an error instead
+	bufferedWriter := myHttp.NewBufferedResponseWriter(writer, request)
+	bufferedWriter.WriteHeader(statusCode)
+
+	err = tmpl.ExecuteTemplate(bufferedWriter, name, data)
 	if err != nil {
-		slog.Error("Error executing template", slog.Any("reason", err))
+		slog.ErrorContext(request.Context(), "Error executing template", slog.Any("reason", err))
 		http.Error(writer, "could not render template", http.StatusInternalServerError)
 		return
 	}
 
+	// pages change with every edit, browsers keep them but revalidate with the
+	// ETag the buffered writer derives from the body
 	header := writer.Header()
-	header.Add("Content-Type", "text/html; charset=utf-8")
-	header.Add("Cache-Control", "no-store")
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
 
 	err = bufferedWriter.Close()
 	if err != nil {
-		slog.Error("error writing response", slog.Any("reason", err))
-		panic(err)
+		// the client is most likely gone, there is no one left to tell
+		slog.WarnContext(request.Context(), "error writing response", slog.Any("reason", err))
 	}
 }
 
 type indexHandler struct {
 	templateHandler
-	manifest       manifest
 	mealDayService *domain.MealDayService
 }
 
@@ -146,108 +291,479 @@ type indexData struct {
 
 func (h *indexHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	start := time.Now()
-	end := start.Add(7 * 24 * time.Hour)
+	end := start.AddDate(0, 0, 6)
 
-	meals, err := h.mealDayService.FindByDateRange(context.TODO(), start, end)
+	meals, err := h.mealDayService.FindByDateRange(request.Context(), start, end)
 	if err != nil {
-		slog.Error("error retrieving meals from repository", slog.Any("reason", err))
-		http.Error(writer, "failed retrieving meal days", http.StatusInternalServerError)
+		h.serveError(writer, request, fmt.Errorf("retrieving meals: %w", err))
 		return
 	}
 
-	h.serveTemplate(writer, "index.gohtml", indexData{
+	h.serveTemplate(writer, request, "index.gohtml", indexData{
 		Manifest: h.manifest,
 		Meals:    meals,
 	})
 }
 
+// pathDate parses the date path value of requests like GET /meals/{date}.
+func pathDate(request *http.Request) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", request.PathValue("date"))
+	if err != nil {
+		return time.Time{}, domain.NewError(domain.ErrorKindValidation, "date must be an ISO date")
+	}
+
+	return date, nil
+}
+
 type mealHandler struct {
 	templateHandler
-	mealDayService *domain.MealDayService
+	mealDayService   *domain.MealDayService
+	recipeService    *domain.RecipeService
+	nutritionService *domain.NutritionService
 }
 
 func (h *mealHandler) getMeals(writer http.ResponseWriter, request *http.Request) {
 	start := time.Now()
-	end := start.Add(7 * 24 * time.Hour)
+	end := start.AddDate(0, 0, 6)
 
-	meals, err := h.mealDayService.FindByDateRange(context.TODO(), start, end)
+	meals, err := h.mealDayService.FindByDateRange(request.Context(), start, end)
 	if err != nil {
-		slog.Error("error retrieving meals from repository", slog.Any("reason", err))
-		http.Error(writer, "failed retrieving meal days", http.StatusInternalServerError)
+		h.serveError(writer, request, fmt.Errorf("retrieving meals: %w", err))
 		return
 	}
 
-	h.serveTemplate(writer, "meal-list", meals)
+	h.serveTemplate(writer, request, "meal-list", meals)
 }
 
 func (h *mealHandler) getMealByDate(writer http.ResponseWriter, request *http.Request) {
-	dateString := request.PathValue("date")
-	date, err := time.Parse("2006-01-02", dateString)
+	date, err := pathDate(request)
 	if err != nil {
-		slog.Error("error parsing date", slog.Any("reason", err))
-		http.Error(writer, "date must be an ISO date", http.StatusBadRequest)
+		h.serveError(writer, request, err)
 		return
 	}
 
-	meal, err := h.mealDayService.FindByDate(context.TODO(), date)
+	meal, err := h.mealDayService.FindByDate(request.Context(), date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving meal: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "meal-day", meal)
+}
+
+// mealDayForm is the meal day being edited and the problems with the values
+// submitted for it by field name.
+type mealDayForm struct {
+	domain.MealDay
+	Errors map[string]string
+	// Earlier are the days before whose meals can have leftovers for the day.
+	Earlier []domain.MealDay
+}
+
+// slotForm holds the fields of a cooked slot in the meal day form besides the
+// meal: who eats it and what it is leftovers of.
+type slotForm struct {
+	Date time.Time
+	domain.Attendance
+	// Default is the number of servings if none are set.
+	Default       int
+	Members       []string
+	ServingsError string
+	AbsentError   string
+	// Leftovers is the selected leftovers option, empty if the slot is
+	// cooked for.
+	Leftovers        string
+	LeftoversOptions []leftoversOption
+	LeftoversError   string
+}
+
+// leftoversOption is an earlier meal a slot can eat the leftovers of.
+type leftoversOption struct {
+	Value string
+	Label string
+}
+
+// IsAbsent reports whether member was marked as eating elsewhere.
+func (form slotForm) IsAbsent(member string) bool {
+	return slices.ContainsFunc(form.Absent, func(absent string) bool {
+		return strings.EqualFold(absent, member)
+	})
+}
+
+// SlotForm returns the fields of slot.
+func (form mealDayForm) SlotForm(slot domain.MealSlot) slotForm {
+	withoutServings := form.MealDay
+	attendance := form.AttendanceOf(slot)
+	withoutServings.Attendance = []domain.Attendance{{Slot: slot, Absent: attendance.Absent}}
+
+	fields := slotForm{
+		Date:           form.Date,
+		Attendance:     attendance,
+		Default:        withoutServings.Servings(slot),
+		Members:        form.Household.Members,
+		ServingsError:  form.Errors[string(slot)+"-servings"],
+		AbsentError:    form.Errors[string(slot)+"-absent"],
+		LeftoversError: form.Errors[string(slot)+"-leftovers"],
+	}
+
+	if of := form.LeftoversOf(slot); of != nil {
+		fields.Leftovers = formatMealRef(*of)
+	}
+
+	days := append(slices.Clone(form.Earlier), form.MealDay)
+	for _, day := range days {
+		for _, meal := range day.PlannedMeals() {
+			ref := domain.MealRef{Date: day.Date, Slot: meal.Slot}
+			if !slices.Contains(domain.CookedSlots, meal.Slot) || meal.LeftoversOf != nil || !ref.Before(domain.MealRef{Date: form.Date, Slot: slot}) {
+				continue
+			}
+
+			fields.LeftoversOptions = append(fields.LeftoversOptions, leftoversOption{
+				Value: formatMealRef(ref),
+				Label: day.Date.Format("Mon 2.1.") + " " + string(meal.Slot) + ": " + meal.Name,
+			})
+		}
+	}
+
+	return fields
+}
+
+// formatMealRef writes ref like the leftovers options of the meal day form,
+// e.g. "2024-06-02/dinner".
+func formatMealRef(ref domain.MealRef) string {
+	return ref.Date.Format("2006-01-02") + "/" + string(ref.Slot)
+}
+
+// parseSlots reads the servings, absent members and leftovers of the cooked
+// slots in the meal day form, e.g. "lunch-servings", "lunch-absent" and
+// "lunch-leftovers". An empty number of servings serves the household, empty
+// leftovers are cooked for.
+func parseSlots(form url.Values) ([]domain.Attendance, []domain.Leftovers, map[string]string) {
+	attendance := make([]domain.Attendance, 0, len(domain.CookedSlots))
+	leftovers := make([]domain.Leftovers, 0)
+	fieldErrors := map[string]string{}
+
+	for _, slot := range domain.CookedSlots {
+		slotAttendance := domain.Attendance{Slot: slot, Absent: form[string(slot)+"-absent"]}
+
+		if servings := strings.TrimSpace(form.Get(string(slot) + "-servings")); servings != "" {
+			var err error
+			slotAttendance.Servings, err = strconv.Atoi(servings)
+			if err != nil {
+				fieldErrors[string(slot)+"-servings"] = "must be a whole number"
+			}
+		}
+
+		attendance = append(attendance, slotAttendance)
+
+		if of := form.Get(string(slot) + "-leftovers"); of != "" {
+			date, ofSlot, _ := strings.Cut(of, "/")
+			parsed, err := time.Parse("2006-01-02", date)
+			if err != nil || !domain.MealSlot(ofSlot).Valid() {
+				fieldErrors[string(slot)+"-leftovers"] = "must be a planned meal"
+				continue
+			}
+
+			leftovers = append(leftovers, domain.Leftovers{Slot: slot, Of: domain.MealRef{Date: parsed, Slot: domain.MealSlot(ofSlot)}})
+		}
+	}
+
+	return attendance, leftovers, fieldErrors
+}
+
+// serveMealForm serves the meal day form with the earlier days that can have
+// leftovers for it.
+func (h *mealHandler) serveMealForm(writer http.ResponseWriter, request *http.Request, statusCode int, form mealDayForm) {
+	earlier, err := h.mealDayService.FindByDateRange(request.Context(), form.Date.AddDate(0, 0, -domain.MaxLeftoversDays), form.Date.AddDate(0, 0, -1))
 	if err != nil {
-		slog.Error("error retrieving meal from service", slog.Any("reason", err))
-		http.Error(writer, "failed retrieving meal", http.StatusInternalServerError)
+		h.serveError(writer, request, fmt.Errorf("retrieving earlier meals: %w", err))
 		return
 	}
 
-	h.serveTemplate(writer, "meal-day", meal)
+	form.Earlier = earlier
+	h.serveTemplateWithStatus(writer, request, statusCode, "meal-day-form", form)
 }
 
 func (h *mealHandler) getMealFormByDate(writer http.ResponseWriter, request *http.Request) {
-	dateString := request.PathValue("date")
-	date, err := time.Parse("2006-01-02", dateString)
+	date, err := pathDate(request)
 	if err != nil {
-		slog.Error("error parsing date", slog.Any("reason", err))
-		http.Error(writer, "date must be an ISO date", http.StatusBadRequest)
+		h.serveError(writer, request, err)
 		return
 	}
 
-	meal, err := h.mealDayService.FindByDate(context.TODO(), date)
+	meal, err := h.mealDayService.FindByDate(request.Context(), date)
 	if err != nil {
-		slog.Error("error retrieving meal from service", slog.Any("reason", err))
-		http.Error(writer, "failed retrieving meal", http.StatusInternalServerError)
+		h.serveError(writer, request, fmt.Errorf("retrieving meal: %w", err))
 		return
 	}
 
-	h.serveTemplate(writer, "meal-day-form", meal)
+	h.serveMealForm(writer, request, http.StatusOK, mealDayForm{MealDay: meal})
 }
 
 func (h *mealHandler) updateMealByDate(writer http.ResponseWriter, request *http.Request) {
-	dateString := request.PathValue("date")
-	date, err := time.Parse("2006-01-02", dateString)
+	date, err := pathDate(request)
 	if err != nil {
-		slog.Error("error parsing date", slog.Any("reason", err))
-		http.Error(writer, "date must be an ISO date", http.StatusBadRequest)
+		h.serveError(writer, request, err)
 		return
 	}
 
 	err = request.ParseForm()
 	if err != nil {
-		slog.Error("error parsing form", slog.Any("reason", err))
-		http.Error(writer, "could not parse form", http.StatusBadRequest)
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "could not parse form"))
+		return
+	}
+
+	version, err := strconv.Atoi(request.Form.Get("version"))
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "version must be a number"))
 		return
 	}
 
+	attendance, leftovers, fieldErrors := parseSlots(request.Form)
 	meal := domain.MealDay{
-		Date:      date,
-		Breakfast: request.Form.Get("breakfast"),
-		Lunch:     request.Form.Get("lunch"),
-		Dinner:    request.Form.Get("dinner"),
-		Snacks:    strings.Split(request.Form.Get("snacks"), ","),
+		Date:       date,
+		Breakfast:  request.Form.Get("breakfast"),
+		Lunch:      request.Form.Get("lunch"),
+		Dinner:     request.Form.Get("dinner"),
+		Snacks:     strings.Split(request.Form.Get("snacks"), ","),
+		Attendance: attendance,
+		Leftovers:  leftovers,
+		Household:  h.mealDayService.Household(),
+		Version:    version,
 	}
 
-	meal, err = h.mealDayService.Upsert(context.TODO(), meal)
+	if len(fieldErrors) > 0 {
+		slog.InfoContext(request.Context(), "Rejected invalid meal", slog.String("date", date.Format("2006-01-02")), slog.Any("reason", fieldErrors))
+		h.serveMealForm(writer, request, http.StatusUnprocessableEntity, mealDayForm{MealDay: meal, Errors: fieldErrors})
+		return
+	}
+
+	updated, err := h.mealDayService.Upsert(request.Context(), meal)
+	if errors.Is(err, domain.MealConflict) {
+		h.serveMealConflict(writer, request, meal)
+		return
+	}
+
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		slog.InfoContext(request.Context(), "Rejected invalid meal", slog.String("date", date.Format("2006-01-02")), slog.Any("reason", invalid))
+		h.serveMealForm(writer, request, http.StatusUnprocessableEntity, mealDayForm{MealDay: meal, Errors: invalid.Fields})
+		return
+	}
+
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("updating meal: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "meal-day", updated)
+}
+
+type mealDayConflictData struct {
+	Date    time.Time
+	Mine    domain.MealDay
+	Current domain.MealDay
+}
+
+func (h *mealHandler) serveMealConflict(writer http.ResponseWriter, request *http.Request, mine domain.MealDay) {
+	current, err := h.mealDayService.FindByDate(request.Context(), mine.Date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving meal: %w", err))
+		return
+	}
+
+	h.serveTemplateWithStatus(writer, request, http.StatusConflict, "meal-day-conflict", mealDayConflictData{
+		Date:    mine.Date,
+		Mine:    mine,
+		Current: current,
+	})
+}
+
+type mealDayHistoryData struct {
+	Date    time.Time
+	Changes []domain.MealDayChange
+}
+
+func (h *mealHandler) getMealHistoryByDate(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
 	if err != nil {
-		slog.Error("error updating meal", slog.Any("reason", err))
-		http.Error(writer, "failed updating meal", http.StatusInternalServerError)
+		h.serveError(writer, request, err)
 		return
 	}
 
-	h.serveTemplate(writer, "meal-day", meal)
+	changes, err := h.mealDayService.FindHistory(request.Context(), date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving meal history: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "meal-day-history", mealDayHistoryData{
+		Date:    date,
+		Changes: changes,
+	})
+}
+
+func (h *mealHandler) restoreMealByDate(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	historyID, err := strconv.ParseInt(request.PathValue("id"), 10, 64)
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "id must be a number"))
+		return
+	}
+
+	meal, err := h.mealDayService.Restore(request.Context(), date, historyID)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("restoring meal: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "meal-day", meal)
+}
+
+// plannedMealView is a planned meal with the recipe it is linked to, which is
+// nil for meals without a recipe.
+type plannedMealView struct {
+	domain.PlannedMeal
+	Recipe *domain.Recipe
+}
+
+// eatenMealForm holds the values submitted to log a planned meal, so they
+// survive validation errors.
+type eatenMealForm struct {
+	Slot     domain.MealSlot
+	Name     string
+	Portions string
+}
+
+type mealDayEatenData struct {
+	Date   time.Time
+	Meals  []plannedMealView
+	Form   eatenMealForm
+	Errors map[string]string
+	// Logged is the diary entry of the meal logged last.
+	Logged *domain.DiaryEntry
+}
+
+func (h *mealHandler) getMealEatenByDate(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	h.serveMealDayEaten(writer, request, http.StatusOK, mealDayEatenData{Date: date})
+}
+
+// logMealAsEaten adds portions of the recipe of a planned meal to the food
+// diary of the day.
+func (h *mealHandler) logMealAsEaten(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	err = request.ParseForm()
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "could not parse form"))
+		return
+	}
+
+	form := eatenMealForm{
+		Slot:     domain.MealSlot(request.PostForm.Get("slot")),
+		Name:     strings.TrimSpace(request.PostForm.Get("meal")),
+		Portions: request.PostForm.Get("portions"),
+	}
+
+	meal, err := h.mealDayService.FindByDate(request.Context(), date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving meal: %w", err))
+		return
+	}
+
+	planned := slices.ContainsFunc(meal.PlannedMeals(), func(planned domain.PlannedMeal) bool {
+		return planned.Slot == form.Slot && planned.Name == form.Name
+	})
+	if !planned {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "meal is not planned for this day"))
+		return
+	}
+
+	fieldErrors := map[string]string{}
+	recipe, err := h.recipeService.FindByName(request.Context(), form.Name)
+	if errors.Is(err, domain.RecipeNotFound) {
+		fieldErrors["meal"] = "has no recipe"
+	} else if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving recipe: %w", err))
+		return
+	}
+
+	portions, err := strconv.ParseFloat(strings.TrimSpace(form.Portions), 64)
+	if err != nil || math.IsNaN(portions) || math.IsInf(portions, 0) {
+		fieldErrors["portions"] = "must be a number"
+	}
+
+	if len(fieldErrors) > 0 {
+		h.serveInvalidEatenMeal(writer, request, date, form, &domain.ValidationError{Fields: fieldErrors})
+		return
+	}
+
+	_, err = h.nutritionService.LogRecipe(request.Context(), date, form.Slot, recipe, portions)
+
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		h.serveInvalidEatenMeal(writer, request, date, form, invalid)
+		return
+	}
+
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("logging meal: %w", err))
+		return
+	}
+
+	h.serveMealDayEaten(writer, request, http.StatusOK, mealDayEatenData{
+		Date: date,
+		Logged: &domain.DiaryEntry{
+			FoodName:  recipe.Name,
+			Portions:  portions,
+			Nutrients: recipe.PerServing.Scale(portions),
+		},
+	})
+}
+
+func (h *mealHandler) serveInvalidEatenMeal(writer http.ResponseWriter, request *http.Request, date time.Time, form eatenMealForm, invalid *domain.ValidationError) {
+	slog.InfoContext(request.Context(), "Rejected invalid eaten meal", slog.String("date", date.Format("2006-01-02")), slog.Any("reason", invalid))
+
+	h.serveMealDayEaten(writer, request, http.StatusUnprocessableEntity, mealDayEatenData{Date: date, Form: form, Errors: invalid.Fields})
+}
+
+// serveMealDayEaten lists the planned meals of the day with their recipes, so
+// they can be logged as eaten.
+func (h *mealHandler) serveMealDayEaten(writer http.ResponseWriter, request *http.Request, statusCode int, data mealDayEatenData) {
+	meal, err := h.mealDayService.FindByDate(request.Context(), data.Date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving meal: %w", err))
+		return
+	}
+
+	for _, planned := range meal.PlannedMeals() {
+		view := plannedMealView{PlannedMeal: planned}
+
+		recipe, err := h.recipeService.FindByName(request.Context(), planned.Name)
+		if err == nil {
+			view.Recipe = &recipe
+		} else if !errors.Is(err, domain.RecipeNotFound) {
+			h.serveError(writer, request, fmt.Errorf("retrieving recipe: %w", err))
+			return
+		}
+
+		data.Meals = append(data.Meals, view)
+	}
+
+	h.serveTemplateWithStatus(writer, request, statusCode, "meal-day-eaten", data)
 }
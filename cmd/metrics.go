@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"meal-planning/domain"
+	myHttp "meal-planning/http"
+	"meal-planning/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type appMetrics struct {
+	registry             *metrics.Registry
+	httpRequests         *metrics.CounterVec
+	httpRequestDurations *metrics.HistogramVec
+	queryDurations       *metrics.HistogramVec
+}
+
+func newAppMetrics(db *sql.DB) *appMetrics {
+	registry := metrics.NewRegistry()
+
+	m := &appMetrics{
+		registry: registry,
+		httpRequests: registry.NewCounterVec(
+			"meal_planner_http_requests_total",
+			"Number of handled HTTP requests.",
+			"method", "pattern", "status",
+		),
+		httpRequestDurations: registry.NewHistogramVec(
+			"meal_planner_http_request_duration_seconds",
+			"Time spent handling HTTP requests.",
+			metrics.DefaultBuckets,
+			"method", "pattern",
+		),
+		queryDurations: registry.NewHistogramVec(
+			"meal_planner_db_query_duration_seconds",
+			"Time spent in repository methods.",
+			metrics.DefaultBuckets,
+			"repository", "method",
+		),
+	}
+
+	registry.NewGaugeFunc("meal_planner_db_max_open_connections", "Maximum number of open connections to the database.", func(ctx context.Context) (float64, error) {
+		return float64(db.Stats().MaxOpenConnections), nil
+	})
+	registry.NewGaugeFunc("meal_planner_db_open_connections", "Number of established connections to the database.", func(ctx context.Context) (float64, error) {
+		return float64(db.Stats().OpenConnections), nil
+	})
+	registry.NewGaugeFunc("meal_planner_db_in_use_connections", "Number of connections currently in use.", func(ctx context.Context) (float64, error) {
+		return float64(db.Stats().InUse), nil
+	})
+	registry.NewGaugeFunc("meal_planner_db_idle_connections", "Number of idle connections.", func(ctx context.Context) (float64, error) {
+		return float64(db.Stats().Idle), nil
+	})
+	registry.NewCounterFunc("meal_planner_db_wait_count_total", "Number of connections waited for.", func(ctx context.Context) (float64, error) {
+		return float64(db.Stats().WaitCount), nil
+	})
+	registry.NewCounterFunc("meal_planner_db_wait_duration_seconds_total", "Time spent waiting for new connections.", func(ctx context.Context) (float64, error) {
+		return db.Stats().WaitDuration.Seconds(), nil
+	})
+
+	return m
+}
+
+func (m *appMetrics) registerDomainGauges(mealDayService *domain.MealDayService, nutritionService *domain.NutritionService) {
+	m.registry.NewGaugeFunc("meal_planner_days_planned_this_week", "Number of days of the current week with at least one planned meal.", func(ctx context.Context) (float64, error) {
+		monday, sunday := currentWeek(time.Now())
+
+		count, err := mealDayService.CountPlannedDays(ctx, monday, sunday)
+		return float64(count), err
+	})
+
+	m.registry.NewGaugeFunc("meal_planner_latest_weight_kilograms", "Most recently recorded weight, NaN if no weight was recorded yet.", func(ctx context.Context) (float64, error) {
+		nutrition, err := nutritionService.FindLatestWeight(ctx)
+		if errors.Is(err, domain.NutritionNotFound) {
+			return math.NaN(), nil
+		}
+
+		return float64(nutrition.Weight) / 1000, err
+	})
+}
+
+func (m *appMetrics) observeRequest(request *http.Request, summary myHttp.RequestSummary) {
+	pattern := summary.Pattern
+	if pattern == "" {
+		pattern = "unmatched"
+	}
+
+	m.httpRequests.Inc(request.Method, pattern, strconv.Itoa(summary.StatusCode))
+	m.httpRequestDurations.Observe(summary.Duration.Seconds(), request.Method, pattern)
+}
+
+func (m *appMetrics) observeQuery(repository, method string, duration time.Duration) {
+	m.queryDurations.Observe(duration.Seconds(), repository, method)
+}
+
+// currentWeek returns the Monday and Sunday of the week containing now.
+func currentWeek(now time.Time) (time.Time, time.Time) {
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	monday := now.AddDate(0, 0, -daysSinceMonday)
+
+	return monday, monday.AddDate(0, 0, 6)
+}
@@ -1,20 +1,24 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"meal-planning/domain"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 )
 
 type nutritionHandler struct {
 	templateHandler
-	manifest         manifest
 	nutritionService *domain.NutritionService
+	foodService      *domain.FoodService
+	mealDayService   *domain.MealDayService
+	recipeService    *domain.RecipeService
 }
 
 type nutritionData struct {
@@ -22,6 +26,16 @@ type nutritionData struct {
 	NutritionEntries            []nutritionView
 	NutritionJSON               string
 	TotalDailyEnergyExpenditure totalDailyEnergyExpenditureView
+	PlannedVsActual             []plannedVsActualView
+}
+
+// plannedVsActualView compares the calories of the meals planned for a day
+// with the calories logged for it.
+type plannedVsActualView struct {
+	Date      time.Time
+	Planned   int
+	Actual    int
+	Unmatched []string
 }
 
 type totalDailyEnergyExpenditureView struct {
@@ -33,49 +47,76 @@ type totalDailyEnergyExpenditureView struct {
 }
 
 type nutritionView struct {
-	Date     time.Time `json:"date"`
-	Calories int       `json:"calories,omitempty"`
-	Weight   float64   `json:"weight,omitempty"`
+	Date              time.Time `json:"date"`
+	Calories          int       `json:"calories,omitempty"`
+	CaloriesFromDiary bool      `json:"caloriesFromDiary,omitempty"`
+	Protein           int       `json:"protein,omitempty"`
+	Carbs             int       `json:"carbs,omitempty"`
+	Fat               int       `json:"fat,omitempty"`
+	Weight            float64   `json:"weight,omitempty"`
+	Version           int       `json:"version"`
+	// Errors holds problems with submitted values by field name.
+	Errors map[string]string `json:"-"`
 }
 
 func (h *nutritionHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	end := time.Now()
-	start := end.Add(-7 * 24 * time.Hour)
+	start := end.AddDate(0, 0, -6)
 
-	nutritionList, err := h.nutritionService.FindByDateRange(context.TODO(), start, end)
+	nutritionList, err := h.nutritionService.FindByDateRange(request.Context(), start, end)
 	if err != nil {
-		slog.Error("error retrieving meals from repository", slog.Any("reason", err))
-		http.Error(writer, "failed retrieving meal days", http.StatusInternalServerError)
+		h.serveError(writer, request, fmt.Errorf("retrieving nutrition: %w", err))
 		return
 	}
 
 	nutritionEntries := make([]nutritionView, len(nutritionList))
 	for i, nutrition := range nutritionList {
-
-		nutritionEntries[i] = nutritionView{
-			Date:     nutrition.Date,
-			Calories: nutrition.Calories,
-			Weight:   float64(nutrition.Weight) / 1000,
-		}
+		nutritionEntries[i] = newNutritionView(nutrition)
 	}
 
 	nutritionJSON, err := json.Marshal(nutritionEntries)
 	if err != nil {
-		slog.Error("error marshaling meals to JSON", slog.Any("reason", err))
-		http.Error(writer, "failed marshaling meals to JSON", http.StatusInternalServerError)
+		h.serveError(writer, request, fmt.Errorf("encoding nutrition: %w", err))
 		return
 	}
 
-	totalDailyEnergyExpenditure, err := h.nutritionService.CalculateTotalDailyEnergyExpenditure(context.TODO(), start, end)
+	totalDailyEnergyExpenditure, err := h.nutritionService.CalculateTotalDailyEnergyExpenditure(request.Context(), start, end)
 	if err != nil {
-		slog.Error("error retrieving meals from repository", slog.Any("reason", err))
-		http.Error(writer, "failed retrieving meal days", http.StatusInternalServerError)
+		h.serveError(writer, request, fmt.Errorf("calculating total daily energy expenditure: %w", err))
 		return
 	}
 
-	h.serveTemplate(writer, "nutrition.gohtml", nutritionData{
+	mealDays, err := h.mealDayService.FindByDateRange(request.Context(), start, end)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving meals: %w", err))
+		return
+	}
+
+	plannedNutrition, err := h.recipeService.PlannedNutrition(request.Context(), mealDays)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("calculating planned nutrition: %w", err))
+		return
+	}
+
+	// both lists hold one entry for every day of the range in the same order
+	plannedVsActual := make([]plannedVsActualView, len(plannedNutrition))
+	for i, planned := range plannedNutrition {
+		plannedVsActual[i] = plannedVsActualView{
+			Date:      planned.Date,
+			Planned:   int(math.Round(planned.Nutrients.Kcal)),
+			Actual:    nutritionList[i].Calories,
+			Unmatched: planned.Unmatched,
+		}
+	}
+
+	// the chart reads the entries oldest first, the lists show the latest day on top
+	latestFirst := slices.Clone(nutritionEntries)
+	slices.Reverse(latestFirst)
+	slices.Reverse(plannedVsActual)
+
+	h.serveTemplate(writer, request, "nutrition.gohtml", nutritionData{
 		Manifest:         h.manifest,
-		NutritionEntries: nutritionEntries,
+		NutritionEntries: latestFirst,
 		NutritionJSON:    string(nutritionJSON),
 		TotalDailyEnergyExpenditure: totalDailyEnergyExpenditureView{
 			Start:                       totalDailyEnergyExpenditure.Start,
@@ -84,62 +125,429 @@ func (h *nutritionHandler) ServeHTTP(writer http.ResponseWriter, request *http.R
 			PeriodWeightDifference:      float64(totalDailyEnergyExpenditure.PeriodWeightDifference) / 1000,
 			TotalDailyEnergyExpenditure: totalDailyEnergyExpenditure.TotalDailyEnergyExpenditure,
 		},
+		PlannedVsActual: plannedVsActual,
 	})
 }
 
 func (h *nutritionHandler) updateNutritionEntry(writer http.ResponseWriter, request *http.Request) {
-	dateString := request.PathValue("date")
-	date, err := time.Parse("2006-01-02", dateString)
+	date, err := pathDate(request)
 	if err != nil {
-		slog.Error("error parsing date", slog.Any("reason", err))
-		http.Error(writer, "date must be an ISO date", http.StatusBadRequest)
+		h.serveError(writer, request, err)
 		return
 	}
 
 	err = request.ParseForm()
 	if err != nil {
-		slog.Error("error parsing form", slog.Any("reason", err))
-		http.Error(writer, "could not parse form", http.StatusBadRequest)
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "could not parse form"))
+		return
+	}
+
+	version, err := strconv.Atoi(request.FormValue("version"))
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "version must be a number"))
+		return
+	}
+
+	nutrition := domain.Nutrition{
+		Date:    date,
+		Version: version,
+	}
+
+	// empty fields mean nothing was entered, anything else has to parse. Empty
+	// calories are taken from the food diary.
+	fieldErrors := map[string]string{}
+	if value := request.FormValue("calories"); value != "" {
+		nutrition.Calories, err = strconv.Atoi(value)
+		if err != nil {
+			fieldErrors["calories"] = "must be a whole number"
+		}
+	}
+
+	if value := request.FormValue("weight"); value != "" {
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			fieldErrors["weight"] = "must be a number"
+		} else {
+			nutrition.Weight = int(math.Round(weight * 1000))
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		h.serveInvalidNutritionEntry(writer, request, nutrition, &domain.ValidationError{Fields: fieldErrors})
 		return
 	}
 
-	calories, err := strconv.Atoi(request.FormValue("calories"))
-	if err == nil {
-		slog.Warn("error parsing calories", slog.Any("reason", err))
+	updated, err := h.nutritionService.Upsert(request.Context(), nutrition)
+	if errors.Is(err, domain.NutritionConflict) {
+		h.serveNutritionConflict(writer, request, nutrition)
+		return
+	}
+
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		h.serveInvalidNutritionEntry(writer, request, nutrition, invalid)
+		return
 	}
 
-	weight, err := strconv.ParseFloat(request.FormValue("weight"), 64)
 	if err != nil {
-		slog.Warn("error parsing weight", slog.Any("reason", err))
+		h.serveError(writer, request, fmt.Errorf("updating nutrition: %w", err))
+		return
 	}
 
-	nutrition := domain.Nutrition{
-		Date:     date,
-		Calories: calories,
-		Weight:   int(weight * 1000),
+	h.serveNutritionEntry(writer, request, updated)
+}
+
+// serveInvalidNutritionEntry shows the entry as submitted with the problems
+// next to the fields.
+func (h *nutritionHandler) serveInvalidNutritionEntry(writer http.ResponseWriter, request *http.Request, nutrition domain.Nutrition, invalid *domain.ValidationError) {
+	slog.InfoContext(request.Context(), "Rejected invalid nutrition", slog.String("date", nutrition.Date.Format("2006-01-02")), slog.Any("reason", invalid))
+
+	nutritionEntry := newNutritionView(nutrition)
+	nutritionEntry.Errors = invalid.Fields
+
+	h.serveTemplateWithStatus(writer, request, http.StatusUnprocessableEntity, "nutrition-entry", nutritionEntry)
+}
+
+type nutritionConflictData struct {
+	Date    time.Time
+	Mine    nutritionView
+	Current nutritionView
+}
+
+func (h *nutritionHandler) serveNutritionConflict(writer http.ResponseWriter, request *http.Request, mine domain.Nutrition) {
+	current, err := h.nutritionService.FindByDate(request.Context(), mine.Date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving nutrition: %w", err))
+		return
 	}
 
-	nutrition, err = h.nutritionService.Upsert(context.TODO(), nutrition)
+	h.serveTemplateWithStatus(writer, request, http.StatusConflict, "nutrition-entry-conflict", nutritionConflictData{
+		Date:    mine.Date,
+		Mine:    newNutritionView(mine),
+		Current: newNutritionView(current),
+	})
+}
+
+func (h *nutritionHandler) getNutritionEntryByDate(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
 	if err != nil {
-		slog.Error("error updating nutrition", slog.Any("reason", err))
-		http.Error(writer, "could not update nutrition", http.StatusInternalServerError)
+		h.serveError(writer, request, err)
 		return
 	}
 
-	nutritionEntry := nutritionView{
-		Date:     nutrition.Date,
-		Calories: nutrition.Calories,
-		Weight:   float64(nutrition.Weight) / 1000,
+	nutrition, err := h.nutritionService.FindByDate(request.Context(), date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving nutrition: %w", err))
+		return
 	}
 
-	nutritionJSON, err := json.Marshal(nutritionEntry)
+	h.serveTemplate(writer, request, "nutrition-entry", newNutritionView(nutrition))
+}
+
+type nutritionChangeView struct {
+	ID        int64
+	Action    domain.HistoryAction
+	Author    string
+	ChangedAt time.Time
+	Old       *nutritionView
+	New       *nutritionView
+}
+
+type nutritionHistoryData struct {
+	Date    time.Time
+	Changes []nutritionChangeView
+}
+
+func (h *nutritionHandler) getNutritionHistoryByDate(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
 	if err != nil {
-		slog.Error("error marshaling meals to JSON", slog.Any("reason", err))
-		http.Error(writer, "failed marshaling meals to JSON", http.StatusInternalServerError)
+		h.serveError(writer, request, err)
 		return
 	}
 
+	changes, err := h.nutritionService.FindHistory(request.Context(), date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving nutrition history: %w", err))
+		return
+	}
+
+	views := make([]nutritionChangeView, len(changes))
+	for i, change := range changes {
+		views[i] = nutritionChangeView{
+			ID:        change.ID,
+			Action:    change.Action,
+			Author:    change.Author,
+			ChangedAt: change.ChangedAt,
+		}
+
+		if change.Old != nil {
+			old := newNutritionView(*change.Old)
+			views[i].Old = &old
+		}
+
+		if change.New != nil {
+			updated := newNutritionView(*change.New)
+			views[i].New = &updated
+		}
+	}
+
+	h.serveTemplate(writer, request, "nutrition-entry-history", nutritionHistoryData{
+		Date:    date,
+		Changes: views,
+	})
+}
+
+func (h *nutritionHandler) restoreNutritionEntry(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	historyID, err := strconv.ParseInt(request.PathValue("id"), 10, 64)
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "id must be a number"))
+		return
+	}
+
+	nutrition, err := h.nutritionService.Restore(request.Context(), date, historyID)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("restoring nutrition: %w", err))
+		return
+	}
+
+	h.serveNutritionEntry(writer, request, nutrition)
+}
+
+func (h *nutritionHandler) serveNutritionEntry(writer http.ResponseWriter, request *http.Request, nutrition domain.Nutrition) {
+	nutritionEntry := newNutritionView(nutrition)
+
+	err := triggerNutritionUpdate(writer, nutritionEntry)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	h.serveTemplate(writer, request, "nutrition-entry", nutritionEntry)
+}
+
+// triggerNutritionUpdate tells the chart on the page about the new values of
+// the day.
+func triggerNutritionUpdate(writer http.ResponseWriter, nutritionEntry nutritionView) error {
+	nutritionJSON, err := json.Marshal(nutritionEntry)
+	if err != nil {
+		return fmt.Errorf("encoding nutrition: %w", err)
+	}
+
 	writer.Header().Set("HX-Trigger", fmt.Sprintf(`{ "updateNutritionData": %s }`, string(nutritionJSON)))
+	return nil
+}
+
+type diarySlotView struct {
+	Slot    domain.MealSlot
+	Entries []domain.DiaryEntry
+}
+
+// diaryEntryForm holds the values of the form to log food, so they survive
+// validation errors.
+type diaryEntryForm struct {
+	Slot  domain.MealSlot
+	Food  domain.Food
+	Grams string
+}
+
+type nutritionDiaryData struct {
+	Date      time.Time
+	Nutrition nutritionView
+	Total     domain.Nutrients
+	Slots     []diarySlotView
+	Form      diaryEntryForm
+	// Errors holds problems with the submitted entry by field name.
+	Errors map[string]string
+}
+
+type diaryFoodOptionsData struct {
+	Date  time.Time
+	Foods []domain.Food
+}
+
+func (h *nutritionHandler) getNutritionDiaryByDate(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	nutrition, err := h.nutritionService.FindByDate(request.Context(), date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving nutrition: %w", err))
+		return
+	}
+
+	h.serveNutritionDiary(writer, request, http.StatusOK, nutrition, diaryEntryForm{Slot: domain.MealSlotBreakfast}, nil)
+}
+
+// searchDiaryFoods answers the food search of the diary as you type.
+func (h *nutritionHandler) searchDiaryFoods(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	foods, err := h.foodService.Search(request.Context(), request.URL.Query().Get("q"))
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("searching foods: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "nutrition-diary-foods", diaryFoodOptionsData{Date: date, Foods: foods})
+}
+
+func (h *nutritionHandler) logFood(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	err = request.ParseForm()
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "could not parse form"))
+		return
+	}
+
+	form := diaryEntryForm{
+		Slot:  domain.MealSlot(request.FormValue("slot")),
+		Grams: request.FormValue("grams"),
+	}
+
+	fieldErrors := map[string]string{}
+	foodID, err := strconv.ParseInt(request.FormValue("food_id"), 10, 64)
+	if err != nil {
+		fieldErrors["food"] = "must be chosen"
+	} else {
+		form.Food, err = h.foodService.FindByID(request.Context(), foodID)
+		if errors.Is(err, domain.FoodNotFound) {
+			fieldErrors["food"] = "must be chosen"
+		} else if err != nil {
+			h.serveError(writer, request, fmt.Errorf("retrieving food: %w", err))
+			return
+		}
+	}
+
+	grams, err := strconv.ParseFloat(form.Grams, 64)
+	if err != nil || math.IsNaN(grams) || math.IsInf(grams, 0) {
+		fieldErrors["grams"] = "must be a number"
+	}
+
+	if len(fieldErrors) > 0 {
+		h.serveInvalidDiaryEntry(writer, request, date, form, &domain.ValidationError{Fields: fieldErrors})
+		return
+	}
+
+	nutrition, err := h.nutritionService.LogFood(request.Context(), date, form.Slot, form.Food, grams)
 
-	h.serveTemplate(writer, "nutrition-entry", nutritionEntry)
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		h.serveInvalidDiaryEntry(writer, request, date, form, invalid)
+		return
+	}
+
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("logging food: %w", err))
+		return
+	}
+
+	err = triggerNutritionUpdate(writer, newNutritionView(nutrition))
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	// the next entry is most likely for the same meal
+	h.serveNutritionDiary(writer, request, http.StatusOK, nutrition, diaryEntryForm{Slot: form.Slot}, nil)
+}
+
+// serveInvalidDiaryEntry shows the diary with the entry as submitted and the
+// problems next to the fields.
+func (h *nutritionHandler) serveInvalidDiaryEntry(writer http.ResponseWriter, request *http.Request, date time.Time, form diaryEntryForm, invalid *domain.ValidationError) {
+	slog.InfoContext(request.Context(), "Rejected invalid diary entry", slog.String("date", date.Format("2006-01-02")), slog.Any("reason", invalid))
+
+	nutrition, err := h.nutritionService.FindByDate(request.Context(), date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving nutrition: %w", err))
+		return
+	}
+
+	h.serveNutritionDiary(writer, request, http.StatusUnprocessableEntity, nutrition, form, invalid.Fields)
+}
+
+func (h *nutritionHandler) removeDiaryEntry(writer http.ResponseWriter, request *http.Request) {
+	date, err := pathDate(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	nutrition, err := h.nutritionService.RemoveDiaryEntry(request.Context(), date, id)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("removing diary entry: %w", err))
+		return
+	}
+
+	err = triggerNutritionUpdate(writer, newNutritionView(nutrition))
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	h.serveNutritionDiary(writer, request, http.StatusOK, nutrition, diaryEntryForm{Slot: domain.MealSlotBreakfast}, nil)
+}
+
+// serveNutritionDiary renders the diary of the day grouped by meal slot.
+func (h *nutritionHandler) serveNutritionDiary(writer http.ResponseWriter, request *http.Request, statusCode int, nutrition domain.Nutrition, form diaryEntryForm, fieldErrors map[string]string) {
+	entries, err := h.nutritionService.FindDiary(request.Context(), nutrition.Date)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving food diary: %w", err))
+		return
+	}
+
+	slots := make([]diarySlotView, len(domain.MealSlots))
+	for i, slot := range domain.MealSlots {
+		slots[i].Slot = slot
+		for _, entry := range entries {
+			if entry.Slot == slot {
+				slots[i].Entries = append(slots[i].Entries, entry)
+			}
+		}
+	}
+
+	h.serveTemplateWithStatus(writer, request, statusCode, "nutrition-diary", nutritionDiaryData{
+		Date:      nutrition.Date,
+		Nutrition: newNutritionView(nutrition),
+		Total:     domain.SumNutrients(entries),
+		Slots:     slots,
+		Form:      form,
+		Errors:    fieldErrors,
+	})
+}
+
+func newNutritionView(nutrition domain.Nutrition) nutritionView {
+	return nutritionView{
+		Date:              nutrition.Date,
+		Calories:          nutrition.Calories,
+		CaloriesFromDiary: nutrition.CaloriesFromDiary,
+		Protein:           nutrition.Protein,
+		Carbs:             nutrition.Carbs,
+		Fat:               nutrition.Fat,
+		Weight:            float64(nutrition.Weight) / 1000,
+		Version:           nutrition.Version,
+	}
 }
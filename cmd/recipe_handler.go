@@ -0,0 +1,556 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"meal-planning/domain"
+	"meal-planning/recipeimport"
+	"meal-planning/units"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type recipeHandler struct {
+	templateHandler
+	recipeService *domain.RecipeService
+	foodService   *domain.FoodService
+}
+
+type recipesData struct {
+	Manifest manifest
+	Query    string
+	Recipes  []domain.Recipe
+}
+
+// recipeForm is the recipe being edited and the problems with the values
+// submitted for it by field name.
+type recipeForm struct {
+	domain.Recipe
+	Errors map[string]string
+}
+
+type recipeData struct {
+	Manifest manifest
+	Form     recipeForm
+	// Imported is set while reviewing an imported recipe that is not saved yet.
+	Imported bool
+	Scaled   recipeScaledData
+}
+
+// recipeScaledData is the ingredients of a saved recipe for a number of
+// servings, in a unit system.
+type recipeScaledData struct {
+	ID          int64
+	Servings    int
+	Units       units.System
+	Ingredients []domain.Ingredient
+}
+
+// maxRecipeImportSize allows for saved pages with their scripts and styles
+// inlined.
+const maxRecipeImportSize = 10 << 20
+
+type recipeImportData struct {
+	Manifest manifest
+	Source   string
+	Error    string
+}
+
+func (h *recipeHandler) getRecipes(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query().Get("q")
+
+	recipes, err := h.recipeService.Search(request.Context(), query)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("searching recipes: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "recipes.gohtml", recipesData{
+		Manifest: h.manifest,
+		Query:    query,
+		Recipes:  recipes,
+	})
+}
+
+// searchRecipes answers the search field of the recipe page as you type.
+func (h *recipeHandler) searchRecipes(writer http.ResponseWriter, request *http.Request) {
+	recipes, err := h.recipeService.Search(request.Context(), request.URL.Query().Get("q"))
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("searching recipes: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "recipe-list", recipes)
+}
+
+// getNewRecipe shows an empty recipe form. The name can be given to create
+// the recipe of a planned meal.
+func (h *recipeHandler) getNewRecipe(writer http.ResponseWriter, request *http.Request) {
+	h.serveTemplate(writer, request, "recipe.gohtml", recipeData{
+		Manifest: h.manifest,
+		Form:     recipeForm{Recipe: domain.Recipe{Name: request.URL.Query().Get("name"), Servings: 1}},
+	})
+}
+
+func (h *recipeHandler) getRecipe(writer http.ResponseWriter, request *http.Request) {
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	recipe, err := h.recipeService.FindByID(request.Context(), id)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving recipe: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "recipe.gohtml", recipeData{
+		Manifest: h.manifest,
+		Form:     recipeForm{Recipe: recipe},
+		Scaled:   newRecipeScaledData(recipe, recipe.Servings, units.AsWritten),
+	})
+}
+
+// getScaledIngredients shows the ingredients of a recipe for the servings and
+// in the units asked for.
+func (h *recipeHandler) getScaledIngredients(writer http.ResponseWriter, request *http.Request) {
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	query := request.URL.Query()
+
+	servings, err := strconv.Atoi(query.Get("servings"))
+	if err != nil || servings < 1 || servings > domain.MaxRecipeServings {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, fmt.Sprintf("servings must be a whole number from 1 to %d", domain.MaxRecipeServings)))
+		return
+	}
+
+	system, err := units.ParseSystem(query.Get("units"))
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "units must be metric or imperial"))
+		return
+	}
+
+	recipe, err := h.recipeService.FindByID(request.Context(), id)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving recipe: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "recipe-scaled", newRecipeScaledData(recipe, servings, system))
+}
+
+func newRecipeScaledData(recipe domain.Recipe, servings int, system units.System) recipeScaledData {
+	return recipeScaledData{
+		ID:          recipe.ID,
+		Servings:    servings,
+		Units:       system,
+		Ingredients: recipe.Scale(servings).Convert(system).Ingredients,
+	}
+}
+
+func (h *recipeHandler) createRecipe(writer http.ResponseWriter, request *http.Request) {
+	recipe, fieldErrors, err := parseRecipeForm(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	if len(fieldErrors) > 0 {
+		h.serveInvalidRecipe(writer, request, recipe, &domain.ValidationError{Fields: fieldErrors})
+		return
+	}
+
+	_, err = h.recipeService.Create(request.Context(), recipe)
+
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		h.serveInvalidRecipe(writer, request, recipe, invalid)
+		return
+	}
+
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("creating recipe: %w", err))
+		return
+	}
+
+	redirect(writer, request, "/recipes")
+}
+
+func (h *recipeHandler) updateRecipe(writer http.ResponseWriter, request *http.Request) {
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	recipe, fieldErrors, err := parseRecipeForm(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+	recipe.ID = id
+
+	if len(fieldErrors) > 0 {
+		h.serveInvalidRecipe(writer, request, recipe, &domain.ValidationError{Fields: fieldErrors})
+		return
+	}
+
+	_, err = h.recipeService.Update(request.Context(), recipe)
+
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		h.serveInvalidRecipe(writer, request, recipe, invalid)
+		return
+	}
+
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("updating recipe: %w", err))
+		return
+	}
+
+	redirect(writer, request, "/recipes")
+}
+
+// previewRecipe recalculates the recipe form as ingredients are added, removed
+// or changed, without saving the recipe.
+func (h *recipeHandler) previewRecipe(writer http.ResponseWriter, request *http.Request) {
+	recipe, fieldErrors, err := parseRecipeForm(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	if value := request.PostForm.Get("id"); value != "" {
+		recipe.ID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "id must be a number"))
+			return
+		}
+	}
+
+	if value := request.PostForm.Get("remove"); value != "" {
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 || i >= len(recipe.Ingredients) {
+			h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "no such ingredient"))
+			return
+		}
+
+		recipe.Ingredients = slices.Delete(recipe.Ingredients, i, i+1)
+	}
+
+	if request.PostForm.Get("add") != "" {
+		// a food picked from the search is added, otherwise the search text
+		ingredient := domain.Ingredient{Name: strings.TrimSpace(request.PostForm.Get("ingredient_search"))}
+		if value := request.PostForm.Get("add_food"); value != "" {
+			ingredient.Name = ""
+			ingredient.FoodID, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "food must be a number"))
+				return
+			}
+		}
+
+		if ingredient.Name != "" || ingredient.FoodID != 0 {
+			recipe.Ingredients = append(recipe.Ingredients, ingredient)
+		}
+	}
+
+	recipe, err = h.recipeService.Calculate(request.Context(), recipe)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("calculating recipe: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "recipe-form", recipeForm{Recipe: recipe, Errors: fieldErrors})
+}
+
+// searchIngredientFoods answers the food search of the ingredients as you
+// type.
+func (h *recipeHandler) searchIngredientFoods(writer http.ResponseWriter, request *http.Request) {
+	foods, err := h.foodService.Search(request.Context(), request.URL.Query().Get("ingredient_search"))
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("searching foods: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "recipe-ingredient-foods", foods)
+}
+
+func (h *recipeHandler) getRecipeImport(writer http.ResponseWriter, request *http.Request) {
+	h.serveTemplate(writer, request, "recipe-import.gohtml", recipeImportData{Manifest: h.manifest})
+}
+
+// importRecipe reads the recipe of an uploaded or pasted page, or of an
+// uploaded Cooklang or Markdown file, and shows it in the recipe form for
+// review. Nothing is saved until the form is.
+func (h *recipeHandler) importRecipe(writer http.ResponseWriter, request *http.Request) {
+	request.Body = http.MaxBytesReader(writer, request.Body, maxRecipeImportSize)
+
+	err := request.ParseMultipartForm(maxRecipeImportSize)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		h.serveInvalidRecipeImport(writer, request, "", "The page could not be read, it may be larger than 10 MB.")
+		return
+	}
+
+	pasted := request.FormValue("source")
+	source := []byte(pasted)
+
+	file, header, err := request.FormFile("file")
+	fileName := ""
+	if err == nil {
+		fileName = header.Filename
+		defer file.Close()
+
+		source, err = io.ReadAll(file)
+		if err != nil {
+			h.serveError(writer, request, fmt.Errorf("reading uploaded page: %w", err))
+			return
+		}
+	} else if !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart) {
+		h.serveError(writer, request, fmt.Errorf("reading uploaded page: %w", err))
+		return
+	}
+
+	if len(bytes.TrimSpace(source)) == 0 {
+		h.serveInvalidRecipeImport(writer, request, pasted, "Choose a saved page or paste its source.")
+		return
+	}
+
+	// recipe files are picked by extension, anything else is taken for a page
+	var recipe domain.Recipe
+	if format, ok := recipeimport.FormatOf(fileName); ok {
+		recipe = format.Parse(fileName, source)
+	} else {
+		recipe, err = recipeimport.ParseJSONLD(source)
+	}
+	if errors.Is(err, recipeimport.NoRecipe) {
+		h.serveInvalidRecipeImport(writer, request, pasted, "The page has no recipe described with schema.org JSON-LD.")
+		return
+	} else if err != nil {
+		h.serveError(writer, request, fmt.Errorf("importing recipe: %w", err))
+		return
+	}
+
+	recipe, err = h.recipeService.Calculate(request.Context(), recipe)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("calculating recipe: %w", err))
+		return
+	}
+
+	slog.InfoContext(request.Context(), "Imported recipe for review", slog.String("name", recipe.Name), slog.Int("ingredients", len(recipe.Ingredients)))
+
+	h.serveTemplate(writer, request, "recipe.gohtml", recipeData{
+		Manifest: h.manifest,
+		Form:     recipeForm{Recipe: recipe},
+		Imported: true,
+	})
+}
+
+// exportRecipe downloads the recipe as a Cooklang or Markdown file.
+func (h *recipeHandler) exportRecipe(writer http.ResponseWriter, request *http.Request) {
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	format, err := recipeimport.ParseFormat(request.URL.Query().Get("format"))
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "format must be cooklang or markdown"))
+		return
+	}
+
+	recipe, err := h.recipeService.FindByID(request.Context(), id)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving recipe: %w", err))
+		return
+	}
+
+	var file bytes.Buffer
+	err = format.Write(&file, recipe)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("exporting recipe: %w", err))
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if format == recipeimport.Markdown {
+		contentType = "text/markdown; charset=utf-8"
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": recipeimport.FileName(recipe, format)}))
+	writer.Write(file.Bytes())
+}
+
+func (h *recipeHandler) serveInvalidRecipeImport(writer http.ResponseWriter, request *http.Request, source string, message string) {
+	slog.InfoContext(request.Context(), "Rejected recipe import", slog.String("reason", message))
+
+	h.serveTemplateWithStatus(writer, request, http.StatusUnprocessableEntity, "recipe-import.gohtml", recipeImportData{
+		Manifest: h.manifest,
+		Source:   source,
+		Error:    message,
+	})
+}
+
+func (h *recipeHandler) deleteRecipe(writer http.ResponseWriter, request *http.Request) {
+	id, err := pathID(request)
+	if err != nil {
+		h.serveError(writer, request, err)
+		return
+	}
+
+	err = h.recipeService.Delete(request.Context(), id)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("deleting recipe: %w", err))
+		return
+	}
+
+	redirect(writer, request, "/recipes")
+}
+
+// serveInvalidRecipe shows the recipe as submitted with the problems next to
+// the fields.
+func (h *recipeHandler) serveInvalidRecipe(writer http.ResponseWriter, request *http.Request, recipe domain.Recipe, invalid *domain.ValidationError) {
+	slog.InfoContext(request.Context(), "Rejected invalid recipe", slog.Int64("id", recipe.ID), slog.Any("reason", invalid))
+
+	h.serveTemplateWithStatus(writer, request, http.StatusUnprocessableEntity, "recipe-form", recipeForm{Recipe: recipe, Errors: invalid.Fields})
+}
+
+// parseRecipeForm reads the recipe of the recipe form. Values that do not
+// parse are returned as field errors, a form that cannot be read at all as
+// error.
+func parseRecipeForm(request *http.Request) (domain.Recipe, map[string]string, error) {
+	err := request.ParseForm()
+	if err != nil {
+		return domain.Recipe{}, nil, domain.NewError(domain.ErrorKindValidation, "could not parse form")
+	}
+
+	recipe := domain.Recipe{
+		Name: request.PostForm.Get("name"),
+	}
+
+	if value := request.PostForm.Get("version"); value != "" {
+		recipe.Version, err = strconv.Atoi(value)
+		if err != nil {
+			return domain.Recipe{}, nil, domain.NewError(domain.ErrorKindValidation, "version must be a number")
+		}
+	}
+
+	fieldErrors := map[string]string{}
+	recipe.Servings, err = strconv.Atoi(strings.TrimSpace(request.PostForm.Get("servings")))
+	if err != nil {
+		fieldErrors["servings"] = "must be a whole number"
+	}
+
+	// empty fields mean nothing was entered, anything else has to parse
+	parseNutrient := func(field string) float64 {
+		value := strings.TrimSpace(request.PostForm.Get(field))
+		if value == "" {
+			return 0
+		}
+
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			fieldErrors[field] = "must be a number"
+			return 0
+		}
+
+		return number
+	}
+
+	recipe.PerServing = domain.Nutrients{
+		Kcal:    parseNutrient("kcal"),
+		Protein: parseNutrient("protein"),
+		Carbs:   parseNutrient("carbs"),
+		Fat:     parseNutrient("fat"),
+	}
+
+	// every ingredient row has a quantity, unit, name, food and grams field
+	names := request.PostForm["ingredient_name"]
+	quantities := request.PostForm["ingredient_quantity"]
+	units := request.PostForm["ingredient_unit"]
+	foodIDs := request.PostForm["ingredient_food"]
+	amounts := request.PostForm["ingredient_grams"]
+	if len(quantities) != len(names) || len(units) != len(names) || len(foodIDs) != len(names) || len(amounts) != len(names) {
+		return domain.Recipe{}, nil, domain.NewError(domain.ErrorKindValidation, "ingredients are incomplete")
+	}
+
+	// only amounts imported as fixed are marked, forms of other clients may
+	// leave the field out
+	fixed := request.PostForm["ingredient_fixed"]
+	if fixed != nil && len(fixed) != len(names) {
+		return domain.Recipe{}, nil, domain.NewError(domain.ErrorKindValidation, "ingredients are incomplete")
+	}
+
+	for i, name := range names {
+		ingredient := domain.Ingredient{Name: name, Unit: strings.TrimSpace(units[i])}
+		if fixed != nil {
+			ingredient.Fixed = fixed[i] == "true"
+		}
+
+		if quantity := strings.TrimSpace(quantities[i]); quantity != "" {
+			number, err := strconv.ParseFloat(quantity, 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				fieldErrors["ingredients"] = "quantities must be numbers"
+			} else {
+				ingredient.Quantity = number
+			}
+		}
+
+		if foodIDs[i] != "" {
+			ingredient.FoodID, err = strconv.ParseInt(foodIDs[i], 10, 64)
+			if err != nil {
+				return domain.Recipe{}, nil, domain.NewError(domain.ErrorKindValidation, "ingredient food must be a number")
+			}
+		}
+
+		if amount := strings.TrimSpace(amounts[i]); amount != "" {
+			grams, err := strconv.ParseFloat(amount, 64)
+			if err != nil || math.IsNaN(grams) || math.IsInf(grams, 0) {
+				fieldErrors["ingredients"] = "amounts must be numbers"
+			} else {
+				ingredient.Grams = grams
+			}
+		}
+
+		recipe.Ingredients = append(recipe.Ingredients, ingredient)
+	}
+
+	if instructions := request.PostForm.Get("instructions"); instructions != "" {
+		recipe.Instructions = strings.Split(instructions, "\n")
+	}
+
+	parseMinutes := func(field, key string) time.Duration {
+		value := strings.TrimSpace(request.PostForm.Get(field))
+		if value == "" {
+			return 0
+		}
+
+		minutes, err := strconv.Atoi(value)
+		if err != nil {
+			fieldErrors[key] = "must be a whole number of minutes"
+			return 0
+		}
+
+		return time.Duration(minutes) * time.Minute
+	}
+
+	recipe.PrepTime = parseMinutes("prep_minutes", "prepTime")
+	recipe.CookTime = parseMinutes("cook_minutes", "cookTime")
+
+	return recipe, fieldErrors, nil
+}
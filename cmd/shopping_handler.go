@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"meal-planning/domain"
+	"meal-planning/units"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxShoppingDays is the longest stretch a shopping list adds up, about one
+// big shop a month.
+const maxShoppingDays = 31
+
+type shoppingHandler struct {
+	templateHandler
+	mealDayService *domain.MealDayService
+	recipeService  *domain.RecipeService
+}
+
+type shoppingData struct {
+	Manifest manifest
+	Start    time.Time
+	Days     int
+	Units    units.System
+	List     domain.ShoppingList
+}
+
+// getShoppingList lists the ingredients of the meals planned from the start
+// date, today by default, for a number of days, a week by default.
+func (h *shoppingHandler) getShoppingList(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+
+	start := time.Now()
+	if value := query.Get("start"); value != "" {
+		var err error
+		start, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "start must be an ISO date"))
+			return
+		}
+	}
+
+	days := 7
+	if value := query.Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > maxShoppingDays {
+			h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, fmt.Sprintf("days must be a whole number from 1 to %d", maxShoppingDays)))
+			return
+		}
+	}
+
+	system, err := units.ParseSystem(query.Get("units"))
+	if err != nil {
+		h.serveError(writer, request, domain.NewError(domain.ErrorKindValidation, "units must be metric or imperial"))
+		return
+	}
+	mealDays, err := h.mealDayService.FindByDateRange(request.Context(), start, start.AddDate(0, 0, days-1))
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("retrieving meals: %w", err))
+		return
+	}
+
+	list, err := h.recipeService.ShoppingList(request.Context(), mealDays, system)
+	if err != nil {
+		h.serveError(writer, request, fmt.Errorf("listing ingredients: %w", err))
+		return
+	}
+
+	h.serveTemplate(writer, request, "shopping.gohtml", shoppingData{
+		Manifest: h.manifest,
+		Start:    start,
+		Days:     days,
+		Units:    list.System,
+		List:     list,
+	})
+}
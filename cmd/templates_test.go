@@ -0,0 +1,43 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplateHandlerReloadsTemplates(t *testing.T) {
+	views := fstest.MapFS{
+		"greeting.gohtml": {Data: []byte(`{{ define "greeting" }}Hello{{ end }}`)},
+	}
+
+	tmpl, err := parseTemplates(views)
+	if err != nil {
+		t.Fatalf("parsing templates: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		handler  templateHandler
+		expected string
+	}{
+		{"embedded", templateHandler{template: tmpl}, "Hello"},
+		{"dev", templateHandler{template: tmpl, reload: func() (*template.Template, error) { return parseTemplates(views) }}, "Good morning"},
+	}
+
+	views["greeting.gohtml"] = &fstest.MapFile{Data: []byte(`{{ define "greeting" }}Good morning{{ end }}`)}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			test.handler.serveTemplate(recorder, httptest.NewRequest(http.MethodGet, "/", nil), "greeting", nil)
+
+			if body := strings.TrimSpace(recorder.Body.String()); body != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, body)
+			}
+		})
+	}
+}
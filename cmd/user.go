@@ -0,0 +1,23 @@
+package main
+
+import (
+	"meal-planning/domain"
+	"net/http"
+)
+
+// userHeaders are set by the authenticating reverse proxy in front of the
+// planner, e.g. Authelia or oauth2-proxy.
+var userHeaders = []string{"Remote-User", "X-Forwarded-User"}
+
+func withUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		for _, header := range userHeaders {
+			if user := request.Header.Get(header); user != "" {
+				request = request.WithContext(domain.WithUser(request.Context(), user))
+				break
+			}
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
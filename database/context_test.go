@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"meal-planning/domain"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "meal-planner.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = Migrate(context.Background(), db)
+	if err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+
+	return db
+}
+
+func cancelledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	return ctx
+}
+
+func TestRepositoriesStopOnCancelledContext(t *testing.T) {
+	db := newTestDatabase(t)
+	mealDays := NewSqlMealDayRepository(db)
+	nutrition := NewSqlNutritionRepository(db)
+	history := NewSqlHistoryRepository(db)
+	date := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"meal day find by date", func(ctx context.Context) error {
+			_, err := mealDays.FindByDate(ctx, date)
+			return err
+		}},
+		{"meal day find by date range", func(ctx context.Context) error {
+			_, err := mealDays.FindByDateRange(ctx, date, date.AddDate(0, 0, 6))
+			return err
+		}},
+		{"meal day create", func(ctx context.Context) error {
+			_, err := mealDays.Create(ctx, domain.MealDay{Date: date, Dinner: "Pasta"})
+			return err
+		}},
+		{"nutrition find average", func(ctx context.Context) error {
+			_, err := nutrition.FindAverageNutrition(ctx, date, date.AddDate(0, 0, 6))
+			return err
+		}},
+		{"nutrition create", func(ctx context.Context) error {
+			_, err := nutrition.Create(ctx, domain.Nutrition{Date: date, Calories: 2000})
+			return err
+		}},
+		{"history append", func(ctx context.Context) error {
+			_, err := history.Append(ctx, domain.HistoryEntry{Entity: domain.HistoryEntityMealDay, Date: date, Action: domain.HistoryActionCreate, Author: "test"})
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call(cancelledContext())
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+		})
+	}
+
+	_, err := mealDays.FindByDate(context.Background(), date)
+	if !errors.Is(err, domain.MealNotFound) {
+		t.Errorf("expected cancelled create to write nothing, got %v", err)
+	}
+
+	_, err = nutrition.FindByDate(context.Background(), date)
+	if !errors.Is(err, domain.NutritionNotFound) {
+		t.Errorf("expected cancelled create to write nothing, got %v", err)
+	}
+}
+
+func TestUpsertStopsWhenRequestIsCancelled(t *testing.T) {
+	db := newTestDatabase(t)
+	history := NewSqlHistoryRepository(db)
+	service := domain.NewMealDayService(NewSqlMealDayRepository(db), history, NewTransactor(db), domain.NewEventBus(), domain.Household{People: 1})
+	date := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.Upsert(cancelledContext(), domain.MealDay{Date: date, Dinner: "Pasta"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	meal, err := service.FindByDate(context.Background(), date)
+	if err != nil {
+		t.Fatalf("finding meal: %v", err)
+	}
+
+	if meal.Dinner != "" {
+		t.Errorf("expected no meal to be saved, got %q", meal.Dinner)
+	}
+
+	entries, err := history.FindByEntityAndDate(context.Background(), domain.HistoryEntityMealDay, date)
+	if err != nil {
+		t.Fatalf("finding history: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("expected no history entries, got %d", len(entries))
+	}
+}
+
+func TestQueriesTimeOutWithRequestDeadline(t *testing.T) {
+	db := newTestDatabase(t)
+	mealDays := NewSqlMealDayRepository(db)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := mealDays.FindByDateRange(ctx, time.Now(), time.Now().AddDate(0, 0, 6))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"meal-planning/domain"
+	"time"
+)
+
+type sqlDiaryRepository struct {
+	db *sql.DB
+}
+
+func NewSqlDiaryRepository(db *sql.DB) domain.DiaryRepository {
+	return &sqlDiaryRepository{db}
+}
+
+func (s *sqlDiaryRepository) FindByDate(ctx context.Context, date time.Time) ([]domain.DiaryEntry, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, date, slot, COALESCE(food_id, 0), COALESCE(recipe_id, 0), food_name, grams, portions, kcal, protein, carbs, fat FROM diary_entries WHERE date = date(?) ORDER BY id`,
+		date.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.DiaryEntry, 0)
+	for rows.Next() {
+		var entry domain.DiaryEntry
+		var entryDate, slot string
+		err = rows.Scan(&entry.ID, &entryDate, &slot, &entry.FoodID, &entry.RecipeID, &entry.FoodName, &entry.Grams, &entry.Portions, &entry.Nutrients.Kcal, &entry.Nutrients.Protein, &entry.Nutrients.Carbs, &entry.Nutrients.Fat)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Date, err = time.Parse("2006-01-02", entryDate)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Slot = domain.MealSlot(slot)
+		list = append(list, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *sqlDiaryRepository) Create(ctx context.Context, entry domain.DiaryEntry) (domain.DiaryEntry, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	foodID := sql.NullInt64{Int64: entry.FoodID, Valid: entry.FoodID != 0}
+	recipeID := sql.NullInt64{Int64: entry.RecipeID, Valid: entry.RecipeID != 0}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO diary_entries (date, slot, food_id, recipe_id, food_name, grams, portions, kcal, protein, carbs, fat) VALUES (date(?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Date.Format("2006-01-02"), string(entry.Slot), foodID, recipeID, entry.FoodName, entry.Grams, entry.Portions, entry.Nutrients.Kcal, entry.Nutrients.Protein, entry.Nutrients.Carbs, entry.Nutrients.Fat,
+	)
+	if err != nil {
+		return domain.DiaryEntry{}, err
+	}
+
+	entry.ID, err = result.LastInsertId()
+	if err != nil {
+		return domain.DiaryEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (s *sqlDiaryRepository) Delete(ctx context.Context, date time.Time, id int64) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM diary_entries WHERE id = ? AND date = date(?)`, id, date.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+
+	return ExpectAffectedRow(result, domain.DiaryEntryNotFound)
+}
@@ -0,0 +1,263 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"meal-planning/domain"
+	"strings"
+)
+
+const foodColumns = `id, name, brand, kcal, protein, carbs, fat, serving_sizes, source, COALESCE(source_id, ''), version`
+
+type food struct {
+	ID           int64
+	Name         string
+	Brand        string
+	Kcal         float64
+	Protein      float64
+	Carbs        float64
+	Fat          float64
+	ServingSizes string
+	Source       string
+	SourceID     string
+	Version      int
+}
+
+func (f food) toDomain() (domain.Food, error) {
+	servingSizes, err := UnmarshalServingSizes(f.ServingSizes)
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	return domain.Food{
+		ID:    f.ID,
+		Name:  f.Name,
+		Brand: f.Brand,
+		Per100g: domain.Nutrients{
+			Kcal:    f.Kcal,
+			Protein: f.Protein,
+			Carbs:   f.Carbs,
+			Fat:     f.Fat,
+		},
+		ServingSizes: servingSizes,
+		Source:       domain.FoodSource(f.Source),
+		SourceID:     f.SourceID,
+		Version:      f.Version,
+	}, nil
+}
+
+type sqlFoodRepository struct {
+	db *sql.DB
+}
+
+func NewSqlFoodRepository(db *sql.DB) domain.FoodRepository {
+	return &sqlFoodRepository{db}
+}
+
+func (s *sqlFoodRepository) FindByID(ctx context.Context, id int64) (domain.Food, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT `+foodColumns+` FROM foods WHERE id = ?`, id)
+
+	f := food{}
+	err := row.Scan(&f.ID, &f.Name, &f.Brand, &f.Kcal, &f.Protein, &f.Carbs, &f.Fat, &f.ServingSizes, &f.Source, &f.SourceID, &f.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Food{}, domain.FoodNotFound
+	} else if err != nil {
+		return domain.Food{}, err
+	}
+
+	return f.toDomain()
+}
+
+func (s *sqlFoodRepository) Search(ctx context.Context, query string, limit int) ([]domain.Food, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	terms := strings.Fields(query)
+
+	conditions := make([]string, 0, len(terms))
+	args := make([]any, 0, 2*len(terms)+2)
+	for _, term := range terms {
+		pattern := "%" + EscapeLike(term) + "%"
+		conditions = append(conditions, `(name LIKE ? ESCAPE '\' OR brand LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = `WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	prefix := "%"
+	if len(terms) > 0 {
+		prefix = EscapeLike(terms[0]) + "%"
+	}
+	args = append(args, prefix, limit)
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+foodColumns+` FROM foods `+where+` ORDER BY CASE WHEN name LIKE ? ESCAPE '\' THEN 0 ELSE 1 END, name COLLATE NOCASE, id LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.Food, 0)
+	for rows.Next() {
+		f := food{}
+		err = rows.Scan(&f.ID, &f.Name, &f.Brand, &f.Kcal, &f.Protein, &f.Carbs, &f.Fat, &f.ServingSizes, &f.Source, &f.SourceID, &f.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := f.toDomain()
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, found)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *sqlFoodRepository) Create(ctx context.Context, f domain.Food) (domain.Food, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	servingSizes, err := MarshalServingSizes(f.ServingSizes)
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO foods (name, brand, kcal, protein, carbs, fat, serving_sizes, source, source_id, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)`,
+		f.Name, f.Brand, f.Per100g.Kcal, f.Per100g.Protein, f.Per100g.Carbs, f.Per100g.Fat, servingSizes, string(f.Source), NullIfEmpty(f.SourceID),
+	)
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	f.ID, err = result.LastInsertId()
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	f.Version = 1
+	return f, nil
+}
+
+func (s *sqlFoodRepository) Update(ctx context.Context, f domain.Food) (domain.Food, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	servingSizes, err := MarshalServingSizes(f.ServingSizes)
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	// the origin of a food does not change by editing it
+	var source string
+	row := s.db.QueryRowContext(
+		ctx,
+		`UPDATE foods SET name = ?, brand = ?, kcal = ?, protein = ?, carbs = ?, fat = ?, serving_sizes = ?, version = version + 1 WHERE id = ? AND version = ? RETURNING source, COALESCE(source_id, '')`,
+		f.Name, f.Brand, f.Per100g.Kcal, f.Per100g.Protein, f.Per100g.Carbs, f.Per100g.Fat, servingSizes, f.ID, f.Version,
+	)
+	err = row.Scan(&source, &f.SourceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Food{}, domain.FoodConflict
+	} else if err != nil {
+		return domain.Food{}, err
+	}
+
+	f.Source = domain.FoodSource(source)
+	f.Version++
+	return f, nil
+}
+
+func (s *sqlFoodRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM foods WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return ExpectAffectedRow(result, domain.FoodNotFound)
+}
+
+func (s *sqlFoodRepository) Import(ctx context.Context, foods []domain.Food) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO foods (name, brand, kcal, protein, carbs, fat, serving_sizes, source, source_id, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT (source, source_id) DO UPDATE SET name = excluded.name, brand = excluded.brand, kcal = excluded.kcal, protein = excluded.protein, carbs = excluded.carbs, fat = excluded.fat, serving_sizes = excluded.serving_sizes, version = foods.version + 1`,
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for _, f := range foods {
+		servingSizes, err := MarshalServingSizes(f.ServingSizes)
+		if err != nil {
+			return err
+		}
+
+		_, err = statement.ExecContext(ctx, f.Name, f.Brand, f.Per100g.Kcal, f.Per100g.Protein, f.Per100g.Carbs, f.Per100g.Fat, servingSizes, string(f.Source), NullIfEmpty(f.SourceID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// MarshalServingSizes encodes serving sizes for the serving_sizes column.
+func MarshalServingSizes(servingSizes []domain.ServingSize) (string, error) {
+	if servingSizes == nil {
+		servingSizes = []domain.ServingSize{}
+	}
+
+	encoded, err := json.Marshal(servingSizes)
+	return string(encoded), err
+}
+
+func UnmarshalServingSizes(encoded string) ([]domain.ServingSize, error) {
+	var servingSizes []domain.ServingSize
+	err := json.Unmarshal([]byte(encoded), &servingSizes)
+	return servingSizes, err
+}
+
+// EscapeLike escapes the wildcards of LIKE patterns in term, for use with
+// ESCAPE '\'.
+func EscapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
+
+// NullIfEmpty stores empty strings as NULL, e.g. for columns with a unique
+// index that only applies to set values.
+func NullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
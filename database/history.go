@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"meal-planning/domain"
+	"time"
+)
+
+type historyEntity struct {
+	id        int64
+	entity    string
+	date      string
+	action    string
+	author    string
+	changedAt string
+	oldValue  sql.NullString
+	newValue  sql.NullString
+}
+
+type sqlHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewSqlHistoryRepository(db *sql.DB) domain.HistoryRepository {
+	return &sqlHistoryRepository{
+		db: db,
+	}
+}
+
+func (s *sqlHistoryRepository) Append(ctx context.Context, entry domain.HistoryEntry) (domain.HistoryEntry, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	oldValue := sql.NullString{
+		String: string(entry.OldValue),
+		Valid:  entry.OldValue != nil,
+	}
+
+	newValue := sql.NullString{
+		String: string(entry.NewValue),
+		Valid:  entry.NewValue != nil,
+	}
+
+	result, err := Conn(ctx, s.db).ExecContext(
+		ctx,
+		`INSERT INTO history (entity, date, action, author, changed_at, old_value, new_value) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		string(entry.Entity),
+		entry.Date.Format("2006-01-02"),
+		string(entry.Action),
+		entry.Author,
+		entry.ChangedAt.UTC().Format(time.RFC3339Nano),
+		oldValue,
+		newValue,
+	)
+	if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	entry.ID, err = result.LastInsertId()
+	if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (s *sqlHistoryRepository) FindByID(ctx context.Context, id int64) (domain.HistoryEntry, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := Conn(ctx, s.db).QueryRowContext(ctx, `SELECT id, entity, date, action, author, changed_at, old_value, new_value FROM history WHERE id = ? LIMIT 1`, id)
+
+	if row.Err() != nil {
+		return domain.HistoryEntry{}, row.Err()
+	}
+
+	entity := historyEntity{}
+	err := row.Scan(&entity.id, &entity.entity, &entity.date, &entity.action, &entity.author, &entity.changedAt, &entity.oldValue, &entity.newValue)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.HistoryEntry{}, domain.HistoryEntryNotFound
+	} else if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	return entity.toDomain()
+}
+
+func (s *sqlHistoryRepository) FindByEntityAndDate(ctx context.Context, entity domain.HistoryEntity, date time.Time) ([]domain.HistoryEntry, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := Conn(ctx, s.db).QueryContext(
+		ctx,
+		`SELECT id, entity, date, action, author, changed_at, old_value, new_value FROM history WHERE entity = ? AND date = date(?) ORDER BY id DESC`,
+		string(entity),
+		date.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.HistoryEntry, 0)
+	for rows.Next() {
+		entity := historyEntity{}
+		err = rows.Scan(&entity.id, &entity.entity, &entity.date, &entity.action, &entity.author, &entity.changedAt, &entity.oldValue, &entity.newValue)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := entity.toDomain()
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (e historyEntity) toDomain() (domain.HistoryEntry, error) {
+	date, err := time.Parse("2006-01-02", e.date)
+	if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	changedAt, err := time.Parse(time.RFC3339Nano, e.changedAt)
+	if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	entry := domain.HistoryEntry{
+		ID:        e.id,
+		Entity:    domain.HistoryEntity(e.entity),
+		Date:      date,
+		Action:    domain.HistoryAction(e.action),
+		Author:    e.author,
+		ChangedAt: changedAt,
+	}
+
+	if e.oldValue.Valid {
+		entry.OldValue = []byte(e.oldValue.String)
+	}
+
+	if e.newValue.Valid {
+		entry.NewValue = []byte(e.newValue.String)
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"meal-planning/domain"
+	"time"
+)
+
+// QueryObserver is called after every repository method with the time the
+// method took.
+type QueryObserver func(repository, method string, duration time.Duration)
+
+type instrumentedMealDayRepository struct {
+	repository domain.MealDayRepository
+	observe    QueryObserver
+}
+
+func InstrumentMealDayRepository(repository domain.MealDayRepository, observe QueryObserver) domain.MealDayRepository {
+	return &instrumentedMealDayRepository{repository: repository, observe: observe}
+}
+
+func (r *instrumentedMealDayRepository) FindByDate(ctx context.Context, date time.Time) (domain.MealDay, error) {
+	defer r.track("FindByDate", time.Now())
+	return r.repository.FindByDate(ctx, date)
+}
+
+func (r *instrumentedMealDayRepository) FindByDateRange(ctx context.Context, start, end time.Time) ([]domain.MealDay, error) {
+	defer r.track("FindByDateRange", time.Now())
+	return r.repository.FindByDateRange(ctx, start, end)
+}
+
+func (r *instrumentedMealDayRepository) Create(ctx context.Context, mealDay domain.MealDay) (domain.MealDay, error) {
+	defer r.track("Create", time.Now())
+	return r.repository.Create(ctx, mealDay)
+}
+
+func (r *instrumentedMealDayRepository) Update(ctx context.Context, mealDay domain.MealDay) (domain.MealDay, error) {
+	defer r.track("Update", time.Now())
+	return r.repository.Update(ctx, mealDay)
+}
+
+func (r *instrumentedMealDayRepository) Delete(ctx context.Context, mealDay domain.MealDay) error {
+	defer r.track("Delete", time.Now())
+	return r.repository.Delete(ctx, mealDay)
+}
+
+func (r *instrumentedMealDayRepository) track(method string, start time.Time) {
+	r.observe("meal_day", method, time.Since(start))
+}
+
+type instrumentedNutritionRepository struct {
+	repository domain.NutritionRepository
+	observe    QueryObserver
+}
+
+func InstrumentNutritionRepository(repository domain.NutritionRepository, observe QueryObserver) domain.NutritionRepository {
+	return &instrumentedNutritionRepository{repository: repository, observe: observe}
+}
+
+func (r *instrumentedNutritionRepository) FindByDate(ctx context.Context, date time.Time) (domain.Nutrition, error) {
+	defer r.track("FindByDate", time.Now())
+	return r.repository.FindByDate(ctx, date)
+}
+
+func (r *instrumentedNutritionRepository) FindByDateRange(ctx context.Context, start, end time.Time) ([]domain.Nutrition, error) {
+	defer r.track("FindByDateRange", time.Now())
+	return r.repository.FindByDateRange(ctx, start, end)
+}
+
+func (r *instrumentedNutritionRepository) FindAverageNutrition(ctx context.Context, start, end time.Time) (domain.AverageNutrition, error) {
+	defer r.track("FindAverageNutrition", time.Now())
+	return r.repository.FindAverageNutrition(ctx, start, end)
+}
+
+func (r *instrumentedNutritionRepository) FindLatestWeight(ctx context.Context) (domain.Nutrition, error) {
+	defer r.track("FindLatestWeight", time.Now())
+	return r.repository.FindLatestWeight(ctx)
+}
+
+func (r *instrumentedNutritionRepository) Create(ctx context.Context, n domain.Nutrition) (domain.Nutrition, error) {
+	defer r.track("Create", time.Now())
+	return r.repository.Create(ctx, n)
+}
+
+func (r *instrumentedNutritionRepository) Update(ctx context.Context, n domain.Nutrition) (domain.Nutrition, error) {
+	defer r.track("Update", time.Now())
+	return r.repository.Update(ctx, n)
+}
+
+func (r *instrumentedNutritionRepository) Delete(ctx context.Context, n domain.Nutrition) error {
+	defer r.track("Delete", time.Now())
+	return r.repository.Delete(ctx, n)
+}
+
+func (r *instrumentedNutritionRepository) track(method string, start time.Time) {
+	r.observe("nutrition", method, time.Since(start))
+}
+
+type instrumentedHistoryRepository struct {
+	repository domain.HistoryRepository
+	observe    QueryObserver
+}
+
+func InstrumentHistoryRepository(repository domain.HistoryRepository, observe QueryObserver) domain.HistoryRepository {
+	return &instrumentedHistoryRepository{repository: repository, observe: observe}
+}
+
+func (r *instrumentedHistoryRepository) Append(ctx context.Context, entry domain.HistoryEntry) (domain.HistoryEntry, error) {
+	defer r.track("Append", time.Now())
+	return r.repository.Append(ctx, entry)
+}
+
+func (r *instrumentedHistoryRepository) FindByID(ctx context.Context, id int64) (domain.HistoryEntry, error) {
+	defer r.track("FindByID", time.Now())
+	return r.repository.FindByID(ctx, id)
+}
+
+func (r *instrumentedHistoryRepository) FindByEntityAndDate(ctx context.Context, entity domain.HistoryEntity, date time.Time) ([]domain.HistoryEntry, error) {
+	defer r.track("FindByEntityAndDate", time.Now())
+	return r.repository.FindByEntityAndDate(ctx, entity, date)
+}
+
+func (r *instrumentedHistoryRepository) track(method string, start time.Time) {
+	r.observe("history", method, time.Since(start))
+}
+
+type instrumentedFoodRepository struct {
+	repository domain.FoodRepository
+	observe    QueryObserver
+}
+
+func InstrumentFoodRepository(repository domain.FoodRepository, observe QueryObserver) domain.FoodRepository {
+	return &instrumentedFoodRepository{repository: repository, observe: observe}
+}
+
+func (r *instrumentedFoodRepository) FindByID(ctx context.Context, id int64) (domain.Food, error) {
+	defer r.track("FindByID", time.Now())
+	return r.repository.FindByID(ctx, id)
+}
+
+func (r *instrumentedFoodRepository) Search(ctx context.Context, query string, limit int) ([]domain.Food, error) {
+	defer r.track("Search", time.Now())
+	return r.repository.Search(ctx, query, limit)
+}
+
+func (r *instrumentedFoodRepository) Create(ctx context.Context, food domain.Food) (domain.Food, error) {
+	defer r.track("Create", time.Now())
+	return r.repository.Create(ctx, food)
+}
+
+func (r *instrumentedFoodRepository) Update(ctx context.Context, food domain.Food) (domain.Food, error) {
+	defer r.track("Update", time.Now())
+	return r.repository.Update(ctx, food)
+}
+
+func (r *instrumentedFoodRepository) Delete(ctx context.Context, id int64) error {
+	defer r.track("Delete", time.Now())
+	return r.repository.Delete(ctx, id)
+}
+
+func (r *instrumentedFoodRepository) Import(ctx context.Context, foods []domain.Food) error {
+	defer r.track("Import", time.Now())
+	return r.repository.Import(ctx, foods)
+}
+
+func (r *instrumentedFoodRepository) track(method string, start time.Time) {
+	r.observe("food", method, time.Since(start))
+}
+
+type instrumentedDiaryRepository struct {
+	repository domain.DiaryRepository
+	observe    QueryObserver
+}
+
+func InstrumentDiaryRepository(repository domain.DiaryRepository, observe QueryObserver) domain.DiaryRepository {
+	return &instrumentedDiaryRepository{repository: repository, observe: observe}
+}
+
+func (r *instrumentedDiaryRepository) FindByDate(ctx context.Context, date time.Time) ([]domain.DiaryEntry, error) {
+	defer r.track("FindByDate", time.Now())
+	return r.repository.FindByDate(ctx, date)
+}
+
+func (r *instrumentedDiaryRepository) Create(ctx context.Context, entry domain.DiaryEntry) (domain.DiaryEntry, error) {
+	defer r.track("Create", time.Now())
+	return r.repository.Create(ctx, entry)
+}
+
+func (r *instrumentedDiaryRepository) Delete(ctx context.Context, date time.Time, id int64) error {
+	defer r.track("Delete", time.Now())
+	return r.repository.Delete(ctx, date, id)
+}
+
+func (r *instrumentedDiaryRepository) track(method string, start time.Time) {
+	r.observe("diary", method, time.Since(start))
+}
+
+type instrumentedRecipeRepository struct {
+	repository domain.RecipeRepository
+	observe    QueryObserver
+}
+
+func InstrumentRecipeRepository(repository domain.RecipeRepository, observe QueryObserver) domain.RecipeRepository {
+	return &instrumentedRecipeRepository{repository: repository, observe: observe}
+}
+
+func (r *instrumentedRecipeRepository) FindByID(ctx context.Context, id int64) (domain.Recipe, error) {
+	defer r.track("FindByID", time.Now())
+	return r.repository.FindByID(ctx, id)
+}
+
+func (r *instrumentedRecipeRepository) FindByNames(ctx context.Context, names []string) ([]domain.Recipe, error) {
+	defer r.track("FindByNames", time.Now())
+	return r.repository.FindByNames(ctx, names)
+}
+
+func (r *instrumentedRecipeRepository) Search(ctx context.Context, query string, limit int) ([]domain.Recipe, error) {
+	defer r.track("Search", time.Now())
+	return r.repository.Search(ctx, query, limit)
+}
+
+func (r *instrumentedRecipeRepository) Create(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	defer r.track("Create", time.Now())
+	return r.repository.Create(ctx, recipe)
+}
+
+func (r *instrumentedRecipeRepository) Update(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	defer r.track("Update", time.Now())
+	return r.repository.Update(ctx, recipe)
+}
+
+func (r *instrumentedRecipeRepository) Delete(ctx context.Context, id int64) error {
+	defer r.track("Delete", time.Now())
+	return r.repository.Delete(ctx, id)
+}
+
+func (r *instrumentedRecipeRepository) track(method string, start time.Time) {
+	r.observe("recipe", method, time.Since(start))
+}
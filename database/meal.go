@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"meal-planning/domain"
 	"strings"
@@ -10,11 +11,71 @@ import (
 )
 
 type mealDay struct {
-	Date      string
-	Breakfast string
-	Lunch     string
-	Dinner    string
-	Snacks    *string
+	Date       string
+	Breakfast  string
+	Lunch      string
+	Dinner     string
+	Snacks     sql.NullString
+	Attendance string
+	Leftovers  string
+	Version    int
+}
+
+// MarshalAttendance encodes the attendance of a meal day for the attendance
+// column.
+func MarshalAttendance(attendance []domain.Attendance) (string, error) {
+	if attendance == nil {
+		attendance = []domain.Attendance{}
+	}
+
+	encoded, err := json.Marshal(attendance)
+	return string(encoded), err
+}
+
+func UnmarshalAttendance(encoded string) ([]domain.Attendance, error) {
+	var attendance []domain.Attendance
+	err := json.Unmarshal([]byte(encoded), &attendance)
+	if len(attendance) == 0 {
+		attendance = nil
+	}
+
+	return attendance, err
+}
+
+// MarshalLeftovers encodes the leftovers of a meal day for the leftovers
+// column.
+func MarshalLeftovers(leftovers []domain.Leftovers) (string, error) {
+	if leftovers == nil {
+		leftovers = []domain.Leftovers{}
+	}
+
+	encoded, err := json.Marshal(leftovers)
+	return string(encoded), err
+}
+
+func UnmarshalLeftovers(encoded string) ([]domain.Leftovers, error) {
+	var leftovers []domain.Leftovers
+	err := json.Unmarshal([]byte(encoded), &leftovers)
+	if len(leftovers) == 0 {
+		leftovers = nil
+	}
+
+	return leftovers, err
+}
+
+// JoinSnacks encodes the snacks of a meal day for the snacks column, separated
+// by commas.
+func JoinSnacks(snacks []string) string {
+	return strings.Join(snacks, ",")
+}
+
+// SplitSnacks decodes the snacks column. NULL and "" are no snacks.
+func SplitSnacks(snacks sql.NullString) []string {
+	if !snacks.Valid || snacks.String == "" {
+		return nil
+	}
+
+	return strings.Split(snacks.String, ",")
 }
 
 type sqlMealDayRepository struct {
@@ -26,14 +87,17 @@ func NewSqlMealDayRepository(db *sql.DB) domain.MealDayRepository {
 }
 
 func (s sqlMealDayRepository) FindByDate(ctx context.Context, date time.Time) (domain.MealDay, error) {
-	row := s.db.QueryRowContext(ctx, `SELECT * FROM meals WHERE "date" = date(?) LIMIT 1`, date.Format("2006-01-02"))
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := Conn(ctx, s.db).QueryRowContext(ctx, `SELECT date, breakfast, lunch, dinner, snacks, attendance, leftovers, version FROM meals WHERE "date" = date(?) LIMIT 1`, date.Format("2006-01-02"))
 
 	if row.Err() != nil {
 		return domain.MealDay{}, row.Err()
 	}
 
 	day := new(mealDay)
-	err := row.Scan(&day.Date, &day.Breakfast, &day.Lunch, &day.Dinner, &day.Snacks)
+	err := row.Scan(&day.Date, &day.Breakfast, &day.Lunch, &day.Dinner, &day.Snacks, &day.Attendance, &day.Leftovers, &day.Version)
 	if errors.Is(err, sql.ErrNoRows) {
 		return domain.MealDay{}, domain.MealNotFound
 	} else if err != nil {
@@ -45,19 +109,35 @@ func (s sqlMealDayRepository) FindByDate(ctx context.Context, date time.Time) (d
 		return domain.MealDay{}, err
 	}
 
+	attendance, err := UnmarshalAttendance(day.Attendance)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	leftovers, err := UnmarshalLeftovers(day.Leftovers)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
 	return domain.MealDay{
-		Date:      parsedDate,
-		Breakfast: day.Breakfast,
-		Lunch:     day.Lunch,
-		Dinner:    day.Dinner,
-		//Snacks:    strings.Split(*day.Snacks, ","),
+		Date:       parsedDate,
+		Breakfast:  day.Breakfast,
+		Lunch:      day.Lunch,
+		Dinner:     day.Dinner,
+		Snacks:     SplitSnacks(day.Snacks),
+		Attendance: attendance,
+		Leftovers:  leftovers,
+		Version:    day.Version,
 	}, nil
 }
 
 func (s sqlMealDayRepository) FindByDateRange(ctx context.Context, start, end time.Time) ([]domain.MealDay, error) {
-	rows, err := s.db.QueryContext(
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := Conn(ctx, s.db).QueryContext(
 		ctx,
-		"SELECT * FROM meals WHERE date >= date(?) AND date <= date(?)",
+		"SELECT date, breakfast, lunch, dinner, snacks, attendance, leftovers, version FROM meals WHERE date >= date(?) AND date <= date(?) ORDER BY date",
 		start.Format("2006-01-02"),
 		end.Format("2006-01-02"),
 	)
@@ -70,7 +150,7 @@ func (s sqlMealDayRepository) FindByDateRange(ctx context.Context, start, end ti
 	for rows.Next() {
 		meal := mealDay{}
 
-		err = rows.Scan(&meal.Date, &meal.Breakfast, &meal.Lunch, &meal.Dinner, &meal.Snacks)
+		err = rows.Scan(&meal.Date, &meal.Breakfast, &meal.Lunch, &meal.Dinner, &meal.Snacks, &meal.Attendance, &meal.Leftovers, &meal.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -80,12 +160,25 @@ func (s sqlMealDayRepository) FindByDateRange(ctx context.Context, start, end ti
 			return nil, err
 		}
 
+		attendance, err := UnmarshalAttendance(meal.Attendance)
+		if err != nil {
+			return nil, err
+		}
+
+		leftovers, err := UnmarshalLeftovers(meal.Leftovers)
+		if err != nil {
+			return nil, err
+		}
+
 		list = append(list, domain.MealDay{
-			Date:      date,
-			Breakfast: meal.Breakfast,
-			Lunch:     meal.Lunch,
-			Dinner:    meal.Dinner,
-			//Snacks:    strings.Split(*meal.Snacks, ","),
+			Date:       date,
+			Breakfast:  meal.Breakfast,
+			Lunch:      meal.Lunch,
+			Dinner:     meal.Dinner,
+			Snacks:     SplitSnacks(meal.Snacks),
+			Attendance: attendance,
+			Leftovers:  leftovers,
+			Version:    meal.Version,
 		})
 	}
 
@@ -98,21 +191,68 @@ func (s sqlMealDayRepository) FindByDateRange(ctx context.Context, start, end ti
 }
 
 func (s sqlMealDayRepository) Create(ctx context.Context, mealDay domain.MealDay) (domain.MealDay, error) {
-	_, err := s.db.ExecContext(ctx, `INSERT INTO meals VALUES (?, ?, ?, ?, ?)`, mealDay.Date.Format("2006-01-02"), mealDay.Breakfast, mealDay.Lunch, mealDay.Dinner, strings.Join(mealDay.Snacks, ","))
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	attendance, err := MarshalAttendance(mealDay.Attendance)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	leftovers, err := MarshalLeftovers(mealDay.Leftovers)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	result, err := Conn(ctx, s.db).ExecContext(ctx, `INSERT INTO meals (date, breakfast, lunch, dinner, snacks, attendance, leftovers, version) VALUES (?, ?, ?, ?, ?, ?, ?, 1) ON CONFLICT (date) DO NOTHING`, mealDay.Date.Format("2006-01-02"), mealDay.Breakfast, mealDay.Lunch, mealDay.Dinner, JoinSnacks(mealDay.Snacks), attendance, leftovers)
+
+	if err != nil {
+		return domain.MealDay{}, err
+	}
 
+	err = ExpectAffectedRow(result, domain.MealConflict)
 	if err != nil {
 		return domain.MealDay{}, err
 	}
 
+	mealDay.Version = 1
 	return mealDay, nil
 }
 
 func (s sqlMealDayRepository) Update(ctx context.Context, mealDay domain.MealDay) (domain.MealDay, error) {
-	_, err := s.db.ExecContext(ctx, `UPDATE meals SET breakfast = ?, lunch = ?, dinner = ?, snacks = ? WHERE date = date(?)`, mealDay.Breakfast, mealDay.Lunch, mealDay.Dinner, strings.Join(mealDay.Snacks, ","), mealDay.Date.Format("2006-01-02"))
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
 
+	attendance, err := MarshalAttendance(mealDay.Attendance)
 	if err != nil {
 		return domain.MealDay{}, err
 	}
 
+	leftovers, err := MarshalLeftovers(mealDay.Leftovers)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	result, err := Conn(ctx, s.db).ExecContext(ctx, `UPDATE meals SET breakfast = ?, lunch = ?, dinner = ?, snacks = ?, attendance = ?, leftovers = ?, version = version + 1 WHERE date = date(?) AND version = ?`, mealDay.Breakfast, mealDay.Lunch, mealDay.Dinner, JoinSnacks(mealDay.Snacks), attendance, leftovers, mealDay.Date.Format("2006-01-02"), mealDay.Version)
+
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	err = ExpectAffectedRow(result, domain.MealConflict)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	mealDay.Version++
 	return mealDay, nil
 }
+
+func (s sqlMealDayRepository) Delete(ctx context.Context, mealDay domain.MealDay) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := Conn(ctx, s.db).ExecContext(ctx, `DELETE FROM meals WHERE date = date(?)`, mealDay.Date.Format("2006-01-02"))
+
+	return err
+}
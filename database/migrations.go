@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// migrations are applied in order, the index of the last applied migration is
+// tracked in the user_version pragma. Never change an existing migration, add a
+// new one instead. The first migrations use IF NOT EXISTS, because they ran
+// unversioned on startup before.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS meals (date TEXT PRIMARY KEY, breakfast TEXT, lunch TEXT, dinner TEXT, snacks TEXT)`,
+	`CREATE TABLE IF NOT EXISTS nutrition (date TEXT PRIMARY KEY, calories INT, weight INT)`,
+	`CREATE TABLE IF NOT EXISTS history (id INTEGER PRIMARY KEY AUTOINCREMENT, entity TEXT NOT NULL, date TEXT NOT NULL, action TEXT NOT NULL, author TEXT NOT NULL, changed_at TEXT NOT NULL, old_value TEXT, new_value TEXT)`,
+	`CREATE INDEX IF NOT EXISTS history_entity_date ON history (entity, date)`,
+	// history is append-only, reject any attempt to rewrite it
+	`CREATE TRIGGER IF NOT EXISTS history_no_update BEFORE UPDATE ON history BEGIN SELECT RAISE(ABORT, 'history is append-only'); END`,
+	`CREATE TRIGGER IF NOT EXISTS history_no_delete BEFORE DELETE ON history BEGIN SELECT RAISE(ABORT, 'history is append-only'); END`,
+	`ALTER TABLE meals ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE nutrition ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	// foods hold nutrients per 100 g, imported foods are unique per source
+	`CREATE TABLE foods (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, brand TEXT NOT NULL DEFAULT '', kcal REAL NOT NULL, protein REAL NOT NULL, carbs REAL NOT NULL, fat REAL NOT NULL, serving_sizes TEXT NOT NULL DEFAULT '[]', source TEXT NOT NULL DEFAULT 'manual', source_id TEXT, version INTEGER NOT NULL DEFAULT 1)`,
+	`CREATE UNIQUE INDEX foods_source ON foods (source, source_id)`,
+	`CREATE INDEX foods_name ON foods (name COLLATE NOCASE)`,
+	// existing entries were typed in by hand, so calories_from_diary starts unset
+	`ALTER TABLE nutrition ADD COLUMN calories_from_diary INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE nutrition ADD COLUMN protein INT`,
+	`ALTER TABLE nutrition ADD COLUMN carbs INT`,
+	`ALTER TABLE nutrition ADD COLUMN fat INT`,
+	// diary entries keep the name and nutrients of the food when it was logged
+	`CREATE TABLE diary_entries (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT NOT NULL, slot TEXT NOT NULL, food_id INTEGER REFERENCES foods (id) ON DELETE SET NULL, food_name TEXT NOT NULL, grams REAL NOT NULL, kcal REAL NOT NULL, protein REAL NOT NULL, carbs REAL NOT NULL, fat REAL NOT NULL)`,
+	`CREATE INDEX diary_entries_date ON diary_entries (date)`,
+	// planned meals are linked to recipes by name, so names are unique
+	`CREATE TABLE recipes (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, servings INTEGER NOT NULL, kcal REAL NOT NULL, protein REAL NOT NULL, carbs REAL NOT NULL, fat REAL NOT NULL, version INTEGER NOT NULL DEFAULT 1)`,
+	`CREATE UNIQUE INDEX recipes_name ON recipes (name COLLATE NOCASE)`,
+	`ALTER TABLE diary_entries ADD COLUMN recipe_id INTEGER REFERENCES recipes (id) ON DELETE SET NULL`,
+	`ALTER TABLE diary_entries ADD COLUMN portions REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE recipes ADD COLUMN ingredients TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE recipes ADD COLUMN instructions TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE recipes ADD COLUMN prep_minutes INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE recipes ADD COLUMN cook_minutes INTEGER NOT NULL DEFAULT 0`,
+	// slots without attendance are served to the whole household
+	`ALTER TABLE meals ADD COLUMN attendance TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE meals ADD COLUMN leftovers TEXT NOT NULL DEFAULT '[]'`,
+}
+
+// Migrate applies all migrations missing in the database.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	version, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for ; version < len(migrations); version++ {
+		slog.InfoContext(ctx, "Applying migration", slog.Int("version", version+1))
+
+		err = applyMigration(ctx, db, version+1, migrations[version])
+		if err != nil {
+			return fmt.Errorf("applying migration %d: %w", version+1, err)
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the number of migrations applied to the database.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// LatestSchemaVersion is the schema version of a fully migrated database.
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, version int, migration string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, migration)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, version))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
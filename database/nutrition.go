@@ -8,10 +8,39 @@ import (
 	"time"
 )
 
+const nutritionColumns = `date, calories, calories_from_diary, protein, carbs, fat, weight, version`
+
 type nutritionEntity struct {
-	date     string
-	calories sql.NullInt64
-	weight   sql.NullInt64
+	date              string
+	calories          sql.NullInt64
+	caloriesFromDiary bool
+	protein           sql.NullInt64
+	carbs             sql.NullInt64
+	fat               sql.NullInt64
+	weight            sql.NullInt64
+	version           int
+}
+
+func (e *nutritionEntity) scan(row interface{ Scan(dest ...any) error }) error {
+	return row.Scan(&e.date, &e.calories, &e.caloriesFromDiary, &e.protein, &e.carbs, &e.fat, &e.weight, &e.version)
+}
+
+func (e nutritionEntity) toDomain() (domain.Nutrition, error) {
+	date, err := time.Parse("2006-01-02", e.date)
+	if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	return domain.Nutrition{
+		Date:              date,
+		Calories:          int(e.calories.Int64),
+		CaloriesFromDiary: e.caloriesFromDiary,
+		Protein:           int(e.protein.Int64),
+		Carbs:             int(e.carbs.Int64),
+		Fat:               int(e.fat.Int64),
+		Weight:            int(e.weight.Int64),
+		Version:           e.version,
+	}, nil
 }
 
 type averageNutritionEntity struct {
@@ -30,46 +59,33 @@ func NewSqlNutritionRepository(db *sql.DB) domain.NutritionRepository {
 }
 
 func (s *sqlNutritionRepository) FindByDate(ctx context.Context, date time.Time) (domain.Nutrition, error) {
-	row := s.db.QueryRowContext(ctx, `SELECT * FROM nutrition WHERE "date" = date(?) LIMIT 1`, date.Format("2006-01-02"))
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := Conn(ctx, s.db).QueryRowContext(ctx, `SELECT `+nutritionColumns+` FROM nutrition WHERE "date" = date(?) LIMIT 1`, date.Format("2006-01-02"))
 
 	if row.Err() != nil {
 		return domain.Nutrition{}, row.Err()
 	}
 
 	entity := new(nutritionEntity)
-	err := row.Scan(&entity.date, &entity.calories, &entity.weight)
+	err := entity.scan(row)
 	if errors.Is(err, sql.ErrNoRows) {
 		return domain.Nutrition{}, domain.NutritionNotFound
 	} else if err != nil {
 		return domain.Nutrition{}, err
 	}
 
-	parsedDate, err := time.Parse("2006-01-02", entity.date)
-	if err != nil {
-		return domain.Nutrition{}, err
-	}
-
-	var calories int
-	if entity.calories.Valid {
-		calories = int(entity.calories.Int64)
-	}
-
-	var weight int
-	if entity.weight.Valid {
-		weight = int(entity.weight.Int64)
-	}
-
-	return domain.Nutrition{
-		Date:     parsedDate,
-		Calories: calories,
-		Weight:   weight,
-	}, nil
+	return entity.toDomain()
 }
 
 func (s *sqlNutritionRepository) FindByDateRange(ctx context.Context, start, end time.Time) ([]domain.Nutrition, error) {
-	rows, err := s.db.QueryContext(
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := Conn(ctx, s.db).QueryContext(
 		ctx,
-		"SELECT * FROM nutrition WHERE date >= date(?) AND date <= date(?)",
+		"SELECT "+nutritionColumns+" FROM nutrition WHERE date >= date(?) AND date <= date(?) ORDER BY date",
 		start.Format("2006-01-02"),
 		end.Format("2006-01-02"),
 	)
@@ -81,31 +97,17 @@ func (s *sqlNutritionRepository) FindByDateRange(ctx context.Context, start, end
 	list := make([]domain.Nutrition, 0)
 	for rows.Next() {
 		entity := nutritionEntity{}
-		err = rows.Scan(&entity.date, &entity.calories, &entity.weight)
+		err = entity.scan(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		date, err := time.Parse("2006-01-02", entity.date)
+		nutrition, err := entity.toDomain()
 		if err != nil {
 			return nil, err
 		}
 
-		var calories int
-		if entity.calories.Valid {
-			calories = int(entity.calories.Int64)
-		}
-
-		var weight int
-		if entity.weight.Valid {
-			weight = int(entity.weight.Int64)
-		}
-
-		list = append(list, domain.Nutrition{
-			Date:     date,
-			Calories: calories,
-			Weight:   weight,
-		})
+		list = append(list, nutrition)
 	}
 
 	err = rows.Err()
@@ -117,7 +119,10 @@ func (s *sqlNutritionRepository) FindByDateRange(ctx context.Context, start, end
 }
 
 func (s *sqlNutritionRepository) FindAverageNutrition(ctx context.Context, start, end time.Time) (domain.AverageNutrition, error) {
-	row := s.db.QueryRowContext(ctx, `SELECT CAST(AVG(calories) as INT) as calories, CAST(AVG(weight) AS INT) as weight FROM nutrition WHERE date >= date(?) AND date <= date(?)`,
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := Conn(ctx, s.db).QueryRowContext(ctx, `SELECT CAST(AVG(calories) as INT) as calories, CAST(AVG(weight) AS INT) as weight FROM nutrition WHERE date >= date(?) AND date <= date(?)`,
 		start.Format("2006-01-02"),
 		end.Format("2006-01-02"),
 	)
@@ -150,7 +155,31 @@ func (s *sqlNutritionRepository) FindAverageNutrition(ctx context.Context, start
 	}, nil
 }
 
+func (s *sqlNutritionRepository) FindLatestWeight(ctx context.Context) (domain.Nutrition, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := Conn(ctx, s.db).QueryRowContext(ctx, `SELECT `+nutritionColumns+` FROM nutrition WHERE weight IS NOT NULL ORDER BY date DESC LIMIT 1`)
+
+	if row.Err() != nil {
+		return domain.Nutrition{}, row.Err()
+	}
+
+	entity := new(nutritionEntity)
+	err := entity.scan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Nutrition{}, domain.NutritionNotFound
+	} else if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	return entity.toDomain()
+}
+
 func (s *sqlNutritionRepository) Create(ctx context.Context, n domain.Nutrition) (domain.Nutrition, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	calories := sql.NullInt64{
 		Int64: int64(n.Calories),
 		Valid: n.Calories > 0,
@@ -161,16 +190,29 @@ func (s *sqlNutritionRepository) Create(ctx context.Context, n domain.Nutrition)
 		Valid: n.Weight > 0,
 	}
 
-	_, err := s.db.ExecContext(ctx, `INSERT INTO nutrition VALUES (?, ?, ?)`, n.Date.Format("2006-01-02"), calories, weight)
+	result, err := Conn(ctx, s.db).ExecContext(
+		ctx,
+		`INSERT INTO nutrition (date, calories, calories_from_diary, protein, carbs, fat, weight, version) VALUES (?, ?, ?, ?, ?, ?, ?, 1) ON CONFLICT (date) DO NOTHING`,
+		n.Date.Format("2006-01-02"), calories, n.CaloriesFromDiary, nullIfNotPositive(n.Protein), nullIfNotPositive(n.Carbs), nullIfNotPositive(n.Fat), weight,
+	)
 
 	if err != nil {
 		return domain.Nutrition{}, err
 	}
 
+	err = ExpectAffectedRow(result, domain.NutritionConflict)
+	if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	n.Version = 1
 	return n, nil
 }
 
 func (s *sqlNutritionRepository) Update(ctx context.Context, n domain.Nutrition) (domain.Nutrition, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
 	calories := sql.NullInt64{
 		Int64: int64(n.Calories),
 		Valid: n.Calories > 0,
@@ -181,16 +223,39 @@ func (s *sqlNutritionRepository) Update(ctx context.Context, n domain.Nutrition)
 		Valid: n.Weight > 0,
 	}
 
-	_, err := s.db.ExecContext(ctx, `UPDATE nutrition SET calories = ?, weight = ? WHERE date = date(?)`, calories, weight, n.Date.Format("2006-01-02"), calories, weight)
+	result, err := Conn(ctx, s.db).ExecContext(
+		ctx,
+		`UPDATE nutrition SET calories = ?, calories_from_diary = ?, protein = ?, carbs = ?, fat = ?, weight = ?, version = version + 1 WHERE date = date(?) AND version = ?`,
+		calories, n.CaloriesFromDiary, nullIfNotPositive(n.Protein), nullIfNotPositive(n.Carbs), nullIfNotPositive(n.Fat), weight, n.Date.Format("2006-01-02"), n.Version,
+	)
+
+	if err != nil {
+		return domain.Nutrition{}, err
+	}
 
+	err = ExpectAffectedRow(result, domain.NutritionConflict)
 	if err != nil {
 		return domain.Nutrition{}, err
 	}
 
+	n.Version++
 	return n, nil
 }
 
 func (s *sqlNutritionRepository) Delete(ctx context.Context, n domain.Nutrition) error {
-	//TODO implement me
-	panic("implement me")
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := Conn(ctx, s.db).ExecContext(ctx, `DELETE FROM nutrition WHERE date = date(?)`, n.Date.Format("2006-01-02"))
+
+	return err
+}
+
+// nullIfNotPositive stores values that were not recorded as NULL, so they do
+// not count towards averages.
+func nullIfNotPositive(value int) sql.NullInt64 {
+	return sql.NullInt64{
+		Int64: int64(value),
+		Valid: value > 0,
+	}
 }
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// busyTimeout is how long, in milliseconds, a connection waits for a lock held
+// by another process before failing. It matches the query timeout.
+var busyTimeout = strconv.FormatInt(queryTimeout.Milliseconds(), 10)
+
+// Open opens the SQLite database at path with the driver selected at build
+// time: mattn/go-sqlite3 by default, modernc.org/sqlite with the purego tag.
+//
+// SQLite allows a single writer at a time, so the pool is limited to one
+// connection. Requests queue in the pool instead of failing with
+// SQLITE_BUSY.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open(sqliteDriver, sqliteDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+
+	return db, nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOpenConfiguresConnection(t *testing.T) {
+	db := newTestDatabase(t)
+
+	tests := []struct {
+		pragma   string
+		expected string
+	}{
+		{"journal_mode", "wal"},
+		{"busy_timeout", busyTimeout},
+		{"foreign_keys", "1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pragma, func(t *testing.T) {
+			var value string
+			err := db.QueryRowContext(context.Background(), "PRAGMA "+test.pragma).Scan(&value)
+			if err != nil {
+				t.Fatalf("reading pragma: %v", err)
+			}
+
+			if value != test.expected {
+				t.Errorf("expected %s to be %q, got %q", test.pragma, test.expected, value)
+			}
+		})
+	}
+
+	if maxOpen := db.Stats().MaxOpenConnections; maxOpen != 1 {
+		t.Errorf("expected a single connection, got %d", maxOpen)
+	}
+}
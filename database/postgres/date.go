@@ -0,0 +1,14 @@
+package postgres
+
+import "time"
+
+// formatDate passes a calendar day as a DATE parameter. Postgres infers the
+// parameter type from the column it is compared with.
+func formatDate(date time.Time) string {
+	return date.Format("2006-01-02")
+}
+
+// toDate drops the time and location the driver attaches to DATE values.
+func toDate(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"meal-planning/database"
+	"meal-planning/domain"
+	"time"
+)
+
+type diaryRepository struct {
+	db *sql.DB
+}
+
+func NewDiaryRepository(db *sql.DB) domain.DiaryRepository {
+	return &diaryRepository{db}
+}
+
+func (r *diaryRepository) FindByDate(ctx context.Context, date time.Time) ([]domain.DiaryEntry, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, date, slot, COALESCE(food_id, 0), COALESCE(recipe_id, 0), food_name, grams, portions, kcal, protein, carbs, fat FROM diary_entries WHERE date = $1 ORDER BY id`,
+		formatDate(date),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.DiaryEntry, 0)
+	for rows.Next() {
+		var entry domain.DiaryEntry
+		var entryDate time.Time
+		var slot string
+		err = rows.Scan(&entry.ID, &entryDate, &slot, &entry.FoodID, &entry.RecipeID, &entry.FoodName, &entry.Grams, &entry.Portions, &entry.Nutrients.Kcal, &entry.Nutrients.Protein, &entry.Nutrients.Carbs, &entry.Nutrients.Fat)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Date = toDate(entryDate)
+		entry.Slot = domain.MealSlot(slot)
+		list = append(list, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *diaryRepository) Create(ctx context.Context, entry domain.DiaryEntry) (domain.DiaryEntry, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	foodID := sql.NullInt64{Int64: entry.FoodID, Valid: entry.FoodID != 0}
+	recipeID := sql.NullInt64{Int64: entry.RecipeID, Valid: entry.RecipeID != 0}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO diary_entries (date, slot, food_id, recipe_id, food_name, grams, portions, kcal, protein, carbs, fat) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		formatDate(entry.Date), string(entry.Slot), foodID, recipeID, entry.FoodName, entry.Grams, entry.Portions, entry.Nutrients.Kcal, entry.Nutrients.Protein, entry.Nutrients.Carbs, entry.Nutrients.Fat,
+	).Scan(&entry.ID)
+	if err != nil {
+		return domain.DiaryEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *diaryRepository) Delete(ctx context.Context, date time.Time, id int64) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM diary_entries WHERE id = $1 AND date = $2`, id, formatDate(date))
+	if err != nil {
+		return err
+	}
+
+	return database.ExpectAffectedRow(result, domain.DiaryEntryNotFound)
+}
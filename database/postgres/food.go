@@ -0,0 +1,231 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meal-planning/database"
+	"meal-planning/domain"
+	"strings"
+)
+
+const foodColumns = `id, name, brand, kcal, protein, carbs, fat, serving_sizes, source, COALESCE(source_id, ''), version`
+
+type food struct {
+	ID           int64
+	Name         string
+	Brand        string
+	Kcal         float64
+	Protein      float64
+	Carbs        float64
+	Fat          float64
+	ServingSizes string
+	Source       string
+	SourceID     string
+	Version      int
+}
+
+func (f *food) scan(row interface{ Scan(dest ...any) error }) error {
+	return row.Scan(&f.ID, &f.Name, &f.Brand, &f.Kcal, &f.Protein, &f.Carbs, &f.Fat, &f.ServingSizes, &f.Source, &f.SourceID, &f.Version)
+}
+
+func (f food) toDomain() (domain.Food, error) {
+	servingSizes, err := database.UnmarshalServingSizes(f.ServingSizes)
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	return domain.Food{
+		ID:    f.ID,
+		Name:  f.Name,
+		Brand: f.Brand,
+		Per100g: domain.Nutrients{
+			Kcal:    f.Kcal,
+			Protein: f.Protein,
+			Carbs:   f.Carbs,
+			Fat:     f.Fat,
+		},
+		ServingSizes: servingSizes,
+		Source:       domain.FoodSource(f.Source),
+		SourceID:     f.SourceID,
+		Version:      f.Version,
+	}, nil
+}
+
+type foodRepository struct {
+	db *sql.DB
+}
+
+func NewFoodRepository(db *sql.DB) domain.FoodRepository {
+	return &foodRepository{db}
+}
+
+func (r *foodRepository) FindByID(ctx context.Context, id int64) (domain.Food, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	f := food{}
+	err := f.scan(r.db.QueryRowContext(ctx, `SELECT `+foodColumns+` FROM foods WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Food{}, domain.FoodNotFound
+	} else if err != nil {
+		return domain.Food{}, err
+	}
+
+	return f.toDomain()
+}
+
+func (r *foodRepository) Search(ctx context.Context, query string, limit int) ([]domain.Food, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	terms := strings.Fields(query)
+
+	conditions := make([]string, 0, len(terms))
+	args := make([]any, 0, len(terms)+2)
+	for _, term := range terms {
+		args = append(args, "%"+database.EscapeLike(term)+"%")
+		conditions = append(conditions, fmt.Sprintf(`(name ILIKE $%d OR brand ILIKE $%d)`, len(args), len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = `WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	prefix := "%"
+	if len(terms) > 0 {
+		prefix = database.EscapeLike(terms[0]) + "%"
+	}
+	args = append(args, prefix, limit)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf(`SELECT `+foodColumns+` FROM foods %s ORDER BY CASE WHEN name ILIKE $%d THEN 0 ELSE 1 END, lower(name), id LIMIT $%d`, where, len(args)-1, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.Food, 0)
+	for rows.Next() {
+		f := food{}
+		err = f.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := f.toDomain()
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, found)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *foodRepository) Create(ctx context.Context, f domain.Food) (domain.Food, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	servingSizes, err := database.MarshalServingSizes(f.ServingSizes)
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	err = r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO foods (name, brand, kcal, protein, carbs, fat, serving_sizes, source, source_id, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 1) RETURNING id`,
+		f.Name, f.Brand, f.Per100g.Kcal, f.Per100g.Protein, f.Per100g.Carbs, f.Per100g.Fat, servingSizes, string(f.Source), database.NullIfEmpty(f.SourceID),
+	).Scan(&f.ID)
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	f.Version = 1
+	return f, nil
+}
+
+func (r *foodRepository) Update(ctx context.Context, f domain.Food) (domain.Food, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	servingSizes, err := database.MarshalServingSizes(f.ServingSizes)
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	// the origin of a food does not change by editing it
+	var source string
+	err = r.db.QueryRowContext(
+		ctx,
+		`UPDATE foods SET name = $1, brand = $2, kcal = $3, protein = $4, carbs = $5, fat = $6, serving_sizes = $7, version = version + 1 WHERE id = $8 AND version = $9 RETURNING source, COALESCE(source_id, '')`,
+		f.Name, f.Brand, f.Per100g.Kcal, f.Per100g.Protein, f.Per100g.Carbs, f.Per100g.Fat, servingSizes, f.ID, f.Version,
+	).Scan(&source, &f.SourceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Food{}, domain.FoodConflict
+	} else if err != nil {
+		return domain.Food{}, err
+	}
+
+	f.Source = domain.FoodSource(source)
+	f.Version++
+	return f, nil
+}
+
+func (r *foodRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM foods WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return database.ExpectAffectedRow(result, domain.FoodNotFound)
+}
+
+func (r *foodRepository) Import(ctx context.Context, foods []domain.Food) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO foods (name, brand, kcal, protein, carbs, fat, serving_sizes, source, source_id, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 1)
+		ON CONFLICT (source, source_id) DO UPDATE SET name = excluded.name, brand = excluded.brand, kcal = excluded.kcal, protein = excluded.protein, carbs = excluded.carbs, fat = excluded.fat, serving_sizes = excluded.serving_sizes, version = foods.version + 1`,
+	)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for _, f := range foods {
+		servingSizes, err := database.MarshalServingSizes(f.ServingSizes)
+		if err != nil {
+			return err
+		}
+
+		_, err = statement.ExecContext(ctx, f.Name, f.Brand, f.Per100g.Kcal, f.Per100g.Protein, f.Per100g.Carbs, f.Per100g.Fat, servingSizes, string(f.Source), database.NullIfEmpty(f.SourceID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
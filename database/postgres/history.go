@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"meal-planning/database"
+	"meal-planning/domain"
+	"time"
+)
+
+type historyEntity struct {
+	id        int64
+	entity    string
+	date      time.Time
+	action    string
+	author    string
+	changedAt time.Time
+	oldValue  sql.NullString
+	newValue  sql.NullString
+}
+
+func (e historyEntity) toDomain() domain.HistoryEntry {
+	entry := domain.HistoryEntry{
+		ID:        e.id,
+		Entity:    domain.HistoryEntity(e.entity),
+		Date:      toDate(e.date),
+		Action:    domain.HistoryAction(e.action),
+		Author:    e.author,
+		ChangedAt: e.changedAt,
+	}
+
+	if e.oldValue.Valid {
+		entry.OldValue = []byte(e.oldValue.String)
+	}
+
+	if e.newValue.Valid {
+		entry.NewValue = []byte(e.newValue.String)
+	}
+
+	return entry
+}
+
+type historyRepository struct {
+	db *sql.DB
+}
+
+func NewHistoryRepository(db *sql.DB) domain.HistoryRepository {
+	return &historyRepository{db}
+}
+
+func (r *historyRepository) Append(ctx context.Context, entry domain.HistoryEntry) (domain.HistoryEntry, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	oldValue := sql.NullString{
+		String: string(entry.OldValue),
+		Valid:  entry.OldValue != nil,
+	}
+
+	newValue := sql.NullString{
+		String: string(entry.NewValue),
+		Valid:  entry.NewValue != nil,
+	}
+
+	err := database.Conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`INSERT INTO history (entity, date, action, author, changed_at, old_value, new_value) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		string(entry.Entity),
+		formatDate(entry.Date),
+		string(entry.Action),
+		entry.Author,
+		entry.ChangedAt.UTC(),
+		oldValue,
+		newValue,
+	).Scan(&entry.ID)
+	if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *historyRepository) FindByID(ctx context.Context, id int64) (domain.HistoryEntry, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := database.Conn(ctx, r.db).QueryRowContext(ctx, `SELECT id, entity, date, action, author, changed_at, old_value, new_value FROM history WHERE id = $1`, id)
+
+	entity := historyEntity{}
+	err := row.Scan(&entity.id, &entity.entity, &entity.date, &entity.action, &entity.author, &entity.changedAt, &entity.oldValue, &entity.newValue)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.HistoryEntry{}, domain.HistoryEntryNotFound
+	} else if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	return entity.toDomain(), nil
+}
+
+func (r *historyRepository) FindByEntityAndDate(ctx context.Context, entity domain.HistoryEntity, date time.Time) ([]domain.HistoryEntry, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := database.Conn(ctx, r.db).QueryContext(
+		ctx,
+		`SELECT id, entity, date, action, author, changed_at, old_value, new_value FROM history WHERE entity = $1 AND date = $2 ORDER BY id DESC`,
+		string(entity),
+		formatDate(date),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.HistoryEntry, 0)
+	for rows.Next() {
+		entity := historyEntity{}
+		err = rows.Scan(&entity.id, &entity.entity, &entity.date, &entity.action, &entity.author, &entity.changedAt, &entity.oldValue, &entity.newValue)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, entity.toDomain())
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"meal-planning/database"
+	"meal-planning/domain"
+	"time"
+)
+
+type mealDay struct {
+	Date       time.Time
+	Breakfast  string
+	Lunch      string
+	Dinner     string
+	Snacks     sql.NullString
+	Attendance string
+	Leftovers  string
+	Version    int
+}
+
+func (m mealDay) toDomain() (domain.MealDay, error) {
+	attendance, err := database.UnmarshalAttendance(m.Attendance)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	leftovers, err := database.UnmarshalLeftovers(m.Leftovers)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	return domain.MealDay{
+		Date:       toDate(m.Date),
+		Breakfast:  m.Breakfast,
+		Lunch:      m.Lunch,
+		Dinner:     m.Dinner,
+		Snacks:     database.SplitSnacks(m.Snacks),
+		Attendance: attendance,
+		Leftovers:  leftovers,
+		Version:    m.Version,
+	}, nil
+}
+
+type mealDayRepository struct {
+	db *sql.DB
+}
+
+func NewMealDayRepository(db *sql.DB) domain.MealDayRepository {
+	return &mealDayRepository{db}
+}
+
+func (r *mealDayRepository) FindByDate(ctx context.Context, date time.Time) (domain.MealDay, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := database.Conn(ctx, r.db).QueryRowContext(ctx, `SELECT date, breakfast, lunch, dinner, snacks, attendance, leftovers, version FROM meals WHERE date = $1`, formatDate(date))
+
+	day := mealDay{}
+	err := row.Scan(&day.Date, &day.Breakfast, &day.Lunch, &day.Dinner, &day.Snacks, &day.Attendance, &day.Leftovers, &day.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.MealDay{}, domain.MealNotFound
+	} else if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	return day.toDomain()
+}
+
+func (r *mealDayRepository) FindByDateRange(ctx context.Context, start, end time.Time) ([]domain.MealDay, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := database.Conn(ctx, r.db).QueryContext(
+		ctx,
+		`SELECT date, breakfast, lunch, dinner, snacks, attendance, leftovers, version FROM meals WHERE date BETWEEN $1 AND $2 ORDER BY date`,
+		formatDate(start),
+		formatDate(end),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.MealDay, 0)
+	for rows.Next() {
+		day := mealDay{}
+		err = rows.Scan(&day.Date, &day.Breakfast, &day.Lunch, &day.Dinner, &day.Snacks, &day.Attendance, &day.Leftovers, &day.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := day.toDomain()
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, found)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *mealDayRepository) Create(ctx context.Context, mealDay domain.MealDay) (domain.MealDay, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	attendance, err := database.MarshalAttendance(mealDay.Attendance)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	leftovers, err := database.MarshalLeftovers(mealDay.Leftovers)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	result, err := database.Conn(ctx, r.db).ExecContext(
+		ctx,
+		`INSERT INTO meals (date, breakfast, lunch, dinner, snacks, attendance, leftovers, version) VALUES ($1, $2, $3, $4, $5, $6, $7, 1) ON CONFLICT (date) DO NOTHING`,
+		formatDate(mealDay.Date), mealDay.Breakfast, mealDay.Lunch, mealDay.Dinner, database.JoinSnacks(mealDay.Snacks), attendance, leftovers,
+	)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	err = database.ExpectAffectedRow(result, domain.MealConflict)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	mealDay.Version = 1
+	return mealDay, nil
+}
+
+func (r *mealDayRepository) Update(ctx context.Context, mealDay domain.MealDay) (domain.MealDay, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	attendance, err := database.MarshalAttendance(mealDay.Attendance)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	leftovers, err := database.MarshalLeftovers(mealDay.Leftovers)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	result, err := database.Conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE meals SET breakfast = $1, lunch = $2, dinner = $3, snacks = $4, attendance = $5, leftovers = $6, version = version + 1 WHERE date = $7 AND version = $8`,
+		mealDay.Breakfast, mealDay.Lunch, mealDay.Dinner, database.JoinSnacks(mealDay.Snacks), attendance, leftovers, formatDate(mealDay.Date), mealDay.Version,
+	)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	err = database.ExpectAffectedRow(result, domain.MealConflict)
+	if err != nil {
+		return domain.MealDay{}, err
+	}
+
+	mealDay.Version++
+	return mealDay, nil
+}
+
+func (r *mealDayRepository) Delete(ctx context.Context, mealDay domain.MealDay) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := database.Conn(ctx, r.db).ExecContext(ctx, `DELETE FROM meals WHERE date = $1`, formatDate(mealDay.Date))
+
+	return err
+}
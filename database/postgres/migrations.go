@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// migrations are applied in order, applied versions are tracked in the
+// schema_migrations table. Never change an existing migration, add a new one
+// instead.
+var migrations = []string{
+	`CREATE TABLE meals (date DATE PRIMARY KEY, breakfast TEXT NOT NULL DEFAULT '', lunch TEXT NOT NULL DEFAULT '', dinner TEXT NOT NULL DEFAULT '', snacks TEXT, version INTEGER NOT NULL DEFAULT 1)`,
+	`CREATE TABLE nutrition (date DATE PRIMARY KEY, calories INTEGER, weight INTEGER, version INTEGER NOT NULL DEFAULT 1)`,
+	`CREATE TABLE history (id BIGSERIAL PRIMARY KEY, entity TEXT NOT NULL, date DATE NOT NULL, action TEXT NOT NULL, author TEXT NOT NULL, changed_at TIMESTAMPTZ NOT NULL, old_value TEXT, new_value TEXT)`,
+	`CREATE INDEX history_entity_date ON history (entity, date)`,
+	// history is append-only, reject any attempt to rewrite it
+	`CREATE FUNCTION history_append_only() RETURNS trigger LANGUAGE plpgsql AS $$ BEGIN RAISE EXCEPTION 'history is append-only'; END $$`,
+	`CREATE TRIGGER history_no_rewrite BEFORE UPDATE OR DELETE ON history FOR EACH ROW EXECUTE FUNCTION history_append_only()`,
+	// foods hold nutrients per 100 g, imported foods are unique per source
+	`CREATE TABLE foods (id BIGSERIAL PRIMARY KEY, name TEXT NOT NULL, brand TEXT NOT NULL DEFAULT '', kcal DOUBLE PRECISION NOT NULL, protein DOUBLE PRECISION NOT NULL, carbs DOUBLE PRECISION NOT NULL, fat DOUBLE PRECISION NOT NULL, serving_sizes TEXT NOT NULL DEFAULT '[]', source TEXT NOT NULL DEFAULT 'manual', source_id TEXT, version INTEGER NOT NULL DEFAULT 1)`,
+	`CREATE UNIQUE INDEX foods_source ON foods (source, source_id)`,
+	`CREATE INDEX foods_name ON foods (lower(name))`,
+	// existing entries were typed in by hand, so calories_from_diary starts unset
+	`ALTER TABLE nutrition ADD COLUMN calories_from_diary BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN protein INTEGER, ADD COLUMN carbs INTEGER, ADD COLUMN fat INTEGER`,
+	// diary entries keep the name and nutrients of the food when it was logged
+	`CREATE TABLE diary_entries (id BIGSERIAL PRIMARY KEY, date DATE NOT NULL, slot TEXT NOT NULL, food_id BIGINT REFERENCES foods (id) ON DELETE SET NULL, food_name TEXT NOT NULL, grams DOUBLE PRECISION NOT NULL, kcal DOUBLE PRECISION NOT NULL, protein DOUBLE PRECISION NOT NULL, carbs DOUBLE PRECISION NOT NULL, fat DOUBLE PRECISION NOT NULL)`,
+	`CREATE INDEX diary_entries_date ON diary_entries (date)`,
+	// planned meals are linked to recipes by name, so names are unique
+	`CREATE TABLE recipes (id BIGSERIAL PRIMARY KEY, name TEXT NOT NULL, servings INTEGER NOT NULL, kcal DOUBLE PRECISION NOT NULL, protein DOUBLE PRECISION NOT NULL, carbs DOUBLE PRECISION NOT NULL, fat DOUBLE PRECISION NOT NULL, version INTEGER NOT NULL DEFAULT 1)`,
+	`CREATE UNIQUE INDEX recipes_name ON recipes (lower(name))`,
+	`ALTER TABLE diary_entries ADD COLUMN recipe_id BIGINT REFERENCES recipes (id) ON DELETE SET NULL, ADD COLUMN portions DOUBLE PRECISION NOT NULL DEFAULT 0`,
+	`ALTER TABLE recipes ADD COLUMN ingredients TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE recipes ADD COLUMN instructions TEXT NOT NULL DEFAULT '[]', ADD COLUMN prep_minutes INTEGER NOT NULL DEFAULT 0, ADD COLUMN cook_minutes INTEGER NOT NULL DEFAULT 0`,
+	// slots without attendance are served to the whole household
+	`ALTER TABLE meals ADD COLUMN attendance TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE meals ADD COLUMN leftovers TEXT NOT NULL DEFAULT '[]'`,
+}
+
+// Migrate applies all migrations missing in the database.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	version, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for ; version < len(migrations); version++ {
+		slog.InfoContext(ctx, "Applying migration", slog.Int("version", version+1))
+
+		err = applyMigration(ctx, db, version+1, migrations[version])
+		if err != nil {
+			return fmt.Errorf("applying migration %d: %w", version+1, err)
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the number of migrations applied to the database.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// LatestSchemaVersion is the schema version of a fully migrated database.
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// applyMigration runs the migration in a transaction. Inserting the version
+// fails with a unique violation if another instance applied it concurrently.
+func applyMigration(ctx context.Context, db *sql.DB, version int, migration string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, migration)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"meal-planning/database"
+	"meal-planning/domain"
+	"time"
+)
+
+const nutritionColumns = `date, calories, calories_from_diary, protein, carbs, fat, weight, version`
+
+type nutritionEntity struct {
+	date              time.Time
+	calories          sql.NullInt64
+	caloriesFromDiary bool
+	protein           sql.NullInt64
+	carbs             sql.NullInt64
+	fat               sql.NullInt64
+	weight            sql.NullInt64
+	version           int
+}
+
+func (e *nutritionEntity) scan(row interface{ Scan(dest ...any) error }) error {
+	return row.Scan(&e.date, &e.calories, &e.caloriesFromDiary, &e.protein, &e.carbs, &e.fat, &e.weight, &e.version)
+}
+
+func (e nutritionEntity) toDomain() domain.Nutrition {
+	return domain.Nutrition{
+		Date:              toDate(e.date),
+		Calories:          int(e.calories.Int64),
+		CaloriesFromDiary: e.caloriesFromDiary,
+		Protein:           int(e.protein.Int64),
+		Carbs:             int(e.carbs.Int64),
+		Fat:               int(e.fat.Int64),
+		Weight:            int(e.weight.Int64),
+		Version:           e.version,
+	}
+}
+
+type nutritionRepository struct {
+	db *sql.DB
+}
+
+func NewNutritionRepository(db *sql.DB) domain.NutritionRepository {
+	return &nutritionRepository{db}
+}
+
+func (r *nutritionRepository) FindByDate(ctx context.Context, date time.Time) (domain.Nutrition, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := database.Conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+nutritionColumns+` FROM nutrition WHERE date = $1`, formatDate(date))
+
+	entity := nutritionEntity{}
+	err := entity.scan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Nutrition{}, domain.NutritionNotFound
+	} else if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	return entity.toDomain(), nil
+}
+
+func (r *nutritionRepository) FindByDateRange(ctx context.Context, start, end time.Time) ([]domain.Nutrition, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := database.Conn(ctx, r.db).QueryContext(
+		ctx,
+		`SELECT `+nutritionColumns+` FROM nutrition WHERE date BETWEEN $1 AND $2 ORDER BY date`,
+		formatDate(start),
+		formatDate(end),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.Nutrition, 0)
+	for rows.Next() {
+		entity := nutritionEntity{}
+		err = entity.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, entity.toDomain())
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// FindAverageNutrition truncates the averages like the SQLite repository does,
+// a plain cast to INTEGER would round them.
+func (r *nutritionRepository) FindAverageNutrition(ctx context.Context, start, end time.Time) (domain.AverageNutrition, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := database.Conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT TRUNC(AVG(calories))::INTEGER, TRUNC(AVG(weight))::INTEGER FROM nutrition WHERE date BETWEEN $1 AND $2`,
+		formatDate(start),
+		formatDate(end),
+	)
+
+	var calories, weight sql.NullInt64
+	err := row.Scan(&calories, &weight)
+	if err != nil {
+		return domain.AverageNutrition{}, err
+	}
+
+	return domain.AverageNutrition{
+		Calories: int(calories.Int64),
+		Weight:   int(weight.Int64),
+	}, nil
+}
+
+func (r *nutritionRepository) FindLatestWeight(ctx context.Context) (domain.Nutrition, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	row := database.Conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+nutritionColumns+` FROM nutrition WHERE weight IS NOT NULL ORDER BY date DESC LIMIT 1`)
+
+	entity := nutritionEntity{}
+	err := entity.scan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Nutrition{}, domain.NutritionNotFound
+	} else if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	return entity.toDomain(), nil
+}
+
+func (r *nutritionRepository) Create(ctx context.Context, n domain.Nutrition) (domain.Nutrition, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := database.Conn(ctx, r.db).ExecContext(
+		ctx,
+		`INSERT INTO nutrition (date, calories, calories_from_diary, protein, carbs, fat, weight, version) VALUES ($1, $2, $3, $4, $5, $6, $7, 1) ON CONFLICT (date) DO NOTHING`,
+		formatDate(n.Date), nullIfNotPositive(n.Calories), n.CaloriesFromDiary, nullIfNotPositive(n.Protein), nullIfNotPositive(n.Carbs), nullIfNotPositive(n.Fat), nullIfNotPositive(n.Weight),
+	)
+	if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	err = database.ExpectAffectedRow(result, domain.NutritionConflict)
+	if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	n.Version = 1
+	return n, nil
+}
+
+func (r *nutritionRepository) Update(ctx context.Context, n domain.Nutrition) (domain.Nutrition, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := database.Conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE nutrition SET calories = $1, calories_from_diary = $2, protein = $3, carbs = $4, fat = $5, weight = $6, version = version + 1 WHERE date = $7 AND version = $8`,
+		nullIfNotPositive(n.Calories), n.CaloriesFromDiary, nullIfNotPositive(n.Protein), nullIfNotPositive(n.Carbs), nullIfNotPositive(n.Fat), nullIfNotPositive(n.Weight), formatDate(n.Date), n.Version,
+	)
+	if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	err = database.ExpectAffectedRow(result, domain.NutritionConflict)
+	if err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	n.Version++
+	return n, nil
+}
+
+func (r *nutritionRepository) Delete(ctx context.Context, n domain.Nutrition) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := database.Conn(ctx, r.db).ExecContext(ctx, `DELETE FROM nutrition WHERE date = $1`, formatDate(n.Date))
+
+	return err
+}
+
+// nullIfNotPositive stores values that were not recorded as NULL, so they do
+// not count towards averages.
+func nullIfNotPositive(value int) sql.NullInt64 {
+	return sql.NullInt64{
+		Int64: int64(value),
+		Valid: value > 0,
+	}
+}
@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meal-planning/database"
+	"meal-planning/domain"
+	"strings"
+)
+
+const recipeColumns = `id, name, servings, kcal, protein, carbs, fat, ingredients, instructions, prep_minutes, cook_minutes, version`
+
+type recipeRepository struct {
+	db *sql.DB
+}
+
+func NewRecipeRepository(db *sql.DB) domain.RecipeRepository {
+	return &recipeRepository{db}
+}
+
+func (r *recipeRepository) FindByID(ctx context.Context, id int64) (domain.Recipe, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	recipe, err := database.ScanRecipe(r.db.QueryRowContext(ctx, `SELECT `+recipeColumns+` FROM recipes WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Recipe{}, domain.RecipeNotFound
+	} else if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	return recipe, nil
+}
+
+func (r *recipeRepository) FindByNames(ctx context.Context, names []string) ([]domain.Recipe, error) {
+	if len(names) == 0 {
+		return []domain.Recipe{}, nil
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	placeholders := make([]string, len(names))
+	args := make([]any, len(names))
+	for i, name := range names {
+		placeholders[i] = fmt.Sprintf("lower($%d)", i+1)
+		args[i] = name
+	}
+
+	return r.query(ctx, `SELECT `+recipeColumns+` FROM recipes WHERE lower(name) IN (`+strings.Join(placeholders, ", ")+`) ORDER BY lower(name)`, args...)
+}
+
+func (r *recipeRepository) Search(ctx context.Context, query string, limit int) ([]domain.Recipe, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	terms := strings.Fields(query)
+
+	conditions := make([]string, 0, len(terms))
+	args := make([]any, 0, len(terms)+1)
+	for _, term := range terms {
+		args = append(args, "%"+database.EscapeLike(term)+"%")
+		conditions = append(conditions, fmt.Sprintf(`name ILIKE $%d`, len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = `WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	args = append(args, limit)
+
+	return r.query(ctx, fmt.Sprintf(`SELECT `+recipeColumns+` FROM recipes %s ORDER BY lower(name), id LIMIT $%d`, where, len(args)), args...)
+}
+
+func (r *recipeRepository) query(ctx context.Context, query string, args ...any) ([]domain.Recipe, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.Recipe, 0)
+	for rows.Next() {
+		recipe, err := database.ScanRecipe(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, recipe)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *recipeRepository) Create(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	values, err := database.RecipeValues(recipe)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	err = r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO recipes (name, servings, kcal, protein, carbs, fat, ingredients, instructions, prep_minutes, cook_minutes, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 1) RETURNING id`,
+		values...,
+	).Scan(&recipe.ID)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	recipe.Version = 1
+	return recipe, nil
+}
+
+func (r *recipeRepository) Update(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	values, err := database.RecipeValues(recipe)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE recipes SET name = $1, servings = $2, kcal = $3, protein = $4, carbs = $5, fat = $6, ingredients = $7, instructions = $8, prep_minutes = $9, cook_minutes = $10, version = version + 1 WHERE id = $11 AND version = $12`,
+		append(values, recipe.ID, recipe.Version)...,
+	)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	err = database.ExpectAffectedRow(result, domain.RecipeConflict)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	recipe.Version++
+	return recipe, nil
+}
+
+func (r *recipeRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM recipes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return database.ExpectAffectedRow(result, domain.RecipeNotFound)
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"meal-planning/domain"
+	"strings"
+	"time"
+)
+
+const recipeColumns = `id, name, servings, kcal, protein, carbs, fat, ingredients, instructions, prep_minutes, cook_minutes, version`
+
+// ScanRecipe reads a row of recipeColumns.
+func ScanRecipe(row interface{ Scan(dest ...any) error }) (domain.Recipe, error) {
+	recipe := domain.Recipe{}
+	var ingredients, instructions string
+	var prepMinutes, cookMinutes int
+	err := row.Scan(&recipe.ID, &recipe.Name, &recipe.Servings, &recipe.PerServing.Kcal, &recipe.PerServing.Protein, &recipe.PerServing.Carbs, &recipe.PerServing.Fat, &ingredients, &instructions, &prepMinutes, &cookMinutes, &recipe.Version)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	recipe.PrepTime = time.Duration(prepMinutes) * time.Minute
+	recipe.CookTime = time.Duration(cookMinutes) * time.Minute
+
+	recipe.Ingredients, err = UnmarshalIngredients(ingredients)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	recipe.Instructions, err = UnmarshalInstructions(instructions)
+	return recipe, err
+}
+
+// RecipeValues returns the columns of recipeColumns the recipe is stored in,
+// from name to cook_minutes. Times are stored to the minute.
+func RecipeValues(recipe domain.Recipe) ([]any, error) {
+	ingredients, err := MarshalIngredients(recipe.Ingredients)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions, err := MarshalInstructions(recipe.Instructions)
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{
+		recipe.Name, recipe.Servings, recipe.PerServing.Kcal, recipe.PerServing.Protein, recipe.PerServing.Carbs, recipe.PerServing.Fat,
+		ingredients, instructions, int(recipe.PrepTime.Minutes()), int(recipe.CookTime.Minutes()),
+	}, nil
+}
+
+// MarshalIngredients encodes ingredients for the ingredients column.
+func MarshalIngredients(ingredients []domain.Ingredient) (string, error) {
+	if ingredients == nil {
+		ingredients = []domain.Ingredient{}
+	}
+
+	encoded, err := json.Marshal(ingredients)
+	return string(encoded), err
+}
+
+func UnmarshalIngredients(encoded string) ([]domain.Ingredient, error) {
+	var ingredients []domain.Ingredient
+	err := json.Unmarshal([]byte(encoded), &ingredients)
+	return ingredients, err
+}
+
+// MarshalInstructions encodes the steps of a recipe for the instructions
+// column.
+func MarshalInstructions(instructions []string) (string, error) {
+	if instructions == nil {
+		instructions = []string{}
+	}
+
+	encoded, err := json.Marshal(instructions)
+	return string(encoded), err
+}
+
+func UnmarshalInstructions(encoded string) ([]string, error) {
+	var instructions []string
+	err := json.Unmarshal([]byte(encoded), &instructions)
+	return instructions, err
+}
+
+type sqlRecipeRepository struct {
+	db *sql.DB
+}
+
+func NewSqlRecipeRepository(db *sql.DB) domain.RecipeRepository {
+	return &sqlRecipeRepository{db}
+}
+
+func (s *sqlRecipeRepository) FindByID(ctx context.Context, id int64) (domain.Recipe, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	recipe, err := ScanRecipe(s.db.QueryRowContext(ctx, `SELECT `+recipeColumns+` FROM recipes WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Recipe{}, domain.RecipeNotFound
+	} else if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	return recipe, nil
+}
+
+func (s *sqlRecipeRepository) FindByNames(ctx context.Context, names []string) ([]domain.Recipe, error) {
+	if len(names) == 0 {
+		return []domain.Recipe{}, nil
+	}
+
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	args := make([]any, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	return s.query(ctx, `SELECT `+recipeColumns+` FROM recipes WHERE name COLLATE NOCASE IN (`+placeholders+`) ORDER BY name COLLATE NOCASE`, args...)
+}
+
+func (s *sqlRecipeRepository) Search(ctx context.Context, query string, limit int) ([]domain.Recipe, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	terms := strings.Fields(query)
+
+	conditions := make([]string, 0, len(terms))
+	args := make([]any, 0, len(terms)+1)
+	for _, term := range terms {
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+EscapeLike(term)+"%")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = `WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	args = append(args, limit)
+
+	return s.query(ctx, `SELECT `+recipeColumns+` FROM recipes `+where+` ORDER BY name COLLATE NOCASE, id LIMIT ?`, args...)
+}
+
+func (s *sqlRecipeRepository) query(ctx context.Context, query string, args ...any) ([]domain.Recipe, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]domain.Recipe, 0)
+	for rows.Next() {
+		recipe, err := ScanRecipe(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, recipe)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *sqlRecipeRepository) Create(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	values, err := RecipeValues(recipe)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO recipes (name, servings, kcal, protein, carbs, fat, ingredients, instructions, prep_minutes, cook_minutes, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)`,
+		values...,
+	)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	recipe.ID, err = result.LastInsertId()
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	recipe.Version = 1
+	return recipe, nil
+}
+
+func (s *sqlRecipeRepository) Update(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	values, err := RecipeValues(recipe)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE recipes SET name = ?, servings = ?, kcal = ?, protein = ?, carbs = ?, fat = ?, ingredients = ?, instructions = ?, prep_minutes = ?, cook_minutes = ?, version = version + 1 WHERE id = ? AND version = ?`,
+		append(values, recipe.ID, recipe.Version)...,
+	)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	err = ExpectAffectedRow(result, domain.RecipeConflict)
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+
+	recipe.Version++
+	return recipe, nil
+}
+
+func (s *sqlRecipeRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM recipes WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return ExpectAffectedRow(result, domain.RecipeNotFound)
+}
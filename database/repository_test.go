@@ -0,0 +1,43 @@
+package database
+
+import (
+	"meal-planning/domain"
+	"meal-planning/domain/domaintest"
+	"testing"
+)
+
+func TestSqlMealDayRepository(t *testing.T) {
+	domaintest.MealDayRepositoryContract(t, func(t *testing.T) domain.MealDayRepository {
+		return NewSqlMealDayRepository(newTestDatabase(t))
+	})
+}
+
+func TestSqlNutritionRepository(t *testing.T) {
+	domaintest.NutritionRepositoryContract(t, func(t *testing.T) domain.NutritionRepository {
+		return NewSqlNutritionRepository(newTestDatabase(t))
+	})
+}
+
+func TestSqlHistoryRepository(t *testing.T) {
+	domaintest.HistoryRepositoryContract(t, func(t *testing.T) domain.HistoryRepository {
+		return NewSqlHistoryRepository(newTestDatabase(t))
+	})
+}
+
+func TestSqlFoodRepository(t *testing.T) {
+	domaintest.FoodRepositoryContract(t, func(t *testing.T) domain.FoodRepository {
+		return NewSqlFoodRepository(newTestDatabase(t))
+	})
+}
+
+func TestSqlDiaryRepository(t *testing.T) {
+	domaintest.DiaryRepositoryContract(t, func(t *testing.T) domain.DiaryRepository {
+		return NewSqlDiaryRepository(newTestDatabase(t))
+	})
+}
+
+func TestSqlRecipeRepository(t *testing.T) {
+	domaintest.RecipeRepositoryContract(t, func(t *testing.T) domain.RecipeRepository {
+		return NewSqlRecipeRepository(newTestDatabase(t))
+	})
+}
@@ -0,0 +1,18 @@
+package database
+
+import "database/sql"
+
+// ExpectAffectedRow returns notAffected if the statement did not change any
+// row, e.g. because a version check in its WHERE clause did not match.
+func ExpectAffectedRow(result sql.Result, notAffected error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notAffected
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !purego
+
+package database
+
+import (
+	"net/url"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteDriver = "sqlite3"
+
+// sqliteDSN adds the connection pragmas in the syntax of mattn/go-sqlite3.
+func sqliteDSN(path string) string {
+	query := url.Values{}
+	query.Set("_journal_mode", "WAL")
+	query.Set("_busy_timeout", busyTimeout)
+	query.Set("_foreign_keys", "on")
+	query.Set("_txlock", "immediate")
+
+	return "file:" + path + "?" + query.Encode()
+}
@@ -0,0 +1,22 @@
+//go:build purego
+
+package database
+
+import (
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteDriver = "sqlite"
+
+// sqliteDSN adds the connection pragmas in the syntax of modernc.org/sqlite.
+func sqliteDSN(path string) string {
+	query := url.Values{}
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout("+busyTimeout+")")
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Set("_txlock", "immediate")
+
+	return "file:" + path + "?" + query.Encode()
+}
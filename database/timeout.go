@@ -0,0 +1,16 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// queryTimeout bounds every query on top of the deadline of the request, so a
+// stuck query cannot hold on to the connection indefinitely.
+const queryTimeout = 5 * time.Second
+
+// WithQueryTimeout derives the context a single repository method runs its
+// queries with.
+func WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, queryTimeout)
+}
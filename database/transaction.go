@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"meal-planning/domain"
+)
+
+// Querier runs queries either directly on the database or in a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// Conn returns the transaction ctx runs in, or db outside of transactions.
+// Repositories taking part in transactions query through it.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+type transactor struct {
+	db *sql.DB
+}
+
+func NewTransactor(db *sql.DB) domain.Transactor {
+	return &transactor{db}
+}
+
+// WithTx runs fn in a new transaction. Calls already running in one join it,
+// it is then committed or rolled back by the outermost call.
+func (t *transactor) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
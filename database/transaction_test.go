@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"meal-planning/domain"
+	"testing"
+	"time"
+)
+
+// failingHistoryRepository appends the entry in the transaction of ctx, then
+// fails as if the append was rejected.
+type failingHistoryRepository struct {
+	domain.HistoryRepository
+}
+
+func (r failingHistoryRepository) Append(ctx context.Context, entry domain.HistoryEntry) (domain.HistoryEntry, error) {
+	_, err := r.HistoryRepository.Append(ctx, entry)
+	if err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	return domain.HistoryEntry{}, errors.New("history is read-only")
+}
+
+func TestChangesAreRolledBackWithoutHistory(t *testing.T) {
+	db := newTestDatabase(t)
+	history := NewSqlHistoryRepository(db)
+	failingHistory := failingHistoryRepository{history}
+	ctx := context.Background()
+	date := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	mealDays := domain.NewMealDayService(NewSqlMealDayRepository(db), history, NewTransactor(db), domain.NewEventBus(), domain.Household{People: 1})
+	created, err := mealDays.Upsert(ctx, domain.MealDay{Date: date, Dinner: "Pasta"})
+	if err != nil {
+		t.Fatalf("creating meal day: %v", err)
+	}
+
+	mealDays = domain.NewMealDayService(NewSqlMealDayRepository(db), failingHistory, NewTransactor(db), domain.NewEventBus(), domain.Household{People: 1})
+	created.Dinner = "Pizza"
+	_, err = mealDays.Upsert(ctx, created)
+	if err == nil {
+		t.Fatalf("expected the failing history to fail the update")
+	}
+
+	_, err = mealDays.Upsert(ctx, domain.MealDay{Date: date.AddDate(0, 0, 1), Dinner: "Soup"})
+	if err == nil {
+		t.Fatalf("expected the failing history to fail the create")
+	}
+
+	err = mealDays.Delete(ctx, date)
+	if err == nil {
+		t.Fatalf("expected the failing history to fail the delete")
+	}
+
+	nutrition := domain.NewNutritionService(NewSqlNutritionRepository(db), NewSqlDiaryRepository(db), failingHistory, NewTransactor(db), domain.NewEventBus())
+	_, err = nutrition.Upsert(ctx, domain.Nutrition{Date: date, Calories: 2000})
+	if err == nil {
+		t.Fatalf("expected the failing history to fail the nutrition create")
+	}
+
+	stored, err := mealDays.FindByDateRange(ctx, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("finding meal days: %v", err)
+	}
+
+	if stored[0].Dinner != "Pasta" || stored[0].Version != 1 || stored[1].IsPlanned() {
+		t.Errorf("expected only the first meal day to be stored, got %+v", stored)
+	}
+
+	_, err = NewSqlNutritionRepository(db).FindByDate(ctx, date)
+	if !errors.Is(err, domain.NutritionNotFound) {
+		t.Errorf("expected no nutrition to be stored, got %v", err)
+	}
+
+	entries, err := history.FindByEntityAndDate(ctx, domain.HistoryEntityMealDay, date)
+	if err != nil {
+		t.Fatalf("finding history: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Action != domain.HistoryActionCreate {
+		t.Errorf("expected only the create to be recorded, got %+v", entries)
+	}
+}
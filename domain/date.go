@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// calendarDay returns midnight UTC of the calendar day t falls on in its own
+// location. Dates read from repositories use the same representation.
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the number of calendar days from start to end, both
+// inclusive.
+func daysBetween(start, end time.Time) int {
+	days := int(calendarDay(end).Sub(calendarDay(start)).Hours()/24) + 1
+	if days < 0 {
+		return 0
+	}
+
+	return days
+}
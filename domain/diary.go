@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxDiaryEntryGrams is the largest amount of a single food that can be
+// logged at once.
+const MaxDiaryEntryGrams = 5000
+
+var DiaryEntryNotFound = NewError(ErrorKindNotFound, "diary entry: not found")
+
+// MealSlot is the meal of the day food was eaten at.
+type MealSlot string
+
+const (
+	MealSlotBreakfast MealSlot = "breakfast"
+	MealSlotLunch     MealSlot = "lunch"
+	MealSlotDinner    MealSlot = "dinner"
+	MealSlotSnack     MealSlot = "snack"
+)
+
+// MealSlots lists the slots in the order of the day.
+var MealSlots = []MealSlot{MealSlotBreakfast, MealSlotLunch, MealSlotDinner, MealSlotSnack}
+
+func (slot MealSlot) Valid() bool {
+	return slices.Contains(MealSlots, slot)
+}
+
+// DiaryEntry is an amount of food eaten on a day.
+type DiaryEntry struct {
+	ID   int64
+	Date time.Time
+	Slot MealSlot
+	// FoodID links the food of the catalog the entry was logged from. It is 0
+	// once that food was deleted.
+	FoodID int64
+	// RecipeID links the recipe of a planned meal logged as eaten. It is 0 for
+	// foods and once the recipe was deleted.
+	RecipeID int64
+	FoodName string
+	// Grams is the amount of food eaten, Portions the servings of a recipe.
+	Grams    float64
+	Portions float64
+	// Nutrients are those of the eaten amount. They are computed when the
+	// entry is logged, so editing the food later does not rewrite the past.
+	Nutrients Nutrients
+}
+
+func newDiaryEntry(date time.Time, slot MealSlot, food Food, grams float64) DiaryEntry {
+	return DiaryEntry{
+		Date:      calendarDay(date),
+		Slot:      slot,
+		FoodID:    food.ID,
+		FoodName:  food.Name,
+		Grams:     grams,
+		Nutrients: food.NutrientsFor(grams),
+	}
+}
+
+func newRecipeDiaryEntry(date time.Time, slot MealSlot, recipe Recipe, portions float64) DiaryEntry {
+	return DiaryEntry{
+		Date:      calendarDay(date),
+		Slot:      slot,
+		RecipeID:  recipe.ID,
+		FoodName:  recipe.Name,
+		Portions:  portions,
+		Nutrients: recipe.PerServing.Scale(portions),
+	}
+}
+
+// Validate rejects entries without food and amounts nobody eats in one go.
+func (entry DiaryEntry) Validate() error {
+	v := validator{}
+
+	v.check(entry.Slot.Valid(), "slot", "must be breakfast, lunch, dinner or snack")
+	v.check(strings.TrimSpace(entry.FoodName) != "", "food", "must be chosen")
+	if entry.RecipeID != 0 {
+		v.check(entry.Portions > 0 && entry.Portions <= MaxPortions, "portions", fmt.Sprintf("must be between 0 and %d", MaxPortions))
+	} else {
+		v.check(entry.Grams > 0 && entry.Grams <= MaxDiaryEntryGrams, "grams", fmt.Sprintf("must be between 0 and %d g", MaxDiaryEntryGrams))
+	}
+
+	return v.err()
+}
+
+type DiaryRepository interface {
+	// FindByDate returns the entries of the day in the order they were logged.
+	FindByDate(ctx context.Context, date time.Time) ([]DiaryEntry, error)
+	Create(ctx context.Context, entry DiaryEntry) (DiaryEntry, error)
+	// Delete removes the entry with the id from the day. It returns
+	// DiaryEntryNotFound if the day has no such entry.
+	Delete(ctx context.Context, date time.Time, id int64) error
+}
+
+// SumNutrients adds up the nutrients of the entries.
+func SumNutrients(entries []DiaryEntry) Nutrients {
+	total := Nutrients{}
+	for _, entry := range entries {
+		total = total.Add(entry.Nutrients)
+	}
+
+	return total
+}
+
+// applyDiary sets the macronutrients to the sum of the diary entries. The
+// calories are derived as well, unless they were entered by hand: a total
+// typed in for a day always wins over an incomplete diary.
+func (nutrition Nutrition) applyDiary(entries []DiaryEntry) Nutrition {
+	total := SumNutrients(entries)
+
+	nutrition.Protein = int(math.Round(total.Protein))
+	nutrition.Carbs = int(math.Round(total.Carbs))
+	nutrition.Fat = int(math.Round(total.Fat))
+
+	if nutrition.CaloriesFromDiary || nutrition.Calories == 0 {
+		nutrition.Calories = int(math.Round(total.Kcal))
+		nutrition.CaloriesFromDiary = len(entries) > 0
+	}
+
+	return nutrition
+}
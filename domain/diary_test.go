@@ -0,0 +1,142 @@
+package domain_test
+
+import (
+	"context"
+	"meal-planning/domain"
+	"meal-planning/memory"
+	"testing"
+	"time"
+)
+
+var oats = domain.Food{ID: 1, Name: "Rolled oats", Per100g: domain.Nutrients{Kcal: 372, Protein: 13.5, Carbs: 58.7, Fat: 7}}
+
+func TestNutritionServiceLogFoodDerivesTotals(t *testing.T) {
+	ctx := context.Background()
+	repository := memory.NewNutritionRepository()
+	service := domain.NewNutritionService(repository, memory.NewDiaryRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus())
+	day := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.Upsert(ctx, domain.Nutrition{Date: day, Weight: 80000})
+	if err != nil {
+		t.Fatalf("storing weight: %v", err)
+	}
+
+	_, err = service.LogFood(ctx, day, domain.MealSlotBreakfast, oats, 80)
+	if err != nil {
+		t.Fatalf("logging breakfast: %v", err)
+	}
+
+	nutrition, err := service.LogFood(ctx, day.Add(18*time.Hour), domain.MealSlotSnack, oats, 20)
+	if err != nil {
+		t.Fatalf("logging snack: %v", err)
+	}
+
+	expected := domain.Nutrition{Date: day, Calories: 372, CaloriesFromDiary: true, Protein: 14, Carbs: 59, Fat: 7, Weight: 80000, Version: 3}
+	if nutrition != expected {
+		t.Errorf("expected %+v, got %+v", expected, nutrition)
+	}
+
+	entries, err := service.FindDiary(ctx, day)
+	if err != nil {
+		t.Fatalf("finding diary: %v", err)
+	}
+
+	nutrition, err = service.RemoveDiaryEntry(ctx, day, entries[0].ID)
+	if err != nil {
+		t.Fatalf("removing breakfast: %v", err)
+	}
+
+	if nutrition.Calories != 74 || nutrition.Protein != 3 || nutrition.Weight != 80000 {
+		t.Errorf("expected the snack only, got %+v", nutrition)
+	}
+
+	nutrition, err = service.RemoveDiaryEntry(ctx, day, entries[1].ID)
+	if err != nil {
+		t.Fatalf("removing snack: %v", err)
+	}
+
+	if nutrition.Calories != 0 || nutrition.CaloriesFromDiary {
+		t.Errorf("expected no calories after emptying the diary, got %+v", nutrition)
+	}
+}
+
+func TestNutritionServiceManualCaloriesOverrideDiary(t *testing.T) {
+	ctx := context.Background()
+	service := domain.NewNutritionService(memory.NewNutritionRepository(), memory.NewDiaryRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus())
+	day := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+
+	logged, err := service.LogFood(ctx, day, domain.MealSlotBreakfast, oats, 100)
+	if err != nil {
+		t.Fatalf("logging food: %v", err)
+	}
+
+	overridden, err := service.Upsert(ctx, domain.Nutrition{Date: day, Calories: 2500, Version: logged.Version})
+	if err != nil {
+		t.Fatalf("overriding calories: %v", err)
+	}
+
+	if overridden.Calories != 2500 || overridden.CaloriesFromDiary || overridden.Protein != 14 {
+		t.Errorf("expected the typed calories with diary macros, got %+v", overridden)
+	}
+
+	logged, err = service.LogFood(ctx, day, domain.MealSlotLunch, oats, 100)
+	if err != nil {
+		t.Fatalf("logging food: %v", err)
+	}
+
+	if logged.Calories != 2500 || logged.Protein != 27 {
+		t.Errorf("expected the override to survive new entries, got %+v", logged)
+	}
+
+	cleared, err := service.Upsert(ctx, domain.Nutrition{Date: day, Version: logged.Version})
+	if err != nil {
+		t.Fatalf("clearing calories: %v", err)
+	}
+
+	if cleared.Calories != 744 || !cleared.CaloriesFromDiary {
+		t.Errorf("expected the diary calories after clearing the override, got %+v", cleared)
+	}
+}
+
+func TestNutritionServiceLogFoodRejectsInvalidEntries(t *testing.T) {
+	service := domain.NewNutritionService(memory.NewNutritionRepository(), memory.NewDiaryRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus())
+	day := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.LogFood(context.Background(), day, "brunch", oats, 0)
+	if domain.KindOf(err) != domain.ErrorKindValidation {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	_, err = service.RemoveDiaryEntry(context.Background(), day, 42)
+	if domain.KindOf(err) != domain.ErrorKindNotFound {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestNutritionServiceCalculateTotalDailyEnergyExpenditureFromDiary(t *testing.T) {
+	ctx := context.Background()
+	service := domain.NewNutritionService(memory.NewNutritionRepository(), memory.NewDiaryRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus())
+	monday := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+
+	// 500 g of oats are 1860 kcal, one day is typed in by hand
+	for day := range 2 {
+		_, err := service.LogFood(ctx, monday.AddDate(0, 0, day), domain.MealSlotLunch, oats, 500)
+		if err != nil {
+			t.Fatalf("logging food: %v", err)
+		}
+	}
+
+	_, err := service.Upsert(ctx, domain.Nutrition{Date: monday.AddDate(0, 0, 2), Calories: 2340})
+	if err != nil {
+		t.Fatalf("storing calories: %v", err)
+	}
+
+	tdee, err := service.CalculateTotalDailyEnergyExpenditure(ctx, monday, monday.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("calculating: %v", err)
+	}
+
+	if tdee.AverageCalories != 2020 || tdee.TotalDailyEnergyExpenditure != 2020 {
+		t.Errorf("expected the average of diary and typed calories, got %+v", tdee)
+	}
+}
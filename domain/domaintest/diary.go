@@ -0,0 +1,114 @@
+package domaintest
+
+import (
+	"context"
+	"meal-planning/domain"
+	"testing"
+	"time"
+)
+
+// DiaryRepositoryContract runs the behaviour every domain.DiaryRepository must
+// have against repositories created by newRepository. Each subtest gets a new,
+// empty repository.
+func DiaryRepositoryContract(t *testing.T, newRepository func(t *testing.T) domain.DiaryRepository) {
+	ctx := context.Background()
+
+	porridge := domain.DiaryEntry{
+		Date:      date(2024, time.June, 3),
+		Slot:      domain.MealSlotBreakfast,
+		FoodName:  "Rolled oats",
+		Grams:     80,
+		Nutrients: domain.Nutrients{Kcal: 297.6, Protein: 10.8, Carbs: 46.96, Fat: 5.6},
+	}
+
+	t.Run("find by date is empty without entries", func(t *testing.T) {
+		repository := newRepository(t)
+
+		entries, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if len(entries) != 0 {
+			t.Errorf("expected no entries, got %+v", entries)
+		}
+	})
+
+	t.Run("create assigns an id and find by date returns the entries in order", func(t *testing.T) {
+		repository := newRepository(t)
+
+		first, err := repository.Create(ctx, porridge)
+		expectNoError(t, err)
+
+		snack := porridge
+		snack.Slot = domain.MealSlotSnack
+		snack.FoodName = "Banana bread"
+		snack.Grams = 0
+		snack.Portions = 1.5
+		snack.Nutrients = domain.Nutrients{Kcal: 480, Protein: 9, Carbs: 72, Fat: 16.5}
+		second, err := repository.Create(ctx, snack)
+		expectNoError(t, err)
+
+		nextDay := porridge
+		nextDay.Date = date(2024, time.June, 4)
+		_, err = repository.Create(ctx, nextDay)
+		expectNoError(t, err)
+
+		if first.ID == 0 || second.ID <= first.ID {
+			t.Fatalf("expected increasing ids, got %d and %d", first.ID, second.ID)
+		}
+
+		entries, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %+v", entries)
+		}
+
+		expectDiaryEntry(t, entries[0], first)
+		expectDiaryEntry(t, entries[1], second)
+	})
+
+	t.Run("delete removes the entry of the day", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, porridge)
+		expectNoError(t, err)
+
+		err = repository.Delete(ctx, date(2024, time.June, 4), created.ID)
+		expectError(t, err, domain.DiaryEntryNotFound)
+
+		err = repository.Delete(ctx, date(2024, time.June, 3), created.ID)
+		expectNoError(t, err)
+
+		err = repository.Delete(ctx, date(2024, time.June, 3), created.ID)
+		expectError(t, err, domain.DiaryEntryNotFound)
+
+		entries, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if len(entries) != 0 {
+			t.Errorf("expected no entries, got %+v", entries)
+		}
+	})
+
+	t.Run("stops on cancelled context", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Create(cancelledContext(), porridge)
+		expectError(t, err, context.Canceled)
+
+		entries, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if len(entries) != 0 {
+			t.Errorf("expected no entries, got %+v", entries)
+		}
+	})
+}
+
+func expectDiaryEntry(t *testing.T, actual, expected domain.DiaryEntry) {
+	t.Helper()
+
+	if actual.ID != expected.ID || !actual.Date.Equal(expected.Date) || actual.Slot != expected.Slot || actual.FoodID != expected.FoodID || actual.RecipeID != expected.RecipeID || actual.FoodName != expected.FoodName || actual.Grams != expected.Grams || actual.Portions != expected.Portions || actual.Nutrients != expected.Nutrients {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
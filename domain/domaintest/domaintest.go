@@ -0,0 +1,53 @@
+// Package domaintest provides contract tests that every implementation of the
+// domain repositories has to pass.
+package domaintest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// date returns midnight UTC of the given day, the representation repositories
+// return dates in.
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func expectError(t *testing.T, err, expected error) {
+	t.Helper()
+
+	if !errors.Is(err, expected) {
+		t.Fatalf("expected error %v, got %v", expected, err)
+	}
+}
+
+func expectNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func expectDates(t *testing.T, actual []time.Time, expected ...time.Time) {
+	t.Helper()
+
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d dates %v, got %d %v", len(expected), expected, len(actual), actual)
+	}
+
+	for i := range expected {
+		if !actual[i].Equal(expected[i]) {
+			t.Errorf("expected date %d to be %s, got %s", i, expected[i].Format("2006-01-02"), actual[i].Format("2006-01-02"))
+		}
+	}
+}
+
+func cancelledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	return ctx
+}
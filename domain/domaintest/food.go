@@ -0,0 +1,241 @@
+package domaintest
+
+import (
+	"context"
+	"meal-planning/domain"
+	"testing"
+)
+
+// FoodRepositoryContract runs the behaviour every domain.FoodRepository must
+// have against repositories created by newRepository. Each subtest gets a new,
+// empty repository.
+func FoodRepositoryContract(t *testing.T, newRepository func(t *testing.T) domain.FoodRepository) {
+	ctx := context.Background()
+
+	oats := domain.Food{
+		Name:         "Rolled oats",
+		Brand:        "Kölln",
+		Per100g:      domain.Nutrients{Kcal: 372, Protein: 13.5, Carbs: 58.7, Fat: 7},
+		ServingSizes: []domain.ServingSize{{Name: "1 cup", Grams: 80}, {Name: "1 tbsp", Grams: 10}},
+		Source:       domain.FoodSourceManual,
+	}
+
+	t.Run("find by id returns not found for unknown foods", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.FindByID(ctx, 42)
+		expectError(t, err, domain.FoodNotFound)
+	})
+
+	t.Run("create assigns an id and stores the food with version 1", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, oats)
+		expectNoError(t, err)
+
+		if created.ID == 0 || created.Version != 1 {
+			t.Errorf("expected an id and version 1, got %d and %d", created.ID, created.Version)
+		}
+
+		found, err := repository.FindByID(ctx, created.ID)
+		expectNoError(t, err)
+
+		expectFood(t, found, created)
+	})
+
+	t.Run("update increments the version and keeps the source", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.Food{Name: "Milk", Per100g: domain.Nutrients{Kcal: 64}, Source: domain.FoodSourceOpenFoodFacts, SourceID: "4000000000000"})
+		expectNoError(t, err)
+
+		edited := created
+		edited.Name = "Whole milk"
+		edited.Per100g.Fat = 3.5
+		edited.ServingSizes = []domain.ServingSize{{Name: "1 glass", Grams: 200}}
+		edited.Source = ""
+		edited.SourceID = ""
+
+		updated, err := repository.Update(ctx, edited)
+		expectNoError(t, err)
+
+		if updated.Version != 2 || updated.Source != domain.FoodSourceOpenFoodFacts || updated.SourceID != "4000000000000" {
+			t.Errorf("expected version 2 from openfoodfacts, got %+v", updated)
+		}
+
+		found, err := repository.FindByID(ctx, created.ID)
+		expectNoError(t, err)
+
+		expectFood(t, found, updated)
+	})
+
+	t.Run("update with a stale version conflicts", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, oats)
+		expectNoError(t, err)
+
+		_, err = repository.Update(ctx, created)
+		expectNoError(t, err)
+
+		_, err = repository.Update(ctx, created)
+		expectError(t, err, domain.FoodConflict)
+	})
+
+	t.Run("update of an unknown food conflicts", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Update(ctx, domain.Food{ID: 42, Name: "Milk", Version: 1})
+		expectError(t, err, domain.FoodConflict)
+	})
+
+	t.Run("delete removes the food", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, oats)
+		expectNoError(t, err)
+
+		err = repository.Delete(ctx, created.ID)
+		expectNoError(t, err)
+
+		_, err = repository.FindByID(ctx, created.ID)
+		expectError(t, err, domain.FoodNotFound)
+
+		err = repository.Delete(ctx, created.ID)
+		expectError(t, err, domain.FoodNotFound)
+	})
+
+	t.Run("search matches every word in name or brand ignoring case", func(t *testing.T) {
+		repository := newRepository(t)
+
+		for _, food := range []domain.Food{
+			{Name: "Oat drink", Brand: "Oatly"},
+			{Name: "Rolled oats", Brand: "Kölln"},
+			{Name: "Whole milk", Brand: "Weihenstephan"},
+			{Name: "Oat milk", Brand: "Alpro"},
+			{Name: "100% rye bread"},
+		} {
+			_, err := repository.Create(ctx, food)
+			expectNoError(t, err)
+		}
+
+		tests := []struct {
+			query    string
+			expected []string
+		}{
+			{"oat", []string{"Oat drink", "Oat milk", "Rolled oats"}},
+			{"OAT milk", []string{"Oat milk"}},
+			{"milk", []string{"Oat milk", "Whole milk"}},
+			{"milk oat", []string{"Oat milk"}},
+			{"alpro", []string{"Oat milk"}},
+			{"100%", []string{"100% rye bread"}},
+			{"_", nil},
+			{"", []string{"100% rye bread", "Oat drink", "Oat milk", "Rolled oats", "Whole milk"}},
+		}
+
+		for _, test := range tests {
+			found, err := repository.Search(ctx, test.query, 10)
+			expectNoError(t, err)
+
+			expectFoodNames(t, test.query, found, test.expected)
+		}
+	})
+
+	t.Run("search returns at most limit foods", func(t *testing.T) {
+		repository := newRepository(t)
+
+		for _, name := range []string{"Apple", "Apricot", "Avocado"} {
+			_, err := repository.Create(ctx, domain.Food{Name: name})
+			expectNoError(t, err)
+		}
+
+		found, err := repository.Search(ctx, "a", 2)
+		expectNoError(t, err)
+
+		expectFoodNames(t, "a", found, []string{"Apple", "Apricot"})
+	})
+
+	t.Run("import creates foods and updates them by source id", func(t *testing.T) {
+		repository := newRepository(t)
+
+		err := repository.Import(ctx, []domain.Food{
+			{Name: "Oat drink", Per100g: domain.Nutrients{Kcal: 46}, Source: domain.FoodSourceOpenFoodFacts, SourceID: "7394376616037"},
+			{Name: "Oats, raw", Per100g: domain.Nutrients{Kcal: 389}, Source: domain.FoodSourceUSDA, SourceID: "173904"},
+		})
+		expectNoError(t, err)
+
+		err = repository.Import(ctx, []domain.Food{
+			{Name: "Oat drink barista", Per100g: domain.Nutrients{Kcal: 59}, ServingSizes: []domain.ServingSize{{Name: "1 glass", Grams: 250}}, Source: domain.FoodSourceOpenFoodFacts, SourceID: "7394376616037"},
+			{Name: "Oat bran", Per100g: domain.Nutrients{Kcal: 246}, Source: domain.FoodSourceOpenFoodFacts, SourceID: "173904"},
+		})
+		expectNoError(t, err)
+
+		found, err := repository.Search(ctx, "oat", 10)
+		expectNoError(t, err)
+
+		expectFoodNames(t, "oat", found, []string{"Oat bran", "Oat drink barista", "Oats, raw"})
+
+		drink := found[1]
+		if drink.Per100g.Kcal != 59 || drink.Version != 2 || len(drink.ServingSizes) != 1 {
+			t.Errorf("expected the imported food to be updated, got %+v", drink)
+		}
+	})
+
+	t.Run("methods stop on a cancelled context", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Search(cancelledContext(), "oat", 10)
+		expectError(t, err, context.Canceled)
+
+		_, err = repository.Create(cancelledContext(), oats)
+		expectError(t, err, context.Canceled)
+
+		err = repository.Import(cancelledContext(), []domain.Food{{Name: "Oat drink", Source: domain.FoodSourceOpenFoodFacts, SourceID: "7394376616037"}})
+		expectError(t, err, context.Canceled)
+
+		found, err := repository.Search(ctx, "", 10)
+		expectNoError(t, err)
+
+		expectFoodNames(t, "", found, nil)
+	})
+}
+
+func expectFood(t *testing.T, actual, expected domain.Food) {
+	t.Helper()
+
+	if actual.ID != expected.ID || actual.Name != expected.Name || actual.Brand != expected.Brand || actual.Per100g != expected.Per100g ||
+		actual.Source != expected.Source || actual.SourceID != expected.SourceID || actual.Version != expected.Version {
+		t.Errorf("expected food %+v, got %+v", expected, actual)
+	}
+
+	if len(actual.ServingSizes) != len(expected.ServingSizes) {
+		t.Fatalf("expected serving sizes %v, got %v", expected.ServingSizes, actual.ServingSizes)
+	}
+
+	for i := range expected.ServingSizes {
+		if actual.ServingSizes[i] != expected.ServingSizes[i] {
+			t.Errorf("expected serving sizes %v, got %v", expected.ServingSizes, actual.ServingSizes)
+		}
+	}
+}
+
+func expectFoodNames(t *testing.T, query string, actual []domain.Food, expected []string) {
+	t.Helper()
+
+	names := make([]string, len(actual))
+	for i, food := range actual {
+		names[i] = food.Name
+	}
+
+	if len(names) != len(expected) {
+		t.Errorf("expected search for %q to find %v, got %v", query, expected, names)
+		return
+	}
+
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected search for %q to find %v, got %v", query, expected, names)
+			return
+		}
+	}
+}
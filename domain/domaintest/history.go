@@ -0,0 +1,96 @@
+package domaintest
+
+import (
+	"context"
+	"meal-planning/domain"
+	"testing"
+	"time"
+)
+
+// HistoryRepositoryContract runs the behaviour every domain.HistoryRepository
+// must have against repositories created by newRepository. Each subtest gets a
+// new, empty repository.
+func HistoryRepositoryContract(t *testing.T, newRepository func(t *testing.T) domain.HistoryRepository) {
+	ctx := context.Background()
+	// microseconds are the finest precision all backends keep
+	changedAt := time.Date(2024, time.June, 3, 18, 30, 15, 123456000, time.UTC)
+
+	t.Run("append assigns an id and find by id returns the entry", func(t *testing.T) {
+		repository := newRepository(t)
+
+		appended, err := repository.Append(ctx, domain.HistoryEntry{
+			Entity:    domain.HistoryEntityMealDay,
+			Date:      date(2024, time.June, 3),
+			Action:    domain.HistoryActionUpdate,
+			Author:    "alice",
+			ChangedAt: changedAt,
+			OldValue:  []byte(`{"Dinner":"Pasta"}`),
+			NewValue:  []byte(`{"Dinner":"Pizza"}`),
+		})
+		expectNoError(t, err)
+
+		if appended.ID == 0 {
+			t.Fatal("expected an id to be assigned")
+		}
+
+		found, err := repository.FindByID(ctx, appended.ID)
+		expectNoError(t, err)
+
+		if found.Entity != domain.HistoryEntityMealDay || found.Action != domain.HistoryActionUpdate || found.Author != "alice" {
+			t.Errorf("unexpected entry %+v", found)
+		}
+
+		if !found.Date.Equal(date(2024, time.June, 3)) || !found.ChangedAt.Equal(changedAt) {
+			t.Errorf("expected date 2024-06-03 changed at %s, got %s changed at %s", changedAt, found.Date, found.ChangedAt)
+		}
+
+		if string(found.OldValue) != `{"Dinner":"Pasta"}` || string(found.NewValue) != `{"Dinner":"Pizza"}` {
+			t.Errorf("unexpected values %s and %s", found.OldValue, found.NewValue)
+		}
+	})
+
+	t.Run("missing values stay nil", func(t *testing.T) {
+		repository := newRepository(t)
+
+		appended, err := repository.Append(ctx, domain.HistoryEntry{Entity: domain.HistoryEntityNutrition, Date: date(2024, time.June, 3), Action: domain.HistoryActionCreate, Author: "alice", ChangedAt: changedAt, NewValue: []byte(`{}`)})
+		expectNoError(t, err)
+
+		found, err := repository.FindByID(ctx, appended.ID)
+		expectNoError(t, err)
+
+		if found.OldValue != nil {
+			t.Errorf("expected no old value, got %q", found.OldValue)
+		}
+	})
+
+	t.Run("find by id returns not found for unknown ids", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.FindByID(ctx, 42)
+		expectError(t, err, domain.HistoryEntryNotFound)
+	})
+
+	t.Run("find by entity and date returns matching entries newest first", func(t *testing.T) {
+		repository := newRepository(t)
+
+		entries := []domain.HistoryEntry{
+			{Entity: domain.HistoryEntityMealDay, Date: date(2024, time.June, 3), Action: domain.HistoryActionCreate, Author: "first"},
+			{Entity: domain.HistoryEntityNutrition, Date: date(2024, time.June, 3), Action: domain.HistoryActionCreate, Author: "other entity"},
+			{Entity: domain.HistoryEntityMealDay, Date: date(2024, time.June, 4), Action: domain.HistoryActionCreate, Author: "other date"},
+			{Entity: domain.HistoryEntityMealDay, Date: date(2024, time.June, 3), Action: domain.HistoryActionUpdate, Author: "second"},
+		}
+
+		for _, entry := range entries {
+			entry.ChangedAt = changedAt
+			_, err := repository.Append(ctx, entry)
+			expectNoError(t, err)
+		}
+
+		found, err := repository.FindByEntityAndDate(ctx, domain.HistoryEntityMealDay, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if len(found) != 2 || found[0].Author != "second" || found[1].Author != "first" {
+			t.Errorf("expected entries by second and first, got %+v", found)
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package domaintest
+
+import (
+	"context"
+	"meal-planning/domain"
+	"slices"
+	"testing"
+	"time"
+)
+
+// MealDayRepositoryContract runs the behaviour every domain.MealDayRepository
+// must have against repositories created by newRepository. Each subtest gets a
+// new, empty repository.
+func MealDayRepositoryContract(t *testing.T, newRepository func(t *testing.T) domain.MealDayRepository) {
+	ctx := context.Background()
+
+	t.Run("find by date returns not found for unknown days", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectError(t, err, domain.MealNotFound)
+	})
+
+	t.Run("create stores the meal day with version 1", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, 3), Breakfast: "Porridge", Lunch: "Soup", Dinner: "Pasta"})
+		expectNoError(t, err)
+
+		if created.Version != 1 {
+			t.Errorf("expected version 1, got %d", created.Version)
+		}
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if !found.Date.Equal(date(2024, time.June, 3)) {
+			t.Errorf("expected date 2024-06-03, got %s", found.Date)
+		}
+
+		if found.Breakfast != "Porridge" || found.Lunch != "Soup" || found.Dinner != "Pasta" {
+			t.Errorf("expected stored meals, got %+v", found)
+		}
+
+		if found.Version != 1 {
+			t.Errorf("expected version 1, got %d", found.Version)
+		}
+	})
+
+	t.Run("find by date ignores the time of day", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, 3), Dinner: "Pasta"})
+		expectNoError(t, err)
+
+		found, err := repository.FindByDate(ctx, time.Date(2024, time.June, 3, 18, 30, 0, 0, time.UTC))
+		expectNoError(t, err)
+
+		if found.Dinner != "Pasta" {
+			t.Errorf("expected dinner Pasta, got %q", found.Dinner)
+		}
+	})
+
+	t.Run("create conflicts with an existing meal day", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, 3), Dinner: "Pasta"})
+		expectNoError(t, err)
+
+		_, err = repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, 3), Dinner: "Pizza"})
+		expectError(t, err, domain.MealConflict)
+	})
+
+	t.Run("update increments the version", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, 3), Dinner: "Pasta"})
+		expectNoError(t, err)
+
+		created.Dinner = "Pizza"
+		updated, err := repository.Update(ctx, created)
+		expectNoError(t, err)
+
+		if updated.Version != 2 {
+			t.Errorf("expected version 2, got %d", updated.Version)
+		}
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if found.Dinner != "Pizza" || found.Version != 2 {
+			t.Errorf("expected updated meal day with version 2, got %+v", found)
+		}
+	})
+
+	t.Run("stores snacks", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, 3), Snacks: []string{"Apple", "Yoghurt"}})
+		expectNoError(t, err)
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if !slices.Equal(found.Snacks, []string{"Apple", "Yoghurt"}) {
+			t.Errorf("expected snacks Apple and Yoghurt, got %q", found.Snacks)
+		}
+
+		created.Snacks = nil
+		_, err = repository.Update(ctx, created)
+		expectNoError(t, err)
+
+		meals, err := repository.FindByDateRange(ctx, date(2024, time.June, 3), date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if len(meals) != 1 || len(meals[0].Snacks) != 0 {
+			t.Errorf("expected no snacks, got %+v", meals)
+		}
+	})
+
+	t.Run("stores the attendance of slots", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.MealDay{
+			Date:       date(2024, time.June, 3),
+			Lunch:      "Soup",
+			Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Absent: []string{"Alex"}}},
+		})
+		expectNoError(t, err)
+
+		created.Attendance = append(created.Attendance, domain.Attendance{Slot: domain.MealSlotDinner, Servings: 6})
+		_, err = repository.Update(ctx, created)
+		expectNoError(t, err)
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		lunch, dinner := found.AttendanceOf(domain.MealSlotLunch), found.AttendanceOf(domain.MealSlotDinner)
+		if len(found.Attendance) != 2 || len(lunch.Absent) != 1 || lunch.Absent[0] != "Alex" || dinner.Servings != 6 {
+			t.Errorf("expected Alex absent at lunch and 6 servings of dinner, got %+v", found.Attendance)
+		}
+	})
+
+	t.Run("stores leftovers", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Create(ctx, domain.MealDay{
+			Date:      date(2024, time.June, 3),
+			Lunch:     "Chili",
+			Leftovers: []domain.Leftovers{{Slot: domain.MealSlotLunch, Of: domain.MealRef{Date: date(2024, time.June, 2), Slot: domain.MealSlotDinner}}},
+		})
+		expectNoError(t, err)
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		of := found.LeftoversOf(domain.MealSlotLunch)
+		if len(found.Leftovers) != 1 || of == nil || !of.Date.Equal(date(2024, time.June, 2)) || of.Slot != domain.MealSlotDinner {
+			t.Errorf("expected lunch to be leftovers of the dinner before, got %+v", found.Leftovers)
+		}
+	})
+
+	t.Run("update rejects stale versions", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, 3), Dinner: "Pasta"})
+		expectNoError(t, err)
+
+		_, err = repository.Update(ctx, created)
+		expectNoError(t, err)
+
+		created.Dinner = "Pizza"
+		_, err = repository.Update(ctx, created)
+		expectError(t, err, domain.MealConflict)
+	})
+
+	t.Run("update conflicts with missing meal days", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Update(ctx, domain.MealDay{Date: date(2024, time.June, 3), Dinner: "Pasta", Version: 1})
+		expectError(t, err, domain.MealConflict)
+	})
+
+	t.Run("delete removes the meal day", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, 3), Dinner: "Pasta"})
+		expectNoError(t, err)
+
+		err = repository.Delete(ctx, created)
+		expectNoError(t, err)
+
+		_, err = repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectError(t, err, domain.MealNotFound)
+	})
+
+	t.Run("find by date range is inclusive and ascending", func(t *testing.T) {
+		repository := newRepository(t)
+
+		for _, day := range []int{9, 5, 2, 3, 10, 7} {
+			_, err := repository.Create(ctx, domain.MealDay{Date: date(2024, time.June, day), Dinner: "Pasta"})
+			expectNoError(t, err)
+		}
+
+		meals, err := repository.FindByDateRange(ctx, date(2024, time.June, 3), date(2024, time.June, 9))
+		expectNoError(t, err)
+
+		dates := make([]time.Time, len(meals))
+		for i, meal := range meals {
+			dates[i] = meal.Date
+		}
+
+		expectDates(t, dates, date(2024, time.June, 3), date(2024, time.June, 5), date(2024, time.June, 7), date(2024, time.June, 9))
+	})
+
+	t.Run("find by date range returns an empty list without meal days", func(t *testing.T) {
+		repository := newRepository(t)
+
+		meals, err := repository.FindByDateRange(ctx, date(2024, time.June, 3), date(2024, time.June, 9))
+		expectNoError(t, err)
+
+		if meals == nil || len(meals) != 0 {
+			t.Errorf("expected an empty list, got %#v", meals)
+		}
+	})
+
+	t.Run("stops on cancelled context", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Create(cancelledContext(), domain.MealDay{Date: date(2024, time.June, 3), Dinner: "Pasta"})
+		expectError(t, err, context.Canceled)
+
+		_, err = repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectError(t, err, domain.MealNotFound)
+	})
+}
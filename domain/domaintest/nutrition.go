@@ -0,0 +1,223 @@
+package domaintest
+
+import (
+	"context"
+	"meal-planning/domain"
+	"testing"
+	"time"
+)
+
+// NutritionRepositoryContract runs the behaviour every
+// domain.NutritionRepository must have against repositories created by
+// newRepository. Each subtest gets a new, empty repository.
+func NutritionRepositoryContract(t *testing.T, newRepository func(t *testing.T) domain.NutritionRepository) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, repository domain.NutritionRepository, entries ...domain.Nutrition) {
+		t.Helper()
+
+		for _, entry := range entries {
+			_, err := repository.Create(ctx, entry)
+			expectNoError(t, err)
+		}
+	}
+
+	t.Run("find by date returns not found for unknown days", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectError(t, err, domain.NutritionNotFound)
+	})
+
+	t.Run("create stores the entry with version 1", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2100, Weight: 80500})
+		expectNoError(t, err)
+
+		if created.Version != 1 {
+			t.Errorf("expected version 1, got %d", created.Version)
+		}
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		expected := domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2100, Weight: 80500, Version: 1}
+		if !found.Date.Equal(expected.Date) || found.Calories != expected.Calories || found.Weight != expected.Weight || found.Version != expected.Version {
+			t.Errorf("expected %+v, got %+v", expected, found)
+		}
+	})
+
+	t.Run("missing values are stored as zero", func(t *testing.T) {
+		repository := newRepository(t)
+
+		create(t, repository, domain.Nutrition{Date: date(2024, time.June, 3), Weight: 80500})
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if found.Calories != 0 || found.Weight != 80500 {
+			t.Errorf("expected only a weight, got %+v", found)
+		}
+	})
+
+	t.Run("stores the totals of the food diary", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2100, CaloriesFromDiary: true, Protein: 120, Carbs: 230, Fat: 70})
+		expectNoError(t, err)
+
+		created.Protein = 0
+		_, err = repository.Update(ctx, created)
+		expectNoError(t, err)
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if found.Calories != 2100 || !found.CaloriesFromDiary || found.Protein != 0 || found.Carbs != 230 || found.Fat != 70 {
+			t.Errorf("expected the diary totals without protein, got %+v", found)
+		}
+	})
+
+	t.Run("create conflicts with an existing entry", func(t *testing.T) {
+		repository := newRepository(t)
+
+		create(t, repository, domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2100})
+
+		_, err := repository.Create(ctx, domain.Nutrition{Date: date(2024, time.June, 3), Calories: 1800})
+		expectError(t, err, domain.NutritionConflict)
+	})
+
+	t.Run("update increments the version and rejects stale versions", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2100})
+		expectNoError(t, err)
+
+		created.Calories = 1800
+		updated, err := repository.Update(ctx, created)
+		expectNoError(t, err)
+
+		if updated.Version != 2 {
+			t.Errorf("expected version 2, got %d", updated.Version)
+		}
+
+		_, err = repository.Update(ctx, created)
+		expectError(t, err, domain.NutritionConflict)
+
+		found, err := repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectNoError(t, err)
+
+		if found.Calories != 1800 || found.Version != 2 {
+			t.Errorf("expected updated entry with version 2, got %+v", found)
+		}
+	})
+
+	t.Run("update conflicts with missing entries", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Update(ctx, domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2100, Version: 1})
+		expectError(t, err, domain.NutritionConflict)
+	})
+
+	t.Run("delete removes the entry", func(t *testing.T) {
+		repository := newRepository(t)
+
+		create(t, repository, domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2100})
+
+		err := repository.Delete(ctx, domain.Nutrition{Date: date(2024, time.June, 3)})
+		expectNoError(t, err)
+
+		_, err = repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectError(t, err, domain.NutritionNotFound)
+	})
+
+	t.Run("find by date range is inclusive and ascending", func(t *testing.T) {
+		repository := newRepository(t)
+
+		for _, day := range []int{9, 5, 2, 3, 10, 7} {
+			create(t, repository, domain.Nutrition{Date: date(2024, time.June, day), Calories: 2000})
+		}
+
+		entries, err := repository.FindByDateRange(ctx, date(2024, time.June, 3), date(2024, time.June, 9))
+		expectNoError(t, err)
+
+		dates := make([]time.Time, len(entries))
+		for i, entry := range entries {
+			dates[i] = entry.Date
+		}
+
+		expectDates(t, dates, date(2024, time.June, 3), date(2024, time.June, 5), date(2024, time.June, 7), date(2024, time.June, 9))
+	})
+
+	t.Run("find average nutrition ignores missing values", func(t *testing.T) {
+		repository := newRepository(t)
+
+		create(t, repository,
+			domain.Nutrition{Date: date(2024, time.June, 2), Calories: 5000, Weight: 90000},
+			domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2000, Weight: 80000},
+			domain.Nutrition{Date: date(2024, time.June, 4), Calories: 2500},
+			domain.Nutrition{Date: date(2024, time.June, 5), Weight: 80600},
+			domain.Nutrition{Date: date(2024, time.June, 9), Calories: 1800, Weight: 79900},
+			domain.Nutrition{Date: date(2024, time.June, 10), Calories: 5000, Weight: 90000},
+		)
+
+		average, err := repository.FindAverageNutrition(ctx, date(2024, time.June, 3), date(2024, time.June, 9))
+		expectNoError(t, err)
+
+		if average.Calories != 2100 {
+			t.Errorf("expected average calories 2100, got %d", average.Calories)
+		}
+
+		if average.Weight != 80166 {
+			t.Errorf("expected average weight 80166, got %d", average.Weight)
+		}
+	})
+
+	t.Run("find average nutrition is zero without entries", func(t *testing.T) {
+		repository := newRepository(t)
+
+		average, err := repository.FindAverageNutrition(ctx, date(2024, time.June, 3), date(2024, time.June, 9))
+		expectNoError(t, err)
+
+		if average != (domain.AverageNutrition{}) {
+			t.Errorf("expected zero average, got %+v", average)
+		}
+	})
+
+	t.Run("find latest weight skips entries without weight", func(t *testing.T) {
+		repository := newRepository(t)
+
+		create(t, repository,
+			domain.Nutrition{Date: date(2024, time.June, 3), Weight: 80000},
+			domain.Nutrition{Date: date(2024, time.June, 5), Weight: 79500},
+			domain.Nutrition{Date: date(2024, time.June, 6), Calories: 2000},
+		)
+
+		latest, err := repository.FindLatestWeight(ctx)
+		expectNoError(t, err)
+
+		if !latest.Date.Equal(date(2024, time.June, 5)) || latest.Weight != 79500 {
+			t.Errorf("expected 79500 on 2024-06-05, got %+v", latest)
+		}
+	})
+
+	t.Run("find latest weight returns not found without weights", func(t *testing.T) {
+		repository := newRepository(t)
+
+		create(t, repository, domain.Nutrition{Date: date(2024, time.June, 6), Calories: 2000})
+
+		_, err := repository.FindLatestWeight(ctx)
+		expectError(t, err, domain.NutritionNotFound)
+	})
+
+	t.Run("stops on cancelled context", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Create(cancelledContext(), domain.Nutrition{Date: date(2024, time.June, 3), Calories: 2100})
+		expectError(t, err, context.Canceled)
+
+		_, err = repository.FindByDate(ctx, date(2024, time.June, 3))
+		expectError(t, err, domain.NutritionNotFound)
+	})
+}
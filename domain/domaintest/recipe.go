@@ -0,0 +1,211 @@
+package domaintest
+
+import (
+	"context"
+	"meal-planning/domain"
+	"slices"
+	"testing"
+	"time"
+)
+
+// RecipeRepositoryContract runs the behaviour every domain.RecipeRepository
+// must have against repositories created by newRepository. Each subtest gets a
+// new, empty repository.
+func RecipeRepositoryContract(t *testing.T, newRepository func(t *testing.T) domain.RecipeRepository) {
+	ctx := context.Background()
+
+	chili := domain.Recipe{
+		Name:       "Chili sin carne",
+		Servings:   4,
+		PerServing: domain.Nutrients{Kcal: 520, Protein: 24, Carbs: 70, Fat: 12.5},
+		Ingredients: []domain.Ingredient{
+			{Name: "Kidney beans", FoodID: 7, Grams: 480, Nutrients: domain.Nutrients{Kcal: 610, Protein: 40, Carbs: 100, Fat: 2}},
+			{Name: "Chili powder", Grams: 5},
+			{Name: "Salt"},
+		},
+		Instructions: []string{"Fry the onions.", "Add everything else and simmer."},
+		PrepTime:     15 * time.Minute,
+		CookTime:     time.Hour,
+	}
+
+	create := func(t *testing.T, repository domain.RecipeRepository, names ...string) {
+		t.Helper()
+
+		for _, name := range names {
+			_, err := repository.Create(ctx, domain.Recipe{Name: name, Servings: 1})
+			expectNoError(t, err)
+		}
+	}
+
+	t.Run("find by id returns not found for unknown recipes", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.FindByID(ctx, 42)
+		expectError(t, err, domain.RecipeNotFound)
+	})
+
+	t.Run("create assigns an id and stores the recipe with version 1", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, chili)
+		expectNoError(t, err)
+
+		if created.ID == 0 || created.Version != 1 {
+			t.Errorf("expected an id and version 1, got %d and %d", created.ID, created.Version)
+		}
+
+		found, err := repository.FindByID(ctx, created.ID)
+		expectNoError(t, err)
+
+		expectRecipe(t, found, created)
+	})
+
+	t.Run("update increments the version and rejects stale versions", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, chili)
+		expectNoError(t, err)
+
+		edited := created
+		edited.Servings = 6
+		edited.PerServing.Kcal = 350
+		edited.Ingredients = edited.Ingredients[:1]
+		edited.Instructions = nil
+		edited.CookTime = 90 * time.Minute
+
+		updated, err := repository.Update(ctx, edited)
+		expectNoError(t, err)
+
+		if updated.Version != 2 {
+			t.Errorf("expected version 2, got %d", updated.Version)
+		}
+
+		found, err := repository.FindByID(ctx, created.ID)
+		expectNoError(t, err)
+
+		expectRecipe(t, found, updated)
+
+		_, err = repository.Update(ctx, edited)
+		expectError(t, err, domain.RecipeConflict)
+
+		_, err = repository.Update(ctx, domain.Recipe{ID: 42, Name: "Pancakes", Servings: 1, Version: 1})
+		expectError(t, err, domain.RecipeConflict)
+	})
+
+	t.Run("delete removes the recipe", func(t *testing.T) {
+		repository := newRepository(t)
+
+		created, err := repository.Create(ctx, chili)
+		expectNoError(t, err)
+
+		err = repository.Delete(ctx, created.ID)
+		expectNoError(t, err)
+
+		_, err = repository.FindByID(ctx, created.ID)
+		expectError(t, err, domain.RecipeNotFound)
+
+		err = repository.Delete(ctx, created.ID)
+		expectError(t, err, domain.RecipeNotFound)
+	})
+
+	t.Run("find by names ignores case and unknown names", func(t *testing.T) {
+		repository := newRepository(t)
+
+		create(t, repository, "Pancakes", "Chili sin carne", "Porridge")
+
+		found, err := repository.FindByNames(ctx, []string{"porridge", "CHILI SIN CARNE", "Lasagne", "porridge"})
+		expectNoError(t, err)
+
+		expectRecipeNames(t, "porridge, chili", found, []string{"Chili sin carne", "Porridge"})
+
+		found, err = repository.FindByNames(ctx, nil)
+		expectNoError(t, err)
+
+		expectRecipeNames(t, "no names", found, nil)
+	})
+
+	t.Run("search matches every word of the name ignoring case", func(t *testing.T) {
+		repository := newRepository(t)
+
+		create(t, repository, "Pancakes", "Chili sin carne", "Chili con carne", "100% rye bread")
+
+		tests := []struct {
+			query    string
+			expected []string
+		}{
+			{"chili", []string{"Chili con carne", "Chili sin carne"}},
+			{"CARNE sin", []string{"Chili sin carne"}},
+			{"100%", []string{"100% rye bread"}},
+			{"_", nil},
+			{"", []string{"100% rye bread", "Chili con carne", "Chili sin carne", "Pancakes"}},
+		}
+
+		for _, test := range tests {
+			found, err := repository.Search(ctx, test.query, 10)
+			expectNoError(t, err)
+
+			expectRecipeNames(t, test.query, found, test.expected)
+		}
+
+		found, err := repository.Search(ctx, "", 2)
+		expectNoError(t, err)
+
+		expectRecipeNames(t, "limit 2", found, []string{"100% rye bread", "Chili con carne"})
+	})
+
+	t.Run("methods stop on a cancelled context", func(t *testing.T) {
+		repository := newRepository(t)
+
+		_, err := repository.Create(cancelledContext(), chili)
+		expectError(t, err, context.Canceled)
+
+		_, err = repository.FindByNames(cancelledContext(), []string{chili.Name})
+		expectError(t, err, context.Canceled)
+
+		found, err := repository.Search(ctx, "", 10)
+		expectNoError(t, err)
+
+		expectRecipeNames(t, "", found, nil)
+	})
+}
+
+func expectRecipe(t *testing.T, actual, expected domain.Recipe) {
+	t.Helper()
+
+	if actual.ID != expected.ID || actual.Name != expected.Name || actual.Servings != expected.Servings ||
+		actual.PerServing != expected.PerServing || actual.PrepTime != expected.PrepTime || actual.CookTime != expected.CookTime ||
+		actual.Version != expected.Version || !slices.Equal(actual.Instructions, expected.Instructions) {
+		t.Errorf("expected recipe %+v, got %+v", expected, actual)
+	}
+
+	if len(actual.Ingredients) != len(expected.Ingredients) {
+		t.Fatalf("expected ingredients %+v, got %+v", expected.Ingredients, actual.Ingredients)
+	}
+
+	for i := range expected.Ingredients {
+		if actual.Ingredients[i] != expected.Ingredients[i] {
+			t.Errorf("expected ingredients %+v, got %+v", expected.Ingredients, actual.Ingredients)
+		}
+	}
+}
+
+func expectRecipeNames(t *testing.T, query string, found []domain.Recipe, expected []string) {
+	t.Helper()
+
+	names := make([]string, len(found))
+	for i, recipe := range found {
+		names[i] = recipe.Name
+	}
+
+	if len(names) != len(expected) {
+		t.Errorf("%q: expected %v, got %v", query, expected, names)
+		return
+	}
+
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("%q: expected %v, got %v", query, expected, names)
+			return
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "errors"
+
+// ErrorKind says what went wrong in terms the caller can act on. Handlers map
+// it to a status code.
+type ErrorKind string
+
+const (
+	// ErrorKindInternal is the kind of every error that does not carry one.
+	ErrorKindInternal   ErrorKind = "internal"
+	ErrorKindValidation ErrorKind = "validation"
+	ErrorKindNotFound   ErrorKind = "not found"
+	ErrorKindConflict   ErrorKind = "conflict"
+	ErrorKindForbidden  ErrorKind = "forbidden"
+)
+
+// Error is an error of a known kind whose message may be shown to the user.
+type Error struct {
+	kind    ErrorKind
+	message string
+}
+
+func NewError(kind ErrorKind, message string) *Error {
+	return &Error{kind: kind, message: message}
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+func (e *Error) Kind() ErrorKind {
+	return e.kind
+}
+
+// KindOf returns the kind of the first error in err's tree that has one and
+// ErrorKindInternal otherwise.
+func KindOf(err error) ErrorKind {
+	var kinded interface{ Kind() ErrorKind }
+	if errors.As(err, &kinded) {
+		return kinded.Kind()
+	}
+
+	return ErrorKindInternal
+}
+
+// UserMessage returns the message of the first error in err's tree that has a
+// kind. Internal errors have no message meant for users and return false.
+func UserMessage(err error) (string, bool) {
+	var kinded interface {
+		error
+		Kind() ErrorKind
+	}
+	if errors.As(err, &kinded) {
+		return kinded.Error(), true
+	}
+
+	return "", false
+}
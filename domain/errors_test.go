@@ -0,0 +1,35 @@
+package domain_test
+
+import (
+	"errors"
+	"fmt"
+	"meal-planning/domain"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		kind    domain.ErrorKind
+		message string
+	}{
+		{"sentinel", domain.MealNotFound, domain.ErrorKindNotFound, "meal: not found"},
+		{"wrapped", fmt.Errorf("updating nutrition: %w", domain.NutritionConflict), domain.ErrorKindConflict, "nutrition modified concurrently"},
+		{"new error", domain.NewError(domain.ErrorKindForbidden, "not yours"), domain.ErrorKindForbidden, "not yours"},
+		{"plain error", errors.New("disk full"), domain.ErrorKindInternal, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if kind := domain.KindOf(test.err); kind != test.kind {
+				t.Errorf("expected kind %q, got %q", test.kind, kind)
+			}
+
+			message, ok := domain.UserMessage(test.err)
+			if ok != (test.message != "") || message != test.message {
+				t.Errorf("expected message %q, got %q (%v)", test.message, message, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventMealDayChanged   EventType = "meal-day"
+	EventNutritionChanged EventType = "nutrition-entry"
+)
+
+// Event announces a change that was successfully written. Depending on the
+// type either MealDay or Nutrition carries the new state.
+type Event struct {
+	Type      EventType
+	Date      time.Time
+	MealDay   MealDay
+	Nutrition Nutrition
+}
+
+type EventPublisher interface {
+	Publish(event Event)
+}
+
+// subscriberBufferSize is the number of events a subscriber may lag behind
+// before it starts missing events.
+const subscriberBufferSize = 16
+
+// EventBus is an in-process publisher for events. The planner is deployed per
+// household, so every subscriber receives every event.
+type EventBus struct {
+	mutex       sync.Mutex
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish delivers the event to all subscribers without blocking. Subscribers
+// that do not keep up miss the event.
+func (bus *EventBus) Publish(event Event) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	for subscriber := range bus.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			slog.Warn("Dropping event for slow subscriber", slog.String("type", string(event.Type)), slog.String("date", event.Date.Format("2006-01-02")))
+		}
+	}
+}
+
+// Subscribe returns a channel receiving all events published from now on and a
+// function to end the subscription.
+func (bus *EventBus) Subscribe() (<-chan Event, func()) {
+	subscriber := make(chan Event, subscriberBufferSize)
+
+	bus.mutex.Lock()
+	if bus.closed {
+		close(subscriber)
+	} else {
+		bus.subscribers[subscriber] = struct{}{}
+	}
+	bus.mutex.Unlock()
+
+	unsubscribe := func() {
+		bus.mutex.Lock()
+		defer bus.mutex.Unlock()
+
+		if _, ok := bus.subscribers[subscriber]; ok {
+			delete(bus.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+
+	return subscriber, unsubscribe
+}
+
+// Close ends all subscriptions by closing their channels. Events published
+// afterwards are dropped.
+func (bus *EventBus) Close() {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	bus.closed = true
+	for subscriber := range bus.subscribers {
+		delete(bus.subscribers, subscriber)
+		close(subscriber)
+	}
+}
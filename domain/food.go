@@ -0,0 +1,247 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// FoodSearchLimit is the maximum number of foods a search returns.
+	FoodSearchLimit = 50
+	// foodImportBatchSize is the number of foods imported per repository call.
+	foodImportBatchSize = 500
+
+	MaxFoodNameLength = 200
+	// MaxKcalPer100g allows for pure fat and a bit of rounding in food labels.
+	MaxKcalPer100g = 950
+)
+
+var (
+	FoodNotFound = NewError(ErrorKindNotFound, "food: not found")
+	FoodConflict = NewError(ErrorKindConflict, "food: modified concurrently")
+)
+
+// FoodSource tells where a food came from. Imported foods are identified by
+// their source and the ID the source gave them.
+type FoodSource string
+
+const (
+	FoodSourceManual        FoodSource = "manual"
+	FoodSourceOpenFoodFacts FoodSource = "openfoodfacts"
+	FoodSourceUSDA          FoodSource = "usda"
+)
+
+// Nutrients are the energy in kcal and the macronutrients in grams of some
+// amount of food.
+type Nutrients struct {
+	Kcal    float64 `json:"kcal"`
+	Protein float64 `json:"protein"`
+	Carbs   float64 `json:"carbs"`
+	Fat     float64 `json:"fat"`
+}
+
+// Scale returns the nutrients of factor times the amount.
+func (n Nutrients) Scale(factor float64) Nutrients {
+	return Nutrients{
+		Kcal:    n.Kcal * factor,
+		Protein: n.Protein * factor,
+		Carbs:   n.Carbs * factor,
+		Fat:     n.Fat * factor,
+	}
+}
+
+func (n Nutrients) Add(other Nutrients) Nutrients {
+	return Nutrients{
+		Kcal:    n.Kcal + other.Kcal,
+		Protein: n.Protein + other.Protein,
+		Carbs:   n.Carbs + other.Carbs,
+		Fat:     n.Fat + other.Fat,
+	}
+}
+
+// ServingSize is a named portion of a food, e.g. "1 slice" of 30 g.
+type ServingSize struct {
+	Name  string  `json:"name"`
+	Grams float64 `json:"grams"`
+}
+
+type Food struct {
+	ID    int64
+	Name  string
+	Brand string
+	// Per100g holds the nutrients in 100 g of the food.
+	Per100g      Nutrients
+	ServingSizes []ServingSize
+	Source       FoodSource
+	// SourceID is the barcode or database ID of imported foods and empty for
+	// foods entered by hand.
+	SourceID string
+	// Version is incremented on every update. Updates must carry the version
+	// they are based on.
+	Version int
+}
+
+// NutrientsFor returns the nutrients in the given amount of the food.
+func (food Food) NutrientsFor(grams float64) Nutrients {
+	return food.Per100g.Scale(grams / 100)
+}
+
+// Validate rejects foods without a name and nutrient values no food can have.
+func (food Food) Validate() error {
+	v := validator{}
+
+	name := strings.TrimSpace(food.Name)
+	v.check(name != "", "name", "must not be empty")
+	v.check(utf8.RuneCountInString(name) <= MaxFoodNameLength, "name", fmt.Sprintf("must be at most %d characters", MaxFoodNameLength))
+	v.check(utf8.RuneCountInString(food.Brand) <= MaxFoodNameLength, "brand", fmt.Sprintf("must be at most %d characters", MaxFoodNameLength))
+
+	v.check(food.Per100g.Kcal >= 0 && food.Per100g.Kcal <= MaxKcalPer100g, "kcal", fmt.Sprintf("must be between 0 and %d", MaxKcalPer100g))
+	v.check(food.Per100g.Protein >= 0 && food.Per100g.Protein <= 100, "protein", "must be between 0 and 100 g")
+	v.check(food.Per100g.Carbs >= 0 && food.Per100g.Carbs <= 100, "carbs", "must be between 0 and 100 g")
+	v.check(food.Per100g.Fat >= 0 && food.Per100g.Fat <= 100, "fat", "must be between 0 and 100 g")
+
+	for _, servingSize := range food.ServingSizes {
+		v.check(strings.TrimSpace(servingSize.Name) != "", "servingSizes", "need a name")
+		v.check(servingSize.Grams > 0, "servingSizes", "must weigh more than 0 g")
+	}
+
+	return v.err()
+}
+
+type FoodRepository interface {
+	FindByID(ctx context.Context, id int64) (Food, error)
+	// Search returns up to limit foods whose name or brand contains every word
+	// of query, ignoring case. Foods whose name starts with the first word come
+	// first, then foods are ordered by name.
+	Search(ctx context.Context, query string, limit int) ([]Food, error)
+	Create(ctx context.Context, food Food) (Food, error)
+	Update(ctx context.Context, food Food) (Food, error)
+	Delete(ctx context.Context, id int64) error
+	// Import creates the foods or updates the ones with the same source and
+	// source ID.
+	Import(ctx context.Context, foods []Food) error
+}
+
+// FoodReader reads foods from a dump one by one. Read returns io.EOF after the
+// last food.
+type FoodReader interface {
+	Read() (Food, error)
+}
+
+type FoodImportResult struct {
+	Imported int
+	Skipped  int
+}
+
+type FoodService struct {
+	repository FoodRepository
+}
+
+func NewFoodService(repository FoodRepository) *FoodService {
+	return &FoodService{repository: repository}
+}
+
+func (service *FoodService) Search(ctx context.Context, query string) ([]Food, error) {
+	slog.InfoContext(ctx, "Searching foods", slog.String("query", query))
+
+	return service.repository.Search(ctx, strings.TrimSpace(query), FoodSearchLimit)
+}
+
+func (service *FoodService) FindByID(ctx context.Context, id int64) (Food, error) {
+	slog.InfoContext(ctx, "Finding food", slog.Int64("id", id))
+
+	return service.repository.FindByID(ctx, id)
+}
+
+func (service *FoodService) Create(ctx context.Context, food Food) (Food, error) {
+	slog.InfoContext(ctx, "Creating food", slog.String("name", food.Name))
+
+	food.Name = strings.TrimSpace(food.Name)
+	food.Source = FoodSourceManual
+	food.SourceID = ""
+
+	err := food.Validate()
+	if err != nil {
+		return Food{}, err
+	}
+
+	return service.repository.Create(ctx, food)
+}
+
+func (service *FoodService) Update(ctx context.Context, food Food) (Food, error) {
+	slog.InfoContext(ctx, "Updating food", slog.Int64("id", food.ID))
+
+	food.Name = strings.TrimSpace(food.Name)
+
+	err := food.Validate()
+	if err != nil {
+		return Food{}, err
+	}
+
+	return service.repository.Update(ctx, food)
+}
+
+func (service *FoodService) Delete(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "Deleting food", slog.Int64("id", id))
+
+	return service.repository.Delete(ctx, id)
+}
+
+// Import stores all foods of the reader that pass validation. Importing the
+// same dump again updates the foods instead of adding them twice.
+func (service *FoodService) Import(ctx context.Context, reader FoodReader) (FoodImportResult, error) {
+	slog.InfoContext(ctx, "Importing foods")
+
+	result := FoodImportResult{}
+	batch := make([]Food, 0, foodImportBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := service.repository.Import(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		result.Imported += len(batch)
+		batch = batch[:0]
+
+		slog.InfoContext(ctx, "Imported foods", slog.Int("imported", result.Imported), slog.Int("skipped", result.Skipped))
+		return nil
+	}
+
+	for {
+		food, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return result, err
+		}
+
+		food.Name = strings.TrimSpace(food.Name)
+		if food.SourceID == "" || food.Validate() != nil {
+			result.Skipped++
+			continue
+		}
+
+		batch = append(batch, food)
+		if len(batch) == foodImportBatchSize {
+			err = flush()
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	err := flush()
+	return result, err
+}
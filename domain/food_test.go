@@ -0,0 +1,111 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"meal-planning/domain"
+	"meal-planning/memory"
+	"testing"
+)
+
+// sliceFoodReader reads foods from a slice and fails with err at the end if
+// it is set.
+type sliceFoodReader struct {
+	foods []domain.Food
+	err   error
+}
+
+func (r *sliceFoodReader) Read() (domain.Food, error) {
+	if len(r.foods) == 0 {
+		if r.err != nil {
+			return domain.Food{}, r.err
+		}
+
+		return domain.Food{}, io.EOF
+	}
+
+	food := r.foods[0]
+	r.foods = r.foods[1:]
+	return food, nil
+}
+
+func TestFoodNutrientsFor(t *testing.T) {
+	food := domain.Food{Per100g: domain.Nutrients{Kcal: 372, Protein: 13.5, Carbs: 58.7, Fat: 7}}
+
+	nutrients := food.NutrientsFor(50)
+
+	if nutrients != (domain.Nutrients{Kcal: 186, Protein: 6.75, Carbs: 29.35, Fat: 3.5}) {
+		t.Errorf("unexpected nutrients for 50 g: %+v", nutrients)
+	}
+}
+
+func TestFoodServiceCreateIsManual(t *testing.T) {
+	service := domain.NewFoodService(memory.NewFoodRepository())
+
+	created, err := service.Create(context.Background(), domain.Food{Name: " Oat drink ", Source: domain.FoodSourceOpenFoodFacts, SourceID: "7394376616037"})
+	if err != nil {
+		t.Fatalf("creating food: %v", err)
+	}
+
+	if created.Name != "Oat drink" || created.Source != domain.FoodSourceManual || created.SourceID != "" {
+		t.Errorf("expected a trimmed manual food, got %+v", created)
+	}
+
+	_, err = service.Create(context.Background(), domain.Food{Name: "Oat drink", Per100g: domain.Nutrients{Kcal: 1000}})
+	if domain.KindOf(err) != domain.ErrorKindValidation {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestFoodServiceImport(t *testing.T) {
+	repository := memory.NewFoodRepository()
+	service := domain.NewFoodService(repository)
+
+	foods := make([]domain.Food, 0, 1203)
+	for i := range 1200 {
+		foods = append(foods, domain.Food{Name: fmt.Sprintf("Food %d", i), Source: domain.FoodSourceUSDA, SourceID: fmt.Sprint(i)})
+	}
+	foods = append(foods,
+		domain.Food{Name: "Without id", Source: domain.FoodSourceUSDA},
+		domain.Food{Name: " ", Source: domain.FoodSourceUSDA, SourceID: "blank"},
+		domain.Food{Name: "Too much", Per100g: domain.Nutrients{Fat: 101}, Source: domain.FoodSourceUSDA, SourceID: "fat"},
+	)
+
+	result, err := service.Import(context.Background(), &sliceFoodReader{foods: foods})
+	if err != nil {
+		t.Fatalf("importing foods: %v", err)
+	}
+
+	if result != (domain.FoodImportResult{Imported: 1200, Skipped: 3}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	found, err := repository.Search(context.Background(), "food", 2000)
+	if err != nil {
+		t.Fatalf("searching foods: %v", err)
+	}
+
+	if len(found) != 1200 {
+		t.Errorf("expected 1200 foods, got %d", len(found))
+	}
+}
+
+func TestFoodServiceImportStopsOnReadErrors(t *testing.T) {
+	service := domain.NewFoodService(memory.NewFoodRepository())
+	broken := errors.New("unexpected end of dump")
+
+	result, err := service.Import(context.Background(), &sliceFoodReader{
+		foods: []domain.Food{{Name: "Oat drink", Source: domain.FoodSourceOpenFoodFacts, SourceID: "7394376616037"}},
+		err:   broken,
+	})
+
+	if !errors.Is(err, broken) {
+		t.Errorf("expected the read error, got %v", err)
+	}
+
+	if result.Imported != 0 {
+		t.Errorf("expected the unfinished batch not to be imported, got %+v", result)
+	}
+}
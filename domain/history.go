@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type HistoryAction string
+
+const (
+	HistoryActionCreate HistoryAction = "create"
+	HistoryActionUpdate HistoryAction = "update"
+	HistoryActionDelete HistoryAction = "delete"
+)
+
+type HistoryEntity string
+
+const (
+	HistoryEntityMealDay   HistoryEntity = "meal"
+	HistoryEntityNutrition HistoryEntity = "nutrition"
+)
+
+var HistoryEntryNotFound = NewError(ErrorKindNotFound, "history entry: not found")
+
+// HistoryEntry is a single change to a meal day or nutrition entry. OldValue
+// and NewValue hold the JSON encoded entity before and after the change and are
+// nil if the entity did not exist at that point.
+type HistoryEntry struct {
+	ID        int64
+	Entity    HistoryEntity
+	Date      time.Time
+	Action    HistoryAction
+	Author    string
+	ChangedAt time.Time
+	OldValue  []byte
+	NewValue  []byte
+}
+
+// HistoryRepository stores history entries. It is append-only, entries are
+// never updated or deleted.
+type HistoryRepository interface {
+	Append(ctx context.Context, entry HistoryEntry) (HistoryEntry, error)
+	FindByID(ctx context.Context, id int64) (HistoryEntry, error)
+	FindByEntityAndDate(ctx context.Context, entity HistoryEntity, date time.Time) ([]HistoryEntry, error)
+}
+
+func newHistoryEntry(ctx context.Context, entity HistoryEntity, date time.Time, action HistoryAction, oldValue, newValue any) (HistoryEntry, error) {
+	entry := HistoryEntry{
+		Entity:    entity,
+		Date:      date,
+		Action:    action,
+		Author:    UserFromContext(ctx),
+		ChangedAt: time.Now(),
+	}
+
+	var err error
+	if oldValue != nil {
+		entry.OldValue, err = json.Marshal(oldValue)
+		if err != nil {
+			return HistoryEntry{}, err
+		}
+	}
+
+	if newValue != nil {
+		entry.NewValue, err = json.Marshal(newValue)
+		if err != nil {
+			return HistoryEntry{}, err
+		}
+	}
+
+	return entry, nil
+}
+
+func decodeHistoryValue[T any](value []byte) (*T, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	decoded := new(T)
+	err := json.Unmarshal(value, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
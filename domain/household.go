@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxHouseholdSize bounds the people a household plans meals for.
+const MaxHouseholdSize = 20
+
+// Household is the people meals are planned for. Every planned meal serves
+// all of them unless the meal day says otherwise.
+type Household struct {
+	// People is the number of people if they are not named.
+	People int
+	// Members are the names of the people, if known. Meal days can only tell
+	// who is eating elsewhere for named members.
+	Members []string
+}
+
+// ParseHousehold reads a household as it is configured: the number of people,
+// e.g. "4", or the names of the members separated by commas, e.g.
+// "Alex, Sam". Empty is a household of one.
+func ParseHousehold(value string) (Household, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Household{People: 1}, nil
+	}
+
+	if people, err := strconv.Atoi(value); err == nil {
+		if people < 1 || people > MaxHouseholdSize {
+			return Household{}, fmt.Errorf("household must have between 1 and %d people", MaxHouseholdSize)
+		}
+
+		return Household{People: people}, nil
+	}
+
+	household := Household{}
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+
+		if household.HasMember(member) {
+			return Household{}, fmt.Errorf("household member %q is named twice", member)
+		}
+
+		household.Members = append(household.Members, member)
+	}
+
+	if len(household.Members) > MaxHouseholdSize {
+		return Household{}, fmt.Errorf("household must have between 1 and %d people", MaxHouseholdSize)
+	}
+
+	return household, nil
+}
+
+// Size returns the number of people in the household, at least one.
+func (household Household) Size() int {
+	return max(household.People, len(household.Members), 1)
+}
+
+// HasMember reports whether name is a member of the household, ignoring case.
+func (household Household) HasMember(name string) bool {
+	return slices.ContainsFunc(household.Members, func(member string) bool {
+		return strings.EqualFold(member, name)
+	})
+}
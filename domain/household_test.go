@@ -0,0 +1,43 @@
+package domain_test
+
+import (
+	"meal-planning/domain"
+	"slices"
+	"testing"
+)
+
+func TestParseHousehold(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		size    int
+		members []string
+	}{
+		{"unset", "", 1, nil},
+		{"number of people", " 4 ", 4, nil},
+		{"members", "Alex, Sam,Kim", 3, []string{"Alex", "Sam", "Kim"}},
+		{"empty names", "Alex,,", 1, []string{"Alex"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			household, err := domain.ParseHousehold(test.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if household.Size() != test.size || !slices.Equal(household.Members, test.members) {
+				t.Errorf("expected %d people named %v, got %d named %v", test.size, test.members, household.Size(), household.Members)
+			}
+		})
+	}
+
+	for _, value := range []string{"0", "21", "Alex, Sam, alex"} {
+		t.Run("rejects "+value, func(t *testing.T) {
+			_, err := domain.ParseHousehold(value)
+			if err == nil {
+				t.Errorf("expected an error for %q", value)
+			}
+		})
+	}
+}
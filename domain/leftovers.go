@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// MaxLeftoversDays is how many days after cooking leftovers can be planned.
+const MaxLeftoversDays = 4
+
+// CookedSlots are the slots meals are cooked for, so they can have leftovers
+// and be leftovers. Snacks are not cooked.
+var CookedSlots = []MealSlot{MealSlotBreakfast, MealSlotLunch, MealSlotDinner}
+
+// MealRef names a slot of a day.
+type MealRef struct {
+	Date time.Time `json:"date"`
+	Slot MealSlot  `json:"slot"`
+}
+
+// Before reports whether ref is eaten before other.
+func (ref MealRef) Before(other MealRef) bool {
+	date, otherDate := calendarDay(ref.Date), calendarDay(other.Date)
+	if !date.Equal(otherDate) {
+		return date.Before(otherDate)
+	}
+
+	return slices.Index(MealSlots, ref.Slot) < slices.Index(MealSlots, other.Slot)
+}
+
+// Leftovers marks a slot as eating what was cooked for an earlier meal, which
+// is cooked for both.
+type Leftovers struct {
+	Slot MealSlot `json:"slot"`
+	Of   MealRef  `json:"of"`
+}
+
+// LeftoversUse is a later meal eating the leftovers of a slot.
+type LeftoversUse struct {
+	// Slot is the slot the meal is cooked for.
+	Slot MealSlot
+	// At is the meal eating the leftovers.
+	At MealRef
+	// Servings are the servings cooked for the later meal.
+	Servings int
+}
+
+// LeftoversOf returns the meal slot eats the leftovers of, or nil if it is
+// cooked for.
+func (mealDay MealDay) LeftoversOf(slot MealSlot) *MealRef {
+	for _, leftovers := range mealDay.Leftovers {
+		if leftovers.Slot == slot {
+			of := leftovers.Of
+			return &of
+		}
+	}
+
+	return nil
+}
+
+// LeftoversUsesOf returns the later meals eating the leftovers of slot.
+func (mealDay MealDay) LeftoversUsesOf(slot MealSlot) []LeftoversUse {
+	uses := make([]LeftoversUse, 0)
+	for _, use := range mealDay.LeftoversUses {
+		if use.Slot == slot {
+			uses = append(uses, use)
+		}
+	}
+
+	return uses
+}
+
+// CookedServings returns the servings to cook for slot: the ones eaten in the
+// slot and the ones kept for later meals. Slots eating leftovers are not
+// cooked for.
+func (mealDay MealDay) CookedServings(slot MealSlot) int {
+	if mealDay.LeftoversOf(slot) != nil {
+		return 0
+	}
+
+	servings := mealDay.Servings(slot)
+	for _, use := range mealDay.LeftoversUsesOf(slot) {
+		servings += use.Servings
+	}
+
+	return servings
+}
+
+// meal returns the name of the meal planned for a cooked slot.
+func (mealDay MealDay) meal(slot MealSlot) string {
+	switch slot {
+	case MealSlotBreakfast:
+		return mealDay.Breakfast
+	case MealSlotLunch:
+		return mealDay.Lunch
+	case MealSlotDinner:
+		return mealDay.Dinner
+	}
+
+	return ""
+}
+
+// setMeal plans name for a cooked slot.
+func (mealDay *MealDay) setMeal(slot MealSlot, name string) {
+	switch slot {
+	case MealSlotBreakfast:
+		mealDay.Breakfast = name
+	case MealSlotLunch:
+		mealDay.Lunch = name
+	case MealSlotDinner:
+		mealDay.Dinner = name
+	}
+}
+
+// leftoversSourceProblem tells what keeps slot of source from having
+// leftovers, or returns "" if it can.
+func leftoversSourceProblem(source MealDay, slot MealSlot) string {
+	switch {
+	case source.meal(slot) == "":
+		return "must be of a planned meal"
+	case source.LeftoversOf(slot) != nil:
+		return "must be of a cooked meal, not of leftovers"
+	}
+
+	return ""
+}
+
+// linkLeftovers plans the meals eating leftovers of the days with the meal
+// they eat and tells the days cooking it. Links to meals that are no longer
+// planned or cooked are dropped, the slot is then cooked for as planned.
+func linkLeftovers(days []MealDay) {
+	indexByDate := make(map[string]int, len(days))
+	for i, day := range days {
+		indexByDate[day.Date.Format("2006-01-02")] = i
+		days[i].LeftoversUses = nil
+	}
+
+	// sources are looked at as stored, before any of their own links are
+	// dropped
+	sources := slices.Clone(days)
+	for i := range days {
+		kept := make([]Leftovers, 0, len(days[i].Leftovers))
+		for _, leftovers := range days[i].Leftovers {
+			sourceIndex, ok := indexByDate[leftovers.Of.Date.Format("2006-01-02")]
+			if !ok || leftoversSourceProblem(sources[sourceIndex], leftovers.Of.Slot) != "" {
+				continue
+			}
+
+			kept = append(kept, leftovers)
+			days[i].setMeal(leftovers.Slot, sources[sourceIndex].meal(leftovers.Of.Slot))
+			days[sourceIndex].LeftoversUses = append(days[sourceIndex].LeftoversUses, LeftoversUse{
+				Slot:     leftovers.Of.Slot,
+				At:       MealRef{Date: days[i].Date, Slot: leftovers.Slot},
+				Servings: days[i].Servings(leftovers.Slot),
+			})
+		}
+
+		if len(kept) == 0 {
+			kept = nil
+		}
+		days[i].Leftovers = kept
+	}
+}
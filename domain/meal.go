@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -13,88 +15,483 @@ type MealDay struct {
 	Lunch     string
 	Dinner    string
 	Snacks    []string
+	// Attendance lists the slots not served to the whole household, at most
+	// one per slot.
+	Attendance []Attendance
+	// Leftovers lists the slots eating what was cooked for an earlier meal,
+	// at most one per slot. Their meal is the one they eat the leftovers of.
+	Leftovers []Leftovers
+	// Household is who the meals are planned for. It is not stored with the
+	// day, the MealDayService sets it on the days it returns.
+	Household Household `json:"-"`
+	// LeftoversUses lists the later meals eating leftovers of the day. They
+	// are not stored with the day, the MealDayService finds them.
+	LeftoversUses []LeftoversUse `json:"-"`
+	// Version is incremented on every update and is 0 for days that were never
+	// saved. Updates must carry the version they are based on.
+	Version int
 }
 
-var MealNotFound = errors.New("meal: not found")
+// IsPlanned reports whether any meal of the day is planned.
+func (mealDay MealDay) IsPlanned() bool {
+	if mealDay.Breakfast != "" || mealDay.Lunch != "" || mealDay.Dinner != "" {
+		return true
+	}
+
+	for _, snack := range mealDay.Snacks {
+		if snack != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Attendance is who eats a slot of a meal day, if it is not the whole
+// household.
+type Attendance struct {
+	Slot MealSlot `json:"slot"`
+	// Servings overrides the number of servings, e.g. for guests. 0 serves
+	// one for every member of the household eating.
+	Servings int `json:"servings,omitempty"`
+	// Absent are the members of the household eating elsewhere.
+	Absent []string `json:"absent,omitempty"`
+}
+
+// AttendanceOf returns who eats slot.
+func (mealDay MealDay) AttendanceOf(slot MealSlot) Attendance {
+	for _, attendance := range mealDay.Attendance {
+		if attendance.Slot == slot {
+			return attendance
+		}
+	}
+
+	return Attendance{Slot: slot}
+}
+
+// ServesHousehold reports whether slot is served to the whole household.
+func (mealDay MealDay) ServesHousehold(slot MealSlot) bool {
+	attendance := mealDay.AttendanceOf(slot)
+	return attendance.Servings == 0 && len(attendance.Absent) == 0
+}
+
+// Servings returns the number of servings planned for slot: the ones set for
+// it, or else one for every member of the household not eating elsewhere.
+func (mealDay MealDay) Servings(slot MealSlot) int {
+	attendance := mealDay.AttendanceOf(slot)
+	if attendance.Servings > 0 {
+		return attendance.Servings
+	}
+
+	return max(mealDay.Household.Size()-len(attendance.Absent), 0)
+}
+
+// PlannedMeal is a meal planned for a slot of a day.
+type PlannedMeal struct {
+	Slot MealSlot
+	Name string
+	// Servings is the number of servings planned for the slot, every snack
+	// is served to everyone eating snacks.
+	Servings int
+	// Cooked is the number of servings to cook, with the ones for later
+	// meals eating the leftovers. It is 0 for meals eating leftovers.
+	Cooked int
+	// LeftoversOf is the meal whose leftovers are eaten, if any.
+	LeftoversOf *MealRef
+}
+
+// PlannedMeals lists the meals of the day in the order of the day, leaving out
+// empty slots.
+func (mealDay MealDay) PlannedMeals() []PlannedMeal {
+	meals := make([]PlannedMeal, 0, 3+len(mealDay.Snacks))
+
+	add := func(slot MealSlot, name string) {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			meals = append(meals, PlannedMeal{
+				Slot:        slot,
+				Name:        name,
+				Servings:    mealDay.Servings(slot),
+				Cooked:      mealDay.CookedServings(slot),
+				LeftoversOf: mealDay.LeftoversOf(slot),
+			})
+		}
+	}
+
+	add(MealSlotBreakfast, mealDay.Breakfast)
+	add(MealSlotLunch, mealDay.Lunch)
+	add(MealSlotDinner, mealDay.Dinner)
+	for _, snack := range mealDay.Snacks {
+		add(MealSlotSnack, snack)
+	}
+
+	return meals
+}
+
+var (
+	MealNotFound = NewError(ErrorKindNotFound, "meal: not found")
+	MealConflict = NewError(ErrorKindConflict, "meal: modified concurrently")
+)
 
 type MealDayRepository interface {
 	FindByDate(ctx context.Context, date time.Time) (MealDay, error)
 	FindByDateRange(ctx context.Context, start, end time.Time) ([]MealDay, error)
 	Create(ctx context.Context, mealDay MealDay) (MealDay, error)
 	Update(ctx context.Context, mealDay MealDay) (MealDay, error)
+	Delete(ctx context.Context, mealDay MealDay) error
+}
+
+type MealDayChange struct {
+	ID        int64
+	Action    HistoryAction
+	Author    string
+	ChangedAt time.Time
+	Old       *MealDay
+	New       *MealDay
 }
 
 type MealDayService struct {
 	repository MealDayRepository
+	history    HistoryRepository
+	// transactor stores a change together with its history entry.
+	transactor Transactor
+	events     EventPublisher
+	household  Household
+}
+
+func NewMealDayService(repository MealDayRepository, history HistoryRepository, transactor Transactor, events EventPublisher, household Household) *MealDayService {
+	return &MealDayService{repository: repository, history: history, transactor: transactor, events: events, household: household}
 }
 
-func NewMealDayService(repository MealDayRepository) *MealDayService {
-	return &MealDayService{repository: repository}
+// Household returns who the meals are planned for.
+func (service *MealDayService) Household() Household {
+	return service.household
 }
 
+// FindByDateRange returns one meal day for every calendar day from start to
+// end, both inclusive, in ascending order. Days without a plan are empty.
+// Leftovers are linked with the meals they are cooked for.
 func (service *MealDayService) FindByDateRange(ctx context.Context, start, end time.Time) ([]MealDay, error) {
-	slog.Info("Finding meals by date range", slog.String("start", start.Format("2006-01-02")), slog.String("end", end.Format("2006-01-02")))
+	start, end = calendarDay(start), calendarDay(end)
+	slog.InfoContext(ctx, "Finding meals by date range", slog.String("start", start.Format("2006-01-02")), slog.String("end", end.Format("2006-01-02")))
 
-	numberOfDays := int64(end.Sub(start).Hours() / 24)
-	meals := make([]MealDay, 0, numberOfDays)
+	if end.Before(start) {
+		return []MealDay{}, nil
+	}
 
-	dbMeals, err := service.repository.FindByDateRange(ctx, start, end)
+	// leftovers are eaten at most MaxLeftoversDays after cooking, the days
+	// that far around the range are needed to link them
+	windowStart, windowEnd := start.AddDate(0, 0, -MaxLeftoversDays), end.AddDate(0, 0, MaxLeftoversDays)
+	dbMeals, err := service.repository.FindByDateRange(ctx, windowStart, windowEnd)
 	if err != nil {
 		return nil, err
 	}
 
-	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
-		meal := MealDay{
-			Date: day,
-		}
+	meals := service.fillDays(windowStart, windowEnd, dbMeals)
+	linkLeftovers(meals)
 
-		for _, dbMeal := range dbMeals {
-			if dbMeal.Date.Format("2006-01-02") == day.Format("2006-01-02") {
-				meal = dbMeal
+	return meals[MaxLeftoversDays : len(meals)-MaxLeftoversDays], nil
+}
+
+// fillDays returns one meal day of the household for every calendar day from
+// start to end, taking the planned ones from mealDays.
+func (service *MealDayService) fillDays(start, end time.Time, mealDays []MealDay) []MealDay {
+	mealsByDate := make(map[string]MealDay, len(mealDays))
+	for _, mealDay := range mealDays {
+		mealsByDate[mealDay.Date.Format("2006-01-02")] = mealDay
+	}
+
+	meals := make([]MealDay, 0, daysBetween(start, end))
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		meal, ok := mealsByDate[day.Format("2006-01-02")]
+		if !ok {
+			meal = MealDay{
+				Date: day,
 			}
 		}
 
+		meal.Household = service.household
 		meals = append(meals, meal)
 	}
 
-	return meals, nil
+	return meals
+}
+
+func (service *MealDayService) CountPlannedDays(ctx context.Context, start, end time.Time) (int, error) {
+	meals, err := service.repository.FindByDateRange(ctx, start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, meal := range meals {
+		if meal.IsPlanned() {
+			count++
+		}
+	}
+
+	return count, nil
 }
 
+// FindByDate returns the meal day at date, which is empty if nothing is
+// planned. Leftovers are linked with the meals they are cooked for.
 func (service *MealDayService) FindByDate(ctx context.Context, date time.Time) (MealDay, error) {
-	slog.Info("Finding meals by date", slog.String("date", date.Format("2006-01-02")))
+	slog.InfoContext(ctx, "Finding meals by date", slog.String("date", date.Format("2006-01-02")))
 
 	meal, err := service.repository.FindByDate(ctx, date)
-	if errors.Is(MealNotFound, err) {
-		return MealDay{
+	if errors.Is(err, MealNotFound) {
+		meal = MealDay{
 			Date: date,
-		}, nil
+		}
+	} else if err != nil {
+		return MealDay{}, err
 	}
 
+	day := calendarDay(date)
+	start, end := day.AddDate(0, 0, -MaxLeftoversDays), day.AddDate(0, 0, MaxLeftoversDays)
+	neighbours, err := service.repository.FindByDateRange(ctx, start, end)
 	if err != nil {
 		return MealDay{}, err
 	}
 
-	return meal, nil
+	meals := service.fillDays(start, end, neighbours)
+	meal.Household = service.household
+	meals[MaxLeftoversDays] = meal
+	linkLeftovers(meals)
+
+	return meals[MaxLeftoversDays], nil
 }
 
 func (service *MealDayService) Upsert(ctx context.Context, mealDay MealDay) (MealDay, error) {
-	slog.Info("Upserting meal", slog.String("date", mealDay.Date.Format("2006-01-02")))
+	slog.InfoContext(ctx, "Upserting meal", slog.String("date", mealDay.Date.Format("2006-01-02")))
+
+	mealDay.Household = service.household
+	mealDay.Attendance = slices.DeleteFunc(slices.Clone(mealDay.Attendance), func(attendance Attendance) bool {
+		return attendance.Servings == 0 && len(attendance.Absent) == 0
+	})
+
+	err := mealDay.Validate()
+	if err != nil {
+		return MealDay{}, err
+	}
+
+	err = service.planLeftovers(ctx, &mealDay)
+	if err != nil {
+		return MealDay{}, err
+	}
 
 	meal, err := service.repository.FindByDate(ctx, mealDay.Date)
 	if err != nil && !errors.Is(err, MealNotFound) {
 		return MealDay{}, err
 	}
 
+	if meal.Version != mealDay.Version {
+		slog.InfoContext(ctx, "Meal was modified concurrently", slog.String("date", mealDay.Date.Format("2006-01-02")), slog.Int("version", meal.Version), slog.Int("expectedVersion", mealDay.Version))
+		return MealDay{}, MealConflict
+	}
+
 	if errors.Is(err, MealNotFound) {
-		slog.Debug("Meal does not exist", slog.String("date", mealDay.Date.Format("2006-01-02")))
-		slog.Info("Creating meal", slog.String("date", mealDay.Date.Format("2006-01-02")))
+		slog.DebugContext(ctx, "Meal does not exist", slog.String("date", mealDay.Date.Format("2006-01-02")))
+		slog.InfoContext(ctx, "Creating meal", slog.String("date", mealDay.Date.Format("2006-01-02")))
+
+		err = service.transactor.WithTx(ctx, func(ctx context.Context) error {
+			created, err := service.repository.Create(ctx, mealDay)
+			if err != nil {
+				return err
+			}
+
+			return service.recordHistory(ctx, mealDay.Date, HistoryActionCreate, nil, created)
+		})
+		if err != nil {
+			return MealDay{}, err
+		}
+
+		return service.publishChanged(ctx, mealDay.Date)
+	}
+
+	slog.InfoContext(ctx, "Updating meal", slog.String("date", mealDay.Date.Format("2006-01-02")))
+
+	err = service.transactor.WithTx(ctx, func(ctx context.Context) error {
+		updated, err := service.repository.Update(ctx, mealDay)
+		if err != nil {
+			return err
+		}
+
+		return service.recordHistory(ctx, mealDay.Date, HistoryActionUpdate, meal, updated)
+	})
+	if err != nil {
+		return MealDay{}, err
+	}
+
+	return service.publishChanged(ctx, mealDay.Date)
+}
 
-		meal, err = service.repository.Create(ctx, mealDay)
-	} else {
-		slog.Info("Updating meal", slog.String("date", mealDay.Date.Format("2006-01-02")))
+func (service *MealDayService) Delete(ctx context.Context, date time.Time) error {
+	slog.InfoContext(ctx, "Deleting meal", slog.String("date", date.Format("2006-01-02")))
 
-		meal, err = service.repository.Update(ctx, mealDay)
+	meal, err := service.repository.FindByDate(ctx, date)
+	if errors.Is(err, MealNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = service.transactor.WithTx(ctx, func(ctx context.Context) error {
+		err := service.repository.Delete(ctx, meal)
+		if err != nil {
+			return err
+		}
+
+		return service.recordHistory(ctx, date, HistoryActionDelete, meal, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = service.publishChanged(ctx, date)
+	return err
+}
+
+func (service *MealDayService) FindHistory(ctx context.Context, date time.Time) ([]MealDayChange, error) {
+	slog.InfoContext(ctx, "Finding meal history", slog.String("date", date.Format("2006-01-02")))
+
+	entries, err := service.history.FindByEntityAndDate(ctx, HistoryEntityMealDay, date)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]MealDayChange, 0, len(entries))
+	for _, entry := range entries {
+		change := MealDayChange{
+			ID:        entry.ID,
+			Action:    entry.Action,
+			Author:    entry.Author,
+			ChangedAt: entry.ChangedAt,
+		}
+
+		change.Old, err = decodeHistoryValue[MealDay](entry.OldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		change.New, err = decodeHistoryValue[MealDay](entry.NewValue)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// Restore resets the meal day to the state it had right after the given
+// history entry. Restoring is itself recorded as a change.
+func (service *MealDayService) Restore(ctx context.Context, date time.Time, historyID int64) (MealDay, error) {
+	slog.InfoContext(ctx, "Restoring meal", slog.String("date", date.Format("2006-01-02")), slog.Int64("historyID", historyID))
+
+	entry, err := service.history.FindByID(ctx, historyID)
+	if err != nil {
+		return MealDay{}, err
+	}
+
+	if entry.Entity != HistoryEntityMealDay || entry.Date.Format("2006-01-02") != date.Format("2006-01-02") {
+		return MealDay{}, HistoryEntryNotFound
+	}
+
+	restored, err := decodeHistoryValue[MealDay](entry.NewValue)
+	if err != nil {
+		return MealDay{}, err
+	}
+
+	if restored == nil {
+		return MealDay{Date: date, Household: service.household}, service.Delete(ctx, date)
+	}
+
+	current, err := service.FindByDate(ctx, date)
+	if err != nil {
+		return MealDay{}, err
+	}
+
+	restored.Version = current.Version
+
+	return service.Upsert(ctx, *restored)
+}
+
+// planLeftovers plans the meals the slots of mealDay eat the leftovers of,
+// which must be cooked.
+func (service *MealDayService) planLeftovers(ctx context.Context, mealDay *MealDay) error {
+	v := validator{}
+	for _, leftovers := range mealDay.Leftovers {
+		source := *mealDay
+		if !calendarDay(leftovers.Of.Date).Equal(calendarDay(mealDay.Date)) {
+			found, err := service.repository.FindByDate(ctx, leftovers.Of.Date)
+			if err != nil && !errors.Is(err, MealNotFound) {
+				return err
+			}
+
+			source = found
+		}
+
+		problem := leftoversSourceProblem(source, leftovers.Of.Slot)
+		v.check(problem == "", string(leftovers.Slot)+"-leftovers", problem)
+		if problem == "" {
+			mealDay.setMeal(leftovers.Slot, source.meal(leftovers.Of.Slot))
+		}
+	}
+
+	return v.err()
+}
+
+// publishChanged publishes the meal day at date and the later days eating its
+// leftovers, whose meals change with it. It returns the day at date.
+func (service *MealDayService) publishChanged(ctx context.Context, date time.Time) (MealDay, error) {
+	end := date.AddDate(0, 0, MaxLeftoversDays)
+	stored, err := service.repository.FindByDateRange(ctx, date, end)
+	if err != nil {
+		return MealDay{}, err
+	}
+
+	// links dropped because the meal is gone are only in the stored days
+	eatingLeftovers := map[string]bool{}
+	for _, mealDay := range stored {
+		for _, leftovers := range mealDay.Leftovers {
+			if calendarDay(leftovers.Of.Date).Equal(calendarDay(date)) {
+				eatingLeftovers[mealDay.Date.Format("2006-01-02")] = true
+			}
+		}
+	}
+
+	mealDays, err := service.FindByDateRange(ctx, date, end)
+	if err != nil {
+		return MealDay{}, err
+	}
+
+	for i, mealDay := range mealDays {
+		if i == 0 || eatingLeftovers[mealDay.Date.Format("2006-01-02")] {
+			service.publish(mealDay)
+		}
+	}
+
+	return mealDays[0], nil
+}
+
+func (service *MealDayService) publish(mealDay MealDay) {
+	service.events.Publish(Event{
+		Type:    EventMealDayChanged,
+		Date:    mealDay.Date,
+		MealDay: mealDay,
+	})
+}
+
+func (service *MealDayService) recordHistory(ctx context.Context, date time.Time, action HistoryAction, oldValue, newValue any) error {
+	entry, err := newHistoryEntry(ctx, HistoryEntityMealDay, date, action, oldValue, newValue)
+	if err != nil {
+		return err
 	}
 
-	return meal, err
+	_, err = service.history.Append(ctx, entry)
+	return err
 }
@@ -0,0 +1,342 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"meal-planning/domain"
+	"meal-planning/memory"
+	"testing"
+	"time"
+)
+
+func newMealDayService(repository domain.MealDayRepository) *domain.MealDayService {
+	return domain.NewMealDayService(repository, memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus(), domain.Household{People: 1})
+}
+
+func TestMealDayServiceFindByDateRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   []domain.MealDay
+		start    time.Time
+		end      time.Time
+		expected []string
+	}{
+		{
+			name:     "fills a week without plans",
+			start:    time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, time.June, 9, 0, 0, 0, 0, time.UTC),
+			expected: []string{"Mon 2024-06-03 ", "Tue 2024-06-04 ", "Wed 2024-06-05 ", "Thu 2024-06-06 ", "Fri 2024-06-07 ", "Sat 2024-06-08 ", "Sun 2024-06-09 "},
+		},
+		{
+			name: "keeps planned days in order",
+			stored: []domain.MealDay{
+				{Date: time.Date(2024, time.June, 7, 0, 0, 0, 0, time.UTC), Dinner: "Pizza"},
+				{Date: time.Date(2024, time.June, 2, 0, 0, 0, 0, time.UTC), Dinner: "Before"},
+				{Date: time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC), Dinner: "Pasta"},
+				{Date: time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC), Dinner: "After"},
+			},
+			start:    time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, time.June, 9, 0, 0, 0, 0, time.UTC),
+			expected: []string{"Mon 2024-06-03 Pasta", "Tue 2024-06-04 ", "Wed 2024-06-05 ", "Thu 2024-06-06 ", "Fri 2024-06-07 Pizza", "Sat 2024-06-08 ", "Sun 2024-06-09 "},
+		},
+		{
+			name: "includes the last day regardless of the time of day",
+			stored: []domain.MealDay{
+				{Date: time.Date(2024, time.June, 9, 0, 0, 0, 0, time.UTC), Dinner: "Roast"},
+			},
+			start:    time.Date(2024, time.June, 3, 20, 15, 0, 0, time.UTC),
+			end:      time.Date(2024, time.June, 9, 7, 45, 0, 0, time.UTC),
+			expected: []string{"Mon 2024-06-03 ", "Tue 2024-06-04 ", "Wed 2024-06-05 ", "Thu 2024-06-06 ", "Fri 2024-06-07 ", "Sat 2024-06-08 ", "Sun 2024-06-09 Roast"},
+		},
+		{
+			name:     "uses the calendar day of the given location",
+			start:    time.Date(2024, time.June, 3, 23, 30, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+			end:      time.Date(2024, time.June, 4, 0, 30, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+			expected: []string{"Mon 2024-06-03 ", "Tue 2024-06-04 "},
+		},
+		{
+			name:     "returns a single day",
+			start:    time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC),
+			expected: []string{"Wed 2024-06-05 "},
+		},
+		{
+			name:     "returns nothing when end is before start",
+			start:    time.Date(2024, time.June, 9, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC),
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := memory.NewMealDayRepository()
+			for _, mealDay := range test.stored {
+				_, err := repository.Create(context.Background(), mealDay)
+				if err != nil {
+					t.Fatalf("creating meal day: %v", err)
+				}
+			}
+
+			meals, err := newMealDayService(repository).FindByDateRange(context.Background(), test.start, test.end)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actual := make([]string, len(meals))
+			for i, meal := range meals {
+				actual[i] = meal.Date.Format("Mon 2006-01-02 ") + meal.Dinner
+			}
+
+			if fmt.Sprint(actual) != fmt.Sprint(test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+// wrappingMealDayRepository wraps errors like repositories adding context to
+// their errors do.
+type wrappingMealDayRepository struct {
+	domain.MealDayRepository
+}
+
+func (r wrappingMealDayRepository) FindByDate(ctx context.Context, date time.Time) (domain.MealDay, error) {
+	mealDay, err := r.MealDayRepository.FindByDate(ctx, date)
+	if err != nil {
+		return domain.MealDay{}, fmt.Errorf("finding meal day: %w", err)
+	}
+
+	return mealDay, nil
+}
+
+func TestMealDayServiceFindByDate(t *testing.T) {
+	date := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		repository domain.MealDayRepository
+	}{
+		{"plain errors", memory.NewMealDayRepository()},
+		{"wrapped errors", wrappingMealDayRepository{memory.NewMealDayRepository()}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mealDay, err := newMealDayService(test.repository).FindByDate(context.Background(), date)
+			if err != nil {
+				t.Fatalf("expected an empty meal day, got error %v", err)
+			}
+
+			if !mealDay.Date.Equal(date) || mealDay.IsPlanned() {
+				t.Errorf("expected an empty meal day for %s, got %+v", date, mealDay)
+			}
+		})
+	}
+}
+
+func TestMealDayServiceUpsert(t *testing.T) {
+	ctx := domain.WithUser(context.Background(), "alice")
+	date := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+	service := newMealDayService(memory.NewMealDayRepository())
+
+	created, err := service.Upsert(ctx, domain.MealDay{Date: date, Dinner: "Pasta"})
+	if err != nil {
+		t.Fatalf("creating meal day: %v", err)
+	}
+
+	_, err = service.Upsert(ctx, domain.MealDay{Date: date, Dinner: "Pizza"})
+	if !errors.Is(err, domain.MealConflict) {
+		t.Errorf("expected a conflict for a stale version, got %v", err)
+	}
+
+	created.Dinner = "Pizza"
+	_, err = service.Upsert(ctx, created)
+	if err != nil {
+		t.Fatalf("updating meal day: %v", err)
+	}
+
+	changes, err := service.FindHistory(ctx, date)
+	if err != nil {
+		t.Fatalf("finding history: %v", err)
+	}
+
+	if len(changes) != 2 || changes[0].Action != domain.HistoryActionUpdate || changes[1].Action != domain.HistoryActionCreate {
+		t.Fatalf("expected an update and a create, got %+v", changes)
+	}
+
+	if changes[0].Author != "alice" || changes[0].Old.Dinner != "Pasta" || changes[0].New.Dinner != "Pizza" {
+		t.Errorf("unexpected change %+v", changes[0])
+	}
+}
+
+// failingHistoryRepository cannot append to the history.
+type failingHistoryRepository struct {
+	domain.HistoryRepository
+}
+
+func (r failingHistoryRepository) Append(ctx context.Context, entry domain.HistoryEntry) (domain.HistoryEntry, error) {
+	return domain.HistoryEntry{}, errors.New("history is read-only")
+}
+
+// expectNoEvent fails if an event was published to events.
+func expectNoEvent(t *testing.T, events <-chan domain.Event) {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		t.Errorf("expected no event, got %+v", event)
+	default:
+	}
+}
+
+func TestMealDayServicePublishesOnlyRecordedChanges(t *testing.T) {
+	date := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+	bus := domain.NewEventBus()
+	events, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	service := domain.NewMealDayService(memory.NewMealDayRepository(), failingHistoryRepository{memory.NewHistoryRepository()}, memory.NewTransactor(), bus, domain.Household{People: 1})
+
+	_, err := service.Upsert(context.Background(), domain.MealDay{Date: date, Dinner: "Pasta"})
+	if err == nil {
+		t.Fatalf("expected the failing history to fail the upsert")
+	}
+
+	expectNoEvent(t, events)
+}
+
+func TestMealDayServings(t *testing.T) {
+	household := domain.Household{Members: []string{"Alex", "Sam", "Kim"}}
+	mealDay := domain.MealDay{
+		Household: household,
+		Breakfast: "Porridge",
+		Lunch:     "Soup",
+		Dinner:    "Pasta",
+		Attendance: []domain.Attendance{
+			{Slot: domain.MealSlotLunch, Absent: []string{"Alex"}},
+			{Slot: domain.MealSlotDinner, Servings: 5, Absent: []string{"Sam"}},
+		},
+	}
+
+	expected := map[domain.MealSlot]int{domain.MealSlotBreakfast: 3, domain.MealSlotLunch: 2, domain.MealSlotDinner: 5}
+	for _, meal := range mealDay.PlannedMeals() {
+		if meal.Servings != expected[meal.Slot] {
+			t.Errorf("expected %d servings of %s, got %d", expected[meal.Slot], meal.Slot, meal.Servings)
+		}
+	}
+
+	if !mealDay.ServesHousehold(domain.MealSlotBreakfast) || mealDay.ServesHousehold(domain.MealSlotLunch) {
+		t.Errorf("expected only breakfast to serve the household")
+	}
+
+	if servings := (domain.MealDay{Lunch: "Soup"}).Servings(domain.MealSlotLunch); servings != 1 {
+		t.Errorf("expected one serving without a household, got %d", servings)
+	}
+}
+
+func TestMealDayServiceUpsertAttendance(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2024, time.June, 4, 0, 0, 0, 0, time.UTC)
+	household := domain.Household{Members: []string{"Alex", "Sam"}}
+	service := domain.NewMealDayService(memory.NewMealDayRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus(), household)
+
+	_, err := service.Upsert(ctx, domain.MealDay{Date: date, Lunch: "Soup", Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Absent: []string{"Kim"}}}})
+	expectInvalidFields(t, err, []string{"lunch-absent"})
+
+	_, err = service.Upsert(ctx, domain.MealDay{Date: date, Lunch: "Soup", Attendance: []domain.Attendance{
+		{Slot: domain.MealSlotBreakfast},
+		{Slot: domain.MealSlotLunch, Absent: []string{"Alex"}},
+	}})
+	if err != nil {
+		t.Fatalf("saving meal day: %v", err)
+	}
+
+	found, err := service.FindByDate(ctx, date)
+	if err != nil {
+		t.Fatalf("finding meal day: %v", err)
+	}
+
+	if len(found.Attendance) != 1 || found.Servings(domain.MealSlotLunch) != 1 || found.Servings(domain.MealSlotDinner) != 2 {
+		t.Errorf("expected only the lunch attendance with Alex eating out, got %+v", found.Attendance)
+	}
+}
+
+func TestMealDayServiceLeftovers(t *testing.T) {
+	ctx := context.Background()
+	sunday := time.Date(2024, time.June, 9, 0, 0, 0, 0, time.UTC)
+	monday := sunday.AddDate(0, 0, 1)
+	service := domain.NewMealDayService(memory.NewMealDayRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus(), domain.Household{People: 2})
+	sundayDinner := domain.MealRef{Date: sunday, Slot: domain.MealSlotDinner}
+
+	_, err := service.Upsert(ctx, domain.MealDay{Date: monday, Leftovers: []domain.Leftovers{{Slot: domain.MealSlotLunch, Of: sundayDinner}}})
+	expectInvalidFields(t, err, []string{"lunch-leftovers"})
+
+	cooked, err := service.Upsert(ctx, domain.MealDay{Date: sunday, Dinner: "Chili"})
+	if err != nil {
+		t.Fatalf("planning the cooked meal: %v", err)
+	}
+
+	leftovers, err := service.Upsert(ctx, domain.MealDay{
+		Date:       monday,
+		Lunch:      "whatever was typed",
+		Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Servings: 1}},
+		Leftovers:  []domain.Leftovers{{Slot: domain.MealSlotLunch, Of: sundayDinner}},
+	})
+	if err != nil {
+		t.Fatalf("planning the leftovers: %v", err)
+	}
+
+	if leftovers.Lunch != "Chili" || leftovers.CookedServings(domain.MealSlotLunch) != 0 {
+		t.Errorf("expected the leftovers to be Chili and not cooked for, got %+v", leftovers)
+	}
+
+	_, err = service.Upsert(ctx, domain.MealDay{Date: monday.AddDate(0, 0, 1), Leftovers: []domain.Leftovers{{Slot: domain.MealSlotLunch, Of: domain.MealRef{Date: monday, Slot: domain.MealSlotLunch}}}})
+	expectInvalidFields(t, err, []string{"lunch-leftovers"})
+
+	days, err := service.FindByDateRange(ctx, sunday, sunday)
+	if err != nil {
+		t.Fatalf("finding the cooked meal: %v", err)
+	}
+
+	uses := days[0].LeftoversUsesOf(domain.MealSlotDinner)
+	if len(uses) != 1 || !uses[0].At.Date.Equal(monday) || days[0].CookedServings(domain.MealSlotDinner) != 3 {
+		t.Errorf("expected to cook 3 servings with 1 for Monday lunch, got %+v", days[0].LeftoversUses)
+	}
+
+	cooked.Dinner = "Pizza"
+	_, err = service.Upsert(ctx, cooked)
+	if err != nil {
+		t.Fatalf("changing the cooked meal: %v", err)
+	}
+
+	found, err := service.FindByDate(ctx, monday)
+	if err != nil {
+		t.Fatalf("finding the leftovers: %v", err)
+	}
+
+	if found.Lunch != "Pizza" {
+		t.Errorf("expected the leftovers to follow the cooked meal, got %q", found.Lunch)
+	}
+
+	cooked, err = service.FindByDate(ctx, sunday)
+	if err != nil {
+		t.Fatalf("finding the cooked meal: %v", err)
+	}
+
+	cooked.Dinner = ""
+	_, err = service.Upsert(ctx, cooked)
+	if err != nil {
+		t.Fatalf("clearing the cooked meal: %v", err)
+	}
+
+	found, err = service.FindByDate(ctx, monday)
+	if err != nil {
+		t.Fatalf("finding the leftovers: %v", err)
+	}
+
+	if found.LeftoversOf(domain.MealSlotLunch) != nil || found.CookedServings(domain.MealSlotLunch) != 1 {
+		t.Errorf("expected the lunch to be cooked for without the dinner, got %+v", found)
+	}
+}
@@ -11,12 +11,25 @@ const (
 	CaloriesPerKilogramBodyFat = 7700
 )
 
-var NutritionNotFound = errors.New("nutrition not found")
+var (
+	NutritionNotFound = NewError(ErrorKindNotFound, "nutrition not found")
+	NutritionConflict = NewError(ErrorKindConflict, "nutrition modified concurrently")
+)
 
 type Nutrition struct {
 	Date     time.Time
 	Calories int
-	Weight   int
+	// CaloriesFromDiary is set if Calories is the sum of the food diary of
+	// the day. Otherwise the calories were entered by hand as a total.
+	CaloriesFromDiary bool
+	// Protein, Carbs and Fat are the grams eaten according to the food diary.
+	Protein int
+	Carbs   int
+	Fat     int
+	Weight  int
+	// Version is incremented on every update and is 0 for entries that were
+	// never saved. Updates must carry the version they are based on.
+	Version int
 }
 
 type AverageNutrition struct {
@@ -36,40 +49,60 @@ type NutritionRepository interface {
 	FindByDate(ctx context.Context, date time.Time) (Nutrition, error)
 	FindByDateRange(ctx context.Context, start, end time.Time) ([]Nutrition, error)
 	FindAverageNutrition(ctx context.Context, start, end time.Time) (AverageNutrition, error)
+	FindLatestWeight(ctx context.Context) (Nutrition, error)
 	Create(ctx context.Context, n Nutrition) (Nutrition, error)
 	Update(ctx context.Context, n Nutrition) (Nutrition, error)
 	Delete(ctx context.Context, n Nutrition) error
 }
 
+type NutritionChange struct {
+	ID        int64
+	Action    HistoryAction
+	Author    string
+	ChangedAt time.Time
+	Old       *Nutrition
+	New       *Nutrition
+}
+
+// diaryRefreshAttempts is how often the totals of a day are recomputed after
+// a diary change when the entry is modified concurrently.
+const diaryRefreshAttempts = 3
+
 type NutritionService struct {
 	repository NutritionRepository
+	diary      DiaryRepository
+	history    HistoryRepository
+	// transactor stores a change together with its history entry.
+	transactor Transactor
+	events     EventPublisher
 }
 
-func NewNutritionService(repository NutritionRepository) *NutritionService {
-	return &NutritionService{repository: repository}
+func NewNutritionService(repository NutritionRepository, diary DiaryRepository, history HistoryRepository, transactor Transactor, events EventPublisher) *NutritionService {
+	return &NutritionService{repository: repository, diary: diary, history: history, transactor: transactor, events: events}
 }
 
+// FindByDateRange returns one nutrition entry for every calendar day from start
+// to end, both inclusive, in ascending order. Days without an entry are empty.
 func (service *NutritionService) FindByDateRange(ctx context.Context, start, end time.Time) ([]Nutrition, error) {
-	slog.Info("Finding nutrition by date range", slog.String("start", start.Format("2006-01-02")), slog.String("end", end.Format("2006-01-02")))
-
-	numberOfDays := int64(end.Sub(start).Hours() / 24)
-	nutritionList := make([]Nutrition, 0, numberOfDays)
+	start, end = calendarDay(start), calendarDay(end)
+	slog.InfoContext(ctx, "Finding nutrition by date range", slog.String("start", start.Format("2006-01-02")), slog.String("end", end.Format("2006-01-02")))
 
 	dbNutritionList, err := service.repository.FindByDateRange(ctx, start, end)
 	if err != nil {
 		return nil, err
 	}
 
-	for day := end; day.After(start); day = day.AddDate(0, 0, -1) {
-		nutrition := Nutrition{
-			Date:     day,
-			Calories: 0,
-			Weight:   0,
-		}
+	nutritionByDate := make(map[string]Nutrition, len(dbNutritionList))
+	for _, dbNutrition := range dbNutritionList {
+		nutritionByDate[dbNutrition.Date.Format("2006-01-02")] = dbNutrition
+	}
 
-		for _, dbNutrition := range dbNutritionList {
-			if dbNutrition.Date.Format("2006-01-02") == day.Format("2006-01-02") {
-				nutrition = dbNutrition
+	nutritionList := make([]Nutrition, 0, daysBetween(start, end))
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		nutrition, ok := nutritionByDate[day.Format("2006-01-02")]
+		if !ok {
+			nutrition = Nutrition{
+				Date: day,
 			}
 		}
 
@@ -80,10 +113,10 @@ func (service *NutritionService) FindByDateRange(ctx context.Context, start, end
 }
 
 func (service *NutritionService) FindByDate(ctx context.Context, date time.Time) (Nutrition, error) {
-	slog.Info("Finding nutrition by date", slog.String("date", date.Format("2006-01-02")))
+	slog.InfoContext(ctx, "Finding nutrition by date", slog.String("date", date.Format("2006-01-02")))
 
-	meal, err := service.repository.FindByDate(ctx, date)
-	if errors.Is(MealNotFound, err) {
+	nutrition, err := service.repository.FindByDate(ctx, date)
+	if errors.Is(err, NutritionNotFound) {
 		return Nutrition{
 			Date: date,
 		}, nil
@@ -93,61 +126,312 @@ func (service *NutritionService) FindByDate(ctx context.Context, date time.Time)
 		return Nutrition{}, err
 	}
 
-	return meal, nil
+	return nutrition, nil
 }
 
+// FindLatestWeight returns the most recent entry with a weight. It returns
+// NutritionNotFound if no weight was ever recorded.
+func (service *NutritionService) FindLatestWeight(ctx context.Context) (Nutrition, error) {
+	return service.repository.FindLatestWeight(ctx)
+}
+
+// Upsert stores the calories and weight of a day. Calories left at 0 are
+// derived from the food diary, anything else overrides it.
 func (service *NutritionService) Upsert(ctx context.Context, nutrition Nutrition) (Nutrition, error) {
-	slog.Info("Upserting dbNutrition", slog.String("date", nutrition.Date.Format("2006-01-02")))
+	slog.InfoContext(ctx, "Upserting dbNutrition", slog.String("date", nutrition.Date.Format("2006-01-02")))
+
+	err := nutrition.Validate(time.Now())
+	if err != nil {
+		return Nutrition{}, err
+	}
 
 	dbNutrition, err := service.repository.FindByDate(ctx, nutrition.Date)
 	if err != nil && !errors.Is(err, NutritionNotFound) {
 		return Nutrition{}, err
 	}
 
-	if errors.Is(err, NutritionNotFound) {
-		slog.Debug("Nutrition does not exist", slog.String("date", nutrition.Date.Format("2006-01-02")))
-		slog.Info("Creating Nutrition", slog.String("date", nutrition.Date.Format("2006-01-02")))
+	if dbNutrition.Version != nutrition.Version {
+		slog.InfoContext(ctx, "Nutrition was modified concurrently", slog.String("date", nutrition.Date.Format("2006-01-02")), slog.Int("version", dbNutrition.Version), slog.Int("expectedVersion", nutrition.Version))
+		return Nutrition{}, NutritionConflict
+	}
+
+	entries, err := service.diary.FindByDate(ctx, nutrition.Date)
+	if err != nil {
+		return Nutrition{}, err
+	}
+
+	return service.store(ctx, dbNutrition, nutrition.applyDiary(entries))
+}
+
+// store creates or updates the entry depending on whether stored was ever
+// saved, then records the change and announces it.
+func (service *NutritionService) store(ctx context.Context, stored, nutrition Nutrition) (Nutrition, error) {
+	if stored.Version == 0 {
+		slog.DebugContext(ctx, "Nutrition does not exist", slog.String("date", nutrition.Date.Format("2006-01-02")))
+		slog.InfoContext(ctx, "Creating Nutrition", slog.String("date", nutrition.Date.Format("2006-01-02")))
+
+		var created Nutrition
+		err := service.transactor.WithTx(ctx, func(ctx context.Context) error {
+			var err error
+			created, err = service.repository.Create(ctx, nutrition)
+			if err != nil {
+				return err
+			}
+
+			return service.recordHistory(ctx, nutrition.Date, HistoryActionCreate, nil, created)
+		})
+		if err != nil {
+			return Nutrition{}, err
+		}
+
+		service.publish(created)
+
+		return created, nil
+	}
+
+	slog.InfoContext(ctx, "Updating Nutrition", slog.String("date", nutrition.Date.Format("2006-01-02")))
+
+	var updated Nutrition
+	err := service.transactor.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		updated, err = service.repository.Update(ctx, nutrition)
+		if err != nil {
+			return err
+		}
 
-		dbNutrition, err = service.repository.Create(ctx, nutrition)
-	} else {
-		slog.Info("Updating Nutrition", slog.String("date", nutrition.Date.Format("2006-01-02")))
+		return service.recordHistory(ctx, nutrition.Date, HistoryActionUpdate, stored, updated)
+	})
+	if err != nil {
+		return Nutrition{}, err
+	}
+
+	service.publish(updated)
+
+	return updated, nil
+}
+
+// FindDiary returns the food diary of the day in the order it was logged.
+func (service *NutritionService) FindDiary(ctx context.Context, date time.Time) ([]DiaryEntry, error) {
+	slog.InfoContext(ctx, "Finding food diary", slog.String("date", date.Format("2006-01-02")))
+
+	return service.diary.FindByDate(ctx, calendarDay(date))
+}
 
-		dbNutrition, err = service.repository.Update(ctx, nutrition)
+// LogFood adds grams of the food to the diary of the day and returns the
+// nutrition of the day with the new totals.
+func (service *NutritionService) LogFood(ctx context.Context, date time.Time, slot MealSlot, food Food, grams float64) (Nutrition, error) {
+	slog.InfoContext(ctx, "Logging food", slog.String("date", date.Format("2006-01-02")), slog.Int64("food", food.ID), slog.Float64("grams", grams))
+
+	return service.logEntry(ctx, newDiaryEntry(date, slot, food, grams))
+}
+
+// LogRecipe adds portions of the recipe to the diary of the day, e.g. for a
+// planned meal that was eaten, and returns the nutrition of the day with the
+// new totals.
+func (service *NutritionService) LogRecipe(ctx context.Context, date time.Time, slot MealSlot, recipe Recipe, portions float64) (Nutrition, error) {
+	slog.InfoContext(ctx, "Logging recipe", slog.String("date", date.Format("2006-01-02")), slog.Int64("recipe", recipe.ID), slog.Float64("portions", portions))
+
+	return service.logEntry(ctx, newRecipeDiaryEntry(date, slot, recipe, portions))
+}
+
+func (service *NutritionService) logEntry(ctx context.Context, entry DiaryEntry) (Nutrition, error) {
+	err := entry.Validate()
+	if err != nil {
+		return Nutrition{}, err
 	}
 
-	return dbNutrition, err
+	_, err = service.diary.Create(ctx, entry)
+	if err != nil {
+		return Nutrition{}, err
+	}
+
+	return service.refreshFromDiary(ctx, entry.Date)
+}
+
+// RemoveDiaryEntry deletes the entry from the diary of the day and returns the
+// nutrition of the day with the new totals.
+func (service *NutritionService) RemoveDiaryEntry(ctx context.Context, date time.Time, id int64) (Nutrition, error) {
+	slog.InfoContext(ctx, "Removing diary entry", slog.String("date", date.Format("2006-01-02")), slog.Int64("id", id))
+
+	date = calendarDay(date)
+
+	err := service.diary.Delete(ctx, date, id)
+	if err != nil {
+		return Nutrition{}, err
+	}
+
+	return service.refreshFromDiary(ctx, date)
+}
+
+// refreshFromDiary recomputes the totals of the day from its diary. The diary
+// is already written, so concurrent changes to the day are retried instead of
+// reported.
+func (service *NutritionService) refreshFromDiary(ctx context.Context, date time.Time) (Nutrition, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := service.FindByDate(ctx, date)
+		if err != nil {
+			return Nutrition{}, err
+		}
+
+		entries, err := service.diary.FindByDate(ctx, date)
+		if err != nil {
+			return Nutrition{}, err
+		}
+
+		refreshed, err := service.store(ctx, current, current.applyDiary(entries))
+		if errors.Is(err, NutritionConflict) && attempt < diaryRefreshAttempts {
+			slog.InfoContext(ctx, "Nutrition was modified concurrently, retrying", slog.String("date", date.Format("2006-01-02")), slog.Int("attempt", attempt))
+			continue
+		}
+
+		return refreshed, err
+	}
 }
 
 func (service *NutritionService) Delete(ctx context.Context, n Nutrition) error {
-	panic("implement me")
+	slog.InfoContext(ctx, "Deleting nutrition", slog.String("date", n.Date.Format("2006-01-02")))
+
+	dbNutrition, err := service.repository.FindByDate(ctx, n.Date)
+	if errors.Is(err, NutritionNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = service.transactor.WithTx(ctx, func(ctx context.Context) error {
+		err := service.repository.Delete(ctx, dbNutrition)
+		if err != nil {
+			return err
+		}
+
+		return service.recordHistory(ctx, n.Date, HistoryActionDelete, dbNutrition, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	service.publish(Nutrition{Date: n.Date})
+
+	return nil
+}
+
+func (service *NutritionService) FindHistory(ctx context.Context, date time.Time) ([]NutritionChange, error) {
+	slog.InfoContext(ctx, "Finding nutrition history", slog.String("date", date.Format("2006-01-02")))
+
+	entries, err := service.history.FindByEntityAndDate(ctx, HistoryEntityNutrition, date)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]NutritionChange, 0, len(entries))
+	for _, entry := range entries {
+		change := NutritionChange{
+			ID:        entry.ID,
+			Action:    entry.Action,
+			Author:    entry.Author,
+			ChangedAt: entry.ChangedAt,
+		}
+
+		change.Old, err = decodeHistoryValue[Nutrition](entry.OldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		change.New, err = decodeHistoryValue[Nutrition](entry.NewValue)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
 }
 
+// Restore resets the nutrition entry to the state it had right after the given
+// history entry. Restoring is itself recorded as a change.
+func (service *NutritionService) Restore(ctx context.Context, date time.Time, historyID int64) (Nutrition, error) {
+	slog.InfoContext(ctx, "Restoring nutrition", slog.String("date", date.Format("2006-01-02")), slog.Int64("historyID", historyID))
+
+	entry, err := service.history.FindByID(ctx, historyID)
+	if err != nil {
+		return Nutrition{}, err
+	}
+
+	if entry.Entity != HistoryEntityNutrition || entry.Date.Format("2006-01-02") != date.Format("2006-01-02") {
+		return Nutrition{}, HistoryEntryNotFound
+	}
+
+	restored, err := decodeHistoryValue[Nutrition](entry.NewValue)
+	if err != nil {
+		return Nutrition{}, err
+	}
+
+	if restored == nil {
+		return Nutrition{Date: date}, service.Delete(ctx, Nutrition{Date: date})
+	}
+
+	current, err := service.FindByDate(ctx, date)
+	if err != nil {
+		return Nutrition{}, err
+	}
+
+	restored.Version = current.Version
+
+	return service.Upsert(ctx, *restored)
+}
+
+func (service *NutritionService) publish(nutrition Nutrition) {
+	service.events.Publish(Event{
+		Type:      EventNutritionChanged,
+		Date:      nutrition.Date,
+		Nutrition: nutrition,
+	})
+}
+
+func (service *NutritionService) recordHistory(ctx context.Context, date time.Time, action HistoryAction, oldValue, newValue any) error {
+	entry, err := newHistoryEntry(ctx, HistoryEntityNutrition, date, action, oldValue, newValue)
+	if err != nil {
+		return err
+	}
+
+	_, err = service.history.Append(ctx, entry)
+	return err
+}
+
+// CalculateTotalDailyEnergyExpenditure estimates the energy expenditure from the
+// average calories eaten between start and end and the change in average
+// weight compared to the week before. Without weights in both periods the
+// weight difference is treated as zero.
 func (service *NutritionService) CalculateTotalDailyEnergyExpenditure(ctx context.Context, start, end time.Time) (TotalDailyEnergyExpenditure, error) {
-	previousPeriodStart := start.Add(-7 * 24 * time.Hour)
-	previousPeriodEnd := previousPeriodStart.Add(end.Sub(start))
+	start, end = calendarDay(start), calendarDay(end)
+	previousPeriodStart := start.AddDate(0, 0, -7)
+	previousPeriodEnd := end.AddDate(0, 0, -7)
 
-	slog.Info("Calculating total daily energy expenditure")
+	slog.InfoContext(ctx, "Calculating total daily energy expenditure")
 
-	slog.Info("Finding average nutrition for previous period", slog.Time("start", previousPeriodStart), slog.Time("end", previousPeriodEnd))
+	slog.InfoContext(ctx, "Finding average nutrition for previous period", slog.Time("start", previousPeriodStart), slog.Time("end", previousPeriodEnd))
 	previousAverage, err := service.repository.FindAverageNutrition(ctx, previousPeriodStart, previousPeriodEnd)
 	if err != nil {
 		return TotalDailyEnergyExpenditure{}, err
 	}
 
-	slog.Info("Finding average nutrition for current period", slog.Time("start", start), slog.Time("end", end))
+	slog.InfoContext(ctx, "Finding average nutrition for current period", slog.Time("start", start), slog.Time("end", end))
 	currentAverage, err := service.repository.FindAverageNutrition(ctx, start, end)
 	if err != nil {
 		return TotalDailyEnergyExpenditure{}, err
 	}
 
-	weightDifference := currentAverage.Weight - previousAverage.Weight
-	averageCalorieDifference := -((float64(weightDifference) / 1000) * CaloriesPerKilogramBodyFat) / 7
-
-	slog.Debug("Calculated average calorie difference", slog.Float64("averageCalorieDifference", averageCalorieDifference), slog.Int("weightDifference", weightDifference))
+	var weightDifference int
+	if currentAverage.Weight > 0 && previousAverage.Weight > 0 {
+		weightDifference = currentAverage.Weight - previousAverage.Weight
+	}
 
-	totalDailyEnergyExpenditure := int(float64(currentAverage.Calories) + averageCalorieDifference)
+	totalDailyEnergyExpenditure := calculateTotalDailyEnergyExpenditure(currentAverage.Calories, weightDifference)
 
-	slog.Debug("Calculated total daily energy expenditure", slog.Int("totalDailyEnergyExpenditure", totalDailyEnergyExpenditure))
+	slog.DebugContext(ctx, "Calculated total daily energy expenditure", slog.Int("totalDailyEnergyExpenditure", totalDailyEnergyExpenditure), slog.Int("weightDifference", weightDifference))
 
 	return TotalDailyEnergyExpenditure{
 		Start:                       start,
@@ -157,3 +441,12 @@ func (service *NutritionService) CalculateTotalDailyEnergyExpenditure(ctx contex
 		TotalDailyEnergyExpenditure: totalDailyEnergyExpenditure,
 	}, nil
 }
+
+// calculateTotalDailyEnergyExpenditure adds the calories stored or burned per
+// day, derived from the weekly weight difference in grams, to the average
+// calories eaten.
+func calculateTotalDailyEnergyExpenditure(averageCalories, weeklyWeightDifference int) int {
+	averageCalorieDifference := -((float64(weeklyWeightDifference) / 1000) * CaloriesPerKilogramBodyFat) / 7
+
+	return int(float64(averageCalories) + averageCalorieDifference)
+}
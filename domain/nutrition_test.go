@@ -0,0 +1,197 @@
+package domain_test
+
+import (
+	"context"
+	"fmt"
+	"meal-planning/domain"
+	"meal-planning/memory"
+	"testing"
+	"time"
+)
+
+func newNutritionService(repository domain.NutritionRepository) *domain.NutritionService {
+	return domain.NewNutritionService(repository, memory.NewDiaryRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus())
+}
+
+func createNutrition(t *testing.T, repository domain.NutritionRepository, entries ...domain.Nutrition) {
+	t.Helper()
+
+	for _, entry := range entries {
+		_, err := repository.Create(context.Background(), entry)
+		if err != nil {
+			t.Fatalf("creating nutrition: %v", err)
+		}
+	}
+}
+
+func TestNutritionServiceFindByDateRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   []domain.Nutrition
+		start    time.Time
+		end      time.Time
+		expected []string
+	}{
+		{
+			name:     "fills a week without entries",
+			start:    time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, time.June, 9, 0, 0, 0, 0, time.UTC),
+			expected: []string{"Mon 2024-06-03 0", "Tue 2024-06-04 0", "Wed 2024-06-05 0", "Thu 2024-06-06 0", "Fri 2024-06-07 0", "Sat 2024-06-08 0", "Sun 2024-06-09 0"},
+		},
+		{
+			name: "includes the first and the last day in ascending order",
+			stored: []domain.Nutrition{
+				{Date: time.Date(2024, time.June, 9, 0, 0, 0, 0, time.UTC), Calories: 1900},
+				{Date: time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC), Calories: 2100},
+				{Date: time.Date(2024, time.June, 2, 0, 0, 0, 0, time.UTC), Calories: 9999},
+			},
+			start:    time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, time.June, 9, 8, 0, 0, 0, time.UTC),
+			expected: []string{"Mon 2024-06-03 2100", "Tue 2024-06-04 0", "Wed 2024-06-05 0", "Thu 2024-06-06 0", "Fri 2024-06-07 0", "Sat 2024-06-08 0", "Sun 2024-06-09 1900"},
+		},
+		{
+			name:     "returns a single day",
+			start:    time.Date(2024, time.June, 5, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, time.June, 5, 23, 0, 0, 0, time.UTC),
+			expected: []string{"Wed 2024-06-05 0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := memory.NewNutritionRepository()
+			createNutrition(t, repository, test.stored...)
+
+			entries, err := newNutritionService(repository).FindByDateRange(context.Background(), test.start, test.end)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actual := make([]string, len(entries))
+			for i, entry := range entries {
+				actual[i] = fmt.Sprintf("%s %d", entry.Date.Format("Mon 2006-01-02"), entry.Calories)
+			}
+
+			if fmt.Sprint(actual) != fmt.Sprint(test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNutritionServicePublishesOnlyRecordedChanges(t *testing.T) {
+	bus := domain.NewEventBus()
+	events, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	service := domain.NewNutritionService(memory.NewNutritionRepository(), memory.NewDiaryRepository(), failingHistoryRepository{memory.NewHistoryRepository()}, memory.NewTransactor(), bus)
+
+	_, err := service.Upsert(context.Background(), domain.Nutrition{Date: time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC), Calories: 2000})
+	if err == nil {
+		t.Fatalf("expected the failing history to fail the upsert")
+	}
+
+	expectNoEvent(t, events)
+}
+
+func TestNutritionServiceCalculateTotalDailyEnergyExpenditure(t *testing.T) {
+	// the current period is the week from Monday 2024-06-10, the previous one
+	// the week before
+	previousWeek := func(day, calories, weight int) domain.Nutrition {
+		return domain.Nutrition{Date: time.Date(2024, time.June, 2+day, 0, 0, 0, 0, time.UTC), Calories: calories, Weight: weight}
+	}
+	currentWeek := func(day, calories, weight int) domain.Nutrition {
+		return domain.Nutrition{Date: time.Date(2024, time.June, 9+day, 0, 0, 0, 0, time.UTC), Calories: calories, Weight: weight}
+	}
+
+	tests := []struct {
+		name                        string
+		stored                      []domain.Nutrition
+		averageCalories             int
+		periodWeightDifference      int
+		totalDailyEnergyExpenditure int
+	}{
+		{
+			name:                        "without entries",
+			averageCalories:             0,
+			periodWeightDifference:      0,
+			totalDailyEnergyExpenditure: 0,
+		},
+		{
+			name:                        "stable weight",
+			stored:                      []domain.Nutrition{previousWeek(1, 2000, 80000), currentWeek(1, 2200, 80000), currentWeek(7, 2400, 80000)},
+			averageCalories:             2300,
+			periodWeightDifference:      0,
+			totalDailyEnergyExpenditure: 2300,
+		},
+		{
+			name:                        "losing a kilogram burns 1100 kcal a day more than eaten",
+			stored:                      []domain.Nutrition{previousWeek(1, 2000, 81000), previousWeek(7, 2000, 81000), currentWeek(1, 2000, 80000), currentWeek(7, 2000, 80000)},
+			averageCalories:             2000,
+			periodWeightDifference:      -1000,
+			totalDailyEnergyExpenditure: 3100,
+		},
+		{
+			name:                        "gaining half a kilogram stores 550 kcal a day",
+			stored:                      []domain.Nutrition{previousWeek(3, 2500, 80000), currentWeek(3, 2500, 80500)},
+			averageCalories:             2500,
+			periodWeightDifference:      500,
+			totalDailyEnergyExpenditure: 1950,
+		},
+		{
+			name:                        "averages only the days with values",
+			stored:                      []domain.Nutrition{previousWeek(2, 0, 80700), previousWeek(4, 0, 80700), currentWeek(1, 1800, 0), currentWeek(2, 2200, 80000), currentWeek(5, 0, 80000)},
+			averageCalories:             2000,
+			periodWeightDifference:      -700,
+			totalDailyEnergyExpenditure: 2770,
+		},
+		{
+			name:                        "ignores the weight difference without a previous weight",
+			stored:                      []domain.Nutrition{previousWeek(1, 2000, 0), currentWeek(1, 2000, 80000)},
+			averageCalories:             2000,
+			periodWeightDifference:      0,
+			totalDailyEnergyExpenditure: 2000,
+		},
+		{
+			name:                        "ignores the weight difference without a current weight",
+			stored:                      []domain.Nutrition{previousWeek(1, 2000, 80000), currentWeek(1, 2000, 0)},
+			averageCalories:             2000,
+			periodWeightDifference:      0,
+			totalDailyEnergyExpenditure: 2000,
+		},
+		{
+			name:                        "ignores entries outside both periods",
+			stored:                      []domain.Nutrition{previousWeek(0, 5000, 99000), previousWeek(1, 2000, 80000), currentWeek(1, 2000, 80000), currentWeek(8, 5000, 60000)},
+			averageCalories:             2000,
+			periodWeightDifference:      0,
+			totalDailyEnergyExpenditure: 2000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := memory.NewNutritionRepository()
+			createNutrition(t, repository, test.stored...)
+
+			start := time.Date(2024, time.June, 10, 18, 0, 0, 0, time.UTC)
+			end := time.Date(2024, time.June, 16, 6, 0, 0, 0, time.UTC)
+
+			result, err := newNutritionService(repository).CalculateTotalDailyEnergyExpenditure(context.Background(), start, end)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.AverageCalories != test.averageCalories {
+				t.Errorf("expected average calories %d, got %d", test.averageCalories, result.AverageCalories)
+			}
+
+			if result.PeriodWeightDifference != test.periodWeightDifference {
+				t.Errorf("expected weight difference %d, got %d", test.periodWeightDifference, result.PeriodWeightDifference)
+			}
+
+			if result.TotalDailyEnergyExpenditure != test.totalDailyEnergyExpenditure {
+				t.Errorf("expected total daily energy expenditure %d, got %d", test.totalDailyEnergyExpenditure, result.TotalDailyEnergyExpenditure)
+			}
+		})
+	}
+}
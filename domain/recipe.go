@@ -0,0 +1,496 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"meal-planning/units"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// RecipeSearchLimit is the maximum number of recipes a search returns.
+	RecipeSearchLimit = 50
+
+	MaxRecipeServings = 100
+	// MaxPortions is the largest number of portions that can be logged as
+	// eaten at once.
+	MaxPortions = 20
+	// MaxKcalPerServing allows for a whole cake served as one.
+	MaxKcalPerServing = 10000
+	MaxIngredients    = 100
+	// MaxIngredientGrams allows for a whole turkey.
+	MaxIngredientGrams = 20000
+	MaxInstructions    = 100
+	// MaxRecipeTime allows for dough that rests for days.
+	MaxRecipeTime = 7 * 24 * time.Hour
+)
+
+var (
+	RecipeNotFound = NewError(ErrorKindNotFound, "recipe: not found")
+	RecipeConflict = NewError(ErrorKindConflict, "recipe: modified concurrently")
+)
+
+// Ingredient is a line of a recipe. Only ingredients linked to a food of the
+// catalog and with an amount count towards the nutrients of the recipe.
+type Ingredient struct {
+	Name string `json:"name"`
+	// Quantity and Unit are the amount as the recipe gives it, e.g. 2 and
+	// "cup". Unit is empty for quantities that are a number of things.
+	Quantity float64 `json:"quantity,omitempty"`
+	Unit     string  `json:"unit,omitempty"`
+	// Fixed amounts stay the same for any number of servings, like a bay leaf.
+	Fixed bool `json:"fixed,omitempty"`
+	// FoodID is the food of the catalog the ingredient is, 0 if there is no
+	// match.
+	FoodID int64 `json:"foodId,omitempty"`
+	// Grams is the weight of the amount. It is calculated for units of mass
+	// and volumes of ingredients with a known density, and entered by hand
+	// otherwise.
+	Grams float64 `json:"grams,omitempty"`
+	// Nutrients are the nutrients in the amount of the food, calculated when
+	// the recipe is saved.
+	Nutrients Nutrients `json:"nutrients"`
+}
+
+// Amount returns the quantity and unit of the ingredient.
+func (ingredient Ingredient) Amount() units.Amount {
+	return units.Amount{Quantity: ingredient.Quantity, Unit: ingredient.Unit}
+}
+
+// Counted tells whether the ingredient is part of the nutrients of its recipe.
+func (ingredient Ingredient) Counted() bool {
+	return ingredient.FoodID != 0 && ingredient.Grams > 0
+}
+
+// Recipe is a dish meals are planned with. Planned meals are linked to the
+// recipe with the same name, ignoring case.
+type Recipe struct {
+	ID       int64
+	Name     string
+	Servings int
+	// PerServing holds the nutrients of one serving. They are calculated from
+	// the ingredients if there are any, and entered by hand otherwise.
+	PerServing  Nutrients
+	Ingredients []Ingredient
+	// Instructions are the steps of the recipe in order.
+	Instructions []string
+	PrepTime     time.Duration
+	CookTime     time.Duration
+	// Version is incremented on every update. Updates must carry the version
+	// they are based on.
+	Version int
+}
+
+// Total returns the nutrients of all servings.
+func (recipe Recipe) Total() Nutrients {
+	return recipe.PerServing.Scale(float64(recipe.Servings))
+}
+
+// Scale returns the recipe for servings instead of its own. The amounts,
+// grams and nutrients of the ingredients change unless they are fixed, the
+// nutrients per serving do not.
+func (recipe Recipe) Scale(servings int) Recipe {
+	if recipe.Servings <= 0 || servings == recipe.Servings {
+		return recipe
+	}
+
+	factor := float64(servings) / float64(recipe.Servings)
+
+	ingredients := make([]Ingredient, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		if ingredient.Fixed {
+			ingredients[i] = ingredient
+			continue
+		}
+
+		ingredient.Quantity *= factor
+		ingredient.Grams *= factor
+		ingredient.Nutrients = ingredient.Nutrients.Scale(factor)
+		ingredients[i] = ingredient
+	}
+
+	recipe.Servings = servings
+	recipe.Ingredients = ingredients
+	return recipe
+}
+
+// Convert returns the recipe with the amounts of its ingredients in system,
+// rounded to what kitchens measure.
+func (recipe Recipe) Convert(system units.System) Recipe {
+	ingredients := make([]Ingredient, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		amount := units.Convert(ingredient.Amount(), system, ingredient.Name)
+		ingredient.Quantity, ingredient.Unit = amount.Quantity, amount.Unit
+		ingredients[i] = ingredient
+	}
+
+	recipe.Ingredients = ingredients
+	return recipe
+}
+
+// Calculated tells whether the nutrients are calculated from the ingredients,
+// which they are once one of them is counted. Otherwise they are entered by
+// hand.
+func (recipe Recipe) Calculated() bool {
+	for _, ingredient := range recipe.Ingredients {
+		if ingredient.Counted() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Uncounted returns the ingredients missing from the nutrients, which are
+// too low by as much as these ingredients contain.
+func (recipe Recipe) Uncounted() []Ingredient {
+	var uncounted []Ingredient
+	for _, ingredient := range recipe.Ingredients {
+		if !ingredient.Counted() {
+			uncounted = append(uncounted, ingredient)
+		}
+	}
+
+	return uncounted
+}
+
+// Validate rejects recipes without a name and servings nobody cooks.
+func (recipe Recipe) Validate() error {
+	v := validator{}
+
+	name := strings.TrimSpace(recipe.Name)
+	v.check(name != "", "name", "must not be empty")
+	v.check(utf8.RuneCountInString(name) <= MaxMealNameLength, "name", fmt.Sprintf("must be at most %d characters", MaxMealNameLength))
+	v.check(recipe.Servings >= 1 && recipe.Servings <= MaxRecipeServings, "servings", fmt.Sprintf("must be between 1 and %d", MaxRecipeServings))
+
+	v.check(recipe.PerServing.Kcal >= 0 && recipe.PerServing.Kcal <= MaxKcalPerServing, "kcal", fmt.Sprintf("must be between 0 and %d", MaxKcalPerServing))
+	v.check(recipe.PerServing.Protein >= 0, "protein", "must not be negative")
+	v.check(recipe.PerServing.Carbs >= 0, "carbs", "must not be negative")
+	v.check(recipe.PerServing.Fat >= 0, "fat", "must not be negative")
+
+	v.check(len(recipe.Ingredients) <= MaxIngredients, "ingredients", fmt.Sprintf("must be at most %d", MaxIngredients))
+	for _, ingredient := range recipe.Ingredients {
+		name := strings.TrimSpace(ingredient.Name)
+		v.check(name != "", "ingredients", "need a name")
+		v.check(utf8.RuneCountInString(name) <= MaxFoodNameLength, "ingredients", fmt.Sprintf("must have names of at most %d characters", MaxFoodNameLength))
+		v.check(ingredient.Quantity >= 0, "ingredients", "must not have negative quantities")
+		v.check(ingredient.Grams >= 0 && ingredient.Grams <= MaxIngredientGrams, "ingredients", fmt.Sprintf("must weigh between 0 and %d g", MaxIngredientGrams))
+	}
+
+	v.check(len(recipe.Instructions) <= MaxInstructions, "instructions", fmt.Sprintf("must be at most %d steps", MaxInstructions))
+	v.check(recipe.PrepTime >= 0 && recipe.PrepTime <= MaxRecipeTime, "prepTime", "must be between 0 and 7 days")
+	v.check(recipe.CookTime >= 0 && recipe.CookTime <= MaxRecipeTime, "cookTime", "must be between 0 and 7 days")
+
+	return v.err()
+}
+
+type RecipeRepository interface {
+	FindByID(ctx context.Context, id int64) (Recipe, error)
+	// FindByNames returns the recipes named like any of names, ignoring case.
+	// Names without a recipe are left out.
+	FindByNames(ctx context.Context, names []string) ([]Recipe, error)
+	// Search returns up to limit recipes whose name contains every word of
+	// query, ignoring case, ordered by name.
+	Search(ctx context.Context, query string, limit int) ([]Recipe, error)
+	Create(ctx context.Context, recipe Recipe) (Recipe, error)
+	Update(ctx context.Context, recipe Recipe) (Recipe, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// RecipeReader reads recipes from files one by one. Read returns io.EOF after
+// the last recipe.
+type RecipeReader interface {
+	Read() (Recipe, error)
+}
+
+type RecipeImportResult struct {
+	Created int
+	Updated int
+	Skipped int
+}
+
+// PlannedNutrition is what the meals planned for a day add up to for one
+// person, counting one serving of each meal however many are planned.
+type PlannedNutrition struct {
+	Date      time.Time
+	Nutrients Nutrients
+	// Unmatched lists the planned meals without a recipe, they are not part
+	// of the nutrients.
+	Unmatched []string
+}
+
+type RecipeService struct {
+	repository RecipeRepository
+	foods      FoodRepository
+}
+
+func NewRecipeService(repository RecipeRepository, foods FoodRepository) *RecipeService {
+	return &RecipeService{repository: repository, foods: foods}
+}
+
+func (service *RecipeService) Search(ctx context.Context, query string) ([]Recipe, error) {
+	slog.InfoContext(ctx, "Searching recipes", slog.String("query", query))
+
+	return service.repository.Search(ctx, strings.TrimSpace(query), RecipeSearchLimit)
+}
+
+// All returns every recipe ordered by name, e.g. to export them.
+func (service *RecipeService) All(ctx context.Context) ([]Recipe, error) {
+	slog.InfoContext(ctx, "Listing all recipes")
+
+	return service.repository.Search(ctx, "", math.MaxInt32)
+}
+
+func (service *RecipeService) FindByID(ctx context.Context, id int64) (Recipe, error) {
+	slog.InfoContext(ctx, "Finding recipe", slog.Int64("id", id))
+
+	return service.repository.FindByID(ctx, id)
+}
+
+// FindByName returns the recipe a planned meal with the name is linked to. It
+// returns RecipeNotFound if there is none.
+func (service *RecipeService) FindByName(ctx context.Context, name string) (Recipe, error) {
+	slog.InfoContext(ctx, "Finding recipe by name", slog.String("name", name))
+
+	recipes, err := service.repository.FindByNames(ctx, []string{strings.TrimSpace(name)})
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	if len(recipes) == 0 {
+		return Recipe{}, RecipeNotFound
+	}
+
+	return recipes[0], nil
+}
+
+func (service *RecipeService) Create(ctx context.Context, recipe Recipe) (Recipe, error) {
+	slog.InfoContext(ctx, "Creating recipe", slog.String("name", recipe.Name))
+
+	recipe, err := service.Calculate(ctx, recipe)
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	err = service.validate(ctx, recipe)
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	return service.repository.Create(ctx, recipe)
+}
+
+func (service *RecipeService) Update(ctx context.Context, recipe Recipe) (Recipe, error) {
+	slog.InfoContext(ctx, "Updating recipe", slog.Int64("id", recipe.ID))
+
+	recipe, err := service.Calculate(ctx, recipe)
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	err = service.validate(ctx, recipe)
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	return service.repository.Update(ctx, recipe)
+}
+
+// Calculate links the ingredients to the food catalog and derives the
+// nutrients of one serving from them. Amounts in units of mass, and volumes of
+// ingredients with a known density, are converted to grams. Ingredients whose
+// food was deleted lose their link. Recipes without counted ingredients keep
+// the nutrients entered by hand.
+func (service *RecipeService) Calculate(ctx context.Context, recipe Recipe) (Recipe, error) {
+	recipe.Name = strings.TrimSpace(recipe.Name)
+
+	instructions := make([]string, 0, len(recipe.Instructions))
+	for _, step := range recipe.Instructions {
+		if step = strings.TrimSpace(step); step != "" {
+			instructions = append(instructions, step)
+		}
+	}
+	recipe.Instructions = instructions
+
+	if len(recipe.Ingredients) == 0 {
+		return recipe, nil
+	}
+
+	ingredients := make([]Ingredient, len(recipe.Ingredients))
+	total := Nutrients{}
+	for i, ingredient := range recipe.Ingredients {
+		ingredient.Name = strings.TrimSpace(ingredient.Name)
+		ingredient.Nutrients = Nutrients{}
+
+		if unit, ok := units.Normalize(ingredient.Unit); ok {
+			ingredient.Unit = unit
+		}
+
+		food := Food{}
+		if ingredient.FoodID != 0 {
+			var err error
+			food, err = service.foods.FindByID(ctx, ingredient.FoodID)
+			if errors.Is(err, FoodNotFound) {
+				ingredient.FoodID = 0
+			} else if err != nil {
+				return Recipe{}, err
+			} else if ingredient.Name == "" {
+				ingredient.Name = food.Name
+			}
+		}
+
+		if grams, ok := units.Grams(ingredient.Amount(), ingredient.Name); ok && ingredient.Quantity > 0 {
+			ingredient.Grams = grams
+		}
+		if ingredient.FoodID != 0 {
+			ingredient.Nutrients = food.NutrientsFor(ingredient.Grams)
+		}
+
+		total = total.Add(ingredient.Nutrients)
+		ingredients[i] = ingredient
+	}
+
+	recipe.Ingredients = ingredients
+	if recipe.Calculated() && recipe.Servings > 0 {
+		recipe.PerServing = total.Scale(1 / float64(recipe.Servings))
+	}
+
+	return recipe, nil
+}
+
+// validate checks the recipe and that no other recipe has its name, which
+// would make linking planned meals ambiguous.
+func (service *RecipeService) validate(ctx context.Context, recipe Recipe) error {
+	err := recipe.Validate()
+	if err != nil {
+		return err
+	}
+
+	namesakes, err := service.repository.FindByNames(ctx, []string{recipe.Name})
+	if err != nil {
+		return err
+	}
+
+	v := validator{}
+	for _, namesake := range namesakes {
+		v.check(namesake.ID == recipe.ID, "name", "is already used by another recipe")
+	}
+
+	return v.err()
+}
+
+// Import creates the recipes of reader, or updates the recipes with the same
+// name, so files can be imported again after they changed. Invalid recipes
+// are skipped.
+func (service *RecipeService) Import(ctx context.Context, reader RecipeReader) (RecipeImportResult, error) {
+	slog.InfoContext(ctx, "Importing recipes")
+
+	result := RecipeImportResult{}
+	for {
+		recipe, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return result, err
+		}
+
+		recipe, err = service.Calculate(ctx, recipe)
+		if err != nil {
+			return result, err
+		}
+
+		if err := recipe.Validate(); err != nil {
+			slog.WarnContext(ctx, "Skipped invalid recipe", slog.String("name", recipe.Name), slog.Any("reason", err))
+			result.Skipped++
+			continue
+		}
+
+		namesakes, err := service.repository.FindByNames(ctx, []string{recipe.Name})
+		if err != nil {
+			return result, err
+		}
+
+		if len(namesakes) > 0 {
+			recipe.ID = namesakes[0].ID
+			recipe.Version = namesakes[0].Version
+
+			_, err = service.repository.Update(ctx, recipe)
+			if err != nil {
+				return result, err
+			}
+
+			result.Updated++
+		} else {
+			_, err = service.repository.Create(ctx, recipe)
+			if err != nil {
+				return result, err
+			}
+
+			result.Created++
+		}
+	}
+
+	return result, nil
+}
+
+func (service *RecipeService) Delete(ctx context.Context, id int64) error {
+	slog.InfoContext(ctx, "Deleting recipe", slog.Int64("id", id))
+
+	return service.repository.Delete(ctx, id)
+}
+
+// PlannedNutrition sums up one serving of every meal planned for each of the
+// days, in the order of mealDays.
+func (service *RecipeService) PlannedNutrition(ctx context.Context, mealDays []MealDay) ([]PlannedNutrition, error) {
+	recipesByName, err := service.findPlannedRecipes(ctx, mealDays)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := make([]PlannedNutrition, len(mealDays))
+	for i, mealDay := range mealDays {
+		planned[i].Date = mealDay.Date
+		for _, meal := range mealDay.PlannedMeals() {
+			recipe, ok := recipesByName[strings.ToLower(meal.Name)]
+			if !ok {
+				planned[i].Unmatched = append(planned[i].Unmatched, meal.Name)
+				continue
+			}
+
+			planned[i].Nutrients = planned[i].Nutrients.Add(recipe.PerServing)
+		}
+	}
+
+	return planned, nil
+}
+
+// findPlannedRecipes returns the recipes of the meals planned for the days by
+// their lowercase name.
+func (service *RecipeService) findPlannedRecipes(ctx context.Context, mealDays []MealDay) (map[string]Recipe, error) {
+	names := make([]string, 0)
+	for _, mealDay := range mealDays {
+		for _, meal := range mealDay.PlannedMeals() {
+			names = append(names, meal.Name)
+		}
+	}
+
+	recipes, err := service.repository.FindByNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	recipesByName := make(map[string]Recipe, len(recipes))
+	for _, recipe := range recipes {
+		recipesByName[strings.ToLower(recipe.Name)] = recipe
+	}
+
+	return recipesByName, nil
+}
@@ -0,0 +1,301 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"meal-planning/domain"
+	"meal-planning/memory"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecipeServiceRejectsDuplicateNames(t *testing.T) {
+	ctx := context.Background()
+	service := domain.NewRecipeService(memory.NewRecipeRepository(), memory.NewFoodRepository())
+
+	chili, err := service.Create(ctx, domain.Recipe{Name: " Chili sin carne ", Servings: 4})
+	if err != nil {
+		t.Fatalf("creating recipe: %v", err)
+	}
+
+	if chili.Name != "Chili sin carne" {
+		t.Errorf("expected a trimmed name, got %q", chili.Name)
+	}
+
+	_, err = service.Create(ctx, domain.Recipe{Name: "chili SIN carne", Servings: 2})
+	if domain.KindOf(err) != domain.ErrorKindValidation {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+
+	chili.Servings = 6
+	_, err = service.Update(ctx, chili)
+	if err != nil {
+		t.Errorf("expected a recipe to keep its own name, got %v", err)
+	}
+}
+
+func TestRecipeServiceCalculatesNutritionFromIngredients(t *testing.T) {
+	ctx := context.Background()
+	foods := memory.NewFoodRepository()
+	service := domain.NewRecipeService(memory.NewRecipeRepository(), foods)
+
+	oats, err := foods.Create(ctx, domain.Food{Name: "Rolled oats", Per100g: domain.Nutrients{Kcal: 370, Protein: 13, Carbs: 59, Fat: 7}})
+	if err != nil {
+		t.Fatalf("creating food: %v", err)
+	}
+
+	milk, err := foods.Create(ctx, domain.Food{Name: "Milk", Per100g: domain.Nutrients{Kcal: 64, Protein: 3.4, Carbs: 4.8, Fat: 3.5}})
+	if err != nil {
+		t.Fatalf("creating food: %v", err)
+	}
+
+	porridge, err := service.Create(ctx, domain.Recipe{
+		Name:       "Porridge",
+		Servings:   2,
+		PerServing: domain.Nutrients{Kcal: 9999},
+		Ingredients: []domain.Ingredient{
+			{FoodID: oats.ID, Grams: 100},
+			{Name: " Whole milk ", FoodID: milk.ID, Grams: 500},
+			{Name: "Cinnamon", Grams: 2},
+			{Name: "Oat milk", FoodID: milk.ID},
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating recipe: %v", err)
+	}
+
+	if porridge.Total() != (domain.Nutrients{Kcal: 690, Protein: 30, Carbs: 83, Fat: 24.5}) {
+		t.Errorf("expected the ingredients to add up, got %+v", porridge.Total())
+	}
+
+	if porridge.PerServing != (domain.Nutrients{Kcal: 345, Protein: 15, Carbs: 41.5, Fat: 12.25}) {
+		t.Errorf("expected half of the ingredients per serving, got %+v", porridge.PerServing)
+	}
+
+	if porridge.Ingredients[0].Name != "Rolled oats" || porridge.Ingredients[1].Name != "Whole milk" {
+		t.Errorf("expected ingredients named after their food and trimmed, got %+v", porridge.Ingredients)
+	}
+
+	uncounted := porridge.Uncounted()
+	if len(uncounted) != 2 || uncounted[0].Name != "Cinnamon" || uncounted[1].Name != "Oat milk" {
+		t.Errorf("expected the ingredients without food or amount to be flagged, got %+v", uncounted)
+	}
+
+	err = foods.Delete(ctx, oats.ID)
+	if err != nil {
+		t.Fatalf("deleting food: %v", err)
+	}
+
+	porridge.Servings = 1
+	porridge, err = service.Update(ctx, porridge)
+	if err != nil {
+		t.Fatalf("updating recipe: %v", err)
+	}
+
+	if porridge.PerServing != (domain.Nutrients{Kcal: 320, Protein: 17, Carbs: 24, Fat: 17.5}) {
+		t.Errorf("expected the nutrients to be recalculated, got %+v", porridge.PerServing)
+	}
+
+	if porridge.Ingredients[0].FoodID != 0 || porridge.Ingredients[0].Counted() {
+		t.Errorf("expected the deleted food to lose its link, got %+v", porridge.Ingredients[0])
+	}
+}
+
+func TestRecipeServiceKeepsNutritionEnteredByHand(t *testing.T) {
+	ctx := context.Background()
+	foods := memory.NewFoodRepository()
+	service := domain.NewRecipeService(memory.NewRecipeRepository(), foods)
+
+	salad, err := service.Create(ctx, domain.Recipe{
+		Name:       "Salad",
+		Servings:   2,
+		PerServing: domain.Nutrients{Kcal: 180, Protein: 4},
+		Ingredients: []domain.Ingredient{
+			{Name: "Lettuce", Quantity: 1},
+			{Name: "Olive oil", Quantity: 2, Unit: "tbsp"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating recipe: %v", err)
+	}
+
+	if salad.Calculated() || salad.PerServing != (domain.Nutrients{Kcal: 180, Protein: 4}) {
+		t.Errorf("expected the nutrients entered by hand without counted ingredients, got %+v", salad.PerServing)
+	}
+
+	if len(salad.Uncounted()) != 2 {
+		t.Errorf("expected the ingredients without food to be flagged, got %+v", salad.Uncounted())
+	}
+
+	oil, err := foods.Create(ctx, domain.Food{Name: "Olive oil", Per100g: domain.Nutrients{Kcal: 900, Fat: 100}})
+	if err != nil {
+		t.Fatalf("creating food: %v", err)
+	}
+
+	salad.Ingredients[1].FoodID = oil.ID
+	salad, err = service.Update(ctx, salad)
+	if err != nil {
+		t.Fatalf("updating recipe: %v", err)
+	}
+
+	if !salad.Calculated() || salad.PerServing.Kcal <= 0 || salad.PerServing.Protein != 0 {
+		t.Errorf("expected the nutrients calculated once an ingredient is counted, got %+v", salad.PerServing)
+	}
+}
+
+// sliceRecipeReader reads recipes from a slice.
+type sliceRecipeReader struct {
+	recipes []domain.Recipe
+}
+
+func (r *sliceRecipeReader) Read() (domain.Recipe, error) {
+	if len(r.recipes) == 0 {
+		return domain.Recipe{}, io.EOF
+	}
+
+	recipe := r.recipes[0]
+	r.recipes = r.recipes[1:]
+	return recipe, nil
+}
+
+func TestRecipeServiceImport(t *testing.T) {
+	ctx := context.Background()
+	service := domain.NewRecipeService(memory.NewRecipeRepository(), memory.NewFoodRepository())
+
+	porridge, err := service.Create(ctx, domain.Recipe{Name: "Porridge", Servings: 1})
+	if err != nil {
+		t.Fatalf("creating recipe: %v", err)
+	}
+
+	result, err := service.Import(ctx, &sliceRecipeReader{recipes: []domain.Recipe{
+		{Name: "porridge", Servings: 2, Instructions: []string{"Stir in the oats."}},
+		{Name: "Chili sin carne", Servings: 4, Ingredients: []domain.Ingredient{{Name: "rice", Quantity: 200, Unit: "g"}}},
+		{Name: " ", Servings: 1},
+	}})
+	if err != nil {
+		t.Fatalf("importing recipes: %v", err)
+	}
+
+	if result != (domain.RecipeImportResult{Created: 1, Updated: 1, Skipped: 1}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	updated, err := service.FindByID(ctx, porridge.ID)
+	if err != nil {
+		t.Fatalf("finding recipe: %v", err)
+	}
+
+	if updated.Servings != 2 || !slices.Equal(updated.Instructions, []string{"Stir in the oats."}) {
+		t.Errorf("expected the recipe with the same name to be updated, got %+v", updated)
+	}
+
+	all, err := service.All(ctx)
+	if err != nil {
+		t.Fatalf("listing recipes: %v", err)
+	}
+
+	if len(all) != 2 || all[0].Name != "Chili sin carne" || all[0].Ingredients[0].Grams != 200 {
+		t.Errorf("expected the new recipe with its ingredients calculated, got %+v", all)
+	}
+}
+
+// failingRecipeRepository rejects new recipes.
+type failingRecipeRepository struct {
+	domain.RecipeRepository
+}
+
+func (r failingRecipeRepository) Create(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	return domain.Recipe{}, errors.New("recipes are read-only")
+}
+
+func TestRecipeServiceImportCountsOnlyStoredRecipes(t *testing.T) {
+	ctx := context.Background()
+	recipes := memory.NewRecipeRepository()
+
+	_, err := domain.NewRecipeService(recipes, memory.NewFoodRepository()).Create(ctx, domain.Recipe{Name: "Porridge", Servings: 1})
+	if err != nil {
+		t.Fatalf("creating recipe: %v", err)
+	}
+
+	service := domain.NewRecipeService(failingRecipeRepository{recipes}, memory.NewFoodRepository())
+	result, err := service.Import(ctx, &sliceRecipeReader{recipes: []domain.Recipe{
+		{Name: "Porridge", Servings: 2},
+		{Name: "Chili sin carne", Servings: 4},
+	}})
+	if err == nil {
+		t.Fatalf("expected the failing repository to fail the import")
+	}
+
+	if result != (domain.RecipeImportResult{Updated: 1}) {
+		t.Errorf("expected only the stored recipe to be counted, got %+v", result)
+	}
+}
+
+func TestRecipeServicePlannedNutrition(t *testing.T) {
+	ctx := context.Background()
+	service := domain.NewRecipeService(memory.NewRecipeRepository(), memory.NewFoodRepository())
+
+	for _, recipe := range []domain.Recipe{
+		{Name: "Porridge", Servings: 1, PerServing: domain.Nutrients{Kcal: 350, Protein: 12}},
+		{Name: "Chili sin carne", Servings: 4, PerServing: domain.Nutrients{Kcal: 520, Protein: 24}},
+		{Name: "Apple", Servings: 1, PerServing: domain.Nutrients{Kcal: 80}},
+	} {
+		_, err := service.Create(ctx, recipe)
+		if err != nil {
+			t.Fatalf("creating recipe: %v", err)
+		}
+	}
+
+	monday := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+	planned, err := service.PlannedNutrition(ctx, []domain.MealDay{
+		{Date: monday, Breakfast: "porridge", Lunch: "Pizza", Dinner: "Chili sin carne", Snacks: []string{"Apple", "", "apple"}},
+		{Date: monday.AddDate(0, 0, 1)},
+	})
+	if err != nil {
+		t.Fatalf("calculating planned nutrition: %v", err)
+	}
+
+	if len(planned) != 2 {
+		t.Fatalf("expected 2 days, got %+v", planned)
+	}
+
+	if planned[0].Nutrients != (domain.Nutrients{Kcal: 1030, Protein: 36}) || !slices.Equal(planned[0].Unmatched, []string{"Pizza"}) {
+		t.Errorf("unexpected plan for monday %+v", planned[0])
+	}
+
+	if !planned[1].Date.Equal(monday.AddDate(0, 0, 1)) || planned[1].Nutrients != (domain.Nutrients{}) || planned[1].Unmatched != nil {
+		t.Errorf("expected nothing planned for tuesday, got %+v", planned[1])
+	}
+}
+
+func TestNutritionServiceLogRecipe(t *testing.T) {
+	ctx := context.Background()
+	service := domain.NewNutritionService(memory.NewNutritionRepository(), memory.NewDiaryRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus())
+	day := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+	chili := domain.Recipe{ID: 1, Name: "Chili sin carne", Servings: 4, PerServing: domain.Nutrients{Kcal: 520, Protein: 24, Carbs: 70, Fat: 12.5}}
+
+	nutrition, err := service.LogRecipe(ctx, day, domain.MealSlotDinner, chili, 1.5)
+	if err != nil {
+		t.Fatalf("logging recipe: %v", err)
+	}
+
+	if nutrition.Calories != 780 || !nutrition.CaloriesFromDiary || nutrition.Protein != 36 || nutrition.Fat != 19 {
+		t.Errorf("expected 1.5 servings in the totals, got %+v", nutrition)
+	}
+
+	entries, err := service.FindDiary(ctx, day)
+	if err != nil {
+		t.Fatalf("finding diary: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].RecipeID != 1 || entries[0].Portions != 1.5 || entries[0].FoodName != "Chili sin carne" {
+		t.Errorf("expected the recipe in the diary, got %+v", entries)
+	}
+
+	_, err = service.LogRecipe(ctx, day, domain.MealSlotDinner, chili, 0)
+	if domain.KindOf(err) != domain.ErrorKindValidation {
+		t.Errorf("expected a validation error for no portions, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"meal-planning/units"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ShoppingItem is an ingredient to buy for the planned meals.
+type ShoppingItem struct {
+	Name string
+	// Amounts add up what the recipes need in as few units as possible. They
+	// are empty for ingredients the recipes give without an amount, like
+	// salt to taste.
+	Amounts []units.Amount
+	// Recipes are the names of the recipes using the ingredient.
+	Recipes []string
+}
+
+// ShoppingList holds the ingredients of the meals planned for some days.
+type ShoppingList struct {
+	// System is the system the amounts are in.
+	System units.System
+	// Items are ordered by name.
+	Items []ShoppingItem
+	// Unmatched are the planned meals without a recipe, whose ingredients are
+	// missing from the list.
+	Unmatched []string
+}
+
+// ShoppingList adds up the ingredients of the meals planned for the days in
+// the units of system. Amounts added up have no unit they were written in,
+// so they are metric unless imperial is asked for. Recipes are scaled to the
+// servings cooked for their slot, with the ones for leftovers. Meals eating
+// leftovers and meals nobody eats are left out. Ingredients are the same if
+// their names are, ignoring case.
+func (service *RecipeService) ShoppingList(ctx context.Context, mealDays []MealDay, system units.System) (ShoppingList, error) {
+	slog.InfoContext(ctx, "Listing ingredients to buy", slog.Int("days", len(mealDays)), slog.String("system", string(system)))
+
+	if system == units.AsWritten {
+		system = units.Metric
+	}
+
+	recipesByName, err := service.findPlannedRecipes(ctx, mealDays)
+	if err != nil {
+		return ShoppingList{}, err
+	}
+
+	type needed struct {
+		item    ShoppingItem
+		amounts []units.Amount
+	}
+
+	list := ShoppingList{System: system}
+	neededByName := map[string]*needed{}
+	for _, mealDay := range mealDays {
+		for _, meal := range mealDay.PlannedMeals() {
+			if meal.Cooked == 0 {
+				continue
+			}
+
+			recipe, ok := recipesByName[strings.ToLower(meal.Name)]
+			if !ok {
+				if !slices.ContainsFunc(list.Unmatched, func(name string) bool { return strings.EqualFold(name, meal.Name) }) {
+					list.Unmatched = append(list.Unmatched, meal.Name)
+				}
+
+				continue
+			}
+
+			for _, ingredient := range recipe.Scale(meal.Cooked).Ingredients {
+				key := strings.ToLower(strings.TrimSpace(ingredient.Name))
+				if key == "" {
+					continue
+				}
+
+				ingredientNeeded, ok := neededByName[key]
+				if !ok {
+					ingredientNeeded = &needed{item: ShoppingItem{Name: strings.TrimSpace(ingredient.Name)}}
+					neededByName[key] = ingredientNeeded
+				}
+
+				ingredientNeeded.amounts = append(ingredientNeeded.amounts, ingredient.Amount())
+				if !slices.Contains(ingredientNeeded.item.Recipes, recipe.Name) {
+					ingredientNeeded.item.Recipes = append(ingredientNeeded.item.Recipes, recipe.Name)
+				}
+			}
+		}
+	}
+
+	for _, ingredientNeeded := range neededByName {
+		item := ingredientNeeded.item
+		for _, amount := range units.Total(ingredientNeeded.amounts, item.Name) {
+			item.Amounts = append(item.Amounts, units.Convert(amount, system, item.Name))
+		}
+
+		list.Items = append(list.Items, item)
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return strings.ToLower(list.Items[i].Name) < strings.ToLower(list.Items[j].Name)
+	})
+
+	return list, nil
+}
@@ -0,0 +1,189 @@
+package domain_test
+
+import (
+	"context"
+	"meal-planning/domain"
+	"meal-planning/memory"
+	"meal-planning/units"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecipeScale(t *testing.T) {
+	chili := domain.Recipe{
+		Name:     "Chili sin carne",
+		Servings: 4,
+		Ingredients: []domain.Ingredient{
+			{Name: "kidney beans", Quantity: 2, Unit: "can"},
+			{Name: "rice", Quantity: 300, Unit: "g", Grams: 300, Nutrients: domain.Nutrients{Kcal: 1050}},
+			{Name: "salt"},
+		},
+	}
+
+	scaled := chili.Scale(6)
+
+	if scaled.Servings != 6 || scaled.Ingredients[0].Quantity != 3 || scaled.Ingredients[1].Grams != 450 || scaled.Ingredients[1].Nutrients.Kcal != 1575 || scaled.Ingredients[2].Quantity != 0 {
+		t.Errorf("expected the amounts for 6 servings, got %+v", scaled)
+	}
+
+	if chili.Ingredients[0].Quantity != 2 {
+		t.Errorf("expected the recipe itself to stay as it is, got %+v", chili.Ingredients)
+	}
+
+	converted := chili.Convert(units.Imperial)
+	if converted.Ingredients[1].Amount() != (units.Amount{Quantity: 5.0 / 3, Unit: "cup"}) {
+		t.Errorf("expected rice in cups, got %+v", converted.Ingredients[1])
+	}
+}
+
+func TestRecipeServiceShoppingList(t *testing.T) {
+	ctx := context.Background()
+	service := domain.NewRecipeService(memory.NewRecipeRepository(), memory.NewFoodRepository())
+
+	for _, recipe := range []domain.Recipe{
+		{Name: "Pancakes", Servings: 4, Ingredients: []domain.Ingredient{
+			{Name: "Flour", Quantity: 250, Unit: "g"},
+			{Name: "milk", Quantity: 2, Unit: "cup"},
+			{Name: "eggs", Quantity: 4},
+		}},
+		{Name: "Bread", Servings: 1, Ingredients: []domain.Ingredient{
+			{Name: "flour", Quantity: 1, Unit: "cup"},
+			{Name: "salt"},
+		}},
+	} {
+		_, err := service.Create(ctx, recipe)
+		if err != nil {
+			t.Fatalf("creating recipe: %v", err)
+		}
+	}
+
+	monday := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+	mealDays := []domain.MealDay{
+		{Date: monday, Breakfast: "pancakes", Lunch: "Pizza", Snacks: []string{"Bread"}},
+		{Date: monday.AddDate(0, 0, 1), Breakfast: "Pancakes", Dinner: "pizza"},
+	}
+
+	list, err := service.ShoppingList(ctx, mealDays, units.AsWritten)
+	if err != nil {
+		t.Fatalf("listing ingredients: %v", err)
+	}
+
+	if list.System != units.Metric {
+		t.Errorf("expected amounts as written to be listed in metric, got %q", list.System)
+	}
+
+	expected := []domain.ShoppingItem{
+		{Name: "eggs", Amounts: []units.Amount{{Quantity: 2}}, Recipes: []string{"Pancakes"}},
+		{Name: "Flour", Amounts: []units.Amount{{Quantity: 250, Unit: "g"}}, Recipes: []string{"Pancakes", "Bread"}},
+		{Name: "milk", Amounts: []units.Amount{{Quantity: 235, Unit: "ml"}}, Recipes: []string{"Pancakes"}},
+		{Name: "salt", Recipes: []string{"Bread"}},
+	}
+	if !slices.EqualFunc(list.Items, expected, func(a, b domain.ShoppingItem) bool {
+		return a.Name == b.Name && slices.Equal(a.Amounts, b.Amounts) && slices.Equal(a.Recipes, b.Recipes)
+	}) {
+		t.Errorf("expected %+v, got %+v", expected, list.Items)
+	}
+
+	if !slices.Equal(list.Unmatched, []string{"Pizza"}) {
+		t.Errorf("expected the meal without a recipe once, got %q", list.Unmatched)
+	}
+
+	list, err = service.ShoppingList(ctx, mealDays, units.Imperial)
+	if err != nil {
+		t.Fatalf("listing ingredients: %v", err)
+	}
+
+	if list.Items[1].Amounts[0] != (units.Amount{Quantity: 2, Unit: "cup"}) || list.Items[2].Amounts[0] != (units.Amount{Quantity: 1, Unit: "cup"}) {
+		t.Errorf("expected cups, got %+v", list.Items)
+	}
+}
+
+func TestRecipeServiceShoppingListScalesToServings(t *testing.T) {
+	ctx := context.Background()
+	service := domain.NewRecipeService(memory.NewRecipeRepository(), memory.NewFoodRepository())
+
+	_, err := service.Create(ctx, domain.Recipe{Name: "Pancakes", Servings: 4, Ingredients: []domain.Ingredient{
+		{Name: "eggs", Quantity: 4},
+	}})
+	if err != nil {
+		t.Fatalf("creating recipe: %v", err)
+	}
+
+	household := domain.Household{Members: []string{"Alex", "Sam", "Kim"}}
+	monday := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+	mealDays := []domain.MealDay{
+		{Date: monday, Household: household, Breakfast: "Pancakes", Attendance: []domain.Attendance{{Slot: domain.MealSlotBreakfast, Absent: []string{"Alex"}}}},
+		{Date: monday.AddDate(0, 0, 1), Household: household, Breakfast: "Pancakes", Dinner: "Pancakes", Attendance: []domain.Attendance{{Slot: domain.MealSlotDinner, Servings: 6}}},
+		{Date: monday.AddDate(0, 0, 2), Household: domain.Household{Members: []string{"Alex"}}, Lunch: "Pancakes", Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Absent: []string{"Alex"}}}},
+	}
+
+	list, err := service.ShoppingList(ctx, mealDays, units.Metric)
+	if err != nil {
+		t.Fatalf("listing ingredients: %v", err)
+	}
+
+	// 2 servings on Monday, 3 for breakfast and 6 for dinner on Tuesday, none
+	// on Wednesday
+	if len(list.Items) != 1 || !slices.Equal(list.Items[0].Amounts, []units.Amount{{Quantity: 11}}) {
+		t.Errorf("expected 11 eggs, got %+v", list.Items)
+	}
+}
+
+func TestRecipeServiceShoppingListCountsLeftoversOnce(t *testing.T) {
+	ctx := context.Background()
+	recipes := domain.NewRecipeService(memory.NewRecipeRepository(), memory.NewFoodRepository())
+	mealDays := domain.NewMealDayService(memory.NewMealDayRepository(), memory.NewHistoryRepository(), memory.NewTransactor(), domain.NewEventBus(), domain.Household{People: 2})
+
+	_, err := recipes.Create(ctx, domain.Recipe{Name: "Chili", Servings: 4, Ingredients: []domain.Ingredient{
+		{Name: "beans", Quantity: 400, Unit: "g"},
+	}})
+	if err != nil {
+		t.Fatalf("creating recipe: %v", err)
+	}
+
+	sunday := time.Date(2024, time.June, 9, 0, 0, 0, 0, time.UTC)
+	for _, mealDay := range []domain.MealDay{
+		{Date: sunday, Dinner: "Chili"},
+		{Date: sunday.AddDate(0, 0, 1), Leftovers: []domain.Leftovers{{Slot: domain.MealSlotLunch, Of: domain.MealRef{Date: sunday, Slot: domain.MealSlotDinner}}}},
+	} {
+		_, err = mealDays.Upsert(ctx, mealDay)
+		if err != nil {
+			t.Fatalf("planning meals: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		days     int
+		expected []units.Amount
+	}{
+		{"cooked and leftovers", sunday, 2, []units.Amount{{Quantity: 400, Unit: "g"}}},
+		{"only cooked", sunday, 1, []units.Amount{{Quantity: 400, Unit: "g"}}},
+		{"only leftovers", sunday.AddDate(0, 0, 1), 1, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			planned, err := mealDays.FindByDateRange(ctx, test.start, test.start.AddDate(0, 0, test.days-1))
+			if err != nil {
+				t.Fatalf("finding meals: %v", err)
+			}
+
+			list, err := recipes.ShoppingList(ctx, planned, units.Metric)
+			if err != nil {
+				t.Fatalf("listing ingredients: %v", err)
+			}
+
+			var amounts []units.Amount
+			for _, item := range list.Items {
+				amounts = append(amounts, item.Amounts...)
+			}
+
+			if !slices.Equal(amounts, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, amounts)
+			}
+		})
+	}
+}
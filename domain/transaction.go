@@ -0,0 +1,12 @@
+package domain
+
+import "context"
+
+// Transactor runs changes that must be stored together, like a meal day and
+// the history entry recording its change, in one transaction.
+type Transactor interface {
+	// WithTx runs fn in a transaction, which repositories called with the
+	// context passed to fn take part in. It is committed if fn returns nil
+	// and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "context"
+
+const AnonymousUser = "anonymous"
+
+type userContextKey struct{}
+
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+func UserFromContext(ctx context.Context) string {
+	user, ok := ctx.Value(userContextKey{}).(string)
+	if !ok || user == "" {
+		return AnonymousUser
+	}
+
+	return user
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxMealNameLength = 100
+	MaxSnacks         = 10
+	MaxCalories       = 15000
+	// MinWeight and MaxWeight bound plausible weigh-ins in grams.
+	MinWeight = 20_000
+	MaxWeight = 400_000
+)
+
+// ValidationError maps the names of invalid fields to what is wrong with them.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	problems := make([]string, len(fields))
+	for i, field := range fields {
+		problems[i] = field + " " + e.Fields[field]
+	}
+
+	return "invalid input: " + strings.Join(problems, ", ")
+}
+
+func (e *ValidationError) Kind() ErrorKind {
+	return ErrorKindValidation
+}
+
+// validator collects the first problem of every field.
+type validator struct {
+	fields map[string]string
+}
+
+func (v *validator) check(valid bool, field, message string) {
+	if valid {
+		return
+	}
+
+	if v.fields == nil {
+		v.fields = map[string]string{}
+	}
+
+	if _, ok := v.fields[field]; !ok {
+		v.fields[field] = message
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Fields: v.fields}
+}
+
+// Validate rejects meal names nobody would type on purpose, attendance naming
+// people outside the household and leftovers of later or long gone meals.
+// Problems with a slot are reported for fields like "lunch-servings",
+// "lunch-absent" and "lunch-leftovers".
+func (mealDay MealDay) Validate() error {
+	v := validator{}
+
+	tooLong := fmt.Sprintf("must be at most %d characters", MaxMealNameLength)
+	v.check(utf8.RuneCountInString(mealDay.Breakfast) <= MaxMealNameLength, "breakfast", tooLong)
+	v.check(utf8.RuneCountInString(mealDay.Lunch) <= MaxMealNameLength, "lunch", tooLong)
+	v.check(utf8.RuneCountInString(mealDay.Dinner) <= MaxMealNameLength, "dinner", tooLong)
+
+	v.check(len(mealDay.Snacks) <= MaxSnacks, "snacks", fmt.Sprintf("must be at most %d snacks", MaxSnacks))
+	for _, snack := range mealDay.Snacks {
+		v.check(utf8.RuneCountInString(snack) <= MaxMealNameLength, "snacks", tooLong)
+		// snacks are stored separated by commas
+		v.check(!strings.Contains(snack, ","), "snacks", "must not contain commas")
+	}
+
+	slots := make([]MealSlot, 0, len(mealDay.Attendance))
+	for _, attendance := range mealDay.Attendance {
+		if !attendance.Slot.Valid() || slices.Contains(slots, attendance.Slot) {
+			v.check(false, "attendance", "must name every meal slot at most once")
+			continue
+		}
+		slots = append(slots, attendance.Slot)
+
+		field := string(attendance.Slot)
+		v.check(attendance.Servings >= 0 && attendance.Servings <= MaxRecipeServings, field+"-servings", fmt.Sprintf("must be between 0 and %d, 0 serves the household", MaxRecipeServings))
+
+		for i, member := range attendance.Absent {
+			v.check(mealDay.Household.HasMember(member), field+"-absent", "must be members of the household")
+			v.check(!slices.ContainsFunc(attendance.Absent[:i], func(other string) bool { return strings.EqualFold(other, member) }), field+"-absent", "must name every member once")
+		}
+	}
+
+	slots = slots[:0]
+	for _, leftovers := range mealDay.Leftovers {
+		if !slices.Contains(CookedSlots, leftovers.Slot) || slices.Contains(slots, leftovers.Slot) {
+			v.check(false, "leftovers", "must name every cooked meal slot at most once")
+			continue
+		}
+		slots = append(slots, leftovers.Slot)
+
+		field := string(leftovers.Slot) + "-leftovers"
+		v.check(slices.Contains(CookedSlots, leftovers.Of.Slot) && leftovers.Of.Before(MealRef{Date: mealDay.Date, Slot: leftovers.Slot}), field, "must be of an earlier meal")
+		v.check(daysBetween(leftovers.Of.Date, mealDay.Date) <= MaxLeftoversDays+1, field, fmt.Sprintf("must be of a meal at most %d days earlier", MaxLeftoversDays))
+	}
+
+	return v.err()
+}
+
+// Validate rejects implausible calories and weights. Zero means nothing was
+// entered and is always valid. Weigh-ins may not be in the future as seen from
+// today; one day of slack allows for users ahead of UTC.
+func (nutrition Nutrition) Validate(today time.Time) error {
+	v := validator{}
+
+	v.check(nutrition.Calories >= 0, "calories", "must not be negative")
+	v.check(nutrition.Calories <= MaxCalories, "calories", fmt.Sprintf("must be at most %d kcal", MaxCalories))
+
+	if nutrition.Weight != 0 {
+		v.check(nutrition.Weight >= MinWeight && nutrition.Weight <= MaxWeight, "weight", fmt.Sprintf("must be between %d and %d kg", MinWeight/1000, MaxWeight/1000))
+
+		latestDay := calendarDay(today).AddDate(0, 0, 1)
+		v.check(!calendarDay(nutrition.Date).After(latestDay), "weight", "cannot be recorded for a future day")
+	}
+
+	return v.err()
+}
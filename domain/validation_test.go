@@ -0,0 +1,108 @@
+package domain_test
+
+import (
+	"errors"
+	"meal-planning/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMealDayValidate(t *testing.T) {
+	household := domain.Household{Members: []string{"Alex", "Sam"}}
+	monday := time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC)
+	leftovers := func(slot domain.MealSlot, date time.Time, of domain.MealSlot) domain.Leftovers {
+		return domain.Leftovers{Slot: slot, Of: domain.MealRef{Date: date, Slot: of}}
+	}
+
+	tests := []struct {
+		name    string
+		mealDay domain.MealDay
+		fields  []string
+	}{
+		{"empty", domain.MealDay{}, nil},
+		{"planned", domain.MealDay{Breakfast: "Porridge", Dinner: "Pasta", Snacks: []string{"Apple"}}, nil},
+		{"longest name", domain.MealDay{Lunch: strings.Repeat("ä", domain.MaxMealNameLength)}, nil},
+		{"name too long", domain.MealDay{Lunch: strings.Repeat("ä", domain.MaxMealNameLength+1)}, []string{"lunch"}},
+		{"too many snacks", domain.MealDay{Snacks: make([]string, domain.MaxSnacks+1)}, []string{"snacks"}},
+		{"snack with a comma", domain.MealDay{Snacks: []string{"Apple, sliced"}}, []string{"snacks"}},
+		{"snack too long", domain.MealDay{Dinner: strings.Repeat("x", 200), Snacks: []string{strings.Repeat("x", 200)}}, []string{"dinner", "snacks"}},
+		{"guests", domain.MealDay{Lunch: "Soup", Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Servings: 6}}}, nil},
+		{"too many servings", domain.MealDay{Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Servings: domain.MaxRecipeServings + 1}}}, []string{"lunch-servings"}},
+		{"negative servings", domain.MealDay{Attendance: []domain.Attendance{{Slot: domain.MealSlotDinner, Servings: -1}}}, []string{"dinner-servings"}},
+		{"member eating out", domain.MealDay{Household: household, Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Absent: []string{"alex"}}}}, nil},
+		{"stranger eating out", domain.MealDay{Household: household, Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Absent: []string{"Kim"}}}}, []string{"lunch-absent"}},
+		{"member eating out twice", domain.MealDay{Household: household, Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Absent: []string{"Alex", "ALEX"}}}}, []string{"lunch-absent"}},
+		{"slot twice", domain.MealDay{Attendance: []domain.Attendance{{Slot: domain.MealSlotLunch, Servings: 2}, {Slot: domain.MealSlotLunch, Servings: 3}}}, []string{"attendance"}},
+		{"unknown slot", domain.MealDay{Attendance: []domain.Attendance{{Slot: "brunch", Servings: 2}}}, []string{"attendance"}},
+		{"leftovers of yesterday", domain.MealDay{Date: monday, Leftovers: []domain.Leftovers{leftovers(domain.MealSlotLunch, monday.AddDate(0, 0, -1), domain.MealSlotDinner)}}, nil},
+		{"leftovers of breakfast", domain.MealDay{Date: monday, Leftovers: []domain.Leftovers{leftovers(domain.MealSlotDinner, monday, domain.MealSlotBreakfast)}}, nil},
+		{"leftovers of a later meal", domain.MealDay{Date: monday, Leftovers: []domain.Leftovers{leftovers(domain.MealSlotLunch, monday, domain.MealSlotDinner)}}, []string{"lunch-leftovers"}},
+		{"leftovers of itself", domain.MealDay{Date: monday, Leftovers: []domain.Leftovers{leftovers(domain.MealSlotLunch, monday, domain.MealSlotLunch)}}, []string{"lunch-leftovers"}},
+		{"old leftovers", domain.MealDay{Date: monday, Leftovers: []domain.Leftovers{leftovers(domain.MealSlotLunch, monday.AddDate(0, 0, -domain.MaxLeftoversDays-1), domain.MealSlotDinner)}}, []string{"lunch-leftovers"}},
+		{"leftovers of snacks", domain.MealDay{Date: monday, Leftovers: []domain.Leftovers{leftovers(domain.MealSlotDinner, monday, domain.MealSlotSnack)}}, []string{"dinner-leftovers"}},
+		{"snacks as leftovers", domain.MealDay{Date: monday, Leftovers: []domain.Leftovers{leftovers(domain.MealSlotSnack, monday, domain.MealSlotLunch)}}, []string{"leftovers"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectInvalidFields(t, test.mealDay.Validate(), test.fields)
+		})
+	}
+}
+
+func TestNutritionValidate(t *testing.T) {
+	today := time.Date(2024, time.June, 10, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		nutrition domain.Nutrition
+		fields    []string
+	}{
+		{"empty", domain.Nutrition{Date: today}, nil},
+		{"plausible", domain.Nutrition{Date: today, Calories: 2100, Weight: 80_500}, nil},
+		{"negative calories", domain.Nutrition{Date: today, Calories: -1}, []string{"calories"}},
+		{"absurd calories", domain.Nutrition{Date: today, Calories: domain.MaxCalories + 1}, []string{"calories"}},
+		{"too light", domain.Nutrition{Date: today, Weight: domain.MinWeight - 1}, []string{"weight"}},
+		{"too heavy", domain.Nutrition{Date: today, Weight: domain.MaxWeight + 1}, []string{"weight"}},
+		{"weigh-in tomorrow", domain.Nutrition{Date: today.AddDate(0, 0, 1), Weight: 80_000}, nil},
+		{"future weigh-in", domain.Nutrition{Date: today.AddDate(0, 0, 2), Weight: 80_000}, []string{"weight"}},
+		{"future calories", domain.Nutrition{Date: today.AddDate(0, 0, 7), Calories: 2000}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectInvalidFields(t, test.nutrition.Validate(today), test.fields)
+		})
+	}
+}
+
+func expectInvalidFields(t *testing.T, err error, fields []string) {
+	t.Helper()
+
+	if len(fields) == 0 {
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return
+	}
+
+	var invalid *domain.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	if domain.KindOf(err) != domain.ErrorKindValidation {
+		t.Errorf("expected kind %q, got %q", domain.ErrorKindValidation, domain.KindOf(err))
+	}
+
+	if len(invalid.Fields) != len(fields) {
+		t.Errorf("expected invalid fields %v, got %v", fields, invalid.Fields)
+	}
+
+	for _, field := range fields {
+		if invalid.Fields[field] == "" {
+			t.Errorf("expected %s to be invalid, got %v", field, invalid.Fields)
+		}
+	}
+}
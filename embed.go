@@ -0,0 +1,36 @@
+// Package mealplanning embeds the views and the esbuild output, so the binary
+// does not depend on its working directory.
+package mealplanning
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed views/*.gohtml
+var views embed.FS
+
+// build holds the output of esbuild.mjs. It only contains .gitkeep until the
+// assets were built.
+//
+//go:embed all:build
+var build embed.FS
+
+// Views returns the templates in views/.
+func Views() fs.FS {
+	return mustSub(views, "views")
+}
+
+// Build returns the esbuild output: manifest.json and the assets/ directory.
+func Build() fs.FS {
+	return mustSub(build, "build")
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
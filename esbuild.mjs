@@ -11,7 +11,7 @@ const result = await esbuild.build({
     ],
     bundle: true,
     minify: true,
-    outdir: 'dist/assets',
+    outdir: 'build/assets',
     metafile: true,
     entryNames: '[dir]/[name]-[hash]',
     sourcemap: true,
@@ -27,9 +27,9 @@ const result = await esbuild.build({
 
 const outputFiles = Object.entries(result.metafile.outputs)
     .map(([filename, details]) => {
-        return filename.slice(5);
+        return filename.slice('build/'.length);
     });
 
 const encoder = new TextEncoder();
 
-writeFileSync("dist/manifest.json", encoder.encode(JSON.stringify({ outputFiles })));
+writeFileSync("build/manifest.json", encoder.encode(JSON.stringify({ outputFiles })));
@@ -0,0 +1,227 @@
+package foodimport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"meal-planning/domain"
+	"strconv"
+	"strings"
+)
+
+// FoodData Central nutrient numbers, see the nutrient.csv of the downloads.
+const (
+	nutrientEnergyKcal            = "208"
+	nutrientEnergyAtwaterSpecific = "958"
+	nutrientEnergyAtwaterGeneral  = "957"
+	nutrientEnergyKilojoules      = "268"
+	nutrientProtein               = "203"
+	nutrientFat                   = "204"
+	nutrientCarbs                 = "205"
+)
+
+// foodDataCentralArrays are the top level keys of the JSON downloads that hold
+// foods.
+var foodDataCentralArrays = map[string]struct{}{
+	"FoundationFoods": {},
+	"SRLegacyFoods":   {},
+	"BrandedFoods":    {},
+	"SurveyFoods":     {},
+}
+
+type foodDataCentralFood struct {
+	FdcID         int64  `json:"fdcId"`
+	Description   string `json:"description"`
+	BrandName     string `json:"brandName"`
+	BrandOwner    string `json:"brandOwner"`
+	FoodNutrients []struct {
+		Nutrient struct {
+			Number string `json:"number"`
+		} `json:"nutrient"`
+		Amount float64 `json:"amount"`
+	} `json:"foodNutrients"`
+	FoodPortions []foodDataCentralPortion `json:"foodPortions"`
+	// branded foods have a single serving instead of portions
+	ServingSize              float64 `json:"servingSize"`
+	ServingSizeUnit          string  `json:"servingSizeUnit"`
+	HouseholdServingFullText string  `json:"householdServingFullText"`
+}
+
+type foodDataCentralPortion struct {
+	Amount             float64 `json:"amount"`
+	GramWeight         float64 `json:"gramWeight"`
+	Modifier           string  `json:"modifier"`
+	PortionDescription string  `json:"portionDescription"`
+	MeasureUnit        struct {
+		Name string `json:"name"`
+	} `json:"measureUnit"`
+}
+
+func (f foodDataCentralFood) toDomain() domain.Food {
+	nutrients := make(map[string]float64, len(f.FoodNutrients))
+	for _, nutrient := range f.FoodNutrients {
+		nutrients[nutrient.Nutrient.Number] = nutrient.Amount
+	}
+
+	kcal, ok := nutrients[nutrientEnergyKcal]
+	if !ok {
+		kcal, ok = nutrients[nutrientEnergyAtwaterSpecific]
+	}
+	if !ok {
+		kcal, ok = nutrients[nutrientEnergyAtwaterGeneral]
+	}
+	if !ok {
+		kcal = nutrients[nutrientEnergyKilojoules] / kilojoulesPerKcal
+	}
+
+	brand := f.BrandName
+	if brand == "" {
+		brand = f.BrandOwner
+	}
+
+	food := domain.Food{
+		Name:  f.Description,
+		Brand: strings.TrimSpace(brand),
+		Per100g: domain.Nutrients{
+			Kcal:    kcal,
+			Protein: nutrients[nutrientProtein],
+			Carbs:   nutrients[nutrientCarbs],
+			Fat:     nutrients[nutrientFat],
+		},
+		Source:   domain.FoodSourceUSDA,
+		SourceID: strconv.FormatInt(f.FdcID, 10),
+	}
+
+	for _, portion := range f.FoodPortions {
+		if portion.GramWeight <= 0 {
+			continue
+		}
+
+		food.ServingSizes = append(food.ServingSizes, domain.ServingSize{Name: portion.name(), Grams: portion.GramWeight})
+	}
+
+	if f.ServingSize > 0 && (strings.EqualFold(f.ServingSizeUnit, "g") || strings.EqualFold(f.ServingSizeUnit, "grm")) {
+		name := strings.TrimSpace(f.HouseholdServingFullText)
+		if name == "" {
+			name = "1 serving"
+		}
+
+		food.ServingSizes = append(food.ServingSizes, domain.ServingSize{Name: name, Grams: f.ServingSize})
+	}
+
+	return food
+}
+
+// name describes a portion like "1 cup, chopped". SR Legacy only has the
+// modifier, Foundation Foods a unit and sometimes a description.
+func (portion foodDataCentralPortion) name() string {
+	if description := strings.TrimSpace(portion.PortionDescription); description != "" && description != "Quantity not specified" {
+		return description
+	}
+
+	parts := make([]string, 0, 3)
+	if portion.Amount > 0 {
+		parts = append(parts, strconv.FormatFloat(portion.Amount, 'f', -1, 64))
+	}
+	if unit := strings.TrimSpace(portion.MeasureUnit.Name); unit != "" && unit != "undetermined" {
+		parts = append(parts, unit)
+	}
+	if modifier := strings.TrimSpace(portion.Modifier); modifier != "" {
+		parts = append(parts, modifier)
+	}
+
+	if len(parts) == 0 {
+		return "1 serving"
+	}
+
+	return strings.Join(parts, " ")
+}
+
+type foodDataCentralReader struct {
+	decoder *json.Decoder
+	// inArray is set while the decoder is inside one of the food arrays.
+	inArray bool
+	started bool
+}
+
+// NewFoodDataCentralReader reads the JSON downloads of USDA FoodData Central.
+// The downloads are a single object holding an array of foods, which is
+// streamed instead of loaded at once.
+func NewFoodDataCentralReader(r io.Reader) domain.FoodReader {
+	return &foodDataCentralReader{decoder: json.NewDecoder(r)}
+}
+
+func (r *foodDataCentralReader) Read() (domain.Food, error) {
+	if !r.started {
+		err := expectDelimiter(r.decoder, '{')
+		if err != nil {
+			return domain.Food{}, err
+		}
+
+		r.started = true
+	}
+
+	for {
+		if r.inArray {
+			if r.decoder.More() {
+				var food foodDataCentralFood
+				err := r.decoder.Decode(&food)
+				if err != nil {
+					return domain.Food{}, fmt.Errorf("parsing food: %w", err)
+				}
+
+				return food.toDomain(), nil
+			}
+
+			err := expectDelimiter(r.decoder, ']')
+			if err != nil {
+				return domain.Food{}, err
+			}
+
+			r.inArray = false
+		}
+
+		if !r.decoder.More() {
+			return domain.Food{}, io.EOF
+		}
+
+		token, err := r.decoder.Token()
+		if err != nil {
+			return domain.Food{}, err
+		}
+
+		key, _ := token.(string)
+		if _, ok := foodDataCentralArrays[key]; !ok {
+			var skipped json.RawMessage
+			err = r.decoder.Decode(&skipped)
+			if err != nil {
+				return domain.Food{}, err
+			}
+
+			continue
+		}
+
+		err = expectDelimiter(r.decoder, '[')
+		if err != nil {
+			return domain.Food{}, err
+		}
+
+		r.inArray = true
+	}
+}
+
+func expectDelimiter(decoder *json.Decoder, expected json.Delim) error {
+	token, err := decoder.Token()
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+
+	if delimiter, ok := token.(json.Delim); !ok || delimiter != expected {
+		return fmt.Errorf("expected %v, got %v", expected, token)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package foodimport reads foods from the offline dumps of OpenFoodFacts and
+// USDA FoodData Central, for domain.FoodService.Import.
+package foodimport
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"meal-planning/domain"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// kilojoulesPerKcal converts energy for dumps that only give kJ.
+const kilojoulesPerKcal = 4.184
+
+// File is a dump opened with Open. Close closes the underlying file.
+type File struct {
+	domain.FoodReader
+	closers []io.Closer
+}
+
+func (f *File) Close() error {
+	var err error
+	for i := len(f.closers) - 1; i >= 0; i-- {
+		if closeErr := f.closers[i].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// Open opens the dump at name and picks the reader by its extension:
+//
+//   - .csv and .tsv: the tab separated OpenFoodFacts export
+//   - .jsonl: the OpenFoodFacts JSONL export
+//   - .json: a FoodData Central download, e.g. Foundation Foods or SR Legacy
+//
+// Any of them may be gzip compressed with an additional .gz extension.
+func Open(name string) (*File, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dump := &File{closers: []io.Closer{file}}
+
+	var reader io.Reader = file
+	extension := strings.ToLower(filepath.Ext(name))
+	if extension == ".gz" {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			dump.Close()
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+
+		dump.closers = append(dump.closers, gzipReader)
+		reader = gzipReader
+		extension = strings.ToLower(filepath.Ext(strings.TrimSuffix(name, filepath.Ext(name))))
+	}
+
+	switch extension {
+	case ".csv", ".tsv":
+		dump.FoodReader, err = NewOpenFoodFactsCSVReader(reader)
+	case ".jsonl":
+		dump.FoodReader = NewOpenFoodFactsJSONReader(reader)
+	case ".json":
+		dump.FoodReader = NewFoodDataCentralReader(reader)
+	default:
+		err = fmt.Errorf("unknown dump format %q", extension)
+	}
+
+	if err != nil {
+		dump.Close()
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	return dump, nil
+}
+
+// parseAmount parses the nutrient values of dumps. Missing values count as 0,
+// values that do not parse as NaN, so validation skips the food instead of
+// importing made up numbers.
+func parseAmount(value string) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return math.NaN()
+	}
+
+	return amount
+}
@@ -0,0 +1,254 @@
+package foodimport
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+	"meal-planning/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const openFoodFactsCSV = "code\tproduct_name\tbrands\tserving_size\tserving_quantity\tenergy-kcal_100g\tenergy_100g\tfat_100g\tcarbohydrates_100g\tproteins_100g\n" +
+	"7394376616037\tOat drink \"Barista\"\tOatly,Oatly AB\t250 ml\t250\t59\t247\t3\t6.6\t1.1\n" +
+	"4008400402222\tNutella\tFerrero\t\t\t\t2252\t30.9\t57.5\t6.3\n" +
+	"0000000000001\tBroken\t\t\t\tmany\t\t\t\t\n"
+
+const openFoodFactsJSONL = `{"code":"7394376616037","product_name":"Oat drink","brands":"Oatly","serving_size":"250 ml","serving_quantity":"250","nutriments":{"energy-kcal_100g":59,"fat_100g":3,"carbohydrates_100g":"6.6","proteins_100g":1.1}}
+
+{"code":"4008400402222","product_name":"Nutella","brands":"Ferrero","serving_quantity":null,"nutriments":{"energy_100g":2252,"fat_100g":30.9}}
+`
+
+const foodDataCentralJSON = `{"FoundationFoods": [
+	{
+		"fdcId": 2346396,
+		"description": "Oats, whole grain, rolled, old fashioned",
+		"foodNutrients": [
+			{"nutrient": {"number": "203", "unitName": "g"}, "amount": 13.5},
+			{"nutrient": {"number": "204", "unitName": "g"}, "amount": 5.89},
+			{"nutrient": {"number": "205", "unitName": "g"}, "amount": 68.7},
+			{"nutrient": {"number": "957", "unitName": "kcal"}, "amount": 382},
+			{"nutrient": {"number": "958", "unitName": "kcal"}, "amount": 375}
+		],
+		"foodPortions": [
+			{"amount": 1, "gramWeight": 81, "modifier": "", "measureUnit": {"name": "cup"}},
+			{"amount": 1, "gramWeight": 0, "measureUnit": {"name": "tbsp"}}
+		]
+	},
+	{
+		"fdcId": 173904,
+		"description": "Butter, salted",
+		"foodNutrients": [{"nutrient": {"number": "268"}, "amount": 3000}],
+		"foodPortions": [{"amount": 1, "gramWeight": 14.2, "modifier": "tbsp", "measureUnit": {"name": "undetermined"}}]
+	}
+], "Notes": {"ignored": [1, 2, 3]}, "BrandedFoods": [
+	{
+		"fdcId": 1234,
+		"description": "Peanut butter",
+		"brandOwner": "Acme Foods",
+		"servingSize": 32,
+		"servingSizeUnit": "GRM",
+		"householdServingFullText": "2 Tbsp",
+		"foodNutrients": [{"nutrient": {"number": "208"}, "amount": 594}]
+	}
+]}`
+
+func TestOpenFoodFactsCSVReader(t *testing.T) {
+	reader, err := NewOpenFoodFactsCSVReader(strings.NewReader(openFoodFactsCSV))
+	if err != nil {
+		t.Fatalf("creating reader: %v", err)
+	}
+
+	foods := readAll(t, reader)
+
+	expectFoods(t, foods, []domain.Food{
+		{
+			Name:         `Oat drink "Barista"`,
+			Brand:        "Oatly",
+			Per100g:      domain.Nutrients{Kcal: 59, Protein: 1.1, Carbs: 6.6, Fat: 3},
+			ServingSizes: []domain.ServingSize{{Name: "250 ml", Grams: 250}},
+			Source:       domain.FoodSourceOpenFoodFacts,
+			SourceID:     "7394376616037",
+		},
+		{
+			Name:     "Nutella",
+			Brand:    "Ferrero",
+			Per100g:  domain.Nutrients{Kcal: 2252 / kilojoulesPerKcal, Protein: 6.3, Carbs: 57.5, Fat: 30.9},
+			Source:   domain.FoodSourceOpenFoodFacts,
+			SourceID: "4008400402222",
+		},
+	})
+
+	if broken := foods[2]; !math.IsNaN(broken.Per100g.Kcal) || broken.Validate() == nil {
+		t.Errorf("expected unparsable values to fail validation, got %+v", broken)
+	}
+}
+
+func TestOpenFoodFactsCSVReaderNeedsHeader(t *testing.T) {
+	_, err := NewOpenFoodFactsCSVReader(strings.NewReader("product_name\tbrands\n"))
+	if err == nil {
+		t.Error("expected an error for a dump without a code column")
+	}
+}
+
+func TestOpenFoodFactsJSONReader(t *testing.T) {
+	foods := readAll(t, NewOpenFoodFactsJSONReader(strings.NewReader(openFoodFactsJSONL)))
+
+	expectFoods(t, foods, []domain.Food{
+		{
+			Name:         "Oat drink",
+			Brand:        "Oatly",
+			Per100g:      domain.Nutrients{Kcal: 59, Protein: 1.1, Carbs: 6.6, Fat: 3},
+			ServingSizes: []domain.ServingSize{{Name: "250 ml", Grams: 250}},
+			Source:       domain.FoodSourceOpenFoodFacts,
+			SourceID:     "7394376616037",
+		},
+		{
+			Name:     "Nutella",
+			Brand:    "Ferrero",
+			Per100g:  domain.Nutrients{Kcal: 2252 / kilojoulesPerKcal, Fat: 30.9},
+			Source:   domain.FoodSourceOpenFoodFacts,
+			SourceID: "4008400402222",
+		},
+	})
+}
+
+func TestFoodDataCentralReader(t *testing.T) {
+	foods := readAll(t, NewFoodDataCentralReader(strings.NewReader(foodDataCentralJSON)))
+
+	expectFoods(t, foods, []domain.Food{
+		{
+			Name:         "Oats, whole grain, rolled, old fashioned",
+			Per100g:      domain.Nutrients{Kcal: 375, Protein: 13.5, Carbs: 68.7, Fat: 5.89},
+			ServingSizes: []domain.ServingSize{{Name: "1 cup", Grams: 81}},
+			Source:       domain.FoodSourceUSDA,
+			SourceID:     "2346396",
+		},
+		{
+			Name:         "Butter, salted",
+			Per100g:      domain.Nutrients{Kcal: 3000 / kilojoulesPerKcal},
+			ServingSizes: []domain.ServingSize{{Name: "1 tbsp", Grams: 14.2}},
+			Source:       domain.FoodSourceUSDA,
+			SourceID:     "173904",
+		},
+		{
+			Name:         "Peanut butter",
+			Brand:        "Acme Foods",
+			Per100g:      domain.Nutrients{Kcal: 594},
+			ServingSizes: []domain.ServingSize{{Name: "2 Tbsp", Grams: 32}},
+			Source:       domain.FoodSourceUSDA,
+			SourceID:     "1234",
+		},
+	})
+}
+
+func TestFoodDataCentralReaderRejectsOtherJSON(t *testing.T) {
+	_, err := NewFoodDataCentralReader(strings.NewReader(`[1, 2]`)).Read()
+	if err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name, content string, compress bool) string {
+		path := filepath.Join(dir, name)
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+		defer file.Close()
+
+		var writer io.Writer = file
+		if compress {
+			gzipWriter := gzip.NewWriter(file)
+			defer gzipWriter.Close()
+			writer = gzipWriter
+		}
+
+		_, err = io.WriteString(writer, content)
+		if err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{"csv", writeFile("products.csv", openFoodFactsCSV, false), 3},
+		{"gzipped csv", writeFile("products.csv.gz", openFoodFactsCSV, true), 3},
+		{"jsonl", writeFile("products.jsonl", openFoodFactsJSONL, false), 2},
+		{"gzipped json", writeFile("FoodData_Central.json.gz", foodDataCentralJSON, true), 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dump, err := Open(test.path)
+			if err != nil {
+				t.Fatalf("opening dump: %v", err)
+			}
+			defer dump.Close()
+
+			if foods := readAll(t, dump); len(foods) != test.expected {
+				t.Errorf("expected %d foods, got %d", test.expected, len(foods))
+			}
+		})
+	}
+
+	_, err := Open(writeFile("products.xml", "<products/>", false))
+	if err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func readAll(t *testing.T, reader domain.FoodReader) []domain.Food {
+	t.Helper()
+
+	foods := make([]domain.Food, 0)
+	for {
+		food, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return foods
+		}
+
+		if err != nil {
+			t.Fatalf("reading foods: %v", err)
+		}
+
+		foods = append(foods, food)
+	}
+}
+
+func expectFoods(t *testing.T, actual, expected []domain.Food) {
+	t.Helper()
+
+	if len(actual) < len(expected) {
+		t.Fatalf("expected at least %d foods, got %d: %+v", len(expected), len(actual), actual)
+	}
+
+	for i, food := range expected {
+		got := actual[i]
+		if got.Name != food.Name || got.Brand != food.Brand || got.Per100g != food.Per100g || got.Source != food.Source || got.SourceID != food.SourceID {
+			t.Errorf("expected food %d to be %+v, got %+v", i, food, got)
+		}
+
+		if len(got.ServingSizes) != len(food.ServingSizes) {
+			t.Errorf("expected food %d to have serving sizes %v, got %v", i, food.ServingSizes, got.ServingSizes)
+			continue
+		}
+
+		for j := range food.ServingSizes {
+			if got.ServingSizes[j] != food.ServingSizes[j] {
+				t.Errorf("expected food %d to have serving sizes %v, got %v", i, food.ServingSizes, got.ServingSizes)
+			}
+		}
+	}
+}
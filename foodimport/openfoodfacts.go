@@ -0,0 +1,216 @@
+package foodimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"meal-planning/domain"
+	"strconv"
+	"strings"
+)
+
+// maxJSONLineSize allows for the long ingredient lists and image metadata some
+// OpenFoodFacts products carry.
+const maxJSONLineSize = 16 * 1024 * 1024
+
+// openFoodFactsProduct holds the fields of a product both OpenFoodFacts
+// exports have in common.
+type openFoodFactsProduct struct {
+	code            string
+	name            string
+	brands          string
+	kcal            float64
+	kilojoules      float64
+	protein         float64
+	carbs           float64
+	fat             float64
+	servingQuantity float64
+	servingSize     string
+}
+
+func (p openFoodFactsProduct) toDomain() domain.Food {
+	kcal := p.kcal
+	if kcal == 0 && p.kilojoules != 0 {
+		kcal = p.kilojoules / kilojoulesPerKcal
+	}
+
+	// brands is a comma separated list, the first is the one on the package
+	brand, _, _ := strings.Cut(p.brands, ",")
+
+	food := domain.Food{
+		Name:  p.name,
+		Brand: strings.TrimSpace(brand),
+		Per100g: domain.Nutrients{
+			Kcal:    kcal,
+			Protein: p.protein,
+			Carbs:   p.carbs,
+			Fat:     p.fat,
+		},
+		Source:   domain.FoodSourceOpenFoodFacts,
+		SourceID: strings.TrimSpace(p.code),
+	}
+
+	if p.servingQuantity > 0 {
+		name := strings.TrimSpace(p.servingSize)
+		if name == "" {
+			name = "1 serving"
+		}
+
+		food.ServingSizes = []domain.ServingSize{{Name: name, Grams: p.servingQuantity}}
+	}
+
+	return food
+}
+
+type openFoodFactsCSVReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// NewOpenFoodFactsCSVReader reads the tab separated CSV export of
+// OpenFoodFacts, starting with its header row.
+func NewOpenFoodFactsCSVReader(r io.Reader) (domain.FoodReader, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	// the export does not quote consistently, product names contain stray quotes
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+
+	if _, ok := columns["code"]; !ok {
+		return nil, errors.New("missing column code")
+	}
+
+	return &openFoodFactsCSVReader{reader: reader, columns: columns}, nil
+}
+
+func (r *openFoodFactsCSVReader) Read() (domain.Food, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		return domain.Food{}, err
+	}
+
+	value := func(column string) string {
+		i, ok := r.columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return record[i]
+	}
+
+	return openFoodFactsProduct{
+		code:            value("code"),
+		name:            value("product_name"),
+		brands:          value("brands"),
+		kcal:            parseAmount(value("energy-kcal_100g")),
+		kilojoules:      parseAmount(value("energy_100g")),
+		protein:         parseAmount(value("proteins_100g")),
+		carbs:           parseAmount(value("carbohydrates_100g")),
+		fat:             parseAmount(value("fat_100g")),
+		servingQuantity: parseAmount(value("serving_quantity")),
+		servingSize:     value("serving_size"),
+	}.toDomain(), nil
+}
+
+// amount is a nutrient value of the JSONL export, which has numbers as well as
+// strings of numbers.
+type amount float64
+
+func (a *amount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*a = 0
+		return nil
+	}
+
+	var value string
+	if json.Unmarshal(data, &value) == nil {
+		*a = amount(parseAmount(value))
+		return nil
+	}
+
+	number, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		number = math.NaN()
+	}
+
+	*a = amount(number)
+	return nil
+}
+
+type openFoodFactsJSONProduct struct {
+	Code            string `json:"code"`
+	ProductName     string `json:"product_name"`
+	Brands          string `json:"brands"`
+	ServingQuantity amount `json:"serving_quantity"`
+	ServingSize     string `json:"serving_size"`
+	Nutriments      struct {
+		Kcal       amount `json:"energy-kcal_100g"`
+		Kilojoules amount `json:"energy_100g"`
+		Protein    amount `json:"proteins_100g"`
+		Carbs      amount `json:"carbohydrates_100g"`
+		Fat        amount `json:"fat_100g"`
+	} `json:"nutriments"`
+}
+
+type openFoodFactsJSONReader struct {
+	scanner *bufio.Scanner
+}
+
+// NewOpenFoodFactsJSONReader reads the JSONL export of OpenFoodFacts, one
+// product per line.
+func NewOpenFoodFactsJSONReader(r io.Reader) domain.FoodReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLineSize)
+
+	return &openFoodFactsJSONReader{scanner: scanner}
+}
+
+func (r *openFoodFactsJSONReader) Read() (domain.Food, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var product openFoodFactsJSONProduct
+		err := json.Unmarshal(line, &product)
+		if err != nil {
+			return domain.Food{}, fmt.Errorf("parsing product: %w", err)
+		}
+
+		return openFoodFactsProduct{
+			code:            product.Code,
+			name:            product.ProductName,
+			brands:          product.Brands,
+			kcal:            float64(product.Nutriments.Kcal),
+			kilojoules:      float64(product.Nutriments.Kilojoules),
+			protein:         float64(product.Nutriments.Protein),
+			carbs:           float64(product.Nutriments.Carbs),
+			fat:             float64(product.Nutriments.Fat),
+			servingQuantity: float64(product.ServingQuantity),
+			servingSize:     product.ServingSize,
+		}.toDomain(), nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return domain.Food{}, err
+	}
+
+	return domain.Food{}, io.EOF
+}
@@ -2,37 +2,179 @@ package http
 
 import (
 	"bytes"
-	"io"
+	"crypto/sha256"
+	"encoding/base64"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
+// BufferedResponseWriter holds back the response until Close, so a handler can
+// still fail with a clean error response, and so the complete body can be
+// described by Content-Length and an ETag. Conditional GET requests whose
+// If-None-Match matches are answered with 304 Not Modified.
+//
+// Flush gives up on buffering: it sends what was written so far and streams
+// everything after it.
 type BufferedResponseWriter struct {
-	parent http.ResponseWriter
-	buffer *bytes.Buffer
+	parent      http.ResponseWriter
+	request     *http.Request
+	buffer      bytes.Buffer
+	statusCode  int
+	wroteHeader bool
+	flushed     bool
 }
 
-func NewBufferedResponseWriter(parent http.ResponseWriter) *BufferedResponseWriter {
-	return &BufferedResponseWriter{
-		parent: parent,
-		buffer: bytes.NewBuffer(make([]byte, 0, 1024)),
+func NewBufferedResponseWriter(parent http.ResponseWriter, request *http.Request) *BufferedResponseWriter {
+	b := &BufferedResponseWriter{
+		parent:     parent,
+		request:    request,
+		statusCode: http.StatusOK,
 	}
+	b.buffer.Grow(1024)
+
+	return b
 }
 
-func (b BufferedResponseWriter) Header() http.Header {
+func (b *BufferedResponseWriter) Header() http.Header {
 	return b.parent.Header()
 }
 
-func (b BufferedResponseWriter) Write(bytes []byte) (int, error) {
+// WriteHeader records the status code to send on Close. Only the first call
+// counts, informational responses are passed on right away.
+func (b *BufferedResponseWriter) WriteHeader(statusCode int) {
+	if statusCode < 200 {
+		b.parent.WriteHeader(statusCode)
+		return
+	}
+
+	if b.wroteHeader {
+		return
+	}
+
+	b.statusCode = statusCode
+	b.wroteHeader = true
+
+	if b.flushed {
+		b.parent.WriteHeader(statusCode)
+	}
+}
+
+func (b *BufferedResponseWriter) Write(bytes []byte) (int, error) {
+	b.wroteHeader = true
+
+	if b.flushed {
+		return b.parent.Write(bytes)
+	}
+
 	return b.buffer.Write(bytes)
 }
 
-func (b BufferedResponseWriter) WriteHeader(statusCode int) {
-	b.parent.Header().Add("Content-Length", strconv.Itoa(b.buffer.Len()))
-	b.parent.WriteHeader(statusCode)
+// StatusCode returns the status code that is or will be sent.
+func (b *BufferedResponseWriter) StatusCode() int {
+	return b.statusCode
 }
 
-func (b BufferedResponseWriter) Close() error {
-	_, err := io.Copy(b.parent, b.buffer)
+// Close sends the buffered response. It does nothing more after Flush.
+func (b *BufferedResponseWriter) Close() error {
+	if b.flushed {
+		return nil
+	}
+	b.flushed = true
+
+	header := b.parent.Header()
+
+	if b.statusCode == http.StatusOK {
+		if header.Get("ETag") == "" {
+			header.Set("ETag", StrongETag(b.buffer.Bytes()))
+		}
+
+		if b.isNotModified(header.Get("ETag")) {
+			// a 304 describes the stored response, it has no body of its own
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			b.parent.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	if bodyAllowed(b.statusCode) {
+		header.Set("Content-Length", strconv.Itoa(b.buffer.Len()))
+	}
+	b.parent.WriteHeader(b.statusCode)
+
+	if !bodyAllowed(b.statusCode) {
+		return nil
+	}
+
+	_, err := b.buffer.WriteTo(b.parent)
 	return err
 }
+
+func (b *BufferedResponseWriter) Flush() {
+	_ = b.FlushError()
+}
+
+// FlushError sends the status, the headers and the body written so far without
+// Content-Length or ETag and passes later writes straight through.
+func (b *BufferedResponseWriter) FlushError() error {
+	if !b.flushed {
+		b.flushed = true
+
+		if b.wroteHeader {
+			b.parent.WriteHeader(b.statusCode)
+		}
+
+		_, err := b.buffer.WriteTo(b.parent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(b.parent).Flush()
+}
+
+func (b *BufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return b.parent
+}
+
+func (b *BufferedResponseWriter) isNotModified(etag string) bool {
+	if b.request == nil {
+		return false
+	}
+
+	if b.request.Method != http.MethodGet && b.request.Method != http.MethodHead {
+		return false
+	}
+
+	return etagMatches(b.request.Header.Get("If-None-Match"), etag)
+}
+
+// etagMatches reports whether one of the entity tags in an If-None-Match header
+// matches etag. If-None-Match uses the weak comparison, so W/ prefixes are
+// ignored on both sides.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StrongETag returns an entity tag derived from the content of body.
+func StrongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+func bodyAllowed(statusCode int) bool {
+	return statusCode != http.StatusNoContent && statusCode != http.StatusNotModified
+}
@@ -0,0 +1,223 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestBufferedResponseWriterSendsOnClose(t *testing.T) {
+	tests := []struct {
+		name       string
+		write      func(writer *BufferedResponseWriter)
+		statusCode int
+		body       string
+	}{
+		{"status before body", func(writer *BufferedResponseWriter) {
+			writer.WriteHeader(http.StatusConflict)
+			writer.Write([]byte("conflict"))
+		}, http.StatusConflict, "conflict"},
+		{"first status wins", func(writer *BufferedResponseWriter) {
+			writer.WriteHeader(http.StatusCreated)
+			writer.WriteHeader(http.StatusInternalServerError)
+			writer.Write([]byte("created"))
+		}, http.StatusCreated, "created"},
+		{"write implies ok", func(writer *BufferedResponseWriter) {
+			writer.Write([]byte("hello, "))
+			writer.Write([]byte("world"))
+			writer.WriteHeader(http.StatusNotFound)
+		}, http.StatusOK, "hello, world"},
+		{"empty body", func(writer *BufferedResponseWriter) {}, http.StatusOK, ""},
+		{"no content", func(writer *BufferedResponseWriter) {
+			writer.WriteHeader(http.StatusNoContent)
+		}, http.StatusNoContent, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			writer := NewBufferedResponseWriter(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			test.write(writer)
+			if recorder.Flushed || recorder.Body.Len() > 0 {
+				t.Fatalf("expected nothing to be sent before Close")
+			}
+
+			err := writer.Close()
+			if err != nil {
+				t.Fatalf("closing writer: %v", err)
+			}
+
+			if recorder.Code != test.statusCode {
+				t.Errorf("expected status %d, got %d", test.statusCode, recorder.Code)
+			}
+
+			if writer.StatusCode() != test.statusCode {
+				t.Errorf("expected recorded status %d, got %d", test.statusCode, writer.StatusCode())
+			}
+
+			if recorder.Body.String() != test.body {
+				t.Errorf("expected body %q, got %q", test.body, recorder.Body.String())
+			}
+
+			if test.statusCode == http.StatusNoContent {
+				if contentLength := recorder.Header().Get("Content-Length"); contentLength != "" {
+					t.Errorf("expected no Content-Length, got %q", contentLength)
+				}
+				return
+			}
+
+			if contentLength := recorder.Header().Get("Content-Length"); contentLength != strconv.Itoa(len(test.body)) {
+				t.Errorf("expected Content-Length %d, got %q", len(test.body), contentLength)
+			}
+		})
+	}
+}
+
+func TestBufferedResponseWriterETag(t *testing.T) {
+	send := func(body string, header http.Header) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		for key, values := range header {
+			recorder.Header()[key] = values
+		}
+
+		writer := NewBufferedResponseWriter(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		writer.Write([]byte(body))
+		writer.Close()
+
+		return recorder
+	}
+
+	first := send("week plan", nil).Header().Get("ETag")
+	if first == "" || first[0] != '"' {
+		t.Fatalf("expected a strong ETag, got %q", first)
+	}
+
+	if again := send("week plan", nil).Header().Get("ETag"); again != first {
+		t.Errorf("expected the same body to get the same ETag, got %q and %q", first, again)
+	}
+
+	if other := send("other plan", nil).Header().Get("ETag"); other == first {
+		t.Errorf("expected a different body to get a different ETag, got %q for both", other)
+	}
+
+	own := send("week plan", http.Header{"Etag": {`"v42"`}}).Header().Get("ETag")
+	if own != `"v42"` {
+		t.Errorf("expected an existing ETag to be kept, got %q", own)
+	}
+}
+
+func TestBufferedResponseWriterNotModified(t *testing.T) {
+	body := "week plan"
+	etag := StrongETag([]byte(body))
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		statusCode  int
+		expected    int
+	}{
+		{"matching etag", http.MethodGet, etag, http.StatusOK, http.StatusNotModified},
+		{"matching weak etag", http.MethodGet, "W/" + etag, http.StatusOK, http.StatusNotModified},
+		{"matching etag in list", http.MethodGet, `"other", ` + etag, http.StatusOK, http.StatusNotModified},
+		{"wildcard", http.MethodGet, "*", http.StatusOK, http.StatusNotModified},
+		{"head request", http.MethodHead, etag, http.StatusOK, http.StatusNotModified},
+		{"other etag", http.MethodGet, `"other"`, http.StatusOK, http.StatusOK},
+		{"no etag", http.MethodGet, "", http.StatusOK, http.StatusOK},
+		{"unsafe method", http.MethodPut, etag, http.StatusOK, http.StatusOK},
+		{"error status", http.MethodGet, etag, http.StatusConflict, http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "/", nil)
+			if test.ifNoneMatch != "" {
+				request.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
+
+			recorder := httptest.NewRecorder()
+			recorder.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+			writer := NewBufferedResponseWriter(recorder, request)
+			writer.WriteHeader(test.statusCode)
+			writer.Write([]byte(body))
+
+			err := writer.Close()
+			if err != nil {
+				t.Fatalf("closing writer: %v", err)
+			}
+
+			if recorder.Code != test.expected {
+				t.Fatalf("expected status %d, got %d", test.expected, recorder.Code)
+			}
+
+			if test.expected != http.StatusNotModified {
+				return
+			}
+
+			if recorder.Body.Len() != 0 {
+				t.Errorf("expected no body, got %q", recorder.Body.String())
+			}
+
+			if recorder.Header().Get("ETag") != etag {
+				t.Errorf("expected ETag %q, got %q", etag, recorder.Header().Get("ETag"))
+			}
+
+			for _, key := range []string{"Content-Type", "Content-Length"} {
+				if value := recorder.Header().Get(key); value != "" {
+					t.Errorf("expected no %s, got %q", key, value)
+				}
+			}
+		})
+	}
+}
+
+func TestBufferedResponseWriterFlush(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := NewBufferedResponseWriter(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var flusher http.Flusher = writer
+	writer.WriteHeader(http.StatusAccepted)
+	writer.Write([]byte("first"))
+	flusher.Flush()
+
+	if !recorder.Flushed || recorder.Code != http.StatusAccepted || recorder.Body.String() != "first" {
+		t.Fatalf("expected flush to send status %d and %q, got %d and %q", http.StatusAccepted, "first", recorder.Code, recorder.Body.String())
+	}
+
+	writer.Write([]byte(", second"))
+	if recorder.Body.String() != "first, second" {
+		t.Errorf("expected writes after flush to pass through, got %q", recorder.Body.String())
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatalf("closing writer: %v", err)
+	}
+
+	if recorder.Body.String() != "first, second" {
+		t.Errorf("expected Close after flush to send nothing, got %q", recorder.Body.String())
+	}
+
+	for _, key := range []string{"Content-Length", "ETag"} {
+		if value := recorder.Header().Get(key); value != "" {
+			t.Errorf("expected no %s on a streamed response, got %q", key, value)
+		}
+	}
+}
+
+func TestBufferedResponseWriterUnwrap(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := NewBufferedResponseWriter(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if writer.Unwrap() != recorder {
+		t.Errorf("expected Unwrap to return the parent writer")
+	}
+
+	err := http.NewResponseController(writer).Flush()
+	if err != nil {
+		t.Errorf("expected ResponseController to flush, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		return gzip.NewWriter(io.Discard)
+	}}
+	brotliWriters = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}}
+)
+
+// Compress encodes responses with brotli or gzip if the client accepts it and
+// the content type is worth compressing. Event streams, partial content and
+// responses that already carry a Content-Encoding are passed through.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		compressor := &compressingResponseWriter{
+			ResponseWriter: writer,
+			encoding:       negotiateEncoding(request.Header.Get("Accept-Encoding")),
+			head:           request.Method == http.MethodHead,
+		}
+		defer compressor.close()
+
+		next.ServeHTTP(compressor, request)
+	})
+}
+
+// negotiateEncoding picks the encoding for the Accept-Encoding header,
+// preferring brotli over gzip at the same quality. It returns an empty string if
+// neither is acceptable.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(key) != "q" {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil {
+				quality = parsed
+			}
+		}
+
+		qualities[coding] = quality
+	}
+
+	quality := func(coding string) float64 {
+		if q, ok := qualities[coding]; ok {
+			return q
+		}
+
+		return qualities["*"]
+	}
+
+	brotliQuality, gzipQuality := quality(encodingBrotli), quality(encodingGzip)
+	switch {
+	case brotliQuality > 0 && brotliQuality >= gzipQuality:
+		return encodingBrotli
+	case gzipQuality > 0:
+		return encodingGzip
+	default:
+		return ""
+	}
+}
+
+// isCompressible reports whether responses of the content type shrink enough
+// to be worth encoding.
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if mediaType == "text/event-stream" {
+		return false
+	}
+
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+
+	switch mediaType {
+	case "application/javascript", "application/json", "application/problem+json", "application/manifest+json", "image/svg+xml":
+		return true
+	}
+
+	return false
+}
+
+// compressingResponseWriter decides on the encoding when the header is
+// written, as only then the content type and status are known.
+type compressingResponseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	head        bool
+	wroteHeader bool
+	encoder     encoder
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+func (c *compressingResponseWriter) WriteHeader(statusCode int) {
+	if c.wroteHeader || statusCode < 200 {
+		c.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	c.wroteHeader = true
+
+	header := c.Header()
+	if isCompressible(header.Get("Content-Type")) {
+		header.Add("Vary", "Accept-Encoding")
+
+		if c.shouldEncode(statusCode) {
+			c.startEncoding()
+		}
+	}
+
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *compressingResponseWriter) shouldEncode(statusCode int) bool {
+	if c.encoding == "" || c.head {
+		return false
+	}
+
+	switch statusCode {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+
+	header := c.Header()
+	return header.Get("Content-Encoding") == "" && header.Get("Content-Range") == ""
+}
+
+func (c *compressingResponseWriter) startEncoding() {
+	header := c.Header()
+	header.Set("Content-Encoding", c.encoding)
+	header.Del("Content-Length")
+
+	// the encoded body is no longer byte for byte what a strong validator
+	// promises, weak comparison still matches it on revalidation
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+
+	switch c.encoding {
+	case encodingBrotli:
+		c.encoder = brotliWriters.Get().(*brotli.Writer)
+	case encodingGzip:
+		c.encoder = gzipWriters.Get().(*gzip.Writer)
+	}
+	c.encoder.Reset(c.ResponseWriter)
+}
+
+func (c *compressingResponseWriter) Write(bytes []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	if c.encoder == nil {
+		return c.ResponseWriter.Write(bytes)
+	}
+
+	return c.encoder.Write(bytes)
+}
+
+func (c *compressingResponseWriter) Flush() {
+	_ = c.FlushError()
+}
+
+func (c *compressingResponseWriter) FlushError() error {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	if c.encoder != nil {
+		err := c.encoder.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+func (c *compressingResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// close finishes the encoded stream and returns the encoder to its pool.
+func (c *compressingResponseWriter) close() {
+	if c.encoder == nil {
+		return
+	}
+
+	_ = c.encoder.Close()
+	c.encoder.Reset(io.Discard)
+
+	switch encoder := c.encoder.(type) {
+	case *brotli.Writer:
+		brotliWriters.Put(encoder)
+	case *gzip.Writer:
+		gzipWriters.Put(encoder)
+	}
+	c.encoder = nil
+}
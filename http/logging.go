@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ContextHandler adds the request ID stored in the context to every record
+// logged with one of the context aware slog functions, e.g. slog.InfoContext.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: handler}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		record.AddAttrs(slog.String("requestID", requestID))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewContextHandler(h.Handler.WithAttrs(attrs))
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return NewContextHandler(h.Handler.WithGroup(name))
+}
+
+// Router resolves the route pattern that handles a request, it is implemented
+// by http.ServeMux.
+type Router interface {
+	Handler(request *http.Request) (http.Handler, string)
+}
+
+type RequestSummary struct {
+	Pattern    string
+	StatusCode int
+	Bytes      int
+	Duration   time.Duration
+}
+
+// Observe calls observer with a summary of every request once it was handled.
+func Observe(router Router, observer func(request *http.Request, summary RequestSummary)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			start := time.Now()
+			_, pattern := router.Handler(request)
+			recorder := newStatusRecorder(writer)
+
+			next.ServeHTTP(recorder, request)
+
+			observer(request, RequestSummary{
+				Pattern:    pattern,
+				StatusCode: recorder.statusCode,
+				Bytes:      recorder.bytes,
+				Duration:   time.Since(start),
+			})
+		})
+	}
+}
+
+// AccessLog logs every request with its route pattern, status code, duration
+// and the number of bytes written.
+func AccessLog(router Router) Middleware {
+	return Observe(router, func(request *http.Request, summary RequestSummary) {
+		slog.InfoContext(
+			request.Context(),
+			"Handled request",
+			slog.String("method", request.Method),
+			slog.String("pattern", summary.Pattern),
+			slog.String("path", request.URL.Path),
+			slog.Int("status", summary.StatusCode),
+			slog.Duration("duration", summary.Duration),
+			slog.Int("bytes", summary.Bytes),
+		)
+	})
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(NewContextHandler(slog.NewJSONHandler(buffer, nil))))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /meals/{date}", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusTeapot)
+		writer.Write([]byte("meals"))
+	})
+	handler := Chain(mux, RequestID, AccessLog(mux))
+
+	request := httptest.NewRequest(http.MethodGet, "/meals/2024-06-03", nil)
+	request.Header.Set(RequestIDHeader, "proxy-4f2a")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	var record struct {
+		Msg       string
+		Method    string
+		Pattern   string
+		Path      string
+		Status    int
+		Bytes     int
+		RequestID string
+	}
+	err := json.Unmarshal(buffer.Bytes(), &record)
+	if err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buffer.String(), err)
+	}
+
+	if record.Msg != "Handled request" || record.Method != http.MethodGet || record.Pattern != "GET /meals/{date}" || record.Path != "/meals/2024-06-03" {
+		t.Errorf("expected the request to be logged with its route pattern, got %+v", record)
+	}
+
+	if record.Status != http.StatusTeapot || record.Bytes != len("meals") || record.RequestID != "proxy-4f2a" {
+		t.Errorf("expected the status, size and request ID to be logged, got %+v", record)
+	}
+}
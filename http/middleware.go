@@ -0,0 +1,15 @@
+package http
+
+import "net/http"
+
+type Middleware func(next http.Handler) http.Handler
+
+// Chain wraps the handler in the given middlewares. The first middleware is the
+// outermost one and sees the request first.
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns panics in handlers into a 500 response rendered by
+// errorPage. If the handler already started the response, the connection is
+// closed instead.
+func Recover(errorPage http.Handler) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			recorder := newStatusRecorder(writer)
+
+			defer func() {
+				reason := recover()
+				if reason == nil {
+					return
+				}
+
+				if reason == http.ErrAbortHandler {
+					panic(reason)
+				}
+
+				slog.ErrorContext(request.Context(), "Recovered from panic", slog.Any("reason", reason), slog.String("stack", string(debug.Stack())))
+
+				if recorder.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+
+				// drop headers the handler set before it panicked
+				header := writer.Header()
+				for key := range header {
+					if key != http.CanonicalHeaderKey(RequestIDHeader) {
+						header.Del(key)
+					}
+				}
+
+				errorPage.ServeHTTP(recorder, request)
+			}()
+
+			next.ServeHTTP(recorder, request)
+		})
+	}
+}
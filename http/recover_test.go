@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// errorPage renders the response to a panic.
+var errorPage = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.WriteHeader(http.StatusInternalServerError)
+	writer.Write([]byte("something went wrong"))
+})
+
+func TestRecoverServesErrorPage(t *testing.T) {
+	handler := Chain(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Cache-Control", "max-age=3600")
+		writer.Header().Set("HX-Trigger", "saved")
+		panic("nil map")
+	}), RequestID, Recover(errorPage))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", recorder.Code)
+	}
+
+	if body := recorder.Body.String(); body != "something went wrong" {
+		t.Errorf("expected the error page, got %q", body)
+	}
+
+	header := recorder.Header()
+	if header.Get(RequestIDHeader) == "" {
+		t.Errorf("expected the request ID to be kept")
+	}
+
+	if header.Get("Cache-Control") != "" || header.Get("HX-Trigger") != "" {
+		t.Errorf("expected the headers of the handler to be dropped, got %v", header)
+	}
+}
+
+func TestRecoverAbortsStartedResponses(t *testing.T) {
+	handler := Recover(errorPage)(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("half a page"))
+		panic("nil map")
+	}))
+
+	defer func() {
+		if reason := recover(); reason != http.ErrAbortHandler {
+			t.Errorf("expected the connection to be aborted, got %v", reason)
+		}
+	}()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits request IDs passed in by clients or proxies.
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// RequestID assigns every request an ID, which is stored in the request
+// context and sent back in the X-Request-ID header. An ID set by a proxy in
+// front of the planner is kept.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requestID := request.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		writer.Header().Set(RequestIDHeader, requestID)
+		ctx := context.WithValue(request.Context(), requestIDContextKey{}, requestID)
+
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{"none", "", false},
+		{"valid", "proxy-4f2a", true},
+		{"with spaces", "proxy 4f2a", false},
+		{"with a newline", "proxy\n4f2a", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requestID string
+			handler := RequestID(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				requestID = RequestIDFromContext(request.Context())
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.incoming != "" {
+				request.Header.Set(RequestIDHeader, test.incoming)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if sent := recorder.Header().Get(RequestIDHeader); sent != requestID {
+				t.Errorf("expected the request ID of the context %q to be sent, got %q", requestID, sent)
+			}
+
+			if test.kept && requestID != test.incoming {
+				t.Errorf("expected the incoming request ID %q to be kept, got %q", test.incoming, requestID)
+			}
+
+			if !test.kept && (requestID == test.incoming || !isValidRequestID(requestID)) {
+				t.Errorf("expected a new request ID instead of %q, got %q", test.incoming, requestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import "net/http"
+
+// statusRecorder remembers the status code and the number of bytes written to
+// the wrapped response writer.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	bytes       int
+	wroteHeader bool
+}
+
+func newStatusRecorder(parent http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{
+		ResponseWriter: parent,
+		statusCode:     http.StatusOK,
+	}
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader && statusCode >= 200 {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(bytes []byte) (int, error) {
+	r.wroteHeader = true
+
+	n, err := r.ResponseWriter.Write(bytes)
+	r.bytes += n
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	_ = r.FlushError()
+}
+
+func (r *statusRecorder) FlushError() error {
+	r.wroteHeader = true
+
+	return http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,20 @@
+package memory
+
+import "time"
+
+// dateKey reduces a date to its calendar day, the same way the SQL
+// repositories store dates.
+func dateKey(date time.Time) string {
+	return date.Format("2006-01-02")
+}
+
+func parseDateKey(key string) time.Time {
+	date, _ := time.Parse("2006-01-02", key)
+	return date
+}
+
+// inRange reports whether the calendar day key lies between start and end,
+// both inclusive.
+func inRange(key string, start, end time.Time) bool {
+	return key >= dateKey(start) && key <= dateKey(end)
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"meal-planning/domain"
+	"sort"
+	"sync"
+	"time"
+)
+
+type diaryRepository struct {
+	mutex   sync.Mutex
+	entries map[int64]domain.DiaryEntry
+	nextID  int64
+}
+
+// NewDiaryRepository returns a domain.DiaryRepository keeping entries in
+// memory. It behaves like the SQL repository and is meant for tests and demos.
+func NewDiaryRepository() domain.DiaryRepository {
+	return &diaryRepository{
+		entries: make(map[int64]domain.DiaryEntry),
+		nextID:  1,
+	}
+}
+
+func (r *diaryRepository) FindByDate(ctx context.Context, date time.Time) ([]domain.DiaryEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := dateKey(date)
+
+	list := make([]domain.DiaryEntry, 0)
+	for _, entry := range r.entries {
+		if dateKey(entry.Date) == key {
+			list = append(list, entry)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	return list, nil
+}
+
+func (r *diaryRepository) Create(ctx context.Context, entry domain.DiaryEntry) (domain.DiaryEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.DiaryEntry{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry.ID = r.nextID
+	entry.Date = parseDateKey(dateKey(entry.Date))
+	r.nextID++
+
+	r.entries[entry.ID] = entry
+
+	return entry, nil
+}
+
+func (r *diaryRepository) Delete(ctx context.Context, date time.Time, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok || dateKey(entry.Date) != dateKey(date) {
+		return domain.DiaryEntryNotFound
+	}
+
+	delete(r.entries, id)
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"meal-planning/domain"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type foodRepository struct {
+	mutex  sync.Mutex
+	foods  map[int64]domain.Food
+	nextID int64
+}
+
+// NewFoodRepository returns a domain.FoodRepository keeping foods in memory. It
+// behaves like the SQL repository and is meant for tests and demos.
+func NewFoodRepository() domain.FoodRepository {
+	return &foodRepository{
+		foods:  make(map[int64]domain.Food),
+		nextID: 1,
+	}
+}
+
+func (r *foodRepository) FindByID(ctx context.Context, id int64) (domain.Food, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Food{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	food, ok := r.foods[id]
+	if !ok {
+		return domain.Food{}, domain.FoodNotFound
+	}
+
+	return copyFood(food), nil
+}
+
+func (r *foodRepository) Search(ctx context.Context, query string, limit int) ([]domain.Food, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	terms := strings.Fields(strings.ToLower(query))
+
+	list := make([]domain.Food, 0)
+	for _, food := range r.foods {
+		if matchesAll(food, terms) {
+			list = append(list, copyFood(food))
+		}
+	}
+
+	prefix := ""
+	if len(terms) > 0 {
+		prefix = terms[0]
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		iPrefix := strings.HasPrefix(strings.ToLower(list[i].Name), prefix)
+		jPrefix := strings.HasPrefix(strings.ToLower(list[j].Name), prefix)
+		if iPrefix != jPrefix {
+			return iPrefix
+		}
+
+		iName, jName := strings.ToLower(list[i].Name), strings.ToLower(list[j].Name)
+		if iName != jName {
+			return iName < jName
+		}
+
+		return list[i].ID < list[j].ID
+	})
+
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list, nil
+}
+
+func matchesAll(food domain.Food, terms []string) bool {
+	name, brand := strings.ToLower(food.Name), strings.ToLower(food.Brand)
+	for _, term := range terms {
+		if !strings.Contains(name, term) && !strings.Contains(brand, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *foodRepository) Create(ctx context.Context, food domain.Food) (domain.Food, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Food{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.create(food), nil
+}
+
+func (r *foodRepository) create(food domain.Food) domain.Food {
+	food.ID = r.nextID
+	food.Version = 1
+	r.nextID++
+
+	r.foods[food.ID] = copyFood(food)
+
+	return food
+}
+
+func (r *foodRepository) Update(ctx context.Context, food domain.Food) (domain.Food, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Food{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, ok := r.foods[food.ID]
+	if !ok || stored.Version != food.Version {
+		return domain.Food{}, domain.FoodConflict
+	}
+
+	// the origin of a food does not change by editing it
+	food.Source = stored.Source
+	food.SourceID = stored.SourceID
+	food.Version++
+	r.foods[food.ID] = copyFood(food)
+
+	return food, nil
+}
+
+func (r *foodRepository) Delete(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.foods[id]; !ok {
+		return domain.FoodNotFound
+	}
+
+	delete(r.foods, id)
+
+	return nil
+}
+
+func (r *foodRepository) Import(ctx context.Context, foods []domain.Food) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, food := range foods {
+		stored, ok := r.findBySource(food.Source, food.SourceID)
+		if !ok {
+			r.create(food)
+			continue
+		}
+
+		food.ID = stored.ID
+		food.Version = stored.Version + 1
+		r.foods[food.ID] = copyFood(food)
+	}
+
+	return nil
+}
+
+func (r *foodRepository) findBySource(source domain.FoodSource, sourceID string) (domain.Food, bool) {
+	for _, food := range r.foods {
+		if food.Source == source && food.SourceID == sourceID {
+			return food, true
+		}
+	}
+
+	return domain.Food{}, false
+}
+
+func copyFood(food domain.Food) domain.Food {
+	food.ServingSizes = slices.Clone(food.ServingSizes)
+	return food
+}
@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"meal-planning/domain"
+	"slices"
+	"sync"
+	"time"
+)
+
+type historyRepository struct {
+	mutex   sync.Mutex
+	entries []domain.HistoryEntry
+}
+
+// NewHistoryRepository returns an append-only domain.HistoryRepository keeping
+// entries in memory.
+func NewHistoryRepository() domain.HistoryRepository {
+	return &historyRepository{}
+}
+
+func (r *historyRepository) Append(ctx context.Context, entry domain.HistoryEntry) (domain.HistoryEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry.ID = int64(len(r.entries) + 1)
+	entry.Date = parseDateKey(dateKey(entry.Date))
+	entry.OldValue = slices.Clone(entry.OldValue)
+	entry.NewValue = slices.Clone(entry.NewValue)
+	r.entries = append(r.entries, entry)
+
+	return entry, nil
+}
+
+func (r *historyRepository) FindByID(ctx context.Context, id int64) (domain.HistoryEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.HistoryEntry{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if id < 1 || id > int64(len(r.entries)) {
+		return domain.HistoryEntry{}, domain.HistoryEntryNotFound
+	}
+
+	return r.entries[id-1], nil
+}
+
+// FindByEntityAndDate returns the matching entries, newest first.
+func (r *historyRepository) FindByEntityAndDate(ctx context.Context, entity domain.HistoryEntity, date time.Time) ([]domain.HistoryEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	list := make([]domain.HistoryEntry, 0)
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		entry := r.entries[i]
+		if entry.Entity == entity && dateKey(entry.Date) == dateKey(date) {
+			list = append(list, entry)
+		}
+	}
+
+	return list, nil
+}
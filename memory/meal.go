@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"meal-planning/domain"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+type mealDayRepository struct {
+	mutex    sync.Mutex
+	mealDays map[string]domain.MealDay
+}
+
+// NewMealDayRepository returns a domain.MealDayRepository keeping meal days in
+// memory. It behaves like the SQL repository and is meant for tests and demos.
+func NewMealDayRepository() domain.MealDayRepository {
+	return &mealDayRepository{
+		mealDays: make(map[string]domain.MealDay),
+	}
+}
+
+func (r *mealDayRepository) FindByDate(ctx context.Context, date time.Time) (domain.MealDay, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.MealDay{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	mealDay, ok := r.mealDays[dateKey(date)]
+	if !ok {
+		return domain.MealDay{}, domain.MealNotFound
+	}
+
+	return copyMealDay(mealDay), nil
+}
+
+func (r *mealDayRepository) FindByDateRange(ctx context.Context, start, end time.Time) ([]domain.MealDay, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	list := make([]domain.MealDay, 0)
+	for key, mealDay := range r.mealDays {
+		if inRange(key, start, end) {
+			list = append(list, copyMealDay(mealDay))
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Date.Before(list[j].Date)
+	})
+
+	return list, nil
+}
+
+func (r *mealDayRepository) Create(ctx context.Context, mealDay domain.MealDay) (domain.MealDay, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.MealDay{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := dateKey(mealDay.Date)
+	if _, ok := r.mealDays[key]; ok {
+		return domain.MealDay{}, domain.MealConflict
+	}
+
+	mealDay.Version = 1
+	r.store(key, mealDay)
+
+	return mealDay, nil
+}
+
+func (r *mealDayRepository) Update(ctx context.Context, mealDay domain.MealDay) (domain.MealDay, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.MealDay{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := dateKey(mealDay.Date)
+	stored, ok := r.mealDays[key]
+	if !ok || stored.Version != mealDay.Version {
+		return domain.MealDay{}, domain.MealConflict
+	}
+
+	mealDay.Version++
+	r.store(key, mealDay)
+
+	return mealDay, nil
+}
+
+func (r *mealDayRepository) Delete(ctx context.Context, mealDay domain.MealDay) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.mealDays, dateKey(mealDay.Date))
+
+	return nil
+}
+
+func (r *mealDayRepository) store(key string, mealDay domain.MealDay) {
+	mealDay = copyMealDay(mealDay)
+	mealDay.Date = parseDateKey(key)
+	// the household and uses of leftovers are not part of the day, like in
+	// the SQL repositories
+	mealDay.Household = domain.Household{}
+	mealDay.LeftoversUses = nil
+	r.mealDays[key] = mealDay
+}
+
+func copyMealDay(mealDay domain.MealDay) domain.MealDay {
+	mealDay.Snacks = slices.Clone(mealDay.Snacks)
+	mealDay.Leftovers = slices.Clone(mealDay.Leftovers)
+	mealDay.Attendance = slices.Clone(mealDay.Attendance)
+	for i := range mealDay.Attendance {
+		mealDay.Attendance[i].Absent = slices.Clone(mealDay.Attendance[i].Absent)
+	}
+
+	return mealDay
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"meal-planning/domain"
+	"meal-planning/domain/domaintest"
+	"testing"
+)
+
+func TestMealDayRepository(t *testing.T) {
+	domaintest.MealDayRepositoryContract(t, func(t *testing.T) domain.MealDayRepository {
+		return NewMealDayRepository()
+	})
+}
+
+func TestNutritionRepository(t *testing.T) {
+	domaintest.NutritionRepositoryContract(t, func(t *testing.T) domain.NutritionRepository {
+		return NewNutritionRepository()
+	})
+}
+
+func TestHistoryRepository(t *testing.T) {
+	domaintest.HistoryRepositoryContract(t, func(t *testing.T) domain.HistoryRepository {
+		return NewHistoryRepository()
+	})
+}
+
+func TestFoodRepository(t *testing.T) {
+	domaintest.FoodRepositoryContract(t, func(t *testing.T) domain.FoodRepository {
+		return NewFoodRepository()
+	})
+}
+
+func TestDiaryRepository(t *testing.T) {
+	domaintest.DiaryRepositoryContract(t, func(t *testing.T) domain.DiaryRepository {
+		return NewDiaryRepository()
+	})
+}
+
+func TestRecipeRepository(t *testing.T) {
+	domaintest.RecipeRepositoryContract(t, func(t *testing.T) domain.RecipeRepository {
+		return NewRecipeRepository()
+	})
+}
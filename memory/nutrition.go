@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"meal-planning/domain"
+	"sort"
+	"sync"
+	"time"
+)
+
+type nutritionRepository struct {
+	mutex     sync.Mutex
+	nutrition map[string]domain.Nutrition
+}
+
+// NewNutritionRepository returns a domain.NutritionRepository keeping entries
+// in memory. It behaves like the SQL repository and is meant for tests and
+// demos.
+func NewNutritionRepository() domain.NutritionRepository {
+	return &nutritionRepository{
+		nutrition: make(map[string]domain.Nutrition),
+	}
+}
+
+func (r *nutritionRepository) FindByDate(ctx context.Context, date time.Time) (domain.Nutrition, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	nutrition, ok := r.nutrition[dateKey(date)]
+	if !ok {
+		return domain.Nutrition{}, domain.NutritionNotFound
+	}
+
+	return nutrition, nil
+}
+
+func (r *nutritionRepository) FindByDateRange(ctx context.Context, start, end time.Time) ([]domain.Nutrition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	list := make([]domain.Nutrition, 0)
+	for key, nutrition := range r.nutrition {
+		if inRange(key, start, end) {
+			list = append(list, nutrition)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Date.Before(list[j].Date)
+	})
+
+	return list, nil
+}
+
+// FindAverageNutrition averages calories and weight separately, ignoring days
+// on which the value was not recorded.
+func (r *nutritionRepository) FindAverageNutrition(ctx context.Context, start, end time.Time) (domain.AverageNutrition, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.AverageNutrition{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var calories, caloriesCount, weight, weightCount int
+	for key, nutrition := range r.nutrition {
+		if !inRange(key, start, end) {
+			continue
+		}
+
+		if nutrition.Calories > 0 {
+			calories += nutrition.Calories
+			caloriesCount++
+		}
+
+		if nutrition.Weight > 0 {
+			weight += nutrition.Weight
+			weightCount++
+		}
+	}
+
+	average := domain.AverageNutrition{}
+	if caloriesCount > 0 {
+		average.Calories = calories / caloriesCount
+	}
+
+	if weightCount > 0 {
+		average.Weight = weight / weightCount
+	}
+
+	return average, nil
+}
+
+func (r *nutritionRepository) FindLatestWeight(ctx context.Context) (domain.Nutrition, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var latest domain.Nutrition
+	for _, nutrition := range r.nutrition {
+		if nutrition.Weight > 0 && nutrition.Date.After(latest.Date) {
+			latest = nutrition
+		}
+	}
+
+	if latest.Weight == 0 {
+		return domain.Nutrition{}, domain.NutritionNotFound
+	}
+
+	return latest, nil
+}
+
+func (r *nutritionRepository) Create(ctx context.Context, n domain.Nutrition) (domain.Nutrition, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := dateKey(n.Date)
+	if _, ok := r.nutrition[key]; ok {
+		return domain.Nutrition{}, domain.NutritionConflict
+	}
+
+	n.Version = 1
+	r.store(key, n)
+
+	return n, nil
+}
+
+func (r *nutritionRepository) Update(ctx context.Context, n domain.Nutrition) (domain.Nutrition, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Nutrition{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := dateKey(n.Date)
+	stored, ok := r.nutrition[key]
+	if !ok || stored.Version != n.Version {
+		return domain.Nutrition{}, domain.NutritionConflict
+	}
+
+	n.Version++
+	r.store(key, n)
+
+	return n, nil
+}
+
+func (r *nutritionRepository) Delete(ctx context.Context, n domain.Nutrition) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.nutrition, dateKey(n.Date))
+
+	return nil
+}
+
+// store keeps the entry like the SQL repository does: negative values are not
+// recorded.
+func (r *nutritionRepository) store(key string, n domain.Nutrition) {
+	n.Date = parseDateKey(key)
+	n.Calories = max(n.Calories, 0)
+	n.Protein = max(n.Protein, 0)
+	n.Carbs = max(n.Carbs, 0)
+	n.Fat = max(n.Fat, 0)
+	n.Weight = max(n.Weight, 0)
+	r.nutrition[key] = n
+}
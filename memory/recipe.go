@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"meal-planning/domain"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type recipeRepository struct {
+	mutex   sync.Mutex
+	recipes map[int64]domain.Recipe
+	nextID  int64
+}
+
+// NewRecipeRepository returns a domain.RecipeRepository keeping recipes in
+// memory. It behaves like the SQL repository and is meant for tests and demos.
+func NewRecipeRepository() domain.RecipeRepository {
+	return &recipeRepository{
+		recipes: make(map[int64]domain.Recipe),
+		nextID:  1,
+	}
+}
+
+func (r *recipeRepository) FindByID(ctx context.Context, id int64) (domain.Recipe, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Recipe{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	recipe, ok := r.recipes[id]
+	if !ok {
+		return domain.Recipe{}, domain.RecipeNotFound
+	}
+
+	return copyRecipe(recipe), nil
+}
+
+func (r *recipeRepository) FindByNames(ctx context.Context, names []string) ([]domain.Recipe, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[strings.ToLower(name)] = true
+	}
+
+	list := make([]domain.Recipe, 0)
+	for _, recipe := range r.recipes {
+		if wanted[strings.ToLower(recipe.Name)] {
+			list = append(list, copyRecipe(recipe))
+		}
+	}
+
+	sortRecipes(list)
+
+	return list, nil
+}
+
+func (r *recipeRepository) Search(ctx context.Context, query string, limit int) ([]domain.Recipe, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	terms := strings.Fields(strings.ToLower(query))
+
+	list := make([]domain.Recipe, 0)
+	for _, recipe := range r.recipes {
+		if containsAll(strings.ToLower(recipe.Name), terms) {
+			list = append(list, copyRecipe(recipe))
+		}
+	}
+
+	sortRecipes(list)
+
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list, nil
+}
+
+func containsAll(value string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(value, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortRecipes(list []domain.Recipe) {
+	sort.Slice(list, func(i, j int) bool {
+		iName, jName := strings.ToLower(list[i].Name), strings.ToLower(list[j].Name)
+		if iName != jName {
+			return iName < jName
+		}
+
+		return list[i].ID < list[j].ID
+	})
+}
+
+func (r *recipeRepository) Create(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Recipe{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	recipe.ID = r.nextID
+	recipe.Version = 1
+	r.nextID++
+
+	r.recipes[recipe.ID] = copyRecipe(recipe)
+
+	return recipe, nil
+}
+
+func (r *recipeRepository) Update(ctx context.Context, recipe domain.Recipe) (domain.Recipe, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Recipe{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, ok := r.recipes[recipe.ID]
+	if !ok || stored.Version != recipe.Version {
+		return domain.Recipe{}, domain.RecipeConflict
+	}
+
+	recipe.Version++
+	r.recipes[recipe.ID] = copyRecipe(recipe)
+
+	return recipe, nil
+}
+
+func (r *recipeRepository) Delete(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.recipes[id]; !ok {
+		return domain.RecipeNotFound
+	}
+
+	delete(r.recipes, id)
+
+	return nil
+}
+
+func copyRecipe(recipe domain.Recipe) domain.Recipe {
+	recipe.Ingredients = slices.Clone(recipe.Ingredients)
+	recipe.Instructions = slices.Clone(recipe.Instructions)
+	return recipe
+}
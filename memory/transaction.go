@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"context"
+	"meal-planning/domain"
+)
+
+type transactor struct{}
+
+// NewTransactor returns a domain.Transactor for the in-memory repositories.
+// They store every change right away, so changes made before fn fails are
+// kept.
+func NewTransactor() domain.Transactor {
+	return transactor{}
+}
+
+func (transactor) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
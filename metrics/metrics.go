@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// DefaultBuckets are histogram buckets in seconds suited for request and query
+// durations.
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	metricName string
+	help       string
+	labelNames []string
+
+	mutex  sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	counter := &CounterVec{
+		metricName: name,
+		help:       help,
+		labelNames: labelNames,
+		values:     make(map[string]*counterValue),
+	}
+	r.register(counter)
+
+	return counter
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if len(labelValues) != len(c.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", c.metricName, len(c.labelNames), len(labelValues)))
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := labelKey(labelValues)
+	counter, ok := c.values[key]
+	if !ok {
+		counter = &counterValue{labelValues: labelValues}
+		c.values[key] = counter
+	}
+
+	counter.value += value
+}
+
+func (c *CounterVec) name() string {
+	return c.metricName
+}
+
+func (c *CounterVec) write(_ context.Context, writer *bufio.Writer) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	writeHeader(writer, c.metricName, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		counter := c.values[key]
+		writeSample(writer, c.metricName, c.labelNames, counter.labelValues, counter.value)
+	}
+
+	return nil
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	metricName string
+	help       string
+	labelNames []string
+	buckets    []float64
+
+	mutex  sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues  []string
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	histogram := &HistogramVec{
+		metricName: name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		values:     make(map[string]*histogramValue),
+	}
+	r.register(histogram)
+
+	return histogram
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	if len(labelValues) != len(h.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", h.metricName, len(h.labelNames), len(labelValues)))
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	key := labelKey(labelValues)
+	histogram, ok := h.values[key]
+	if !ok {
+		histogram = &histogramValue{
+			labelValues:  labelValues,
+			bucketCounts: make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = histogram
+	}
+
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			histogram.bucketCounts[i]++
+		}
+	}
+	histogram.count++
+	histogram.sum += value
+}
+
+func (h *HistogramVec) name() string {
+	return h.metricName
+}
+
+func (h *HistogramVec) write(_ context.Context, writer *bufio.Writer) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	bucketLabelNames := append(append([]string{}, h.labelNames...), "le")
+
+	writeHeader(writer, h.metricName, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		histogram := h.values[key]
+		bucketLabelValues := append(append([]string{}, histogram.labelValues...), "")
+
+		for i, upperBound := range h.buckets {
+			bucketLabelValues[len(bucketLabelValues)-1] = formatValue(upperBound)
+			writeSample(writer, h.metricName+"_bucket", bucketLabelNames, bucketLabelValues, float64(histogram.bucketCounts[i]))
+		}
+
+		bucketLabelValues[len(bucketLabelValues)-1] = formatValue(math.Inf(1))
+		writeSample(writer, h.metricName+"_bucket", bucketLabelNames, bucketLabelValues, float64(histogram.count))
+		writeSample(writer, h.metricName+"_sum", h.labelNames, histogram.labelValues, histogram.sum)
+		writeSample(writer, h.metricName+"_count", h.labelNames, histogram.labelValues, float64(histogram.count))
+	}
+
+	return nil
+}
+
+// valueFunc is a gauge or counter whose value is read on every scrape.
+type valueFunc struct {
+	metricName string
+	help       string
+	metricType string
+	value      func(ctx context.Context) (float64, error)
+}
+
+// NewGaugeFunc registers a gauge that calls value on every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, value func(ctx context.Context) (float64, error)) {
+	r.register(&valueFunc{metricName: name, help: help, metricType: "gauge", value: value})
+}
+
+// NewCounterFunc registers a counter that calls value on every scrape. The
+// value must only ever increase.
+func (r *Registry) NewCounterFunc(name, help string, value func(ctx context.Context) (float64, error)) {
+	r.register(&valueFunc{metricName: name, help: help, metricType: "counter", value: value})
+}
+
+func (f *valueFunc) name() string {
+	return f.metricName
+}
+
+// write collects the value before writing anything, so a failing value leaves
+// no partial series behind.
+func (f *valueFunc) write(ctx context.Context, writer *bufio.Writer) error {
+	value, err := f.value(ctx)
+	if err != nil {
+		return fmt.Errorf("metrics: collecting %s: %w", f.metricName, err)
+	}
+
+	writeHeader(writer, f.metricName, f.help, f.metricType)
+	writeSample(writer, f.metricName, nil, nil, value)
+
+	return nil
+}
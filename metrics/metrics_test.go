@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func expose(t *testing.T, registry *Registry) string {
+	t.Helper()
+
+	buffer := new(bytes.Buffer)
+	err := registry.WriteTo(context.Background(), buffer)
+	if err != nil {
+		t.Fatalf("writing metrics: %v", err)
+	}
+
+	return buffer.String()
+}
+
+func expectExposition(t *testing.T, actual, expected string) {
+	t.Helper()
+
+	if actual != expected {
+		t.Errorf("unexpected exposition\ngot:\n%s\nexpected:\n%s", actual, expected)
+	}
+}
+
+func TestCounterVec(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("http_requests_total", "Handled requests.", "route", "status")
+
+	requests.Inc("/meals", "200")
+	requests.Add(2, "/foods", "404")
+	requests.Inc("/meals", "200")
+
+	expectExposition(t, expose(t, registry), `# HELP http_requests_total Handled requests.
+# TYPE http_requests_total counter
+http_requests_total{route="/foods",status="404"} 2
+http_requests_total{route="/meals",status="200"} 2
+`)
+}
+
+func TestCounterVecWithoutLabels(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("restarts_total", "Restarts.").Inc()
+
+	expectExposition(t, expose(t, registry), `# HELP restarts_total Restarts.
+# TYPE restarts_total counter
+restarts_total 1
+`)
+}
+
+func TestEscaping(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("escaped_total", "Help with a \\ backslash\nand a newline, \"quotes\" stay.", "value")
+
+	counter.Inc("a \"quoted\" \\ value\nover two lines")
+
+	expectExposition(t, expose(t, registry), `# HELP escaped_total Help with a \\ backslash\nand a newline, "quotes" stay.
+# TYPE escaped_total counter
+escaped_total{value="a \"quoted\" \\ value\nover two lines"} 1
+`)
+}
+
+func TestHistogramVec(t *testing.T) {
+	registry := NewRegistry()
+	durations := registry.NewHistogramVec("query_duration_seconds", "Query durations.", []float64{0.1, 0.5, 1}, "query")
+
+	durations.Observe(0.05, "find")
+	durations.Observe(0.5, "find")
+	durations.Observe(3, "find")
+
+	expectExposition(t, expose(t, registry), `# HELP query_duration_seconds Query durations.
+# TYPE query_duration_seconds histogram
+query_duration_seconds_bucket{query="find",le="0.1"} 1
+query_duration_seconds_bucket{query="find",le="0.5"} 2
+query_duration_seconds_bucket{query="find",le="1"} 2
+query_duration_seconds_bucket{query="find",le="+Inf"} 3
+query_duration_seconds_sum{query="find"} 3.55
+query_duration_seconds_count{query="find"} 3
+`)
+}
+
+func TestValueFuncs(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGaugeFunc("weight_kilograms", "Latest weight.", func(ctx context.Context) (float64, error) {
+		return math.NaN(), nil
+	})
+	registry.NewCounterFunc("connections_total", "Opened connections.", func(ctx context.Context) (float64, error) {
+		return math.Inf(1), nil
+	})
+
+	expectExposition(t, expose(t, registry), `# HELP weight_kilograms Latest weight.
+# TYPE weight_kilograms gauge
+weight_kilograms NaN
+# HELP connections_total Opened connections.
+# TYPE connections_total counter
+connections_total +Inf
+`)
+}
+
+func TestFailingValueFuncIsSkipped(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGaugeFunc("before", "Before.", func(ctx context.Context) (float64, error) {
+		return 1, nil
+	})
+	registry.NewGaugeFunc("failing", "Failing.", func(ctx context.Context) (float64, error) {
+		return 0, errors.New("database is gone")
+	})
+	registry.NewGaugeFunc("after", "After.", func(ctx context.Context) (float64, error) {
+		return 2, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+
+	if actual := recorder.Header().Get("Content-Type"); actual != contentType {
+		t.Errorf("expected Content-Type %q, got %q", contentType, actual)
+	}
+
+	expectExposition(t, recorder.Body.String(), `# HELP before Before.
+# TYPE before gauge
+before 1
+# HELP after After.
+# TYPE after gauge
+after 2
+`)
+}
+
+func TestDuplicateMetricsPanic(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("requests_total", "Requests.")
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected registering requests_total twice to panic")
+		}
+	}()
+
+	registry.NewCounterVec("requests_total", "Requests again.")
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type metric interface {
+	name() string
+	write(ctx context.Context, writer *bufio.Writer) error
+}
+
+// Registry holds metrics and exposes them in the Prometheus text exposition
+// format.
+type Registry struct {
+	mutex   sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, registered := range r.metrics {
+		if registered.name() == m.name() {
+			panic("metrics: duplicate metric " + m.name())
+		}
+	}
+
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes every metric in the text exposition format. Metrics that fail
+// to collect are logged and left out, so one failing value does not hide the
+// others.
+func (r *Registry) WriteTo(ctx context.Context, writer io.Writer) error {
+	r.mutex.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mutex.Unlock()
+
+	buffered := bufio.NewWriter(writer)
+	for _, m := range metrics {
+		err := m.write(ctx, buffered)
+		if err != nil {
+			slog.WarnContext(ctx, "error collecting metric", slog.String("metric", m.name()), slog.Any("reason", err))
+		}
+	}
+
+	return buffered.Flush()
+}
+
+func (r *Registry) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	buffer := new(bytes.Buffer)
+	err := r.WriteTo(request.Context(), buffer)
+	if err != nil {
+		slog.ErrorContext(request.Context(), "error collecting metrics", slog.Any("reason", err))
+		http.Error(writer, "failed collecting metrics", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Cache-Control", "no-store")
+
+	_, err = buffer.WriteTo(writer)
+	if err != nil {
+		slog.WarnContext(request.Context(), "error writing metrics", slog.Any("reason", err))
+	}
+}
+
+func writeHeader(writer *bufio.Writer, name, help, metricType string) {
+	writer.WriteString("# HELP ")
+	writer.WriteString(name)
+	writer.WriteString(" ")
+	writer.WriteString(escapeHelp(help))
+	writer.WriteString("\n# TYPE ")
+	writer.WriteString(name)
+	writer.WriteString(" ")
+	writer.WriteString(metricType)
+	writer.WriteString("\n")
+}
+
+func writeSample(writer *bufio.Writer, name string, labelNames, labelValues []string, value float64) {
+	writer.WriteString(name)
+
+	if len(labelNames) > 0 {
+		writer.WriteString("{")
+		for i, labelName := range labelNames {
+			if i > 0 {
+				writer.WriteString(",")
+			}
+
+			writer.WriteString(labelName)
+			writer.WriteString(`="`)
+			writer.WriteString(escapeLabelValue(labelValues[i]))
+			writer.WriteString(`"`)
+		}
+		writer.WriteString("}")
+	}
+
+	writer.WriteString(" ")
+	writer.WriteString(formatValue(value))
+	writer.WriteString("\n")
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// labelKey joins label values into a map key. The separator cannot appear in
+// valid UTF-8 text.
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
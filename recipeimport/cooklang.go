@@ -0,0 +1,394 @@
+package recipeimport
+
+import (
+	"fmt"
+	"io"
+	"meal-planning/domain"
+	"meal-planning/units"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ingredientReference matches an ingredient of a Cooklang step: a single word
+// like @salt, or words followed by an amount in braces like @olive oil{2%tbsp},
+// optionally followed by a preparation in parentheses.
+var ingredientReference = regexp.MustCompile(`@(?:([^@#~{}\n]+?)\{([^}]*)\}|([\p{L}\p{N}_-]+))(?:\(([^)]*)\))?`)
+
+// cookwareReference matches cookware of a Cooklang step, like #pot or
+// #large pot{}.
+var cookwareReference = regexp.MustCompile(`#(?:([^@#~{}\n]+?)\{[^}]*\}|([\p{L}\p{N}_-]+))`)
+
+// timerReference matches a timer of a Cooklang step, like ~{15%minutes} or
+// ~eggs{3%minutes}.
+var timerReference = regexp.MustCompile(`~[^@#~{}\n]*?\{([^}]*)\}`)
+
+var blockCommentPattern = regexp.MustCompile(`(?s)\[-.*?-\]`)
+
+// timePattern matches the parts of times like "1 hour 30 minutes" or "1h30m".
+var timePattern = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(hours?|hrs?|h|minutes?|mins?|m)?`)
+
+// ParseCooklang reads a recipe written in Cooklang, see
+// https://cooklang.org/docs/spec/. The ingredients of the recipe are the ones
+// the steps use, adding up the amounts of an ingredient used in the same unit.
+// The steps are stored as plain text, without the markup of ingredients,
+// cookware and timers. Recipes without a title in their metadata are named
+// after the file name.
+func ParseCooklang(name string, source []byte) domain.Recipe {
+	text := strings.ReplaceAll(string(source), "\r\n", "\n")
+	text = blockCommentPattern.ReplaceAllString(text, "")
+
+	recipe := domain.Recipe{
+		Name:     strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)),
+		Servings: 1,
+	}
+	metadata := map[string]string{}
+
+	lines := strings.Split(text, "\n")
+	if strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				for _, line := range lines[1:i] {
+					addMetadata(metadata, line)
+				}
+
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	// steps are paragraphs, sections and notes stand on their own
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			recipe.Instructions = append(recipe.Instructions, strings.Join(paragraph, " "))
+			paragraph = nil
+		}
+	}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			flush()
+			continue
+		}
+
+		if before, _, found := strings.Cut(line, "--"); found {
+			if line = strings.TrimSpace(before); line == "" {
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, ">>"):
+			addMetadata(metadata, strings.TrimPrefix(line, ">>"))
+		case strings.HasPrefix(line, "=") || strings.HasPrefix(line, ">"):
+			flush()
+			paragraph = append(paragraph, line)
+			flush()
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+
+	applyMetadata(&recipe, metadata)
+	recipe.Ingredients = referencedIngredients(recipe.Instructions)
+	for i, step := range recipe.Instructions {
+		recipe.Instructions[i] = plainStep(step)
+	}
+
+	return recipe
+}
+
+// plainStep returns step without its Cooklang markup: ingredients and cookware
+// by their name, timers by their duration.
+func plainStep(step string) string {
+	step = ingredientReference.ReplaceAllStringFunc(step, func(reference string) string {
+		match := ingredientReference.FindStringSubmatch(reference)
+		ingredient := parseReference(match)
+		if match[4] != "" {
+			return ingredient.Name + " (" + match[4] + ")"
+		}
+
+		return ingredient.Name
+	})
+
+	step = cookwareReference.ReplaceAllStringFunc(step, func(reference string) string {
+		match := cookwareReference.FindStringSubmatch(reference)
+		return strings.TrimSpace(match[1] + match[2])
+	})
+
+	return timerReference.ReplaceAllStringFunc(step, func(reference string) string {
+		duration := timerReference.FindStringSubmatch(reference)[1]
+		return strings.Join(strings.Fields(strings.ReplaceAll(duration, "%", " ")), " ")
+	})
+}
+
+func addMetadata(metadata map[string]string, line string) {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return
+	}
+
+	metadata[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"'`)
+}
+
+// applyMetadata reads the title, servings and times of recipe files, under
+// the keys of the Cooklang spec and a few common others.
+func applyMetadata(recipe *domain.Recipe, metadata map[string]string) {
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := metadata[key]; value != "" {
+				return value
+			}
+		}
+
+		return ""
+	}
+
+	if title := first("title", "name"); title != "" {
+		recipe.Name = title
+	}
+
+	if servings := parseNumber(first("servings", "serves", "yield")); servings >= 1 {
+		recipe.Servings = int(servings)
+	}
+
+	recipe.PrepTime = parseTime(first("prep time", "prep_time", "preptime"))
+	recipe.CookTime = parseTime(first("cook time", "cook_time", "cooktime"))
+	if total := parseTime(first("time required", "total time", "time")); recipe.CookTime == 0 && total > recipe.PrepTime {
+		recipe.CookTime = total - recipe.PrepTime
+	}
+}
+
+// parseTime reads times like "1 hour 30 minutes", "1h30m", "PT1H30M" or a
+// number of minutes.
+func parseTime(text string) time.Duration {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(strings.ToUpper(text), "P") {
+		return parseDuration(text)
+	}
+
+	duration := time.Duration(0)
+	for _, match := range timePattern.FindAllStringSubmatch(text, -1) {
+		value, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", "."), 64)
+		if err != nil {
+			return 0
+		}
+
+		unit := time.Minute
+		if strings.HasPrefix(strings.ToLower(match[2]), "h") {
+			unit = time.Hour
+		}
+
+		duration += time.Duration(value * float64(unit))
+	}
+
+	return duration.Round(time.Minute)
+}
+
+// referencedIngredients returns the ingredients the steps use, in the order
+// they are first used.
+func referencedIngredients(steps []string) []domain.Ingredient {
+	var ingredients []domain.Ingredient
+	positions := map[string]int{}
+
+	for _, step := range steps {
+		for _, match := range ingredientReference.FindAllStringSubmatch(step, -1) {
+			ingredient := parseReference(match)
+
+			key := ingredientKey(ingredient)
+			if i, ok := positions[key]; ok {
+				ingredients[i].Quantity += ingredient.Quantity
+				ingredients[i].Fixed = ingredients[i].Fixed || ingredient.Fixed
+				continue
+			}
+
+			positions[key] = len(ingredients)
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+
+	return ingredients
+}
+
+// ingredientKey identifies the ingredients whose amounts are added up: the same
+// name, ignoring case, in the same unit.
+func ingredientKey(ingredient domain.Ingredient) string {
+	return strings.ToLower(ingredient.Name) + "\x00" + ingredient.Unit
+}
+
+// parseReference reads an ingredient matched by ingredientReference.
+func parseReference(match []string) domain.Ingredient {
+	ingredient := domain.Ingredient{Name: strings.TrimSpace(match[1])}
+	if ingredient.Name == "" {
+		ingredient.Name = match[3]
+	}
+
+	quantity, unit, _ := strings.Cut(match[2], "%")
+
+	// a leading = marks amounts that do not scale with the servings
+	quantity, ingredient.Fixed = strings.CutPrefix(strings.TrimSpace(quantity), "=")
+	if value, ok := parseQuantity(strings.TrimSpace(quantity)); ok {
+		ingredient.Quantity = value
+	}
+
+	ingredient.Unit = strings.TrimSpace(unit)
+	if normalized, ok := units.Normalize(ingredient.Unit); ok {
+		ingredient.Unit = normalized
+	}
+
+	return ingredient
+}
+
+// WriteCooklang writes recipe in Cooklang with its title, servings and times
+// as front matter. Each ingredient is marked up where a step first mentions
+// it by name, ingredients no step mentions are written as a first step, so
+// they are not lost.
+func WriteCooklang(writer io.Writer, recipe domain.Recipe) error {
+	var builder strings.Builder
+
+	builder.WriteString("---\n")
+	fmt.Fprintf(&builder, "title: %s\n", recipe.Name)
+	fmt.Fprintf(&builder, "servings: %d\n", recipe.Servings)
+	if recipe.PrepTime > 0 {
+		fmt.Fprintf(&builder, "prep time: %s\n", formatTime(recipe.PrepTime))
+	}
+	if recipe.CookTime > 0 {
+		fmt.Fprintf(&builder, "cook time: %s\n", formatTime(recipe.CookTime))
+	}
+	builder.WriteString("---\n")
+
+	steps, unmentioned := markUpSteps(recipe)
+	if len(unmentioned) > 0 {
+		references := make([]string, len(unmentioned))
+		for i, ingredient := range unmentioned {
+			references[i] = formatReference(ingredient)
+		}
+
+		steps = append([]string{strings.Join(references, ", ")}, steps...)
+	}
+
+	for _, step := range steps {
+		builder.WriteString("\n")
+		builder.WriteString(step)
+		builder.WriteString("\n")
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// mention is where a step mentions an ingredient by name.
+type mention struct {
+	start, end int
+	ingredient domain.Ingredient
+}
+
+// markUpSteps marks up the first mention of each ingredient in the steps of
+// recipe and returns the ingredients no step mentions. Longer names are looked
+// up first, so "olive oil" is not taken for "oil". Sections and notes are left
+// as they are.
+func markUpSteps(recipe domain.Recipe) (steps []string, unmentioned []domain.Ingredient) {
+	// steps imported before they were stored as plain text keep their markup
+	steps = make([]string, len(recipe.Instructions))
+	for i, step := range recipe.Instructions {
+		steps[i] = plainStep(step)
+	}
+
+	order := make([]int, len(recipe.Ingredients))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return len(recipe.Ingredients[b].Name) - len(recipe.Ingredients[a].Name)
+	})
+
+	mentioned := make([]bool, len(recipe.Ingredients))
+	mentions := make([][]mention, len(steps))
+	for _, i := range order {
+		ingredient := recipe.Ingredients[i]
+		for j, step := range steps {
+			if strings.HasPrefix(step, "=") || strings.HasPrefix(step, ">") {
+				continue
+			}
+
+			if start, end, ok := findMention(step, ingredient.Name, mentions[j]); ok {
+				mentions[j] = append(mentions[j], mention{start, end, ingredient})
+				mentioned[i] = true
+				break
+			}
+		}
+	}
+
+	for i, stepMentions := range mentions {
+		slices.SortFunc(stepMentions, func(a, b mention) int {
+			return b.start - a.start
+		})
+
+		for _, m := range stepMentions {
+			steps[i] = steps[i][:m.start] + formatReference(m.ingredient) + steps[i][m.end:]
+		}
+	}
+
+	for i, ingredient := range recipe.Ingredients {
+		if !mentioned[i] {
+			unmentioned = append(unmentioned, ingredient)
+		}
+	}
+
+	return steps, unmentioned
+}
+
+// findMention finds name as words of step, ignoring case, outside of the
+// mentions already found.
+func findMention(step, name string, taken []mention) (start, end int, ok bool) {
+	if strings.TrimSpace(name) == "" {
+		return 0, 0, false
+	}
+
+	pattern := regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])(` + regexp.QuoteMeta(name) + `)(?:$|[^\p{L}\p{N}])`)
+	for offset := 0; offset < len(step); {
+		match := pattern.FindStringSubmatchIndex(step[offset:])
+		if match == nil {
+			return 0, 0, false
+		}
+
+		start, end = offset+match[2], offset+match[3]
+		if !slices.ContainsFunc(taken, func(m mention) bool { return start < m.end && m.start < end }) {
+			return start, end, true
+		}
+
+		offset = end
+	}
+
+	return 0, 0, false
+}
+
+func formatReference(ingredient domain.Ingredient) string {
+	amount := ""
+	if ingredient.Quantity > 0 {
+		amount = formatQuantity(ingredient.Quantity)
+		if ingredient.Fixed {
+			amount = "=" + amount
+		}
+	}
+	if ingredient.Unit != "" {
+		amount += "%" + ingredient.Unit
+	}
+
+	return "@" + ingredient.Name + "{" + amount + "}"
+}
+
+func formatQuantity(quantity float64) string {
+	return strconv.FormatFloat(quantity, 'f', -1, 64)
+}
+
+func formatTime(duration time.Duration) string {
+	return fmt.Sprintf("%d minutes", int(duration.Minutes()))
+}
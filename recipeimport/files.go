@@ -0,0 +1,207 @@
+package recipeimport
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"meal-planning/domain"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxRecipeFileSize bounds what is read of a single recipe file.
+const maxRecipeFileSize = 1 << 20
+
+// Format is a file format recipes are written in.
+type Format string
+
+const (
+	Cooklang Format = "cooklang"
+	Markdown Format = "markdown"
+)
+
+// ParseFormat returns the format called name, as the formats are named in
+// flags and URLs.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case Cooklang, Markdown:
+		return format, nil
+	}
+
+	return "", fmt.Errorf("unknown recipe format %q", name)
+}
+
+// FormatOf returns the format of a recipe file by its extension: .cook for
+// Cooklang, .md and .markdown for Markdown. It returns false for other files.
+func FormatOf(name string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".cook":
+		return Cooklang, true
+	case ".md", ".markdown":
+		return Markdown, true
+	}
+
+	return "", false
+}
+
+// Extension returns the extension of files in format, with the dot.
+func (format Format) Extension() string {
+	if format == Markdown {
+		return ".md"
+	}
+
+	return ".cook"
+}
+
+// Parse reads a recipe in format. name is the file name, the name of recipes
+// that do not give one.
+func (format Format) Parse(name string, source []byte) domain.Recipe {
+	if format == Markdown {
+		return ParseMarkdown(name, source)
+	}
+
+	return ParseCooklang(name, source)
+}
+
+// Write writes recipe in format.
+func (format Format) Write(writer io.Writer, recipe domain.Recipe) error {
+	if format == Markdown {
+		return WriteMarkdown(writer, recipe)
+	}
+
+	return WriteCooklang(writer, recipe)
+}
+
+// FileName returns a file name for recipe in format, with the characters file
+// systems do not allow replaced.
+func FileName(recipe domain.Recipe, format Format) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '-'
+		}
+
+		return r
+	}, strings.TrimSpace(recipe.Name))
+
+	return strings.TrimLeft(name, ".") + format.Extension()
+}
+
+// Files are the recipe files opened with Open, read in the order of their
+// paths. Close closes the zip archive they are in.
+type Files struct {
+	files  []recipeFile
+	next   int
+	closer io.Closer
+}
+
+type recipeFile struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+var _ domain.RecipeReader = (*Files)(nil)
+
+// Open opens the recipe files at name, which may be a directory, searched
+// with its subdirectories, a .zip archive or a single file. Files with other
+// extensions than the ones FormatOf knows are left out.
+func Open(name string) (*Files, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	files := &Files{}
+	switch {
+	case info.IsDir():
+		err = filepath.WalkDir(name, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if entry.IsDir() && path != name && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			if _, ok := FormatOf(path); ok && !entry.IsDir() {
+				files.files = append(files.files, recipeFile{name: path, open: func() (io.ReadCloser, error) {
+					return os.Open(path)
+				}})
+			}
+
+			return nil
+		})
+	case strings.EqualFold(filepath.Ext(name), ".zip"):
+		var archive *zip.ReadCloser
+		archive, err = zip.OpenReader(name)
+		if err != nil {
+			break
+		}
+
+		files.closer = archive
+		for _, file := range archive.File {
+			// macOS adds resource forks named like the files to its archives
+			if _, ok := FormatOf(file.Name); ok && !file.FileInfo().IsDir() && !strings.HasPrefix(file.Name, "__MACOSX/") {
+				files.files = append(files.files, recipeFile{name: file.Name, open: file.Open})
+			}
+		}
+	default:
+		if _, ok := FormatOf(name); !ok {
+			err = fmt.Errorf("unknown recipe format %q", filepath.Ext(name))
+			break
+		}
+
+		files.files = append(files.files, recipeFile{name: name, open: func() (io.ReadCloser, error) {
+			return os.Open(name)
+		}})
+	}
+
+	if err != nil {
+		files.Close()
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	sort.Slice(files.files, func(i, j int) bool {
+		return files.files[i].name < files.files[j].name
+	})
+
+	return files, nil
+}
+
+func (files *Files) Read() (domain.Recipe, error) {
+	if files.next == len(files.files) {
+		return domain.Recipe{}, io.EOF
+	}
+
+	file := files.files[files.next]
+	files.next++
+
+	reader, err := file.open()
+	if err != nil {
+		return domain.Recipe{}, err
+	}
+	defer reader.Close()
+
+	// one byte more tells files that are too large from the ones that fit
+	source, err := io.ReadAll(io.LimitReader(reader, maxRecipeFileSize+1))
+	if err != nil {
+		return domain.Recipe{}, fmt.Errorf("reading %s: %w", file.name, err)
+	}
+
+	if len(source) > maxRecipeFileSize {
+		return domain.Recipe{}, fmt.Errorf("reading %s: larger than %d MB", file.name, maxRecipeFileSize>>20)
+	}
+
+	format, _ := FormatOf(file.name)
+	return format.Parse(file.name, source), nil
+}
+
+func (files *Files) Close() error {
+	if files.closer == nil {
+		return nil
+	}
+
+	return files.closer.Close()
+}
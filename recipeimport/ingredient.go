@@ -0,0 +1,162 @@
+package recipeimport
+
+import (
+	"html"
+	"meal-planning/domain"
+	"meal-planning/units"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// vulgarFractions are the fraction characters recipes write quantities with.
+var vulgarFractions = map[rune]float64{
+	'½': 1.0 / 2, '⅓': 1.0 / 3, '⅔': 2.0 / 3, '¼': 1.0 / 4, '¾': 3.0 / 4,
+	'⅕': 1.0 / 5, '⅖': 2.0 / 5, '⅗': 3.0 / 5, '⅘': 4.0 / 5, '⅙': 1.0 / 6,
+	'⅚': 5.0 / 6, '⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+const vulgarFractionCharacters = `½⅓⅔¼¾⅕⅖⅗⅘⅙⅚⅛⅜⅝⅞`
+
+// quantityPattern matches the quantity at the start of an ingredient line:
+// mixed numbers like "1 1/2" or "1½", fractions, decimals with a point or a
+// comma and the upper end of ranges like "2-3", which is dropped.
+var quantityPattern = regexp.MustCompile(
+	`^(\d+\s+\d+\s*[/⁄]\s*\d+|\d+\s*[/⁄]\s*\d+|\d+(?:[.,]\d+)?\s*[` + vulgarFractionCharacters + `]?|[` + vulgarFractionCharacters + `])` +
+		`(?:\s*(?:-|–|to)\s*(?:\d+(?:[.,]\d+)?|[` + vulgarFractionCharacters + `]))?`,
+)
+
+// leadingParenthesis matches a note between the quantity and the unit, like
+// the can size in "1 (400 g) can tomatoes".
+var leadingParenthesis = regexp.MustCompile(`^\(([^)]*)\)\s*`)
+
+// ParseIngredient splits an ingredient line like "2 ½ cups flour, sifted" into
+// its quantity, unit and name. Lines without a quantity become an ingredient
+// named like the whole line.
+func ParseIngredient(line string) domain.Ingredient {
+	line = cleanText(line)
+	line = strings.TrimSpace(strings.TrimLeft(line, "-•*·▢ "))
+
+	ingredient := domain.Ingredient{Name: line}
+
+	rest := line
+	if match := quantityPattern.FindStringSubmatch(rest); match != nil {
+		quantity, ok := parseQuantity(match[1])
+		if !ok {
+			return ingredient
+		}
+
+		ingredient.Quantity = quantity
+		rest = strings.TrimSpace(rest[len(match[0]):])
+	} else if article, after, ok := strings.Cut(rest, " "); ok && (strings.EqualFold(article, "a") || strings.EqualFold(article, "an")) {
+		// "a pinch of salt", but not "a few leaves"
+		if unit, _ := parseUnit(after); unit == "" {
+			return ingredient
+		}
+
+		ingredient.Quantity = 1
+		rest = after
+	} else {
+		return ingredient
+	}
+
+	note := ""
+	if match := leadingParenthesis.FindStringSubmatch(rest); match != nil {
+		note = match[1]
+		rest = rest[len(match[0]):]
+	}
+
+	ingredient.Unit, rest = parseUnit(rest)
+
+	name := strings.TrimSpace(rest)
+	if lower := strings.ToLower(name); strings.HasPrefix(lower, "of ") {
+		name = strings.TrimSpace(name[len("of "):])
+	}
+	if note != "" {
+		name = strings.TrimSpace(name + " (" + note + ")")
+	}
+
+	if name == "" {
+		// a line that is nothing but an amount is kept whole
+		return domain.Ingredient{Name: line}
+	}
+
+	ingredient.Name = name
+	return ingredient
+}
+
+// parseQuantity reads a quantity matched by quantityPattern without its range.
+func parseQuantity(text string) (float64, bool) {
+	text = strings.ReplaceAll(text, "⁄", "/")
+
+	if whole, fraction, ok := strings.Cut(text, " "); ok && strings.Contains(fraction, "/") {
+		wholeValue, wholeOk := parseQuantity(whole)
+		fractionValue, fractionOk := parseQuantity(strings.TrimSpace(fraction))
+		return wholeValue + fractionValue, wholeOk && fractionOk
+	}
+
+	if numerator, denominator, ok := strings.Cut(text, "/"); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(numerator), 64)
+		if err != nil {
+			return 0, false
+		}
+
+		d, err := strconv.ParseFloat(strings.TrimSpace(denominator), 64)
+		if err != nil || d == 0 {
+			return 0, false
+		}
+
+		return n / d, true
+	}
+
+	quantity := 0.0
+	text = strings.TrimSpace(text)
+	if last, size := utf8.DecodeLastRuneInString(text); vulgarFractions[last] != 0 {
+		quantity = vulgarFractions[last]
+		text = strings.TrimSpace(text[:len(text)-size])
+		if text == "" {
+			return quantity, true
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", "."), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return number + quantity, true
+}
+
+// parseUnit reads the unit at the start of text, which may follow a quantity
+// without a space as in "200g". It returns an empty unit and text unchanged if
+// text does not start with a unit.
+func parseUnit(text string) (string, string) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return "", text
+	}
+
+	// two word units like "fl oz" first
+	if len(words) > 1 {
+		if unit, ok := units.Normalize(words[0] + " " + words[1]); ok {
+			return unit, strings.Join(words[2:], " ")
+		}
+	}
+
+	if unit, ok := units.Normalize(strings.TrimSuffix(words[0], ",")); ok {
+		return unit, strings.Join(words[1:], " ")
+	}
+
+	return "", text
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// cleanText removes the markup and entities some sites leave in JSON-LD text
+// and collapses whitespace.
+func cleanText(text string) string {
+	text = tagPattern.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,278 @@
+// Package recipeimport reads recipes from pages saved from recipe sites, for
+// review before domain.RecipeService.Create, and reads and writes Cooklang and
+// Markdown recipe files.
+package recipeimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"meal-planning/domain"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var NoRecipe = domain.NewError(domain.ErrorKindValidation, "no schema.org recipe found")
+
+// scriptPattern matches the JSON-LD blocks of an HTML page.
+var scriptPattern = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+
+// durationPattern matches the ISO 8601 durations schema.org uses for times,
+// like "PT1H30M".
+var durationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+var numberPattern = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+
+// stepBreakPattern matches the markup separating steps in HTML instructions.
+var stepBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>`)
+
+// ParseJSONLD reads the first schema.org Recipe of an HTML page with JSON-LD
+// blocks, or of the JSON-LD on its own. It returns NoRecipe if there is none.
+// Ingredient lines are split with ParseIngredient.
+func ParseJSONLD(source []byte) (domain.Recipe, error) {
+	blocks := [][]byte{source}
+	if trimmed := bytes.TrimSpace(source); !bytes.HasPrefix(trimmed, []byte("{")) && !bytes.HasPrefix(trimmed, []byte("[")) {
+		blocks = blocks[:0]
+		for _, match := range scriptPattern.FindAllSubmatch(source, -1) {
+			blocks = append(blocks, match[1])
+		}
+	}
+
+	for _, block := range blocks {
+		var document any
+		if json.Unmarshal(block, &document) != nil {
+			// sites with broken markup often have a good block besides
+			continue
+		}
+
+		if node := findRecipe(document); node != nil {
+			return toRecipe(node), nil
+		}
+	}
+
+	return domain.Recipe{}, NoRecipe
+}
+
+// findRecipe returns the first node typed Recipe, looking through arrays,
+// @graph and nested objects.
+func findRecipe(value any) map[string]any {
+	switch value := value.(type) {
+	case map[string]any:
+		if isType(value["@type"], "Recipe") {
+			return value
+		}
+
+		// sorted, so pages with several recipes import the same one every time
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if node := findRecipe(value[key]); node != nil {
+				return node
+			}
+		}
+	case []any:
+		for _, child := range value {
+			if node := findRecipe(child); node != nil {
+				return node
+			}
+		}
+	}
+
+	return nil
+}
+
+// isType tells whether @type is typeName, which some sites prefix with the
+// schema.org URL or give as an array of types.
+func isType(value any, typeName string) bool {
+	switch value := value.(type) {
+	case string:
+		return value == typeName || strings.HasSuffix(value, "/"+typeName)
+	case []any:
+		for _, item := range value {
+			if isType(item, typeName) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func toRecipe(node map[string]any) domain.Recipe {
+	recipe := domain.Recipe{
+		Name:     cleanText(text(node["name"])),
+		Servings: parseYield(node["recipeYield"]),
+	}
+
+	ingredients := node["recipeIngredient"]
+	if ingredients == nil {
+		ingredients = node["ingredients"]
+	}
+	for _, line := range texts(ingredients) {
+		if ingredient := ParseIngredient(line); ingredient.Name != "" {
+			recipe.Ingredients = append(recipe.Ingredients, ingredient)
+		}
+	}
+
+	recipe.Instructions = instructions(node["recipeInstructions"])
+
+	recipe.PrepTime = parseDuration(text(node["prepTime"]))
+	recipe.CookTime = parseDuration(text(node["cookTime"]))
+	if total := parseDuration(text(node["totalTime"])); recipe.CookTime == 0 && total > recipe.PrepTime {
+		recipe.CookTime = total - recipe.PrepTime
+	}
+
+	// schema.org gives nutrition per serving
+	if nutrition, ok := node["nutrition"].(map[string]any); ok {
+		recipe.PerServing = domain.Nutrients{
+			Kcal:    parseNumber(text(nutrition["calories"])),
+			Protein: parseNumber(text(nutrition["proteinContent"])),
+			Carbs:   parseNumber(text(nutrition["carbohydrateContent"])),
+			Fat:     parseNumber(text(nutrition["fatContent"])),
+		}
+	}
+
+	return recipe
+}
+
+// text returns a string value, the first of an array or the name of an object.
+func text(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []any:
+		if len(value) > 0 {
+			return text(value[0])
+		}
+	case map[string]any:
+		return text(value["name"])
+	}
+
+	return ""
+}
+
+// texts returns the strings of an array, or a single string as one.
+func texts(value any) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if item, ok := item.(string); ok {
+				list = append(list, item)
+			}
+		}
+
+		return list
+	}
+
+	return nil
+}
+
+// instructions flattens recipeInstructions, which may be a text with a step
+// per line, a list of texts, HowToSteps or HowToSections of those.
+func instructions(value any) []string {
+	var steps []string
+
+	switch value := value.(type) {
+	case string:
+		value = stepBreakPattern.ReplaceAllString(value, "\n")
+		for _, line := range strings.Split(value, "\n") {
+			if step := cleanText(line); step != "" {
+				steps = append(steps, step)
+			}
+		}
+	case []any:
+		for _, item := range value {
+			steps = append(steps, instructions(item)...)
+		}
+	case map[string]any:
+		if isType(value["@type"], "HowToSection") {
+			return instructions(value["itemListElement"])
+		}
+
+		step := value["text"]
+		if step == nil {
+			step = value["name"]
+		}
+		if step, ok := step.(string); ok {
+			if step = cleanText(step); step != "" {
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	return steps
+}
+
+// parseYield returns the number of servings of a yield like "4 servings",
+// which may be a number or a list of ways to say it. It is 1 if the yield has
+// no number.
+func parseYield(value any) int {
+	switch value := value.(type) {
+	case float64:
+		if value >= 1 {
+			return int(value)
+		}
+	case string:
+		if number := parseNumber(value); number >= 1 {
+			return int(number)
+		}
+	case []any:
+		for _, item := range value {
+			if servings := parseYield(item); servings > 1 {
+				return servings
+			}
+		}
+	}
+
+	return 1
+}
+
+// parseNumber returns the first number in text, like 320 of "320 kcal".
+func parseNumber(text string) float64 {
+	match := numberPattern.FindString(text)
+	number, err := strconv.ParseFloat(strings.ReplaceAll(match, ",", "."), 64)
+	if err != nil || math.IsInf(number, 0) {
+		return 0
+	}
+
+	return number
+}
+
+// parseDuration reads an ISO 8601 duration like "PT1H30M" to the minute. It
+// returns 0 for anything else.
+func parseDuration(text string) time.Duration {
+	match := durationPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(text)))
+	if match == nil {
+		return 0
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+	duration := time.Duration(0)
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(match[i+1], 64)
+		if err != nil {
+			return 0
+		}
+
+		duration += time.Duration(value * float64(unit))
+	}
+
+	return duration.Round(time.Minute)
+}
@@ -0,0 +1,125 @@
+package recipeimport
+
+import (
+	"fmt"
+	"io"
+	"meal-planning/domain"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// listMarkerPattern matches the bullet or number starting a list item.
+var listMarkerPattern = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+`)
+
+// ParseMarkdown reads a recipe written in Markdown like WriteMarkdown writes
+// it: the name as the first heading, metadata lines like "Servings: 4", and
+// sections headed Ingredients and Instructions with an item per ingredient and
+// step. Ingredient items are split with ParseIngredient. Recipes without a
+// heading are named after the file name.
+func ParseMarkdown(name string, source []byte) domain.Recipe {
+	recipe := domain.Recipe{
+		Name:     strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)),
+		Servings: 1,
+	}
+	metadata := map[string]string{}
+
+	named := false
+	section := ""
+	for _, line := range strings.Split(strings.ReplaceAll(string(source), "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if heading := strings.TrimLeft(line, "#"); heading != line {
+			heading = strings.TrimSpace(heading)
+			if !named && strings.HasPrefix(line, "# ") {
+				recipe.Name = heading
+				named = true
+				continue
+			}
+
+			section = markdownSection(heading)
+			continue
+		}
+
+		item := listMarkerPattern.ReplaceAllString(line, "")
+		switch section {
+		case "ingredients":
+			if ingredient := ParseIngredient(item); ingredient.Name != "" {
+				recipe.Ingredients = append(recipe.Ingredients, ingredient)
+			}
+		case "instructions":
+			recipe.Instructions = append(recipe.Instructions, item)
+		case "":
+			addMetadata(metadata, strings.ReplaceAll(item, "*", ""))
+		}
+	}
+
+	// the heading is the name, whatever the metadata says
+	if named {
+		metadata["title"] = recipe.Name
+	}
+	applyMetadata(&recipe, metadata)
+
+	return recipe
+}
+
+// markdownSection returns the section a heading starts, or "other" for
+// sections the recipe has no place for.
+func markdownSection(heading string) string {
+	switch strings.ToLower(strings.TrimSuffix(heading, ":")) {
+	case "ingredients":
+		return "ingredients"
+	case "instructions", "directions", "method", "preparation", "steps":
+		return "instructions"
+	}
+
+	return "other"
+}
+
+// WriteMarkdown writes recipe in Markdown, readable as it is and by
+// ParseMarkdown.
+func WriteMarkdown(writer io.Writer, recipe domain.Recipe) error {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "# %s\n\n", recipe.Name)
+	fmt.Fprintf(&builder, "- Servings: %d\n", recipe.Servings)
+	if recipe.PrepTime > 0 {
+		fmt.Fprintf(&builder, "- Prep time: %s\n", formatTime(recipe.PrepTime))
+	}
+	if recipe.CookTime > 0 {
+		fmt.Fprintf(&builder, "- Cook time: %s\n", formatTime(recipe.CookTime))
+	}
+
+	if len(recipe.Ingredients) > 0 {
+		builder.WriteString("\n## Ingredients\n\n")
+		for _, ingredient := range recipe.Ingredients {
+			fmt.Fprintf(&builder, "- %s\n", formatIngredient(ingredient))
+		}
+	}
+
+	if len(recipe.Instructions) > 0 {
+		builder.WriteString("\n## Instructions\n\n")
+		for i, step := range recipe.Instructions {
+			fmt.Fprintf(&builder, "%d. %s\n", i+1, step)
+		}
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// formatIngredient writes an ingredient as a line ParseIngredient splits back.
+func formatIngredient(ingredient domain.Ingredient) string {
+	parts := make([]string, 0, 3)
+	if ingredient.Quantity > 0 {
+		parts = append(parts, formatQuantity(ingredient.Quantity))
+		if ingredient.Unit != "" {
+			parts = append(parts, ingredient.Unit)
+		}
+	}
+
+	return strings.Join(append(parts, ingredient.Name), " ")
+}
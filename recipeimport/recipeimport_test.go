@@ -0,0 +1,463 @@
+package recipeimport
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"meal-planning/domain"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const recipePage = `<!DOCTYPE html>
+<html>
+<head>
+<script type="application/ld+json">{"@context": "https://schema.org", "@type": "WebSite", "name": "Cooking &amp; more"</script>
+<script type='application/ld+json'>
+{
+	"@context": "https://schema.org",
+	"@graph": [
+		{"@type": "WebPage", "name": "Chili sin carne - Cooking & more"},
+		{
+			"@type": ["Recipe", "NewsArticle"],
+			"name": "Chili sin carne &amp; rice",
+			"recipeYield": ["4", "4 servings"],
+			"prepTime": "PT15M",
+			"totalTime": "PT1H15M",
+			"recipeIngredient": [
+				"2 tbsp olive oil",
+				"1 large onion, chopped",
+				"1 (400 g) can kidney beans",
+				"1½ cups rice",
+				"Salt &amp; pepper to taste"
+			],
+			"recipeInstructions": [
+				{"@type": "HowToSection", "name": "Chili", "itemListElement": [
+					{"@type": "HowToStep", "text": "Fry the <b>onion</b> in the oil."},
+					{"@type": "HowToStep", "text": "Add the beans and simmer."}
+				]},
+				{"@type": "HowToStep", "name": "Cook the rice."}
+			],
+			"nutrition": {"@type": "NutritionInformation", "calories": "520 kcal", "proteinContent": "24 g", "fatContent": "12,5 g"}
+		}
+	]
+}
+</script>
+</head>
+<body><h1>Chili</h1></body>
+</html>`
+
+func TestParseJSONLD(t *testing.T) {
+	recipe, err := ParseJSONLD([]byte(recipePage))
+	if err != nil {
+		t.Fatalf("parsing page: %v", err)
+	}
+
+	if recipe.Name != "Chili sin carne & rice" || recipe.Servings != 4 {
+		t.Errorf("expected the name and 4 servings, got %q and %d", recipe.Name, recipe.Servings)
+	}
+
+	if recipe.PrepTime != 15*time.Minute || recipe.CookTime != time.Hour {
+		t.Errorf("expected 15 minutes to prepare and the rest of the total to cook, got %v and %v", recipe.PrepTime, recipe.CookTime)
+	}
+
+	expectedIngredients := []domain.Ingredient{
+		{Name: "olive oil", Quantity: 2, Unit: "tbsp"},
+		{Name: "large onion, chopped", Quantity: 1},
+		{Name: "kidney beans (400 g)", Quantity: 1, Unit: "can"},
+		{Name: "rice", Quantity: 1.5, Unit: "cup"},
+		{Name: "Salt & pepper to taste"},
+	}
+	if !slices.Equal(recipe.Ingredients, expectedIngredients) {
+		t.Errorf("expected ingredients %+v, got %+v", expectedIngredients, recipe.Ingredients)
+	}
+
+	expectedInstructions := []string{"Fry the onion in the oil.", "Add the beans and simmer.", "Cook the rice."}
+	if !slices.Equal(recipe.Instructions, expectedInstructions) {
+		t.Errorf("expected instructions %q, got %q", expectedInstructions, recipe.Instructions)
+	}
+
+	if recipe.PerServing != (domain.Nutrients{Kcal: 520, Protein: 24, Fat: 12.5}) {
+		t.Errorf("expected the nutrition per serving, got %+v", recipe.PerServing)
+	}
+}
+
+func TestParseJSONLDOnItsOwn(t *testing.T) {
+	recipe, err := ParseJSONLD([]byte(` {"@type": "http://schema.org/Recipe", "name": "Porridge", "recipeYield": 2,
+		"recipeIngredient": "100 g rolled oats", "cookTime": "P0DT1H5M30S",
+		"recipeInstructions": "Bring the milk to a boil.<br>Stir in the oats.\n\n<p>Serve.</p>"}`))
+	if err != nil {
+		t.Fatalf("parsing JSON-LD: %v", err)
+	}
+
+	if recipe.Name != "Porridge" || recipe.Servings != 2 || recipe.CookTime != 66*time.Minute {
+		t.Errorf("unexpected recipe %+v", recipe)
+	}
+
+	if !slices.Equal(recipe.Ingredients, []domain.Ingredient{{Name: "rolled oats", Quantity: 100, Unit: "g"}}) {
+		t.Errorf("unexpected ingredients %+v", recipe.Ingredients)
+	}
+
+	if !slices.Equal(recipe.Instructions, []string{"Bring the milk to a boil.", "Stir in the oats.", "Serve."}) {
+		t.Errorf("unexpected instructions %q", recipe.Instructions)
+	}
+}
+
+func TestParseJSONLDWithoutRecipe(t *testing.T) {
+	for _, source := range []string{
+		`<html><head><script type="application/ld+json">{"@type": "WebSite"}</script></head></html>`,
+		`<html><body>Just a page</body></html>`,
+		`{"@type": "Recipe"`,
+		``,
+	} {
+		_, err := ParseJSONLD([]byte(source))
+		if !errors.Is(err, NoRecipe) {
+			t.Errorf("%q: expected NoRecipe, got %v", source, err)
+		}
+	}
+}
+
+func TestParseIngredient(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected domain.Ingredient
+	}{
+		{"200g flour", domain.Ingredient{Name: "flour", Quantity: 200, Unit: "g"}},
+		{"2 1/2 cups all-purpose flour, sifted", domain.Ingredient{Name: "all-purpose flour, sifted", Quantity: 2.5, Unit: "cup"}},
+		{"1 ½ Tablespoons sugar", domain.Ingredient{Name: "sugar", Quantity: 1.5, Unit: "tbsp"}},
+		{"¾ tsp. salt", domain.Ingredient{Name: "salt", Quantity: 0.75, Unit: "tsp"}},
+		{"1/3 cup of milk", domain.Ingredient{Name: "milk", Quantity: 1.0 / 3, Unit: "cup"}},
+		{"0,5 l Milch", domain.Ingredient{Name: "Milch", Quantity: 0.5, Unit: "l"}},
+		{"2-3 cloves garlic", domain.Ingredient{Name: "garlic", Quantity: 2, Unit: "clove"}},
+		{"4 to 6 fl oz cream", domain.Ingredient{Name: "cream", Quantity: 4, Unit: "fl oz"}},
+		{"3 eggs", domain.Ingredient{Name: "eggs", Quantity: 3}},
+		{"A pinch of nutmeg", domain.Ingredient{Name: "nutmeg", Quantity: 1, Unit: "pinch"}},
+		{"a few basil leaves", domain.Ingredient{Name: "a few basil leaves"}},
+		{"▢ 1 lb. ground beef", domain.Ingredient{Name: "ground beef", Quantity: 1, Unit: "lb"}},
+		{"500 g", domain.Ingredient{Name: "500 g"}},
+		{"  Fresh   parsley ", domain.Ingredient{Name: "Fresh parsley"}},
+	}
+
+	for _, test := range tests {
+		ingredient := ParseIngredient(test.line)
+		if ingredient != test.expected {
+			t.Errorf("%q: expected %+v, got %+v", test.line, test.expected, ingredient)
+		}
+	}
+}
+
+const chiliCooklang = `---
+title: Chili sin carne
+servings: 4
+prep time: 15 minutes
+---
+
+>> cook time: 1 hour
+
+-- from grandma's notebook
+Fry the @onion(chopped) in @olive oil{2%tbsp} in a #large pot{}.
+Add @kidney beans{1%can} and @salt.
+
+[- careful, it burns -]
+= Rice
+
+Cook @rice{1 1/2%cups} for ~{15%minutes}, then add @salt{} and @olive oil{1%tbsp}.
+> Serve with lime.
+`
+
+func TestParseCooklang(t *testing.T) {
+	recipe := ParseCooklang("chili.cook", []byte(chiliCooklang))
+
+	if recipe.Name != "Chili sin carne" || recipe.Servings != 4 || recipe.PrepTime != 15*time.Minute || recipe.CookTime != time.Hour {
+		t.Errorf("unexpected metadata %+v", recipe)
+	}
+
+	expectedSteps := []string{
+		"Fry the onion (chopped) in olive oil in a large pot. Add kidney beans and salt.",
+		"= Rice",
+		"Cook rice for 15 minutes, then add salt and olive oil.",
+		"> Serve with lime.",
+	}
+	if !slices.Equal(recipe.Instructions, expectedSteps) {
+		t.Errorf("unexpected instructions %q", recipe.Instructions)
+	}
+
+	expectedIngredients := []domain.Ingredient{
+		{Name: "onion"},
+		{Name: "olive oil", Quantity: 3, Unit: "tbsp"},
+		{Name: "kidney beans", Quantity: 1, Unit: "can"},
+		{Name: "salt"},
+		{Name: "rice", Quantity: 1.5, Unit: "cup"},
+	}
+	if !slices.Equal(recipe.Ingredients, expectedIngredients) {
+		t.Errorf("unexpected ingredients %+v", recipe.Ingredients)
+	}
+}
+
+func TestParseCooklangNamesRecipesAfterTheFile(t *testing.T) {
+	recipe := ParseCooklang("recipes/Pancakes.cook", []byte(">> servings: 2\nMix @eggs{3} and @flour{125%g}."))
+
+	if recipe.Name != "Pancakes" || recipe.Servings != 2 {
+		t.Errorf("unexpected recipe %+v", recipe)
+	}
+}
+
+func TestWriteCooklangRoundTrips(t *testing.T) {
+	recipe := ParseCooklang("chili.cook", []byte(chiliCooklang))
+
+	var written strings.Builder
+	if err := WriteCooklang(&written, recipe); err != nil {
+		t.Fatalf("writing recipe: %v", err)
+	}
+
+	if read := ParseCooklang("other.cook", []byte(written.String())); !equalRecipes(read, recipe) {
+		t.Errorf("expected the recipe back, got %+v from\n%s", read, written.String())
+	}
+}
+
+func TestWriteCooklangKeepsIngredientsWithoutSteps(t *testing.T) {
+	recipe := domain.Recipe{
+		Name:         "Porridge",
+		Servings:     2,
+		Ingredients:  []domain.Ingredient{{Name: "rolled oats", Quantity: 100, Unit: "g"}, {Name: "milk", Quantity: 0.5, Unit: "l"}},
+		Instructions: []string{"Bring the milk to a boil.", "Stir in the oats."},
+	}
+
+	var written strings.Builder
+	if err := WriteCooklang(&written, recipe); err != nil {
+		t.Fatalf("writing recipe: %v", err)
+	}
+
+	read := ParseCooklang("porridge.cook", []byte(written.String()))
+	if !slices.Equal(read.Ingredients, recipe.Ingredients) {
+		t.Errorf("expected the ingredients back, got %+v from\n%s", read.Ingredients, written.String())
+	}
+}
+
+func TestWriteCooklangWritesEditedAmounts(t *testing.T) {
+	recipe := ParseCooklang("chili.cook", []byte(chiliCooklang))
+
+	// the amounts are changed in the planner
+	for i, ingredient := range recipe.Ingredients {
+		switch ingredient.Name {
+		case "olive oil":
+			recipe.Ingredients[i].Quantity = 4
+		case "rice":
+			recipe.Ingredients[i].Unit = "g"
+			recipe.Ingredients[i].Quantity = 300
+		}
+	}
+	recipe.Ingredients = slices.DeleteFunc(recipe.Ingredients, func(ingredient domain.Ingredient) bool {
+		return ingredient.Name == "kidney beans"
+	})
+
+	var written strings.Builder
+	if err := WriteCooklang(&written, recipe); err != nil {
+		t.Fatalf("writing recipe: %v", err)
+	}
+
+	read := ParseCooklang("other.cook", []byte(written.String()))
+
+	expected := slices.Clone(recipe.Ingredients)
+	slices.SortFunc(expected, compareIngredientNames)
+	slices.SortFunc(read.Ingredients, compareIngredientNames)
+	if !slices.Equal(read.Ingredients, expected) {
+		t.Errorf("expected the edited ingredients %+v back, got %+v from\n%s", expected, read.Ingredients, written.String())
+	}
+
+	if !strings.Contains(written.String(), "Fry the @onion{} (chopped) in @olive oil{4%tbsp} in a large pot. Add kidney beans and @salt{}.") {
+		t.Errorf("expected the first mentions to be marked up with the edited amounts, got\n%s", written.String())
+	}
+}
+
+func TestCooklangFixedAmounts(t *testing.T) {
+	recipe := ParseCooklang("soup.cook", []byte(">> servings: 2\nSimmer @lentils{200%g} with @bay leaves{=2} and ~{20%min}.\nAdd @salt{=1%tsp}."))
+
+	expected := []domain.Ingredient{
+		{Name: "lentils", Quantity: 200, Unit: "g"},
+		{Name: "bay leaves", Quantity: 2, Fixed: true},
+		{Name: "salt", Quantity: 1, Unit: "tsp", Fixed: true},
+	}
+	if !slices.Equal(recipe.Ingredients, expected) {
+		t.Errorf("expected the fixed amounts to be marked, got %+v", recipe.Ingredients)
+	}
+
+	scaled := recipe.Scale(4)
+	if scaled.Ingredients[0].Quantity != 400 || scaled.Ingredients[1].Quantity != 2 {
+		t.Errorf("expected only the lentils to scale, got %+v", scaled.Ingredients)
+	}
+
+	var written strings.Builder
+	if err := WriteCooklang(&written, recipe); err != nil {
+		t.Fatalf("writing recipe: %v", err)
+	}
+
+	if !strings.Contains(written.String(), "Simmer @lentils{200%g} with @bay leaves{=2} and 20 min.") {
+		t.Errorf("expected the fixed amounts to be written back, got\n%s", written.String())
+	}
+
+	if read := ParseCooklang("other.cook", []byte(written.String())); !equalRecipes(read, recipe) {
+		t.Errorf("expected the recipe back, got %+v from\n%s", read, written.String())
+	}
+}
+
+func compareIngredientNames(a, b domain.Ingredient) int {
+	return strings.Compare(a.Name, b.Name)
+}
+
+func TestWriteMarkdownRoundTrips(t *testing.T) {
+	recipe := domain.Recipe{
+		Name:     "Chili sin carne",
+		Servings: 4,
+		PrepTime: 15 * time.Minute,
+		CookTime: 90 * time.Minute,
+		Ingredients: []domain.Ingredient{
+			{Name: "olive oil", Quantity: 2, Unit: "tbsp"},
+			{Name: "kidney beans (400 g)", Quantity: 1, Unit: "can"},
+			{Name: "rice", Quantity: 1.5, Unit: "cup"},
+			{Name: "Salt & pepper to taste"},
+		},
+		Instructions: []string{"Fry the onion in the oil.", "Add the beans and simmer."},
+	}
+
+	var written strings.Builder
+	if err := WriteMarkdown(&written, recipe); err != nil {
+		t.Fatalf("writing recipe: %v", err)
+	}
+
+	if read := ParseMarkdown("other.md", []byte(written.String())); !equalRecipes(read, recipe) {
+		t.Errorf("expected the recipe back, got %+v from\n%s", read, written.String())
+	}
+}
+
+func TestParseMarkdown(t *testing.T) {
+	recipe := ParseMarkdown("porridge.md", []byte(`Notes before the title are ignored.
+
+# Porridge
+
+**Serves:** 2
+Total time: 10 min
+
+## Ingredients
+* 100 g rolled oats
+* ½ l milk
+
+### Method
+1) Bring the milk to a boil.
+2) Stir in the oats.
+
+## Notes
+- Tastes best with cinnamon.
+`))
+
+	if recipe.Name != "Porridge" || recipe.Servings != 2 || recipe.CookTime != 10*time.Minute {
+		t.Errorf("unexpected recipe %+v", recipe)
+	}
+
+	if !slices.Equal(recipe.Ingredients, []domain.Ingredient{{Name: "rolled oats", Quantity: 100, Unit: "g"}, {Name: "milk", Quantity: 0.5, Unit: "l"}}) {
+		t.Errorf("unexpected ingredients %+v", recipe.Ingredients)
+	}
+
+	if !slices.Equal(recipe.Instructions, []string{"Bring the milk to a boil.", "Stir in the oats."}) {
+		t.Errorf("unexpected instructions %q", recipe.Instructions)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	directory := t.TempDir()
+	writeFile(t, filepath.Join(directory, "b", "chili.cook"), chiliCooklang)
+	writeFile(t, filepath.Join(directory, "a.md"), "# Porridge\n")
+	writeFile(t, filepath.Join(directory, "notes.txt"), "not a recipe")
+	writeFile(t, filepath.Join(directory, ".git", "x.md"), "# Hidden\n")
+
+	archivePath := filepath.Join(t.TempDir(), "recipes.zip")
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("creating archive: %v", err)
+	}
+	archive := zip.NewWriter(archiveFile)
+	for name, content := range map[string]string{"b/chili.cook": chiliCooklang, "a.md": "# Porridge\n", "__MACOSX/b/._chili.cook": "", "notes.txt": ""} {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("adding %s: %v", name, err)
+		}
+		file.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("writing archive: %v", err)
+	}
+	archiveFile.Close()
+
+	for _, path := range []string{directory, archivePath} {
+		files, err := Open(path)
+		if err != nil {
+			t.Fatalf("opening %s: %v", path, err)
+		}
+
+		var names []string
+		for {
+			recipe, err := files.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("reading %s: %v", path, err)
+			}
+
+			names = append(names, recipe.Name)
+		}
+		files.Close()
+
+		if !slices.Equal(names, []string{"Porridge", "Chili sin carne"}) {
+			t.Errorf("%s: unexpected recipes %q", path, names)
+		}
+	}
+
+	if _, err := Open(filepath.Join(directory, "notes.txt")); err == nil {
+		t.Errorf("expected files of unknown formats to fail")
+	}
+}
+
+func TestReadRejectsLargeFiles(t *testing.T) {
+	directory := t.TempDir()
+	writeFile(t, filepath.Join(directory, "large.md"), "# Large\n\n"+strings.Repeat("x", maxRecipeFileSize))
+
+	files, err := Open(directory)
+	if err != nil {
+		t.Fatalf("opening %s: %v", directory, err)
+	}
+	defer files.Close()
+
+	_, err = files.Read()
+	if err == nil || !strings.Contains(err.Error(), "large.md") {
+		t.Errorf("expected reading a file over the limit to fail naming it, got %v", err)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	name := FileName(domain.Recipe{Name: "..Mac & cheese: 1/2 batch?"}, Markdown)
+	if name != "Mac & cheese- 1-2 batch-.md" {
+		t.Errorf("unexpected file name %q", name)
+	}
+}
+
+func equalRecipes(a, b domain.Recipe) bool {
+	return a.Name == b.Name && a.Servings == b.Servings && a.PrepTime == b.PrepTime && a.CookTime == b.CookTime &&
+		slices.Equal(a.Ingredients, b.Ingredients) && slices.Equal(a.Instructions, b.Instructions)
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatalf("creating directory: %v", err)
+	}
+
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+}
@@ -0,0 +1,113 @@
+package units
+
+import (
+	"strings"
+	"unicode"
+)
+
+type density struct {
+	gramsPerMilliliter float64
+	// liquid ingredients are measured by volume in metric kitchens too.
+	liquid bool
+}
+
+// densities weigh a milliliter of common ingredients, as measured by cup
+// and spoon, which is less than packed for flour and the like.
+var densities = map[string]density{
+	"water":           {1, true},
+	"milk":            {1.03, true},
+	"buttermilk":      {1.03, true},
+	"cream":           {1.01, true},
+	"coconut milk":    {0.97, true},
+	"oil":             {0.92, true},
+	"olive oil":       {0.91, true},
+	"vinegar":         {1.01, true},
+	"soy sauce":       {1.2, true},
+	"wine":            {0.99, true},
+	"beer":            {1.01, true},
+	"stock":           {1, true},
+	"broth":           {1, true},
+	"juice":           {1.04, true},
+	"lemon juice":     {1.03, true},
+	"honey":           {1.42, true},
+	"maple syrup":     {1.32, true},
+	"syrup":           {1.33, true},
+	"passata":         {1.03, true},
+	"yogurt":          {1.03, false},
+	"yoghurt":         {1.03, false},
+	"sour cream":      {1.01, false},
+	"butter":          {0.96, false},
+	"peanut butter":   {1.09, false},
+	"mayonnaise":      {0.93, false},
+	"ketchup":         {1.15, false},
+	"mustard":         {1.05, false},
+	"tomato paste":    {1.1, false},
+	"jam":             {1.33, false},
+	"flour":           {0.53, false},
+	"cornstarch":      {0.54, false},
+	"cornmeal":        {0.65, false},
+	"sugar":           {0.85, false},
+	"brown sugar":     {0.93, false},
+	"powdered sugar":  {0.51, false},
+	"icing sugar":     {0.51, false},
+	"salt":            {1.22, false},
+	"baking powder":   {0.81, false},
+	"baking soda":     {0.97, false},
+	"yeast":           {0.64, false},
+	"cocoa":           {0.42, false},
+	"cinnamon":        {0.53, false},
+	"paprika":         {0.46, false},
+	"cumin":           {0.43, false},
+	"pepper":          {0.49, false},
+	"rice":            {0.78, false},
+	"oats":            {0.38, false},
+	"quinoa":          {0.72, false},
+	"couscous":        {0.73, false},
+	"lentils":         {0.82, false},
+	"breadcrumbs":     {0.46, false},
+	"parmesan":        {0.42, false},
+	"chocolate chips": {0.71, false},
+	"raisins":         {0.64, false},
+	"almonds":         {0.6, false},
+	"walnuts":         {0.42, false},
+	"pepper flakes":   {0.3, false},
+}
+
+// otherFoods are ingredients named after an ingredient of densities that
+// they are not, like butter beans, which are no butter.
+var otherFoods = map[string]bool{
+	"butter beans":    true,
+	"milk chocolate":  true,
+	"sugar snap peas": true,
+	"snap peas":       true,
+	"water chestnuts": true,
+	"bell pepper":     true,
+	"red pepper":      true,
+	"green pepper":    true,
+	"yellow pepper":   true,
+}
+
+// densityOf looks up the density of ingredient by the longest run of its
+// words that is in densities, so "extra virgin olive oil" is olive oil and
+// "all-purpose flour" is flour. Of runs as long, the last one wins, as the
+// noun comes after the words that describe it: "rice vinegar" is vinegar.
+func densityOf(ingredient string) (density, bool) {
+	words := strings.FieldsFunc(strings.ToLower(ingredient), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for length := len(words); length > 0; length-- {
+		for start := len(words) - length; start >= 0; start-- {
+			name := strings.Join(words[start:start+length], " ")
+			if otherFoods[name] {
+				return density{}, false
+			}
+
+			if found, ok := densities[name]; ok {
+				return found, true
+			}
+		}
+	}
+
+	return density{}, false
+}
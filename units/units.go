@@ -0,0 +1,359 @@
+// Package units converts the amounts of recipe ingredients between units and
+// rounds them to amounts a kitchen measures.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// System is the units amounts are shown in.
+type System string
+
+const (
+	// AsWritten keeps the unit of every amount.
+	AsWritten System = ""
+	// Metric uses g and kg, ml and l, and tsp and tbsp for small volumes.
+	// Volumes of dry ingredients with a known density become weights.
+	Metric System = "metric"
+	// Imperial uses oz and lb, tsp, tbsp, cups and quarts. Weights of
+	// ingredients with a known density become volumes.
+	Imperial System = "imperial"
+)
+
+// ParseSystem returns the system called name, as systems are named in URLs.
+// An empty name is AsWritten.
+func ParseSystem(name string) (System, error) {
+	switch system := System(strings.ToLower(strings.TrimSpace(name))); system {
+	case AsWritten, Metric, Imperial:
+		return system, nil
+	}
+
+	return "", fmt.Errorf("unknown unit system %q", name)
+}
+
+// aliases maps the ways recipes write units to the name the app uses.
+var aliases = map[string]string{
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "gramm": "g",
+	"kg": "kg", "kilo": "kg", "kilos": "kg", "kilogram": "kg", "kilograms": "kg",
+	"mg": "mg", "milligram": "mg", "milligrams": "mg",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"cl": "cl", "centiliter": "cl", "centiliters": "cl", "centilitre": "cl", "centilitres": "cl",
+	"dl": "dl", "deciliter": "dl", "deciliters": "dl", "decilitre": "dl", "decilitres": "dl",
+	"l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp", "tsps": "tsp",
+	"tbsp": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp", "tbsps": "tbsp", "tbs": "tbsp", "tbl": "tbsp",
+	"cup": "cup", "cups": "cup",
+	"fl oz": "fl oz", "fluid ounce": "fl oz", "fluid ounces": "fl oz",
+	"pint": "pint", "pints": "pint", "pt": "pint",
+	"quart": "quart", "quarts": "quart", "qt": "quart",
+	"pinch": "pinch", "pinches": "pinch",
+	"dash": "dash", "dashes": "dash",
+	"clove": "clove", "cloves": "clove",
+	"can": "can", "cans": "can", "tin": "can", "tins": "can",
+	"slice": "slice", "slices": "slice",
+	"piece": "piece", "pieces": "piece", "pc": "piece", "pcs": "piece",
+	"bunch": "bunch", "bunches": "bunch",
+	"handful": "handful", "handfuls": "handful",
+	"sprig": "sprig", "sprigs": "sprig",
+	"stick": "stick", "sticks": "stick",
+	"package": "package", "packages": "package", "pkg": "package", "packet": "package", "packets": "package",
+}
+
+type dimension int
+
+const (
+	mass dimension = iota + 1
+	volume
+)
+
+type unit struct {
+	dimension dimension
+	// size is the unit in grams for masses and milliliters for volumes.
+	size float64
+	// step is the smallest part of the unit kitchens measure.
+	step float64
+	// fractions tells whether quantities in the unit are written like 1½
+	// instead of 1.5.
+	fractions bool
+}
+
+// unitsByName holds the units of mass and volume. Volumes are US customary.
+var unitsByName = map[string]unit{
+	"mg":    {mass, 0.001, 1, false},
+	"g":     {mass, 1, 1, false},
+	"kg":    {mass, 1000, 0.05, false},
+	"oz":    {mass, 28.349523125, 0.25, true},
+	"lb":    {mass, 453.59237, 0.25, true},
+	"ml":    {volume, 1, 1, false},
+	"cl":    {volume, 10, 0.5, false},
+	"dl":    {volume, 100, 0.25, false},
+	"l":     {volume, 1000, 0.05, false},
+	"tsp":   {volume, 4.92892159375, 0.25, true},
+	"tbsp":  {volume, 14.78676478125, 0.5, true},
+	"cup":   {volume, 236.5882365, 0.25, true},
+	"fl oz": {volume, 29.5735295625, 0.5, true},
+	"pint":  {volume, 473.176473, 0.25, true},
+	"quart": {volume, 946.352946, 0.25, true},
+}
+
+// countStep is the smallest part of things and units without a size, like
+// eggs or cans, kitchens use.
+const countStep = 0.25
+
+// Normalize returns the name the app uses for unit, ignoring case and a
+// trailing dot. It returns false for words that are not a known unit.
+func Normalize(unit string) (string, bool) {
+	unit = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(unit)), ".")
+	normalized, ok := aliases[unit]
+	return normalized, ok
+}
+
+// Amount is a quantity of a unit. Unit is empty for a number of things.
+type Amount struct {
+	Quantity float64
+	Unit     string
+}
+
+// Scale returns factor times the amount.
+func (amount Amount) Scale(factor float64) Amount {
+	return Amount{Quantity: amount.Quantity * factor, Unit: amount.Unit}
+}
+
+// String writes the amount like recipes do, e.g. "1½ cup" or "1.25 kg".
+func (amount Amount) String() string {
+	if amount.Quantity <= 0 {
+		return amount.Unit
+	}
+
+	quantity := strconv.FormatFloat(math.Round(amount.Quantity*100)/100, 'f', -1, 64)
+	if amount.Quantity < 1 {
+		// two decimals would round small amounts away
+		quantity = strconv.FormatFloat(amount.Quantity, 'g', 2, 64)
+	}
+	if u, ok := unitsByName[amount.Unit]; !ok || u.fractions {
+		quantity = formatFraction(amount.Quantity)
+	}
+
+	if amount.Unit == "" {
+		return quantity
+	}
+
+	return quantity + " " + amount.Unit
+}
+
+// fractions are the parts kitchens measure with, as written.
+var fractions = []struct {
+	value float64
+	text  string
+}{
+	{1.0 / 8, "⅛"}, {1.0 / 4, "¼"}, {1.0 / 3, "⅓"}, {3.0 / 8, "⅜"}, {1.0 / 2, "½"},
+	{5.0 / 8, "⅝"}, {2.0 / 3, "⅔"}, {3.0 / 4, "¾"}, {7.0 / 8, "⅞"},
+}
+
+func formatFraction(quantity float64) string {
+	whole, part := math.Modf(quantity)
+	for _, fraction := range fractions {
+		if math.Abs(part-fraction.value) < 0.01 {
+			if whole == 0 {
+				return fraction.text
+			}
+
+			return strconv.FormatFloat(whole, 'f', 0, 64) + fraction.text
+		}
+	}
+
+	return strconv.FormatFloat(math.Round(quantity*100)/100, 'f', -1, 64)
+}
+
+// Grams returns the weight of an amount of ingredient. Masses are converted,
+// volumes weighed with the density of the ingredient. It returns false for
+// other units and for volumes of ingredients without a known density.
+func Grams(amount Amount, ingredient string) (float64, bool) {
+	u, ok := unitsByName[amount.Unit]
+	if !ok {
+		return 0, false
+	}
+
+	grams := amount.Quantity * u.size
+	if u.dimension == volume {
+		density, ok := densityOf(ingredient)
+		if !ok {
+			return 0, false
+		}
+
+		grams *= density.gramsPerMilliliter
+	}
+
+	return grams, true
+}
+
+// Convert returns the amount of ingredient in system, rounded to what kitchens
+// measure. The unit is chosen by the size of the amount, e.g. tbsp for a
+// spoonful and cups for more. Things and units without a size, like cans,
+// are only rounded.
+func Convert(amount Amount, system System, ingredient string) Amount {
+	u, ok := unitsByName[amount.Unit]
+	if !ok || system == AsWritten || amount.Quantity <= 0 {
+		return Amount{Quantity: round(amount.Quantity, amount.Unit), Unit: amount.Unit}
+	}
+
+	size := amount.Quantity * u.size
+	dimension := u.dimension
+
+	// kitchens weigh dry ingredients in metric and measure them by cup in
+	// imperial, spoonfuls are spoonfuls in both
+	if density, ok := densityOf(ingredient); ok {
+		switch {
+		case system == Metric && dimension == volume && !density.liquid && size >= 4*unitsByName["tbsp"].size:
+			size *= density.gramsPerMilliliter
+			dimension = mass
+		case system == Imperial && dimension == mass:
+			size /= density.gramsPerMilliliter
+			dimension = volume
+		}
+	}
+
+	name := kitchenUnit(size, dimension, system)
+	return Amount{Quantity: round(size/unitsByName[name].size, name), Unit: name}
+}
+
+// kitchenUnit returns the unit a kitchen measures size grams or milliliters
+// with.
+func kitchenUnit(size float64, dimension dimension, system System) string {
+	// slightly less than a unit is rounded up to it
+	atLeast := func(unit string) bool {
+		return size >= unitsByName[unit].size*0.99
+	}
+
+	switch {
+	case dimension == mass && system == Metric:
+		if atLeast("kg") {
+			return "kg"
+		}
+
+		return "g"
+	case dimension == mass:
+		if atLeast("lb") {
+			return "lb"
+		}
+
+		return "oz"
+	case !atLeast("tbsp"):
+		return "tsp"
+	case size < 4*unitsByName["tbsp"].size:
+		return "tbsp"
+	case system == Metric:
+		if atLeast("l") {
+			return "l"
+		}
+
+		return "ml"
+	case atLeast("quart"):
+		return "quart"
+	}
+
+	return "cup"
+}
+
+// round rounds quantity to a step of the unit kitchens measure, keeping
+// amounts that would be rounded away.
+func round(quantity float64, unit string) float64 {
+	if quantity <= 0 {
+		return quantity
+	}
+
+	step := countStep
+	if u, ok := unitsByName[unit]; ok {
+		step = u.step
+	}
+
+	switch unit {
+	case "g", "ml":
+		// a gram more or less matters for yeast, not for potatoes, and spices
+		// weigh less than a gram
+		switch {
+		case quantity < 1:
+			step = math.Pow(10, math.Floor(math.Log10(quantity))-1)
+		case quantity < 10:
+			step = 0.5
+		case quantity >= 1000:
+			step = 10
+		case quantity >= 100:
+			step = 5
+		}
+	case "tsp":
+		if quantity < 1 {
+			step = 0.125
+		}
+	case "cup":
+		// cups come in quarters and thirds
+		quarters, thirds := math.Round(quantity*4)/4, math.Round(quantity*3)/3
+		if math.Abs(thirds-quantity) < math.Abs(quarters-quantity) && thirds > 0 {
+			return thirds
+		}
+	}
+
+	rounded := math.Round(quantity/step) * step
+	if rounded == 0 {
+		rounded = step
+	}
+
+	// steps like 0.05 leave binary noise
+	return math.Round(rounded*1e6) / 1e6
+}
+
+// Total adds up amounts of ingredient, like the amounts the recipes of a week
+// need. Masses are added up in g and volumes in ml, volumes count as
+// weights if there are both and the density of the ingredient is known.
+// Other units are added up per unit. Amounts without a quantity are left out.
+func Total(amounts []Amount, ingredient string) []Amount {
+	grams, milliliters := 0.0, 0.0
+	others := []Amount{}
+
+	for _, amount := range amounts {
+		if amount.Quantity <= 0 {
+			continue
+		}
+
+		u, ok := unitsByName[amount.Unit]
+		switch {
+		case ok && u.dimension == mass:
+			grams += amount.Quantity * u.size
+		case ok:
+			milliliters += amount.Quantity * u.size
+		default:
+			added := false
+			for i := range others {
+				if others[i].Unit == amount.Unit {
+					others[i].Quantity += amount.Quantity
+					added = true
+					break
+				}
+			}
+
+			if !added {
+				others = append(others, amount)
+			}
+		}
+	}
+
+	if density, ok := densityOf(ingredient); ok && grams > 0 && milliliters > 0 {
+		grams += milliliters * density.gramsPerMilliliter
+		milliliters = 0
+	}
+
+	total := make([]Amount, 0, 2+len(others))
+	if grams > 0 {
+		total = append(total, Amount{Quantity: grams, Unit: "g"})
+	}
+	if milliliters > 0 {
+		total = append(total, Amount{Quantity: milliliters, Unit: "ml"})
+	}
+
+	return append(total, others...)
+}
@@ -0,0 +1,117 @@
+package units
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for unit, expected := range map[string]string{"Tablespoons": "tbsp", "tsp.": "tsp", " Fluid Ounces ": "fl oz", "Kilo": "kg"} {
+		if normalized, ok := Normalize(unit); !ok || normalized != expected {
+			t.Errorf("%q: expected %q, got %q", unit, expected, normalized)
+		}
+	}
+
+	if _, ok := Normalize("large"); ok {
+		t.Errorf("expected words that are no unit to be rejected")
+	}
+}
+
+func TestGrams(t *testing.T) {
+	tests := []struct {
+		amount     Amount
+		ingredient string
+		expected   float64
+		ok         bool
+	}{
+		{Amount{1.5, "kg"}, "potatoes", 1500, true},
+		{Amount{2, "cup"}, "all-purpose flour", 250.78, true},
+		{Amount{1, "tbsp"}, "extra virgin olive oil", 13.46, true},
+		{Amount{1, "cup"}, "chopped parsley", 0, false},
+		{Amount{2, "clove"}, "garlic", 0, false},
+		{Amount{1, "cup"}, "rice vinegar", 238.95, true},
+		{Amount{1, "tsp"}, "red pepper flakes", 1.48, true},
+		{Amount{1, "cup"}, "sugar snap peas", 0, false},
+		{Amount{1, "cup"}, "butter beans", 0, false},
+		{Amount{1, "cup"}, "water chestnuts", 0, false},
+		{Amount{1, "cup"}, "chopped milk chocolate", 0, false},
+		{Amount{1, "cup"}, "milk chocolate chips", 167.98, true},
+	}
+
+	for _, test := range tests {
+		grams, ok := Grams(test.amount, test.ingredient)
+		if ok != test.ok || math.Abs(grams-test.expected) > 0.01 {
+			t.Errorf("%v %s: expected %v, got %v", test.amount, test.ingredient, test.expected, grams)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount     Amount
+		system     System
+		ingredient string
+		expected   Amount
+	}{
+		{Amount{0.3334, "cup"}, AsWritten, "milk", Amount{1.0 / 3, "cup"}},
+		{Amount{1.6666, "egg"}, AsWritten, "eggs", Amount{1.75, "egg"}},
+		{Amount{0.01, ""}, AsWritten, "eggs", Amount{0.25, ""}},
+		{Amount{2, "cup"}, Metric, "milk", Amount{475, "ml"}},
+		{Amount{2, "cup"}, Metric, "flour", Amount{250, "g"}},
+		{Amount{3, "tbsp"}, Metric, "flour", Amount{3, "tbsp"}},
+		{Amount{6, "cup"}, Metric, "water", Amount{1.4, "l"}},
+		{Amount{1250, "g"}, Metric, "potatoes", Amount{1.25, "kg"}},
+		{Amount{0.1, "g"}, Metric, "saffron", Amount{0.1, "g"}},
+		{Amount{0.1234, "g"}, Metric, "saffron", Amount{0.12, "g"}},
+		{Amount{0.0456, "ml"}, AsWritten, "rose water", Amount{0.046, "ml"}},
+		{Amount{0.996, "g"}, Metric, "saffron", Amount{1, "g"}},
+		{Amount{2.3, "g"}, Metric, "yeast", Amount{2.5, "g"}},
+		{Amount{10, "ml"}, Metric, "vanilla extract", Amount{2, "tsp"}},
+		{Amount{250, "g"}, Imperial, "flour", Amount{2, "cup"}},
+		{Amount{500, "g"}, Imperial, "ground beef", Amount{1, "lb"}},
+		{Amount{200, "g"}, Imperial, "ground beef", Amount{7, "oz"}},
+		{Amount{1.5, "l"}, Imperial, "water", Amount{1.5, "quart"}},
+		{Amount{30, "ml"}, Imperial, "soy sauce", Amount{2, "tbsp"}},
+		{Amount{2, "can"}, Imperial, "tomatoes", Amount{2, "can"}},
+		{Amount{500, "g"}, Imperial, "red pepper", Amount{1, "lb"}},
+		{Amount{1, "cup"}, Metric, "rice vinegar", Amount{235, "ml"}},
+	}
+
+	for _, test := range tests {
+		if converted := Convert(test.amount, test.system, test.ingredient); converted != test.expected {
+			t.Errorf("%v %s in %q: expected %v, got %v", test.amount, test.ingredient, test.system, test.expected, converted)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	for amount, expected := range map[Amount]string{
+		{1.5, "cup"}:     "1½ cup",
+		{1.0 / 3, "tsp"}: "⅓ tsp",
+		{2, ""}:          "2",
+		{1.25, "kg"}:     "1.25 kg",
+		{0.7, "can"}:     "0.7 can",
+		{0.12, "g"}:      "0.12 g",
+		{0.046, "ml"}:    "0.046 ml",
+		{0, "pinch"}:     "pinch",
+	} {
+		if text := amount.String(); text != expected {
+			t.Errorf("%#v: expected %q, got %q", amount, expected, text)
+		}
+	}
+}
+
+func TestTotal(t *testing.T) {
+	total := Total([]Amount{{200, "g"}, {1, "cup"}, {0, ""}, {2, "can"}, {1, "can"}, {3, ""}}, "flour")
+
+	expected := []Amount{{200 + 236.5882365*0.53, "g"}, {3, "can"}, {3, ""}}
+	if !slices.Equal(total, expected) {
+		t.Errorf("expected %v, got %v", expected, total)
+	}
+
+	total = Total([]Amount{{200, "g"}, {1, "cup"}}, "chopped parsley")
+	if !slices.Equal(total, []Amount{{200, "g"}, {236.5882365, "ml"}}) {
+		t.Errorf("expected weights and volumes apart without a density, got %v", total)
+	}
+}